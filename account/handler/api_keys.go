@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// APIKeys handler lists the user's API keys. Only their
+// prefixes are listed, as the keys themselves are not stored.
+func (h *Handler) APIKeys(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	ctx := context.Request.Context()
+	apiKeys, err := h.APIKeyService.List(ctx, authUser.UserID)
+
+	if err != nil {
+		log.Printf("Failed to get API keys for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"apiKeys": apiKeys,
+	})
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockAPIKeyService *mocks.MockAPIKeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:        router,
+			APIKeyService: mockAPIKeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		keyID, _ := uuid.NewRandom()
+		mockAPIKeys := []*model.APIKey{
+			{
+				KeyID:     keyID,
+				UserID:    userID,
+				Name:      "CI",
+				Prefix:    "bgo_AbCdEfGh",
+				KeyHash:   "somehash",
+				Scopes:    []string{model.APIKeyScopeRead},
+				CreatedAt: time.Now().UTC(),
+			},
+		}
+
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+		mockAPIKeyService.On("List", mock.Anything, userID).Return(mockAPIKeys, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/api-keys", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"apiKeys": mockAPIKeys,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		assert.NotContains(t, responseRecorder.Body.String(), "somehash")
+		mockAPIKeyService.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+		mockAPIKeyService.On("List", mock.Anything, userID).Return(nil, apperrors.NewInternal())
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/api-keys", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+		mockAPIKeyService.AssertExpectations(t)
+	})
+}
@@ -39,9 +39,13 @@ func bindData(context *gin.Context, request interface{}) bool {
 			var invalidArgs []invalidArgument
 
 			for _, err := range errs {
+				// Only string values are echoed, as objects like
+				// missing credentials have no value to show.
+				value, _ := err.Value().(string)
+
 				invalidArgs = append(invalidArgs, invalidArgument{
 					err.Field(),
-					err.Value().(string),
+					value,
 					err.Tag(),
 					err.Param(),
 				})
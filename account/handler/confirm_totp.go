@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required,max=40"`
+}
+
+// ConfirmTOTP handler enables TOTP with a code of the enrolled secret.
+// The recovery codes are only in this response, so the user must save them now.
+func (h *Handler) ConfirmTOTP(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	var request totpCodeRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	recoveryCodes, err := h.MFAService.ConfirmTOTP(context.Request.Context(), authUser.UserID, request.Code)
+
+	if err != nil {
+		log.Printf("Failed to confirm TOTP of the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"recoveryCodes": recoveryCodes,
+	})
+}
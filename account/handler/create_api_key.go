@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// Omitempty must be listed first (tags evaluated sequentially, it seems).
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=40"`
+	Scopes    []string   `json:"scopes" binding:"omitempty,dive,oneof=read write"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateAPIKey handler creates a named API key for the user.
+// The key is only in this response, so the user must copy it now.
+func (h *Handler) CreateAPIKey(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	var request createAPIKeyRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	apiKey, key, err := h.APIKeyService.Create(ctx, authUser.UserID, request.Name, request.Scopes, request.ExpiresAt)
+
+	if err != nil {
+		log.Printf("Failed to create an API key for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"apiKey": apiKey,
+		"key":    key,
+	})
+}
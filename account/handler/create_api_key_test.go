@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockAPIKeyService *mocks.MockAPIKeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:        router,
+			APIKeyService: mockAPIKeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		expiresAt := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+		mockAPIKey := &model.APIKey{
+			KeyID:     uuid.New(),
+			UserID:    userID,
+			Name:      "CI",
+			Prefix:    "bgo_AbCdEfGh",
+			Scopes:    []string{model.APIKeyScopeRead},
+			ExpiresAt: &expiresAt,
+		}
+
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+		mockAPIKeyService.On("Create", mock.Anything, userID, "CI", []string{model.APIKeyScopeRead}, mock.MatchedBy(func(value *time.Time) bool {
+			return value != nil && value.Equal(expiresAt)
+		})).Return(mockAPIKey, "bgo_AbCdEfGhsomesecret", nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"name":      "CI",
+			"scopes":    []string{model.APIKeyScopeRead},
+			"expiresAt": expiresAt,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/api-keys", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"apiKey": mockAPIKey,
+			"key":    "bgo_AbCdEfGhsomesecret",
+		})
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockAPIKeyService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+
+		for _, body := range []gin.H{
+			{"scopes": []string{model.APIKeyScopeRead}},
+			{"name": "CI", "scopes": []string{"admin"}},
+		} {
+			// A response recorder for getting written an http response.
+			responseRecorder := httptest.NewRecorder()
+			router := newRouter(mockAPIKeyService)
+
+			requestBody, _ := json.Marshal(body)
+
+			request, _ := http.NewRequest(http.MethodPost, "/api-keys", bytes.NewBuffer(requestBody))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(responseRecorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		}
+
+		mockAPIKeyService.AssertNotCalled(t, "Create")
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type createPasskeyRequest struct {
+	Name       string                   `json:"name" binding:"omitempty,max=40"`
+	Credential *model.PasskeyCredential `json:"credential" binding:"required"`
+}
+
+// CreatePasskey handler finishes the registration of a passkey with the
+// credential the authenticator created for the options of /passkeys/options.
+func (h *Handler) CreatePasskey(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	var request createPasskeyRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	passkey, err := h.PasskeyService.FinishRegistration(ctx, authUser.UserID, request.Name, request.Credential)
+
+	if err != nil {
+		log.Printf("Failed to register a passkey for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{
+		"passkey": passkey,
+	})
+}
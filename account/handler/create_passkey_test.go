@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestCreatePasskey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	credential := &model.PasskeyCredential{
+		ID:   "c29tZWNyZWRlbnRpYWw",
+		Type: "public-key",
+		Response: model.PasskeyCredentialResponse{
+			ClientDataJSON:    "e30",
+			AttestationObject: "oA",
+			Transports:        []string{"internal"},
+		},
+	}
+
+	newRouter := func(mockPasskeyService *mocks.MockPasskeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockPasskey := &model.Passkey{
+			PasskeyID:  uuid.New(),
+			UserID:     userID,
+			Name:       "Laptop",
+			Transports: []string{"internal"},
+		}
+
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("FinishRegistration", mock.Anything, userID, "Laptop", credential).Return(mockPasskey, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"name":       "Laptop",
+			"credential": credential,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/passkeys", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"passkey": mockPasskey,
+		})
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockPasskeyService.AssertExpectations(t)
+	})
+
+	t.Run("Credential which doesn't verify", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("FinishRegistration", mock.Anything, userID, "", credential).
+			Return(nil, apperrors.NewAuthorization("The passkey could not be verified"))
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"credential": credential,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/passkeys", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		mockPasskeyService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+
+		for _, body := range []gin.H{
+			{"name": "Laptop"},
+			{"credential": gin.H{"id": "c29tZWNyZWRlbnRpYWw", "type": "password", "response": gin.H{"clientDataJSON": "e30"}}},
+			{"credential": gin.H{"id": "c29tZWNyZWRlbnRpYWw", "type": "public-key", "response": gin.H{}}},
+		} {
+			// A response recorder for getting written an http response.
+			responseRecorder := httptest.NewRecorder()
+			router := newRouter(mockPasskeyService)
+
+			requestBody, _ := json.Marshal(body)
+
+			request, _ := http.NewRequest(http.MethodPost, "/passkeys", bytes.NewBuffer(requestBody))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(responseRecorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		}
+
+		mockPasskeyService.AssertNotCalled(t, "FinishRegistration")
+	})
+}
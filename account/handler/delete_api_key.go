@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// DeleteAPIKey handler revokes one of the user's API keys.
+func (h *Handler) DeleteAPIKey(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	keyID, err := uuid.Parse(context.Param("id"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("API key id must be a valid uuid")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := context.Request.Context()
+	err = h.APIKeyService.Revoke(ctx, authUser.UserID, keyID)
+
+	if err != nil {
+		log.Printf("Failed to revoke the API key: %v. Error: %v\n", keyID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "the API key was revoked successfully!",
+	})
+}
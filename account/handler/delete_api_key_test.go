@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestDeleteAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockAPIKeyService *mocks.MockAPIKeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:        router,
+			APIKeyService: mockAPIKeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		keyID, _ := uuid.NewRandom()
+
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+		mockAPIKeyService.On("Revoke", mock.Anything, userID, keyID).Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/api-keys/"+keyID.String(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockAPIKeyService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid key id", func(t *testing.T) {
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/api-keys/notauuid", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockAPIKeyService.AssertNotCalled(t, "Revoke")
+	})
+
+	t.Run("Key of another user", func(t *testing.T) {
+		keyID, _ := uuid.NewRandom()
+
+		mockAPIKeyService := new(mocks.MockAPIKeyService)
+		mockAPIKeyService.On("Revoke", mock.Anything, userID, keyID).Return(apperrors.NewNotFound("apiKey", keyID.String()))
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockAPIKeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/api-keys/"+keyID.String(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+		mockAPIKeyService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// DeletePasskey handler removes one of the user's passkeys.
+func (h *Handler) DeletePasskey(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	passkeyID, err := uuid.Parse(context.Param("id"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("Passkey id must be a valid uuid")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := context.Request.Context()
+	err = h.PasskeyService.Delete(ctx, authUser.UserID, passkeyID)
+
+	if err != nil {
+		log.Printf("Failed to delete the passkey: %v. Error: %v\n", passkeyID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "the passkey was deleted successfully!",
+	})
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestDeletePasskey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockPasskeyService *mocks.MockPasskeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		passkeyID, _ := uuid.NewRandom()
+
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("Delete", mock.Anything, userID, passkeyID).Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/passkeys/"+passkeyID.String(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockPasskeyService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid passkey id", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/passkeys/notauuid", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockPasskeyService.AssertNotCalled(t, "Delete")
+	})
+
+	t.Run("Passkey of another user", func(t *testing.T) {
+		passkeyID, _ := uuid.NewRandom()
+
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("Delete", mock.Anything, userID, passkeyID).Return(apperrors.NewNotFound("passkey", passkeyID.String()))
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/passkeys/"+passkeyID.String(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+		mockPasskeyService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// DeleteSession handler signs the user out of a single device.
+func (h *Handler) DeleteSession(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	sessionID, err := uuid.Parse(context.Param("id"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("Session id must be a valid uuid")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	ctx := context.Request.Context()
+	err = h.TokenService.DeleteSession(ctx, authUser.UserID, sessionID)
+
+	if err != nil {
+		log.Printf("Failed to delete the session: %v. Error: %v\n", sessionID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "the session signed out successfully!",
+	})
+}
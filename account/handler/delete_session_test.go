@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestDeleteSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockTokenService *mocks.MockTokenService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		sessionID, _ := uuid.NewRandom()
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("DeleteSession", mock.Anything, userID, sessionID).Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockTokenService)
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/sessions/%s", sessionID), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"message": "the session signed out successfully!",
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid session id", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockTokenService)
+
+		request, _ := http.NewRequest(http.MethodDelete, "/sessions/not-a-uuid", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockTokenService.AssertNotCalled(t, "DeleteSession")
+	})
+
+	t.Run("Session not found", func(t *testing.T) {
+		sessionID, _ := uuid.NewRandom()
+		mockError := apperrors.NewNotFound("session", sessionID.String())
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("DeleteSession", mock.Anything, userID, sessionID).Return(mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockTokenService)
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/sessions/%s", sessionID), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+}
@@ -35,6 +35,30 @@ func (h *Handler) Details(context *gin.Context) {
 	}
 
 	ctx := context.Request.Context()
+
+	// The email signs the user in and gets the password reset links,
+	// so an API key may only change the rest of the profile.
+	if _, exists := context.Get("apiKey"); exists {
+		current, err := h.UserService.Get(ctx, authUser.UserID)
+
+		if err != nil {
+			log.Printf("Unable to find user: %v\n%v", authUser.UserID, err)
+
+			context.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		if request.Email != current.Email {
+			err := apperrors.NewForbidden("API keys can't change the email. Sign in instead")
+			context.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+	}
+
 	err := h.UserService.UpdateDetails(ctx, user)
 
 	if err != nil {
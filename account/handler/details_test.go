@@ -143,4 +143,58 @@ func TestDetails(t *testing.T) {
 		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
 		mockUserService.AssertCalled(t, "UpdateDetails", updateArguments...)
 	})
+	t.Run("API key", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+			context.Set("apiKey", &model.APIKey{UserID: userID})
+		})
+
+		NewHandler(&Config{
+			Router:      router,
+			UserService: mockUserService,
+		})
+
+		currentUser := &model.User{
+			UserID: userID,
+			Email:  "kostya@kostya.com",
+		}
+
+		mockUserService.On("Get", mock.Anything, userID).Return(currentUser, nil)
+		mockUserService.On("UpdateDetails", mock.Anything, mock.AnythingOfType("*model.User")).Return(nil)
+
+		// The key can't change the email.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, _ := json.Marshal(gin.H{
+			"username": "Constantine",
+			"email":    "constantine@constantine.com",
+		})
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+		mockUserService.AssertNotCalled(t, "UpdateDetails", mock.Anything, mock.Anything)
+
+		// The rest of the profile it can.
+		responseRecorder = httptest.NewRecorder()
+
+		requestBody, _ = json.Marshal(gin.H{
+			"username": "Constantine",
+			"email":    currentUser.Email,
+		})
+
+		request, _ = http.NewRequest(http.MethodPut, "/details", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockUserService.AssertNumberOfCalls(t, "UpdateDetails", 1)
+	})
 }
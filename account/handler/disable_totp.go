@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// DisableTOTP handler disables TOTP of the user,
+// who proves to still have a TOTP or recovery code.
+func (h *Handler) DisableTOTP(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	var request totpCodeRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	if err := h.MFAService.DisableTOTP(context.Request.Context(), authUser.UserID, request.Code); err != nil {
+		log.Printf("Failed to disable TOTP of the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "TOTP was disabled successfully!",
+	})
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestDisableTOTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	newRouter := func(mockMFAService *mocks.MockMFAService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", &model.User{UserID: userID})
+		})
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("DisableTOTP", mock.Anything, userID, "123456").Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockMFAService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"code": "123456",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/mfa/totp", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockMFAService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockError := apperrors.NewAuthorization("Invalid code")
+
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("DisableTOTP", mock.Anything, userID, "654321").Return(mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockMFAService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"code": "654321",
+		})
+
+		request, _ := http.NewRequest(http.MethodDelete, "/mfa/totp", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+}
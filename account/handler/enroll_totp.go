@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// EnrollTOTP handler creates a TOTP secret for the user. The frontend
+// shows the provisioning URI as a QR code for the authenticator app.
+// TOTP is enabled once the user confirms it with a code.
+func (h *Handler) EnrollTOTP(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	enrollment, err := h.MFAService.EnrollTOTP(context.Request.Context(), authUser.UserID)
+
+	if err != nil {
+		log.Printf("Failed to enroll the user: %v in TOTP. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"totp": enrollment,
+	})
+}
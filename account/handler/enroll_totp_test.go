@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestEnrollTOTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	newRouter := func(mockMFAService *mocks.MockMFAService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", &model.User{UserID: userID})
+		})
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockEnrollment := &model.TOTPEnrollment{
+			Secret: "JBSWY3DPEHPK3PXP",
+			URI:    "otpauth://totp/base-go:kostya@kostya.com?secret=JBSWY3DPEHPK3PXP",
+		}
+
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("EnrollTOTP", mock.Anything, userID).Return(mockEnrollment, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockMFAService)
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/totp", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"totp": mockEnrollment,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockMFAService.AssertExpectations(t)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		mockError := apperrors.NewBadRequest("TOTP is already enabled. Disable it before enrolling again")
+
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("EnrollTOTP", mock.Anything, userID).Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockMFAService)
+
+		request, _ := http.NewRequest(http.MethodPost, "/mfa/totp", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+}
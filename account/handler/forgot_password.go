@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword handler mails a password reset link to the user.
+// The response is the same whether or not the email is registered.
+func (h *Handler) ForgotPassword(context *gin.Context) {
+	var request forgotPasswordRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+
+	if err := h.UserService.ForgotPassword(ctx, request.Email); err != nil {
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "if the email is registered, a link to reset the password was sent to it",
+	})
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,9 +13,16 @@ import (
 
 // Handler struct holds required services for handler to function.
 type Handler struct {
-	UserService  model.UserService
-	TokenService model.TokenService
-	MaxBodyBytes int64
+	UserService    model.UserService
+	TokenService   model.TokenService
+	OAuthService   model.OAuthService
+	APIKeyService  model.APIKeyService
+	MFAService     model.MFAService
+	PasskeyService model.PasskeyService
+	SessionCookie  *SessionCookieConfig
+	MaxBodyBytes   int64
+	RateLimiter    model.RateLimiter
+	RateLimits     map[string]*RouteRateLimit
 }
 
 // Config will hold services that will eventually be injected into this
@@ -22,42 +31,123 @@ type Config struct {
 	Router          *gin.Engine
 	UserService     model.UserService
 	TokenService    model.TokenService
+	OAuthService    model.OAuthService
+	APIKeyService   model.APIKeyService
+	MFAService      model.MFAService
+	PasskeyService  model.PasskeyService
+	SessionCookie   *SessionCookieConfig // Nil keeps the refresh token in the response body.
 	BaseURL         string
 	TimeoutDuration time.Duration
 	MaxBodyBytes    int64
+	RateLimiter     model.RateLimiter
+	RateLimits      map[string]*RouteRateLimit // By "METHOD /path" of the routes. Routes without one aren't limited.
 }
 
 // NewHandler initializes the handler with required injected services along with http routes.
 // Does not return as it deals directly with a reference to the gin Engine.
+// It panics on a rate limit of a route it doesn't register, like gin does on
+// a route registered twice, so a mistyped route isn't silently left unlimited.
 func NewHandler(c *Config) {
 	// Create a handler (with injected services).
 	h := &Handler{
-		UserService:  c.UserService,
-		TokenService: c.TokenService,
-		MaxBodyBytes: c.MaxBodyBytes,
+		UserService:    c.UserService,
+		TokenService:   c.TokenService,
+		OAuthService:   c.OAuthService,
+		APIKeyService:  c.APIKeyService,
+		MFAService:     c.MFAService,
+		PasskeyService: c.PasskeyService,
+		SessionCookie:  c.SessionCookie,
+		MaxBodyBytes:   c.MaxBodyBytes,
+		RateLimiter:    c.RateLimiter,
+		RateLimits:     c.RateLimits,
 	} // Currently has no properties.
 
 	// Create an account group.
 	g := c.Router.Group(c.BaseURL)
 
+	if h.SessionCookie != nil {
+		g.Use(middleware.CSRF(refreshTokenCookieName, csrfTokenCookieName, csrfTokenHeaderName))
+	}
+
+	// handle registers a route with its rate limit, if it has one.
+	routes := make(map[string]bool)
+	handle := func(method string, path string, handlers ...gin.HandlerFunc) {
+		routes[fmt.Sprintf("%s %s", method, path)] = true
+		g.Handle(method, path, h.limited(method, path, handlers)...)
+	}
+
 	if gin.Mode() != gin.TestMode {
 		g.Use(middleware.Timeout(c.TimeoutDuration, apperrors.NewServiceUnavailable()))
-		g.GET("/me", middleware.AuthUser(h.TokenService), h.Me)
-		g.POST("/signout", middleware.AuthUser(h.TokenService), h.SignOut)
-		g.PUT("/details", middleware.AuthUser(h.TokenService), h.Details)
-		g.POST("/image", middleware.AuthUser(h.TokenService), h.Image)
-		g.DELETE("/image", middleware.AuthUser(h.TokenService), h.DeleteImage)
+		handle(http.MethodGet, "/me", middleware.AuthUser(h.TokenService), h.Me)
+		handle(http.MethodPost, "/signout", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.SignOut)
+		handle(http.MethodGet, "/sessions", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.Sessions)
+		handle(http.MethodDelete, "/sessions/:id", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.DeleteSession)
+		handle(http.MethodGet, "/api-keys", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.APIKeys)
+		handle(http.MethodPost, "/api-keys", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.CreateAPIKey)
+		handle(http.MethodDelete, "/api-keys/:id", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.DeleteAPIKey)
+		handle(http.MethodPut, "/details", middleware.AuthUser(h.TokenService), h.Details)
+		handle(http.MethodPut, "/password", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.Password)
+		handle(http.MethodPost, "/image", middleware.AuthUser(h.TokenService), h.Image)
+		handle(http.MethodDelete, "/image", middleware.AuthUser(h.TokenService), h.DeleteImage)
+		handle(http.MethodPost, "/mfa/totp", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.EnrollTOTP)
+		handle(http.MethodPost, "/mfa/totp/confirm", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.ConfirmTOTP)
+		handle(http.MethodDelete, "/mfa/totp", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.DisableTOTP)
+		handle(http.MethodGet, "/passkeys", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.Passkeys)
+		handle(http.MethodPost, "/passkeys/options", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.PasskeyOptions)
+		handle(http.MethodPost, "/passkeys", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.CreatePasskey)
+		handle(http.MethodDelete, "/passkeys/:id", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.DeletePasskey)
+		handle(http.MethodGet, "/oauth/authorize", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.OAuthAuthorize)
+		handle(http.MethodPost, "/oauth/authorize", middleware.AuthUser(h.TokenService), middleware.NoAPIKey(), h.OAuthConsent)
+		handle(http.MethodDelete, "/users/:id/signin-lock", middleware.AuthUser(h.TokenService, adminScope), h.UnlockSignIn)
 
 	} else {
-		g.GET("/me", h.Me)
-		g.POST("/signout", h.SignOut)
-		g.PUT("/details", h.Details)
-		g.POST("/image", h.Image)
-		g.DELETE("/image", h.DeleteImage)
+		handle(http.MethodGet, "/me", h.Me)
+		handle(http.MethodPost, "/signout", middleware.NoAPIKey(), h.SignOut)
+		handle(http.MethodGet, "/sessions", middleware.NoAPIKey(), h.Sessions)
+		handle(http.MethodDelete, "/sessions/:id", middleware.NoAPIKey(), h.DeleteSession)
+		handle(http.MethodGet, "/api-keys", middleware.NoAPIKey(), h.APIKeys)
+		handle(http.MethodPost, "/api-keys", middleware.NoAPIKey(), h.CreateAPIKey)
+		handle(http.MethodDelete, "/api-keys/:id", middleware.NoAPIKey(), h.DeleteAPIKey)
+		handle(http.MethodPut, "/details", h.Details)
+		handle(http.MethodPut, "/password", middleware.NoAPIKey(), h.Password)
+		handle(http.MethodPost, "/image", h.Image)
+		handle(http.MethodDelete, "/image", h.DeleteImage)
+		handle(http.MethodPost, "/mfa/totp", middleware.NoAPIKey(), h.EnrollTOTP)
+		handle(http.MethodPost, "/mfa/totp/confirm", middleware.NoAPIKey(), h.ConfirmTOTP)
+		handle(http.MethodDelete, "/mfa/totp", middleware.NoAPIKey(), h.DisableTOTP)
+		handle(http.MethodGet, "/passkeys", middleware.NoAPIKey(), h.Passkeys)
+		handle(http.MethodPost, "/passkeys/options", middleware.NoAPIKey(), h.PasskeyOptions)
+		handle(http.MethodPost, "/passkeys", middleware.NoAPIKey(), h.CreatePasskey)
+		handle(http.MethodDelete, "/passkeys/:id", middleware.NoAPIKey(), h.DeletePasskey)
+		handle(http.MethodGet, "/oauth/authorize", middleware.NoAPIKey(), h.OAuthAuthorize)
+		handle(http.MethodPost, "/oauth/authorize", middleware.NoAPIKey(), h.OAuthConsent)
+		handle(http.MethodDelete, "/users/:id/signin-lock", h.UnlockSignIn)
 
 	}
 
-	g.POST("/signup", h.SignUp)
-	g.POST("/signin", h.SignIn)
-	g.POST("/tokens", h.Tokens)
+	handle(http.MethodGet, "/.well-known/jwks.json", h.JWKS)
+	handle(http.MethodGet, "/.well-known/openid-configuration", h.OpenIDConfiguration)
+	handle(http.MethodPost, "/signup", h.SignUp)
+	handle(http.MethodPost, "/signin", h.SignIn)
+	handle(http.MethodPost, "/signin/mfa", h.SignInMFA)
+	handle(http.MethodPost, "/signin/mfa/passkey/options", h.SignInMFAPasskeyOptions)
+	handle(http.MethodPost, "/signin/passkey/options", h.SignInPasskeyOptions)
+	handle(http.MethodPost, "/signin/passkey", h.SignInPasskey)
+	handle(http.MethodPost, "/signin/link", h.SignInLink)
+	handle(http.MethodPost, "/signin/link/verify", h.SignInLinkVerify)
+	handle(http.MethodPost, "/tokens", h.Tokens)
+	handle(http.MethodPost, "/password/forgot", h.ForgotPassword)
+	handle(http.MethodPost, "/password/reset", h.ResetPassword)
+	handle(http.MethodPost, "/email/verify", h.VerifyEmail)
+	handle(http.MethodPost, "/oauth/token", h.OAuthToken)
+	handle(http.MethodPost, "/oauth/revoke", h.OAuthRevoke)
+	handle(http.MethodPost, "/oauth/introspect", h.OAuthIntrospect)
+	handle(http.MethodGet, "/userinfo", h.UserInfo)
+	handle(http.MethodPost, "/userinfo", h.UserInfo)
+
+	for route := range h.RateLimits {
+		if !routes[route] {
+			panic(fmt.Sprintf("rate limit of the route %s, which is not registered", route))
+		}
+	}
 }
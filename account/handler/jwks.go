@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS handler serves the public keys used to verify ID tokens,
+// so other services do not need a copy of our PEM files.
+func (h *Handler) JWKS(context *gin.Context) {
+	// Let clients cache the key set for a while. Upcoming keys are
+	// published before they are used for signing.
+	context.Header("Cache-Control", "public, max-age=300")
+
+	context.JSON(http.StatusOK, h.TokenService.JWKS())
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		mockKeySet := &model.JSONWebKeySet{
+			Keys: []model.JSONWebKey{
+				{
+					KeyType:   "RSA",
+					KeyID:     "someKeyID",
+					Use:       "sig",
+					Algorithm: "RS256",
+					N:         "someModulus",
+					E:         "AQAB",
+				},
+			},
+		}
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("JWKS").Return(mockKeySet)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockKeySet)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		assert.NotEmpty(t, responseRecorder.Header().Get("Cache-Control"))
+		mockTokenService.AssertExpectations(t)
+	})
+}
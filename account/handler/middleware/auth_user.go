@@ -24,7 +24,12 @@ type invalidArgument struct {
 // AuthUser extracts a user from the Authorization header
 // which is of the form "Bearer token".
 // It sets the user to the context if the user exists.
-func AuthUser(s model.TokenService) gin.HandlerFunc {
+// Routes which machine clients may call list the scopes the clients need.
+// Access tokens of such clients are accepted too, and the *model.ServicePrincipal
+// is set to the context as "service" instead of a "user", so handlers can
+// tell the two kinds of caller apart.
+// Users can also authenticate with one of their API keys instead of an ID token.
+func AuthUser(s model.TokenService, serviceScopes ...string) gin.HandlerFunc {
 	return func(context *gin.Context) {
 		h := authHeader{}
 
@@ -76,8 +81,20 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(idTokenHeader[1], model.APIKeyPrefix) {
+			authAPIKey(context, s, idTokenHeader[1])
+			return
+		}
+
 		// Validate ID token here.
-		user, err := s.ValidateIDToken(idTokenHeader[1])
+		user, err := s.ValidateIDToken(context.Request.Context(), idTokenHeader[1])
+
+		if err != nil && len(serviceScopes) > 0 {
+			if principal, err := s.ValidateServiceToken(context.Request.Context(), idTokenHeader[1]); err == nil {
+				authService(context, principal, serviceScopes)
+				return
+			}
+		}
 
 		if err != nil {
 			err := apperrors.NewAuthorization("Provided token is invalid")
@@ -89,6 +106,70 @@ func AuthUser(s model.TokenService) gin.HandlerFunc {
 		}
 
 		context.Set("user", user)
+		context.Set("idToken", idTokenHeader[1]) // Lets handlers revoke the token.
+
+		context.Next()
+	}
+}
+
+// authService lets a machine client through if it was granted the scopes.
+func authService(context *gin.Context, principal *model.ServicePrincipal, scopes []string) {
+	if !principal.HasScopes(scopes...) {
+		err := apperrors.NewForbidden("The client was not granted the scopes: " + strings.Join(scopes, " "))
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		context.Abort()
+		return
+	}
+
+	context.Set("service", principal)
+
+	context.Next()
+}
+
+// authAPIKey sets the owner of an API key to the context if the key is
+// valid and its scopes allow the request. The key is set as "apiKey".
+func authAPIKey(context *gin.Context, s model.TokenService, key string) {
+	apiKey, err := s.ValidateAPIKey(context.Request.Context(), key)
+
+	if err != nil {
+		err := apperrors.NewAuthorization("Provided API key is invalid")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		context.Abort()
+		return
+	}
+
+	if !apiKey.AllowsMethod(context.Request.Method) {
+		err := apperrors.NewForbidden("The API key's scopes don't allow the request")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		context.Abort()
+		return
+	}
+
+	context.Set("user", &model.User{UserID: apiKey.UserID})
+	context.Set("apiKey", apiKey)
+
+	context.Next()
+}
+
+// NoAPIKey refuses requests authenticated with an API key. It follows AuthUser
+// on routes which manage credentials and sessions, so a leaked key can't be
+// turned into a takeover of the account.
+func NoAPIKey() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		if _, exists := context.Get("apiKey"); exists {
+			err := apperrors.NewForbidden("API keys can't manage credentials or sessions. Sign in instead")
+			context.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			context.Abort()
+			return
+		}
 
 		context.Next()
 	}
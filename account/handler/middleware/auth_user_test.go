@@ -1,17 +1,23 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/jwa"
 	"github.com/yachnytskyi/base-go/account/model"
 	"github.com/yachnytskyi/base-go/account/model/apperrors"
 	"github.com/yachnytskyi/base-go/account/model/mocks"
+	"github.com/yachnytskyi/base-go/account/service"
 )
 
 func TestAuthUser(t *testing.T) {
@@ -31,8 +37,8 @@ func TestAuthUser(t *testing.T) {
 	invalidTokenHeader := "invalidTokenString"
 	invalidTokenError := apperrors.NewAuthorization("Unable to verify the user from idToken")
 
-	mockTokenService.On("ValidateIDToken", validTokenHeader).Return(user, nil)
-	mockTokenService.On("ValidateIDToken", invalidTokenHeader).Return(nil, invalidTokenError)
+	mockTokenService.On("ValidateIDToken", mock.Anything, validTokenHeader).Return(user, nil)
+	mockTokenService.On("ValidateIDToken", mock.Anything, invalidTokenHeader).Return(nil, invalidTokenError)
 
 	t.Run("Adds a user to context", func(t *testing.T) {
 		responseRecorder := httptest.NewRecorder()
@@ -43,6 +49,7 @@ func TestAuthUser(t *testing.T) {
 		// Will be populated with a user in a handler
 		// if AuthUser middleware is successful.
 		var contextUser *model.User
+		var contextIDToken string
 
 		// See this issue - https://github.com/gin-gonic/gin/issues/323
 		// https://github.com/gin-gonic/gin/blob/master/auth_test.go#L91-L126
@@ -51,6 +58,7 @@ func TestAuthUser(t *testing.T) {
 		testContext.GET("/me", AuthUser(mockTokenService), func(context *gin.Context) {
 			contextKeyValue, _ := context.Get("user")
 			contextUser = contextKeyValue.(*model.User)
+			contextIDToken = context.GetString("idToken")
 		})
 
 		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
@@ -60,8 +68,9 @@ func TestAuthUser(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, responseRecorder.Code)
 		assert.Equal(t, user, contextUser)
+		assert.Equal(t, validTokenHeader, contextIDToken)
 
-		mockTokenService.AssertCalled(t, "ValidateIDToken", validTokenHeader)
+		mockTokenService.AssertCalled(t, "ValidateIDToken", mock.Anything, validTokenHeader)
 
 	})
 
@@ -78,7 +87,7 @@ func TestAuthUser(t *testing.T) {
 		testContext.ServeHTTP(responseRecorder, request)
 
 		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
-		mockTokenService.AssertCalled(t, "ValidateIDToken", invalidTokenHeader)
+		mockTokenService.AssertCalled(t, "ValidateIDToken", mock.Anything, invalidTokenHeader)
 	})
 
 	t.Run("Missing Authorization Header", func(t *testing.T) {
@@ -97,3 +106,251 @@ func TestAuthUser(t *testing.T) {
 		mockTokenService.AssertNotCalled(t, "ValidateIDToken")
 	})
 }
+
+func TestAuthUserClientTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	private, _ := ioutil.ReadFile("../../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := service.NewKeyRing(jwa.RS256, privateKey)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("SetRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).Return(nil)
+	mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+	tokenService := service.NewTokenService(&service.TokenServiceConfig{
+		TokenRepository: mockTokenRepository,
+		KeyRing:         keyRing,
+		RefreshSecrets:  []string{"anothersomerandomtestsecret"},
+		Issuer:          "https://accounts.test",
+		Audience:        "web",
+	})
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	t.Run("Token of an OAuth client can't create API keys", func(t *testing.T) {
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, nil, &model.Session{
+			ClientID: "grafana",
+			Scope:    "openid email",
+		})
+		assert.NoError(t, err)
+
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+
+		handlerCalled := false
+		testContext.POST("/api-keys", AuthUser(tokenService), func(context *gin.Context) {
+			handlerCalled = true
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/api-keys", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenPair.IDToken.SignedString))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.False(t, handlerCalled)
+	})
+
+	t.Run("Token of our own sign in creates API keys", func(t *testing.T) {
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, nil, &model.Session{})
+		assert.NoError(t, err)
+
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+
+		handlerCalled := false
+		testContext.POST("/api-keys", AuthUser(tokenService), func(context *gin.Context) {
+			handlerCalled = true
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/api-keys", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tokenPair.IDToken.SignedString))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.True(t, handlerCalled)
+	})
+}
+
+func TestAuthUserServices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+
+	principal := &model.ServicePrincipal{
+		ClientID: "billing",
+		Scopes:   []string{"users:read"},
+	}
+
+	serviceTokenHeader := "serviceTokenString"
+	invalidTokenError := apperrors.NewAuthorization("Unable to verify the user from idToken")
+
+	mockTokenService.On("ValidateIDToken", mock.Anything, serviceTokenHeader).Return(nil, invalidTokenError)
+	mockTokenService.On("ValidateServiceToken", mock.Anything, serviceTokenHeader).Return(principal, nil)
+
+	t.Run("Adds a service principal to context", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+
+		var contextPrincipal *model.ServicePrincipal
+		var userExists bool
+
+		testContext.GET("/users", AuthUser(mockTokenService, "users:read"), func(context *gin.Context) {
+			contextKeyValue, _ := context.Get("service")
+			contextPrincipal = contextKeyValue.(*model.ServicePrincipal)
+			_, userExists = context.Get("user")
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/users", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceTokenHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, principal, contextPrincipal)
+		assert.False(t, userExists)
+	})
+
+	t.Run("Client without the scope", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+		testContext.DELETE("/users", AuthUser(mockTokenService, "users:write"))
+
+		request, _ := http.NewRequest(http.MethodDelete, "/users", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceTokenHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+	})
+
+	t.Run("Route for users only", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("ValidateIDToken", mock.Anything, serviceTokenHeader).Return(nil, invalidTokenError)
+
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+		testContext.GET("/me", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", serviceTokenHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		mockTokenService.AssertNotCalled(t, "ValidateServiceToken")
+	})
+}
+
+func TestAuthUserAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	keyID, _ := uuid.NewRandom()
+
+	readKeyHeader := model.APIKeyPrefix + "readkey"
+	invalidKeyHeader := model.APIKeyPrefix + "invalidkey"
+
+	readKey := &model.APIKey{
+		KeyID:  keyID,
+		UserID: userID,
+		Scopes: []string{model.APIKeyScopeRead},
+	}
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockTokenService.On("ValidateAPIKey", mock.Anything, readKeyHeader).Return(readKey, nil)
+	mockTokenService.On("ValidateAPIKey", mock.Anything, invalidKeyHeader).Return(nil, apperrors.NewAuthorization("The API key is invalid"))
+
+	t.Run("Adds the owner of the key to context", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+
+		var contextUser *model.User
+		var contextAPIKey *model.APIKey
+
+		testContext.GET("/me", AuthUser(mockTokenService), func(context *gin.Context) {
+			contextKeyValue, _ := context.Get("user")
+			contextUser = contextKeyValue.(*model.User)
+			contextKeyValue, _ = context.Get("apiKey")
+			contextAPIKey = contextKeyValue.(*model.APIKey)
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", readKeyHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, userID, contextUser.UserID)
+		assert.Equal(t, readKey, contextAPIKey)
+		mockTokenService.AssertNotCalled(t, "ValidateIDToken")
+	})
+
+	t.Run("Read only key can't change anything", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+		testContext.PUT("/details", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodPut, "/details", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", readKeyHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+	})
+
+	t.Run("Key can't manage credentials", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+
+		handlerCalled := false
+		testContext.GET("/api-keys", AuthUser(mockTokenService), NoAPIKey(), func(context *gin.Context) {
+			handlerCalled = true
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/api-keys", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", readKeyHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+		assert.False(t, handlerCalled)
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, testContext := gin.CreateTestContext(responseRecorder)
+		testContext.GET("/me", AuthUser(mockTokenService))
+
+		request, _ := http.NewRequest(http.MethodGet, "/me", http.NoBody)
+
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", invalidKeyHeader))
+		testContext.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	})
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// CSRF protects browser sessions with the double submit cookie pattern.
+// Requests which change something and carry the session cookie must send
+// the value of the CSRF cookie in the header. Other sites can make the
+// browser send the cookies, but they can't read them to set the header.
+// Requests without the session cookie, such as those of scripts and
+// machine clients, have nothing to forge and are let through.
+func CSRF(sessionCookie string, csrfCookie string, header string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		switch context.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			context.Next()
+			return
+		}
+
+		if _, err := context.Cookie(sessionCookie); err != nil {
+			context.Next()
+			return
+		}
+
+		csrfToken, err := context.Cookie(csrfCookie)
+
+		if err != nil || csrfToken == "" || subtle.ConstantTimeCompare([]byte(csrfToken), []byte(context.GetHeader(header))) != 1 {
+			err := apperrors.NewForbidden("Missing or invalid CSRF token")
+			context.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
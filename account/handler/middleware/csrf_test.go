@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	responseRecorder := httptest.NewRecorder()
+
+	// Creates a test context and gin engine.
+	_, router := gin.CreateTestContext(responseRecorder)
+	router.Use(CSRF("session", "csrf", "X-CSRF-Token"))
+	router.GET("/me")
+	router.PUT("/details")
+
+	serve := func(method string, path string, cookies map[string]string, header string) int {
+		responseRecorder := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(method, path, http.NoBody)
+
+		for name, value := range cookies {
+			request.AddCookie(&http.Cookie{Name: name, Value: value})
+		}
+
+		if header != "" {
+			request.Header.Set("X-CSRF-Token", header)
+		}
+
+		router.ServeHTTP(responseRecorder, request)
+
+		return responseRecorder.Code
+	}
+
+	sessionCookies := map[string]string{"session": "somerefreshtoken", "csrf": "somecsrftoken"}
+
+	t.Run("Matching token", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/details", sessionCookies, "somecsrftoken"))
+	})
+
+	t.Run("Safe request", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/me", sessionCookies, ""))
+	})
+
+	t.Run("Request without session cookie", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/details", nil, ""))
+	})
+
+	t.Run("Missing or wrong token", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/details", sessionCookies, ""))
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/details", sessionCookies, "anothertoken"))
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/details", map[string]string{"session": "somerefreshtoken"}, ""))
+	})
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// RateLimitKey returns the key the requests of a caller are counted by.
+type RateLimitKey func(context *gin.Context) string
+
+// ByIP counts requests by the IP address of the client.
+func ByIP(context *gin.Context) string {
+	return "ip:" + context.ClientIP()
+}
+
+// ByUser counts requests by the signed in user, and by
+// the IP address of callers who are not signed in.
+// AuthUser has to run before it to set the user.
+func ByUser(context *gin.Context) string {
+	if user, ok := context.Get("user"); ok {
+		return "user:" + user.(*model.User).UserID.String()
+	}
+
+	return ByIP(context)
+}
+
+// ByAPIKey counts requests by the API key they were made with,
+// and falls back to counting by user.
+func ByAPIKey(context *gin.Context) string {
+	if apiKey, ok := context.Get("apiKey"); ok {
+		return "apiKey:" + apiKey.(*model.APIKey).KeyID.String()
+	}
+
+	return ByUser(context)
+}
+
+// RateLimit counts the requests of each key against the policy, and
+// answers with 429 Too Many Requests once the limit is reached.
+// The RateLimit-* headers tell clients how much of the limit is left.
+// Requests are let through if they can't be counted.
+func RateLimit(limiter model.RateLimiter, policy *model.RateLimitPolicy, key RateLimitKey) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		result, err := limiter.Allow(context.Request.Context(), key(context), policy)
+
+		if err != nil {
+			log.Printf("Unable to count the request against the rate limit: %v. Error: %v\n", policy.Name, err)
+			context.Next()
+			return
+		}
+
+		context.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		context.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		context.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		context.Header("RateLimit-Policy", policy.String())
+
+		if !result.Allowed {
+			err := apperrors.NewTooManyRequests("Too many requests. Try again later", result.RetryAfter)
+
+			context.Header("Retry-After", strconv.Itoa(err.RetryAfter))
+			context.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			context.Abort()
+			return
+		}
+
+		context.Next()
+	}
+}
+
+// ceilSeconds rounds the duration up to whole seconds.
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policy := &model.RateLimitPolicy{
+		Name:      "POST /signup",
+		Algorithm: model.SlidingWindow,
+		Limit:     10,
+		Period:    time.Hour,
+	}
+
+	serve := func(mockRateLimiter *mocks.MockRateLimiter) *httptest.ResponseRecorder {
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context and gin engine.
+		_, router := gin.CreateTestContext(responseRecorder)
+		router.POST("/signup", RateLimit(mockRateLimiter, policy, ByIP), func(context *gin.Context) {
+			context.Status(http.StatusCreated)
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signup", http.NoBody)
+		request.RemoteAddr = "203.0.113.7:52000"
+		router.ServeHTTP(responseRecorder, request)
+
+		return responseRecorder
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		mockRateLimiter := new(mocks.MockRateLimiter)
+		mockRateLimiter.On("Allow", mock.Anything, "ip:203.0.113.7", policy).Return(&model.RateLimitResult{
+			Allowed:   true,
+			Remaining: 9,
+			Reset:     time.Hour,
+		}, nil)
+
+		responseRecorder := serve(mockRateLimiter)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		assert.Equal(t, "10", responseRecorder.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "9", responseRecorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "3600", responseRecorder.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "10;w=3600", responseRecorder.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, responseRecorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("Limit reached", func(t *testing.T) {
+		mockRateLimiter := new(mocks.MockRateLimiter)
+		mockRateLimiter.On("Allow", mock.Anything, "ip:203.0.113.7", policy).Return(&model.RateLimitResult{
+			Remaining:  0,
+			Reset:      time.Hour,
+			RetryAfter: 90*time.Second + time.Millisecond,
+		}, nil)
+
+		responseRecorder := serve(mockRateLimiter)
+
+		assert.Equal(t, http.StatusTooManyRequests, responseRecorder.Code)
+		assert.Equal(t, "0", responseRecorder.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "91", responseRecorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("Requests are let through if they can't be counted", func(t *testing.T) {
+		mockRateLimiter := new(mocks.MockRateLimiter)
+		mockRateLimiter.On("Allow", mock.Anything, "ip:203.0.113.7", policy).Return(nil, errors.New("some error"))
+
+		responseRecorder := serve(mockRateLimiter)
+
+		assert.Equal(t, http.StatusCreated, responseRecorder.Code)
+		assert.Empty(t, responseRecorder.Header().Get("RateLimit-Limit"))
+	})
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	keyID, _ := uuid.NewRandom()
+
+	newContext := func() *gin.Context {
+		context, _ := gin.CreateTestContext(httptest.NewRecorder())
+		context.Request, _ = http.NewRequest(http.MethodGet, "/me", http.NoBody)
+		context.Request.RemoteAddr = "203.0.113.7:52000"
+		return context
+	}
+
+	t.Run("Anonymous caller", func(t *testing.T) {
+		context := newContext()
+
+		assert.Equal(t, "ip:203.0.113.7", ByIP(context))
+		assert.Equal(t, "ip:203.0.113.7", ByUser(context))
+		assert.Equal(t, "ip:203.0.113.7", ByAPIKey(context))
+	})
+
+	t.Run("Signed in user", func(t *testing.T) {
+		context := newContext()
+		context.Set("user", &model.User{UserID: userID})
+
+		assert.Equal(t, "user:"+userID.String(), ByUser(context))
+		assert.Equal(t, "user:"+userID.String(), ByAPIKey(context))
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		context := newContext()
+		context.Set("user", &model.User{UserID: userID})
+		context.Set("apiKey", &model.APIKey{KeyID: keyID, UserID: userID})
+
+		assert.Equal(t, "user:"+userID.String(), ByUser(context))
+		assert.Equal(t, "apiKey:"+keyID.String(), ByAPIKey(context))
+	})
+}
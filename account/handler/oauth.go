@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// authenticateClient checks the client credentials with the OAuth service and returns the client.
+// It writes the error response and returns false if the client could not be authenticated.
+func (h *Handler) authenticateClient(context *gin.Context) (*model.OAuthClient, bool) {
+	clientID, clientSecret := clientCredentials(context)
+	ctx := context.Request.Context()
+
+	client, err := h.OAuthService.AuthenticateClient(ctx, clientID, clientSecret)
+
+	if err != nil {
+		oauthError(context, err)
+		return nil, false
+	}
+
+	return client, true
+}
+
+// clientCredentials reads the client credentials from the basic auth header,
+// or from the form body for clients which cannot send the header.
+// Public clients only send their client_id.
+func clientCredentials(context *gin.Context) (string, string) {
+	clientID, clientSecret, ok := context.Request.BasicAuth()
+
+	if !ok {
+		return context.PostForm("client_id"), context.PostForm("client_secret")
+	}
+
+	// RFC 6749 requires the credentials to be form encoded
+	// before they are put in the header.
+	clientID, _ = url.QueryUnescape(clientID)
+	clientSecret, _ = url.QueryUnescape(clientSecret)
+
+	return clientID, clientSecret
+}
+
+// oauthError writes an error in the format OAuth clients expect.
+// Errors which are not OAuth errors are reported as server_error.
+func oauthError(context *gin.Context, err error) {
+	var oauthErr *apperrors.OAuthError
+
+	if !errors.As(err, &oauthErr) {
+		oauthErr = apperrors.NewOAuthError(apperrors.ServerError, "")
+	}
+
+	if oauthErr.Code == apperrors.InvalidClient {
+		context.Header("WWW-Authenticate", "Basic")
+	}
+
+	if oauthErr.Code == apperrors.InvalidToken {
+		context.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.JSON(oauthErr.Status(), oauthErr)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// OAuthAuthorize handler validates an authorization request of an OAuth
+// client for the signed in user. Our frontend forwards the query of the
+// request and shows the consent screen if consent is required.
+func (h *Handler) OAuthAuthorize(context *gin.Context) {
+	user, exists := context.Get("user")
+
+	if !exists {
+		log.Printf("Unable to extract user from request context for unknown reason: %v\n", context)
+		err := apperrors.NewInternal()
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var request model.AuthorizationRequest
+
+	if err := context.ShouldBindQuery(&request); err != nil {
+		oauthError(context, apperrors.NewOAuthError(apperrors.InvalidRequest, "Unable to read the authorization request"))
+		return
+	}
+
+	ctx := context.Request.Context()
+	prompt, err := h.OAuthService.PrepareAuthorization(ctx, user.(*model.User).UserID, &request)
+
+	if err != nil {
+		log.Printf("Failed to prepare the authorization: %v\n", err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"authorization": prompt,
+	})
+}
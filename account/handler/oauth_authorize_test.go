@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", &model.User{
+				UserID: userID,
+			})
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	authorizationRequest := &model.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "grafana",
+		RedirectURI:         "https://grafana.example.com/login/generic_oauth",
+		Scope:               "profile",
+		State:               "somestate",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: "S256",
+	}
+
+	query := url.Values{
+		"response_type":         {authorizationRequest.ResponseType},
+		"client_id":             {authorizationRequest.ClientID},
+		"redirect_uri":          {authorizationRequest.RedirectURI},
+		"scope":                 {authorizationRequest.Scope},
+		"state":                 {authorizationRequest.State},
+		"code_challenge":        {authorizationRequest.CodeChallenge},
+		"code_challenge_method": {authorizationRequest.CodeChallengeMethod},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		prompt := &model.AuthorizationPrompt{
+			Client: &model.OAuthClient{
+				ClientID: "grafana",
+				Name:     "Grafana",
+			},
+			Scopes:          []string{"profile"},
+			ConsentRequired: true,
+		}
+
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("PrepareAuthorization", mock.Anything, userID, authorizationRequest).Return(prompt, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"authorization": prompt,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidRequest, "The redirect_uri is not registered for the client")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("PrepareAuthorization", mock.Anything, userID, authorizationRequest).Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("NoContextUser", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Do not append user to context.
+		router := gin.Default()
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+		mockOAuthService.AssertNotCalled(t, "PrepareAuthorization")
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// oauthConsentRequest holds the parameters of the authorization
+// request along with the decision of the user.
+type oauthConsentRequest struct {
+	model.AuthorizationRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthConsent handler records whether the signed in user allowed an
+// authorization request. It responds with the URL our frontend redirects
+// the user to, which holds the authorization code or the error for the client.
+func (h *Handler) OAuthConsent(context *gin.Context) {
+	user, exists := context.Get("user")
+
+	if !exists {
+		log.Printf("Unable to extract user from request context for unknown reason: %v\n", context)
+		err := apperrors.NewInternal()
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+
+		return
+	}
+
+	var request oauthConsentRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	location, err := h.OAuthService.Authorize(ctx, user.(*model.User), &request.AuthorizationRequest, request.Approve)
+
+	if err != nil {
+		log.Printf("Failed to authorize the client: %v\n", err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"redirectTo": location,
+	})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthConsent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	contextUser := &model.User{
+		UserID: userID,
+	}
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	authorizationRequest := &model.AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "grafana",
+		RedirectURI:         "https://grafana.example.com/login/generic_oauth",
+		Scope:               "profile",
+		State:               "somestate",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: "S256",
+	}
+
+	newRequest := func(approve bool) *http.Request {
+		requestBody, _ := json.Marshal(gin.H{
+			"response_type":         authorizationRequest.ResponseType,
+			"client_id":             authorizationRequest.ClientID,
+			"redirect_uri":          authorizationRequest.RedirectURI,
+			"scope":                 authorizationRequest.Scope,
+			"state":                 authorizationRequest.State,
+			"code_challenge":        authorizationRequest.CodeChallenge,
+			"code_challenge_method": authorizationRequest.CodeChallengeMethod,
+			"approve":               approve,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/oauth/authorize", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		return request
+	}
+
+	t.Run("Approved", func(t *testing.T) {
+		location := "https://grafana.example.com/login/generic_oauth?code=somecode&state=somestate"
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Authorize", mock.Anything, contextUser, authorizationRequest, true).Return(location, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		router.ServeHTTP(responseRecorder, newRequest(true))
+
+		responseBody, _ := json.Marshal(gin.H{
+			"redirectTo": location,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		location := "https://grafana.example.com/login/generic_oauth?error=access_denied&state=somestate"
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Authorize", mock.Anything, contextUser, authorizationRequest, false).Return(location, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		router.ServeHTTP(responseRecorder, newRequest(false))
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Contains(t, responseRecorder.Body.String(), "access_denied")
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidScope, "The scope is not allowed for the client")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Authorize", mock.Anything, contextUser, authorizationRequest, true).Return("", mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		router.ServeHTTP(responseRecorder, newRequest(true))
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// OAuthIntrospect handler reports whether a token is active as described in RFC 7662.
+// Resource servers such as an API gateway authenticate with their client credentials.
+func (h *Handler) OAuthIntrospect(context *gin.Context) {
+	client, ok := h.authenticateClient(context)
+
+	if !ok {
+		return
+	}
+
+	token := context.PostForm("token")
+
+	if token == "" {
+		oauthError(context, apperrors.NewOAuthError(apperrors.InvalidRequest, "The token parameter is required"))
+		return
+	}
+
+	ctx := context.Request.Context()
+	introspection, err := h.OAuthService.Introspect(ctx, client, token, context.PostForm("token_type_hint"))
+
+	if err != nil {
+		log.Printf("Failed to introspect the token: %v\n", err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.JSON(http.StatusOK, introspection)
+}
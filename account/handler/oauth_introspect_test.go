@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthIntrospect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	client := &model.OAuthClient{
+		ClientID: "gateway",
+		Scopes:   []string{model.ScopeIntrospect},
+	}
+
+	newRequest := func(form url.Values) *http.Request {
+		request, _ := http.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.SetBasicAuth("gateway", "gatewaysecret")
+		return request
+	}
+
+	t.Run("Active token", func(t *testing.T) {
+		introspection := &model.TokenIntrospection{
+			Active:    true,
+			TokenType: model.AccessTokenType,
+			Subject:   "someuserid",
+			Username:  "kostya@kostya.com",
+			ExpiresAt: 1700000900,
+			IssuedAt:  1700000000,
+			TokenID:   "sometokenid",
+		}
+
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Introspect", mock.Anything, client, "sometoken", "access_token").Return(introspection, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token":           {"sometoken"},
+			"token_type_hint": {"access_token"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(introspection)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Inactive token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Introspect", mock.Anything, client, "invalidtoken", "").Return(&model.TokenIntrospection{Active: false}, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"invalidtoken"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.JSONEq(t, `{"active":false}`, responseRecorder.Body.String())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid client", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidClient, "Client authentication failed")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"sometoken"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		mockOAuthService.AssertNotCalled(t, "Introspect")
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockOAuthService.AssertNotCalled(t, "Introspect")
+	})
+	t.Run("Client which is not a resource server", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The client is not registered as a resource server")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Introspect", mock.Anything, client, "sometoken", "").Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"sometoken"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+}
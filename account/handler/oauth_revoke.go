@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// OAuthRevoke handler revokes a refresh token as described in RFC 7009.
+// Clients authenticate with their client credentials and may only revoke
+// the tokens which were issued to them.
+func (h *Handler) OAuthRevoke(context *gin.Context) {
+	client, ok := h.authenticateClient(context)
+
+	if !ok {
+		return
+	}
+
+	token := context.PostForm("token")
+
+	if token == "" {
+		oauthError(context, apperrors.NewOAuthError(apperrors.InvalidRequest, "The token parameter is required"))
+		return
+	}
+
+	ctx := context.Request.Context()
+
+	if err := h.OAuthService.Revoke(ctx, client, token, context.PostForm("token_type_hint")); err != nil {
+		log.Printf("Failed to revoke the token: %v\n", err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	// The response is the same whether or not the token was valid.
+	context.Status(http.StatusOK)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	client := &model.OAuthClient{
+		ClientID: "gateway",
+	}
+
+	newRequest := func(form url.Values) *http.Request {
+		request, _ := http.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return request
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Revoke", mock.Anything, client, "sometoken", "refresh_token").Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token":           {"sometoken"},
+			"token_type_hint": {"refresh_token"},
+		})
+		request.SetBasicAuth("gateway", "gatewaysecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Client credentials in form body", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Revoke", mock.Anything, client, "sometoken", "").Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token":         {"sometoken"},
+			"client_id":     {"gateway"},
+			"client_secret": {"gatewaysecret"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid client", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidClient, "Client authentication failed")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "wrongsecret").Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"sometoken"},
+		})
+		request.SetBasicAuth("gateway", "wrongsecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, "Basic", responseRecorder.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertNotCalled(t, "Revoke")
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{})
+		request.SetBasicAuth("gateway", "gatewaysecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Contains(t, responseRecorder.Body.String(), string(apperrors.InvalidRequest))
+		mockOAuthService.AssertNotCalled(t, "Revoke")
+	})
+
+	t.Run("Unsupported token type", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.UnsupportedTokenType, "Access tokens cannot be revoked")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Revoke", mock.Anything, client, "someidtoken", "").Return(mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"someidtoken"},
+		})
+		request.SetBasicAuth("gateway", "gatewaysecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+	t.Run("Token of another client", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The token was not issued to the client")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("AuthenticateClient", mock.Anything, "gateway", "gatewaysecret").Return(client, nil)
+		mockOAuthService.On("Revoke", mock.Anything, client, "sometoken", "").Return(mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"token": {"sometoken"},
+		})
+		request.SetBasicAuth("gateway", "gatewaysecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+}
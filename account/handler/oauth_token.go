@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// OAuthToken handler is the token endpoint of RFC 6749. It exchanges
+// authorization codes for tokens, refreshes tokens of OAuth clients
+// and issues access tokens to machine clients.
+func (h *Handler) OAuthToken(context *gin.Context) {
+	clientID, clientSecret := clientCredentials(context)
+
+	request := &model.TokenRequest{
+		GrantType:    context.PostForm("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Code:         context.PostForm("code"),
+		RedirectURI:  context.PostForm("redirect_uri"),
+		CodeVerifier: context.PostForm("code_verifier"),
+		RefreshToken: context.PostForm("refresh_token"),
+		Scope:        context.PostForm("scope"),
+		Session:      sessionFromRequest(context, ""),
+	}
+
+	ctx := context.Request.Context()
+	response, err := h.OAuthService.Token(ctx, request)
+
+	if err != nil {
+		log.Printf("Failed to issue tokens for the client: %v. Error: %v\n", clientID, err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.Header("Pragma", "no-cache")
+	context.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	newRequest := func(form url.Values) *http.Request {
+		request, _ := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return request
+	}
+
+	tokenResponse := &model.TokenResponse{
+		AccessToken:  "someidtoken",
+		TokenType:    "Bearer",
+		ExpiresIn:    900,
+		RefreshToken: "somerefreshtoken",
+		Scope:        "profile",
+	}
+
+	t.Run("Authorization code of a confidential client", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Token", mock.Anything, mock.MatchedBy(func(request *model.TokenRequest) bool {
+			return request.GrantType == model.AuthorizationCodeGrant &&
+				request.ClientID == "grafana" &&
+				request.ClientSecret == "grafanasecret" &&
+				request.Code == "somecode" &&
+				request.RedirectURI == "https://grafana.example.com/login/generic_oauth" &&
+				request.CodeVerifier == "someverifier" &&
+				request.Session.UserAgent == "Grafana"
+		})).Return(tokenResponse, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"grant_type":    {model.AuthorizationCodeGrant},
+			"code":          {"somecode"},
+			"redirect_uri":  {"https://grafana.example.com/login/generic_oauth"},
+			"code_verifier": {"someverifier"},
+		})
+		request.SetBasicAuth("grafana", "grafanasecret")
+		request.Header.Set("User-Agent", "Grafana")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(tokenResponse)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Refresh token of a public client", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Token", mock.Anything, mock.MatchedBy(func(request *model.TokenRequest) bool {
+			return request.GrantType == model.RefreshTokenGrant &&
+				request.ClientID == "spa" &&
+				request.ClientSecret == "" &&
+				request.RefreshToken == "somerefreshtoken"
+		})).Return(tokenResponse, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"grant_type":    {model.RefreshTokenGrant},
+			"client_id":     {"spa"},
+			"refresh_token": {"somerefreshtoken"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Client credentials of a machine client", func(t *testing.T) {
+		serviceTokenResponse := &model.TokenResponse{
+			AccessToken: "someaccesstoken",
+			TokenType:   "Bearer",
+			ExpiresIn:   900,
+			Scope:       "users:read",
+		}
+
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Token", mock.Anything, mock.MatchedBy(func(request *model.TokenRequest) bool {
+			return request.GrantType == model.ClientCredentialsGrant &&
+				request.ClientID == "billing" &&
+				request.ClientSecret == "billingsecret" &&
+				request.Scope == "users:read"
+		})).Return(serviceTokenResponse, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"grant_type": {model.ClientCredentialsGrant},
+			"scope":      {"users:read"},
+		})
+		request.SetBasicAuth("billing", "billingsecret")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(serviceTokenResponse)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid grant", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidGrant, "The authorization code is invalid or expired")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Token", mock.Anything, mock.AnythingOfType("*model.TokenRequest")).Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request := newRequest(url.Values{
+			"grant_type": {model.AuthorizationCodeGrant},
+			"client_id":  {"spa"},
+			"code":       {"usedcode"},
+		})
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenIDConfiguration handler serves the OpenID Connect discovery document,
+// so standard clients and proxies can be pointed at the issuer alone.
+func (h *Handler) OpenIDConfiguration(context *gin.Context) {
+	context.Header("Cache-Control", "public, max-age=300")
+
+	context.JSON(http.StatusOK, h.OAuthService.Discovery())
+}
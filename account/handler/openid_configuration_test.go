@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOpenIDConfiguration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		mockConfiguration := &model.OpenIDConfiguration{
+			Issuer:                           "http://localhost:8080/api/account",
+			AuthorizationEndpoint:            "http://localhost:8080/authorize",
+			TokenEndpoint:                    "http://localhost:8080/api/account/oauth/token",
+			UserInfoEndpoint:                 "http://localhost:8080/api/account/userinfo",
+			JWKSURI:                          "http://localhost:8080/api/account/.well-known/jwks.json",
+			ResponseTypesSupported:           []string{"code"},
+			IDTokenSigningAlgValuesSupported: []string{"RS256"},
+		}
+
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("Discovery").Return(mockConfiguration)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockConfiguration)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		assert.NotEmpty(t, responseRecorder.Header().Get("Cache-Control"))
+		mockOAuthService.AssertExpectations(t)
+	})
+}
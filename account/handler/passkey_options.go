@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// PasskeyOptions handler starts the registration of a passkey for the user.
+// The frontend passes the options to navigator.credentials.create, and
+// posts the credential it creates to /passkeys.
+func (h *Handler) PasskeyOptions(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	options, err := h.PasskeyService.BeginRegistration(context.Request.Context(), authUser.UserID)
+
+	if err != nil {
+		log.Printf("Failed to begin the registration of a passkey for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// Passkeys handler lists the user's passkeys.
+func (h *Handler) Passkeys(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	ctx := context.Request.Context()
+	passkeys, err := h.PasskeyService.List(ctx, authUser.UserID)
+
+	if err != nil {
+		log.Printf("Failed to get passkeys for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"passkeys": passkeys,
+	})
+}
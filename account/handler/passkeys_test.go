@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestPasskeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRouter := func(mockPasskeyService *mocks.MockPasskeyService) *gin.Engine {
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		passkeyID, _ := uuid.NewRandom()
+		mockPasskeys := []*model.Passkey{
+			{
+				PasskeyID:      passkeyID,
+				UserID:         userID,
+				CredentialID:   []byte("somecredential"),
+				Name:           "Laptop",
+				PublicKey:      []byte("somepublickey"),
+				Transports:     []string{"internal"},
+				BackupEligible: true,
+				CreatedAt:      time.Now().UTC(),
+			},
+		}
+
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("List", mock.Anything, userID).Return(mockPasskeys, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/passkeys", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"passkeys": mockPasskeys,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		assert.NotContains(t, responseRecorder.Body.String(), "publicKey")
+		mockPasskeyService.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("List", mock.Anything, userID).Return(nil, apperrors.NewInternal())
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockPasskeyService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/passkeys", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+		mockPasskeyService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type passwordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+	DeviceName      string `json:"deviceName" binding:"omitempty,max=100"`
+}
+
+// Password handler changes the password of the user.
+// All of the user's sessions are signed out, and the current
+// device gets a new token pair to stay signed in with. The new session
+// keeps how the user signed in, like a TOTP code, along with the password.
+func (h *Handler) Password(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	var request passwordRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	user, err := h.UserService.UpdatePassword(ctx, authUser.UserID, request.CurrentPassword, request.NewPassword)
+
+	if err != nil {
+		log.Printf("Failed to update the password of the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	// Whoever knew the previous password is signed out.
+	if err := h.TokenService.SignOut(ctx, user.UserID); err != nil {
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	session := sessionFromRequest(context, request.DeviceName)
+	session.AMR = []string{model.AMRPassword} // The current password was just verified.
+
+	for _, method := range authUser.AMR {
+		if method != model.AMRPassword {
+			session.AMR = append(session.AMR, method)
+		}
+	}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
+
+	if err != nil {
+		log.Printf("Failed to create tokens for the user: %v. Error: %v\n", user.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.writeTokens(context, http.StatusOK, tokens)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestPassword(t *testing.T) {
+	// Setup.
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	contextUser := &model.User{
+		UserID: userID,
+	}
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	t.Run("Data binding error", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, mockTokenService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"currentPassword": "avalidpassword",
+		})
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockUserService.AssertNotCalled(t, "UpdatePassword")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  "kostya@kostya.com",
+		}
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "newIDToken"},
+			RefreshToken: model.RefreshToken{SignedString: "newRefreshToken"},
+		}
+
+		mockUserService.On("UpdatePassword", mock.Anything, userID, "avalidpassword", "anewvalidpassword").Return(mockUser, nil)
+		mockTokenService.On("SignOut", mock.Anything, userID).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).Return(mockTokenPair, nil)
+
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, mockTokenService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"currentPassword": "avalidpassword",
+			"newPassword":     "anewvalidpassword",
+		})
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Keeps the methods of a TOTP session", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  "kostya@kostya.com",
+		}
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "newIDToken"},
+			RefreshToken: model.RefreshToken{SignedString: "newRefreshToken"},
+		}
+
+		mockUserService.On("UpdatePassword", mock.Anything, userID, "avalidpassword", "anewvalidpassword").Return(mockUser, nil)
+		mockTokenService.On("SignOut", mock.Anything, userID).Return(nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, mockUser, (*model.RefreshToken)(nil), mock.MatchedBy(func(session *model.Session) bool {
+			return assert.ObjectsAreEqual([]string{model.AMRPassword, model.AMROTP}, session.AMR)
+		})).Return(mockTokenPair, nil)
+
+		responseRecorder := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", &model.User{
+				UserID: userID,
+				AMR:    []string{model.AMRPassword, model.AMROTP},
+			})
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		requestBody, _ := json.Marshal(gin.H{
+			"currentPassword": "avalidpassword",
+			"newPassword":     "anewvalidpassword",
+		})
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid current password", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		mockError := apperrors.NewAuthorization("Invalid current password")
+		mockUserService.On("UpdatePassword", mock.Anything, userID, "aninvalidpassword", "anewvalidpassword").Return(nil, mockError)
+
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, mockTokenService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"currentPassword": "aninvalidpassword",
+			"newPassword":     "anewvalidpassword",
+		})
+		request, _ := http.NewRequest(http.MethodPut, "/password", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		mockTokenService.AssertNotCalled(t, "SignOut", mock.Anything, mock.Anything)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/handler/middleware"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// RouteRateLimit limits the requests to a route with the policy,
+// counting them by the key.
+type RouteRateLimit struct {
+	Policy *model.RateLimitPolicy
+	Key    middleware.RateLimitKey
+}
+
+// rateLimitKeys are the keys routes can count requests by.
+var rateLimitKeys = map[string]middleware.RateLimitKey{
+	"ip":     middleware.ByIP,
+	"user":   middleware.ByUser,
+	"apiKey": middleware.ByAPIKey,
+}
+
+// ParseRateLimits parses the rate limits of routes from a comma separated
+// list of "METHOD /path=algorithm:limit/seconds:key" entries, such as
+// "POST /signup=sliding_window:10/3600:ip". The key is one of ip, user or apiKey.
+func ParseRateLimits(rateLimits string) (map[string]*RouteRateLimit, error) {
+	routeRateLimits := make(map[string]*RouteRateLimit)
+
+	for _, entry := range strings.Split(rateLimits, ",") {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "" {
+			continue
+		}
+
+		route, rule, _ := strings.Cut(entry, "=")
+		parts := strings.Split(rule, ":")
+
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("could not parse the rate limit %q as METHOD /path=algorithm:limit/seconds:key", entry)
+		}
+
+		method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+		route = fmt.Sprintf("%s %s", strings.ToUpper(method), strings.TrimSpace(path))
+
+		algorithm := model.RateLimitAlgorithm(parts[0])
+
+		if algorithm != model.TokenBucket && algorithm != model.SlidingWindow {
+			return nil, fmt.Errorf("unknown rate limit algorithm %q of the route %s", parts[0], route)
+		}
+
+		limitString, periodString, _ := strings.Cut(parts[1], "/")
+		limit, err := strconv.Atoi(limitString)
+
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("could not parse the rate limit %q of the route %s as a positive int", limitString, route)
+		}
+
+		period, err := strconv.ParseInt(periodString, 0, 64)
+
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("could not parse the rate limit period %q of the route %s as positive seconds", periodString, route)
+		}
+
+		key, ok := rateLimitKeys[parts[2]]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown rate limit key %q of the route %s", parts[2], route)
+		}
+
+		routeRateLimits[route] = &RouteRateLimit{
+			Policy: &model.RateLimitPolicy{
+				Name:      route,
+				Algorithm: algorithm,
+				Limit:     limit,
+				Period:    time.Duration(period) * time.Second,
+			},
+			Key: key,
+		}
+	}
+
+	return routeRateLimits, nil
+}
+
+// limited puts the rate limit of the route, if it has one, right before
+// its handler, so middlewares authenticating the caller run first.
+func (h *Handler) limited(method string, path string, handlers []gin.HandlerFunc) []gin.HandlerFunc {
+	rateLimit, ok := h.RateLimits[fmt.Sprintf("%s %s", method, path)]
+
+	if !ok || h.RateLimiter == nil {
+		return handlers
+	}
+
+	last := len(handlers) - 1
+	limited := append([]gin.HandlerFunc{}, handlers[:last]...)
+
+	return append(limited, middleware.RateLimit(h.RateLimiter, rateLimit.Policy, rateLimit.Key), handlers[last])
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		rateLimits, err := ParseRateLimits("POST /signup=sliding_window:10/3600:ip, post /image=token_bucket:5/60:user")
+		assert.NoError(t, err)
+
+		assert.Len(t, rateLimits, 2)
+		assert.Equal(t, &model.RateLimitPolicy{
+			Name:      "POST /signup",
+			Algorithm: model.SlidingWindow,
+			Limit:     10,
+			Period:    time.Hour,
+		}, rateLimits["POST /signup"].Policy)
+		assert.Equal(t, model.TokenBucket, rateLimits["POST /image"].Policy.Algorithm)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		rateLimits, err := ParseRateLimits("")
+		assert.NoError(t, err)
+
+		assert.Empty(t, rateLimits)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, rateLimits := range []string{
+			"POST /signup=sliding_window:10/3600",
+			"POST /signup=leaky_bucket:10/3600:ip",
+			"POST /signup=sliding_window:ten/3600:ip",
+			"POST /signup=sliding_window:10/0:ip",
+			"POST /signup=sliding_window:10/3600:email",
+		} {
+			_, err := ParseRateLimits(rateLimits)
+			assert.Error(t, err, rateLimits)
+		}
+	})
+}
+
+func TestRouteRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rateLimits, _ := ParseRateLimits("POST /signup=sliding_window:10/3600:ip")
+
+	mockUserService := new(mocks.MockUserService)
+	mockRateLimiter := new(mocks.MockRateLimiter)
+	mockRateLimiter.On("Allow", mock.Anything, mock.AnythingOfType("string"), rateLimits["POST /signup"].Policy).Return(&model.RateLimitResult{
+		RetryAfter: time.Minute,
+	}, nil)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		Router:      router,
+		UserService: mockUserService,
+		RateLimiter: mockRateLimiter,
+		RateLimits:  rateLimits,
+	})
+
+	t.Run("Limited route", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/signup", http.NoBody)
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, responseRecorder.Code)
+		assert.Equal(t, "60", responseRecorder.Header().Get("Retry-After"))
+		mockUserService.AssertNotCalled(t, "SignUp", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Route without a rate limit", func(t *testing.T) {
+		responseRecorder := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin", http.NoBody)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, responseRecorder.Code)
+
+		assert.Empty(t, responseRecorder.Header().Get("RateLimit-Limit"))
+		mockRateLimiter.AssertNumberOfCalls(t, "Allow", 1)
+	})
+
+	t.Run("Rate limit of an unknown route", func(t *testing.T) {
+		rateLimits, _ := ParseRateLimits("POST /token=token_bucket:30/60:ip")
+
+		assert.PanicsWithValue(t, "rate limit of the route POST /token, which is not registered", func() {
+			NewHandler(&Config{
+				Router:      gin.New(),
+				RateLimiter: mockRateLimiter,
+				RateLimits:  rateLimits,
+			})
+		})
+	})
+}
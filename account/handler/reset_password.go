@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// ResetPassword handler sets a new password with a password reset token
+// and signs the user out of all sessions.
+func (h *Handler) ResetPassword(context *gin.Context) {
+	var request resetPasswordRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	userID, err := h.UserService.ResetPassword(ctx, request.Token, request.NewPassword)
+
+	if err != nil {
+		log.Printf("Failed to reset the password: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.TokenService.SignOut(ctx, userID); err != nil {
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "the password was reset successfully!",
+	})
+}
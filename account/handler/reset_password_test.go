@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockUserService *mocks.MockUserService, mockTokenService *mocks.MockTokenService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		return router
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		userID, _ := uuid.NewRandom()
+
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockUserService.On("ResetPassword", mock.Anything, "somerandomresettoken", "anewvalidpassword").Return(userID, nil)
+		mockTokenService.On("SignOut", mock.Anything, userID).Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, mockTokenService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"token":       "somerandomresettoken",
+			"newPassword": "anewvalidpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockError := apperrors.NewAuthorization("Invalid or expired password reset token")
+		mockUserService.On("ResetPassword", mock.Anything, "someusedresettoken", "anewvalidpassword").Return(uuid.Nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, mockTokenService)
+
+		requestBody, _ := json.Marshal(gin.H{
+			"token":       "someusedresettoken",
+			"newPassword": "anewvalidpassword",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "SignOut", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// Names of the cookies and the header of the browser session mode.
+const (
+	refreshTokenCookieName = "refreshToken"
+	csrfTokenCookieName    = "csrfToken"
+	csrfTokenHeaderName    = "X-CSRF-Token"
+)
+
+// SessionCookieConfig turns on the browser session mode, in which the
+// refresh token is kept in an HttpOnly cookie which scripts can't read.
+// As the browser sends the cookie by itself, requests which change something
+// must also send the CSRF token from the csrfToken cookie in the X-CSRF-Token header.
+type SessionCookieConfig struct {
+	Domain   string        // Leave empty for cookies of the API host only.
+	Path     string        // The path of the account API, the refresh token isn't sent anywhere else.
+	SameSite http.SameSite // SameSiteStrictMode unless the frontend is on another site.
+}
+
+// writeTokens responds with a new token pair. In the browser session mode
+// the refresh token is set as a cookie instead, along with a new CSRF token,
+// and left out of the tokens. The cookie expires with the refresh token,
+// which the session's maximum age and idle timeout may cut short.
+func (h *Handler) writeTokens(context *gin.Context, status int, tokens *model.TokenPair) {
+	if h.SessionCookie == nil {
+		context.JSON(status, gin.H{
+			"tokens": tokens,
+		})
+		return
+	}
+
+	csrfToken, err := newCSRFToken()
+
+	if err != nil {
+		err := apperrors.NewInternal()
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.setSessionCookies(context, tokens.RefreshToken.SignedString, csrfToken, int(tokens.RefreshToken.ExpiresIn.Seconds()))
+
+	context.JSON(status, gin.H{
+		"tokens":    &model.TokenPair{IDToken: tokens.IDToken},
+		"csrfToken": csrfToken,
+	})
+}
+
+// clearSessionCookies removes the cookies of the browser session mode.
+func (h *Handler) clearSessionCookies(context *gin.Context) {
+	if h.SessionCookie != nil {
+		h.setSessionCookies(context, "", "", -1)
+	}
+}
+
+// setSessionCookies sets the refresh token and the CSRF token cookies.
+// The CSRF token cookie can be read by the frontend on any of its pages,
+// so that it can be sent back in the header.
+func (h *Handler) setSessionCookies(context *gin.Context, refreshToken string, csrfToken string, maxAge int) {
+	http.SetCookie(context.Writer, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    refreshToken,
+		Path:     h.SessionCookie.Path,
+		Domain:   h.SessionCookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: h.SessionCookie.SameSite,
+	})
+
+	http.SetCookie(context.Writer, &http.Cookie{
+		Name:     csrfTokenCookieName,
+		Value:    csrfToken,
+		Path:     "/",
+		Domain:   h.SessionCookie.Domain,
+		MaxAge:   maxAge,
+		Secure:   true,
+		SameSite: h.SessionCookie.SameSite,
+	})
+}
+
+// newCSRFToken returns a random token for the double submit cookie pattern.
+func newCSRFToken() (string, error) {
+	token := make([]byte, 32)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
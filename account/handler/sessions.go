@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// Sessions handler lists the devices the user is signed in on.
+func (h *Handler) Sessions(context *gin.Context) {
+	authUser := context.MustGet("user").(*model.User)
+
+	ctx := context.Request.Context()
+	sessions, err := h.TokenService.Sessions(ctx, authUser.UserID)
+
+	if err != nil {
+		log.Printf("Failed to get sessions for the user: %v. Error: %v\n", authUser.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// sessionFromRequest collects the metadata of the client
+// which is stored with the user's refresh token.
+func sessionFromRequest(context *gin.Context, deviceName string) *model.Session {
+	return &model.Session{
+		UserAgent:  context.Request.UserAgent(),
+		IP:         context.ClientIP(),
+		DeviceName: deviceName,
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	contextUser := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		sessionID, _ := uuid.NewRandom()
+		mockSessions := []*model.Session{
+			{
+				ID:              sessionID,
+				UserAgent:       "Mozilla/5.0",
+				IP:              "10.0.0.1",
+				DeviceName:      "Kostya's phone",
+				CreatedAt:       time.Now().Add(-time.Hour).UTC(),
+				LastRefreshedAt: time.Now().UTC(),
+			},
+		}
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Sessions", mock.Anything, userID).Return(mockSessions, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/sessions", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"sessions": mockSessions,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Sessions Error", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("Sessions", mock.Anything, userID).Return(nil, apperrors.NewInternal())
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context for setting a user.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+		})
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodGet, "/sessions", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+	})
+}
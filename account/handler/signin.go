@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yachnytskyi/base-go/account/model"
@@ -11,11 +13,14 @@ import (
 
 // signInRequest is not exported.
 type signInRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=100"`
 }
 
 // SignIn used to authenticate extant user.
+// Too many failed attempts lock sign ins for a while.
+// Users with two-factor authentication enabled get an MFA challenge instead of tokens.
 func (h *Handler) SignIn(context *gin.Context) {
 	var req signInRequest
 
@@ -29,17 +34,34 @@ func (h *Handler) SignIn(context *gin.Context) {
 	}
 
 	ctx := context.Request.Context()
-	err := h.UserService.SignIn(ctx, user)
+	err := h.UserService.SignIn(ctx, user, context.ClientIP())
 
 	if err != nil {
 		log.Printf("Failed to sign in user: %v\n", err.Error())
+
+		// Locked sign ins tell when to try again.
+		var appErr *apperrors.Error
+		if errors.As(err, &appErr) && appErr.RetryAfter > 0 {
+			context.Header("Retry-After", strconv.Itoa(appErr.RetryAfter))
+		}
+
 		context.JSON(apperrors.Status(err), gin.H{
 			"error": err,
 		})
 		return
 	}
 
-	tokens, err := h.TokenService.NewPairFromUser(ctx, user, "")
+	// Users with two-factor authentication get a challenge,
+	// which they answer with a code at /signin/mfa to get tokens.
+	if user.TOTPEnabled {
+		h.writeMFAChallenge(context, user)
+		return
+	}
+
+	session := sessionFromRequest(context, req.DeviceName)
+	session.AMR = []string{model.AMRPassword}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
 
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %v\n", err.Error())
@@ -50,7 +72,26 @@ func (h *Handler) SignIn(context *gin.Context) {
 		return
 	}
 
+	h.writeTokens(context, http.StatusOK, tokens)
+}
+
+// writeMFAChallenge responds with an MFA challenge for the user,
+// who entered the right password.
+func (h *Handler) writeMFAChallenge(context *gin.Context, user *model.User) {
+	challenge, err := h.MFAService.NewChallenge(context.Request.Context(), user)
+
+	if err != nil {
+		log.Printf("Failed to create an MFA challenge for the user: %v. Error: %v\n", user.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
 	context.JSON(http.StatusOK, gin.H{
-		"tokens": tokens,
+		"mfaRequired": true,
+		"mfaToken":    challenge.Token,
+		"expiresIn":   int(challenge.ExpiresIn.Seconds()),
 	})
 }
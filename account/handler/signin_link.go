@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type signInLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// SignInLink handler mails a link to the user, which signs in without a password.
+// The response is the same whether or not the email is registered.
+func (h *Handler) SignInLink(context *gin.Context) {
+	var request signInLinkRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+
+	if err := h.UserService.SendMagicLink(ctx, request.Email); err != nil {
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "if the email can sign in, a sign in link was sent to it",
+	})
+}
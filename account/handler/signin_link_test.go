@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUserService := new(mocks.MockUserService)
+	mockUserService.On("SendMagicLink", mock.Anything, "kostya@kostya.com").Return(nil)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		Router:      router,
+		UserService: mockUserService,
+	})
+
+	t.Run("Invalid email", func(t *testing.T) {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, _ := json.Marshal(gin.H{
+			"email": "notanemail",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin/link", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockUserService.AssertNotCalled(t, "SendMagicLink", mock.Anything, "notanemail")
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, _ := json.Marshal(gin.H{
+			"email": "kostya@kostya.com",
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signin/link", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"message": "if the email can sign in, a sign in link was sent to it",
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type signInLinkVerifyRequest struct {
+	Token      string `json:"token" binding:"required"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=100"`
+}
+
+// SignInLinkVerify handler exchanges the token of a sign in link for tokens.
+// The link is a one-time password sent to the email of the user.
+func (h *Handler) SignInLinkVerify(context *gin.Context) {
+	var request signInLinkVerifyRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	user, err := h.UserService.SignInWithMagicLink(ctx, request.Token)
+
+	if err != nil {
+		log.Printf("Failed to sign in with a sign in link: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	session := sessionFromRequest(context, request.DeviceName)
+	session.AMR = []string{model.AMROTP}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
+
+	if err != nil {
+		log.Printf("Failed to create tokens for the user: %v. Error: %v\n", user.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.writeTokens(context, http.StatusOK, tokens)
+}
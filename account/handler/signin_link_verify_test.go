@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInLinkVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID:        userID,
+		Email:         "kostya@kostya.com",
+		EmailVerified: true,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "idToken"},
+			RefreshToken: model.RefreshToken{SignedString: "refreshToken"},
+		}
+
+		var session *model.Session
+		mockUserService.On("SignInWithMagicLink", mock.Anything, "linktoken").Return(user, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, user, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).
+			Run(func(args mock.Arguments) {
+				session = args.Get(3).(*model.Session)
+			}).Return(mockTokenPair, nil)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"token":      "linktoken",
+			"deviceName": "Kostya's phone",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		assert.Equal(t, []string{model.AMROTP}, session.AMR)
+		assert.Equal(t, "Kostya's phone", session.DeviceName)
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Used link", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+		})
+
+		mockError := apperrors.NewAuthorization("Invalid or expired sign in link")
+		mockUserService.On("SignInWithMagicLink", mock.Anything, "linktoken").Return(nil, mockError)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"token": "linktoken",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Bad request data", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:      router,
+			UserService: mockUserService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"deviceName": "Kostya's phone",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/link/verify", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockUserService.AssertNotCalled(t, "SignInWithMagicLink")
+	})
+}
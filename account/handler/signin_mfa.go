@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// The challenge is answered with either a code or a passkey.
+type signInMFARequest struct {
+	MFAToken   string                   `json:"mfaToken" binding:"required"`
+	Code       string                   `json:"code" binding:"required_without=Passkey,max=40"`
+	Passkey    *model.PasskeyCredential `json:"passkey"`
+	DeviceName string                   `json:"deviceName" binding:"omitempty,max=100"`
+}
+
+// SignInMFA handler finishes the sign in of a user with two-factor
+// authentication, who answers the MFA challenge of the password
+// sign in with a TOTP or recovery code, or with a passkey.
+func (h *Handler) SignInMFA(context *gin.Context) {
+	var request signInMFARequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+
+	var user *model.User
+	var err error
+
+	if request.Passkey != nil {
+		user, err = h.MFAService.VerifyChallengeWithPasskey(ctx, request.MFAToken, request.Passkey)
+	} else {
+		user, err = h.MFAService.VerifyChallenge(ctx, request.MFAToken, request.Code)
+	}
+
+	if err != nil {
+		log.Printf("Failed to verify the MFA challenge: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	session := sessionFromRequest(context, request.DeviceName)
+	session.AMR = user.AMR // How the challenge was answered, like with a TOTP or recovery code.
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
+
+	if err != nil {
+		log.Printf("Failed to create tokens for the user: %v. Error: %v\n", user.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.writeTokens(context, http.StatusOK, tokens)
+}
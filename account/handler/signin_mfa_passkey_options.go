@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type signInMFAPasskeyOptionsRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+}
+
+// SignInMFAPasskeyOptions handler starts answering the MFA challenge
+// of a password sign in with a passkey of the user. The credential
+// the authenticator returns is posted to /signin/mfa.
+func (h *Handler) SignInMFAPasskeyOptions(context *gin.Context) {
+	var request signInMFAPasskeyOptionsRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	options, err := h.MFAService.PasskeyOptions(context.Request.Context(), request.MFAToken)
+
+	if err != nil {
+		log.Printf("Failed to begin answering the MFA challenge with a passkey: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInMFAPasskeyOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		options := &model.PasskeyRequestOptions{
+			Challenge:      "somechallenge",
+			Timeout:        300000,
+			RelyingPartyID: "localhost",
+			AllowCredentials: []model.PasskeyCredentialDescriptor{
+				{Type: "public-key", ID: "c29tZWNyZWRlbnRpYWw"},
+			},
+			UserVerification: "preferred",
+		}
+
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("PasskeyOptions", mock.Anything, "mfaToken").Return(options, nil)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"mfaToken": "mfaToken",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa/passkey/options", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"publicKey": options,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockMFAService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid or expired token", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+		mockMFAService.On("PasskeyOptions", mock.Anything, "expiredToken").
+			Return(nil, apperrors.NewAuthorization("Invalid or expired MFA token"))
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"mfaToken": "expiredToken",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa/passkey/options", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		mockMFAService.AssertExpectations(t)
+	})
+
+	t.Run("Bad request data", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa/passkey/options", bytes.NewBufferString("{}"))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockMFAService.AssertNotCalled(t, "PasskeyOptions")
+	})
+}
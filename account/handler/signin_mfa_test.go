@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInMFA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID:      userID,
+		Email:       "kostya@kostya.com",
+		TOTPEnabled: true,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "idToken"},
+			RefreshToken: model.RefreshToken{SignedString: "refreshToken"},
+		}
+
+		// The service tells how the challenge was answered.
+		signedInUser := *user
+		signedInUser.AMR = []string{model.AMRPassword, model.AMROTP}
+
+		var session *model.Session
+		mockMFAService.On("VerifyChallenge", mock.Anything, "mfaToken", "123456").Return(&signedInUser, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, &signedInUser, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).
+			Run(func(args mock.Arguments) {
+				session = args.Get(3).(*model.Session)
+			}).Return(mockTokenPair, nil)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"mfaToken":   "mfaToken",
+			"code":       "123456",
+			"deviceName": "Kostya's phone",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		assert.Equal(t, []string{model.AMRPassword, model.AMROTP}, session.AMR)
+		assert.Equal(t, "Kostya's phone", session.DeviceName)
+		mockMFAService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Answered with a passkey", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		credential := &model.PasskeyCredential{
+			ID:   "c29tZWNyZWRlbnRpYWw",
+			Type: "public-key",
+			Response: model.PasskeyCredentialResponse{
+				ClientDataJSON:    "e30",
+				AuthenticatorData: "c29tZWRhdGE",
+				Signature:         "c29tZXNpZ25hdHVyZQ",
+			},
+		}
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "idToken"},
+			RefreshToken: model.RefreshToken{SignedString: "refreshToken"},
+		}
+
+		signedInUser := *user
+		signedInUser.AMR = []string{model.AMRPassword, model.AMRPasskey}
+
+		var session *model.Session
+		mockMFAService.On("VerifyChallengeWithPasskey", mock.Anything, "mfaToken", credential).Return(&signedInUser, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, &signedInUser, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).
+			Run(func(args mock.Arguments) {
+				session = args.Get(3).(*model.Session)
+			}).Return(mockTokenPair, nil)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"mfaToken": "mfaToken",
+			"passkey":  credential,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, []string{model.AMRPassword, model.AMRPasskey}, session.AMR)
+		mockMFAService.AssertNotCalled(t, "VerifyChallenge")
+		mockMFAService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		mockError := apperrors.NewAuthorization("Invalid code")
+		mockMFAService.On("VerifyChallenge", mock.Anything, "mfaToken", "654321").Return(nil, mockError)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"mfaToken": "mfaToken",
+			"code":     "654321",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Bad request data", func(t *testing.T) {
+		mockMFAService := new(mocks.MockMFAService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:     router,
+			MFAService: mockMFAService,
+		})
+
+		for _, body := range []gin.H{
+			{"code": "123456"},
+			{"mfaToken": "mfaToken"},
+		} {
+			// A response recorder for getting written http response.
+			responseRecorder := httptest.NewRecorder()
+
+			requestBody, err := json.Marshal(body)
+			assert.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/signin/mfa", bytes.NewBuffer(requestBody))
+			assert.NoError(t, err)
+
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(responseRecorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		}
+
+		mockMFAService.AssertNotCalled(t, "VerifyChallenge")
+		mockMFAService.AssertNotCalled(t, "VerifyChallengeWithPasskey")
+	})
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type signInPasskeyRequest struct {
+	Credential *model.PasskeyCredential `json:"credential" binding:"required"`
+	DeviceName string                   `json:"deviceName" binding:"omitempty,max=100"`
+}
+
+// SignInPasskey handler signs a user in with a passkey instead of a password.
+// The authenticator verified the user, so no MFA challenge follows.
+func (h *Handler) SignInPasskey(context *gin.Context) {
+	var request signInPasskeyRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	user, err := h.PasskeyService.FinishSignIn(ctx, nil, request.Credential)
+
+	if err != nil {
+		log.Printf("Failed to sign in with a passkey: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	session := sessionFromRequest(context, request.DeviceName)
+	session.AMR = []string{model.AMRPasskey}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
+
+	if err != nil {
+		log.Printf("Failed to create tokens for the user: %v. Error: %v\n", user.UserID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	h.writeTokens(context, http.StatusOK, tokens)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// SignInPasskeyOptions handler starts a passwordless sign in. The options
+// name no user, so the authenticator offers the passkeys it holds for the site.
+func (h *Handler) SignInPasskeyOptions(context *gin.Context) {
+	options, err := h.PasskeyService.BeginSignIn(context.Request.Context(), nil)
+
+	if err != nil {
+		log.Printf("Failed to begin a sign in with a passkey: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"publicKey": options,
+	})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInPasskeyOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Success", func(t *testing.T) {
+		options := &model.PasskeyRequestOptions{
+			Challenge:        "somechallenge",
+			Timeout:          300000,
+			RelyingPartyID:   "localhost",
+			AllowCredentials: []model.PasskeyCredentialDescriptor{},
+			UserVerification: "required",
+		}
+
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("BeginSignIn", mock.Anything, (*model.User)(nil)).Return(options, nil)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey/options", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"publicKey": options,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockPasskeyService.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockPasskeyService.On("BeginSignIn", mock.Anything, (*model.User)(nil)).Return(nil, apperrors.NewInternal())
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey/options", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+		mockPasskeyService.AssertExpectations(t)
+	})
+}
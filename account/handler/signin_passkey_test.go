@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestSignInPasskey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	credential := &model.PasskeyCredential{
+		ID:   "c29tZWNyZWRlbnRpYWw",
+		Type: "public-key",
+		Response: model.PasskeyCredentialResponse{
+			ClientDataJSON:    "e30",
+			AuthenticatorData: "c29tZWRhdGE",
+			Signature:         "c29tZXNpZ25hdHVyZQ",
+			UserHandle:        "c29tZXVzZXI",
+		},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:         router,
+			TokenService:   mockTokenService,
+			PasskeyService: mockPasskeyService,
+		})
+
+		mockTokenPair := &model.TokenPair{
+			IDToken:      model.IDToken{SignedString: "idToken"},
+			RefreshToken: model.RefreshToken{SignedString: "refreshToken"},
+		}
+
+		var session *model.Session
+		mockPasskeyService.On("FinishSignIn", mock.Anything, (*model.User)(nil), credential).Return(user, nil)
+		mockTokenService.On("NewPairFromUser", mock.Anything, user, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).
+			Run(func(args mock.Arguments) {
+				session = args.Get(3).(*model.Session)
+			}).Return(mockTokenPair, nil)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"credential": credential,
+			"deviceName": "Kostya's laptop",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"tokens": mockTokenPair,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		assert.Equal(t, []string{model.AMRPasskey}, session.AMR)
+		assert.Equal(t, "Kostya's laptop", session.DeviceName)
+		mockPasskeyService.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Passkey which doesn't verify", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mockTokenService := new(mocks.MockTokenService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:         router,
+			TokenService:   mockTokenService,
+			PasskeyService: mockPasskeyService,
+		})
+
+		mockError := apperrors.NewAuthorization("The passkey could not be verified")
+		mockPasskeyService.On("FinishSignIn", mock.Anything, (*model.User)(nil), credential).Return(nil, mockError)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"credential": credential,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
+	t.Run("Bad request data", func(t *testing.T) {
+		mockPasskeyService := new(mocks.MockPasskeyService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:         router,
+			PasskeyService: mockPasskeyService,
+		})
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"deviceName": "Kostya's laptop",
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin/passkey", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		mockPasskeyService.AssertNotCalled(t, "FinishSignIn")
+	})
+}
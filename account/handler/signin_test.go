@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -60,6 +61,7 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			&model.User{Email: email, Password: password},
+			mock.AnythingOfType("string"),
 		}
 
 		// So we can check for a known status code.
@@ -88,6 +90,45 @@ func TestSignIn(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	})
 
+	t.Run("Sign ins locked", func(t *testing.T) {
+		email := "kostya@kostya.com"
+		password := "passwordlocked123"
+
+		mockUSArgs := mock.Arguments{
+			mock.Anything,
+			&model.User{Email: email, Password: password},
+			mock.AnythingOfType("string"),
+		}
+
+		mockError := apperrors.NewTooManyRequests("Too many failed sign in attempts. Try again later", 90*time.Second)
+		mockUserService.On("SignIn", mockUSArgs...).Return(mockError)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"error": mockError,
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusTooManyRequests, responseRecorder.Code)
+		assert.Equal(t, "90", responseRecorder.Header().Get("Retry-After"))
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
 	t.Run("Successful Token Creation", func(t *testing.T) {
 		email := "kostya@kostya.com"
 		password := "passwordworksgreat123"
@@ -95,6 +136,7 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			&model.User{Email: email, Password: password},
+			mock.AnythingOfType("string"),
 		}
 
 		mockUserService.On("SignIn", mockUSArgs...).Return(nil)
@@ -102,7 +144,8 @@ func TestSignIn(t *testing.T) {
 		mockTSArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			&model.User{Email: email, Password: password},
-			"",
+			(*model.RefreshToken)(nil),
+			mock.AnythingOfType("*model.Session"),
 		}
 
 		mockTokenPair := &model.TokenPair{
@@ -140,6 +183,59 @@ func TestSignIn(t *testing.T) {
 		mockTokenService.AssertCalled(t, "NewPairFromUser", mockTSArgs...)
 	})
 
+	t.Run("MFA required", func(t *testing.T) {
+		email := "mfa@kostya.com"
+		password := "passwordwithsecondfactor"
+
+		mockUserService := new(mocks.MockUserService)
+		mockTokenService := new(mocks.MockTokenService)
+		mockMFAService := new(mocks.MockMFAService)
+
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
+			MFAService:   mockMFAService,
+		})
+
+		mockUserService.On("SignIn", mock.Anything, &model.User{Email: email, Password: password}, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).TOTPEnabled = true
+			}).Return(nil)
+
+		mockChallenge := &model.MFAChallenge{Token: "mfaToken", ExpiresIn: 5 * time.Minute}
+		mockMFAService.On("NewChallenge", mock.Anything, mock.AnythingOfType("*model.User")).Return(mockChallenge, nil)
+
+		// A response recorder for getting written http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, err := json.Marshal(gin.H{
+			"email":    email,
+			"password": password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/signin", bytes.NewBuffer(requestBody))
+		assert.NoError(t, err)
+
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		respBody, err := json.Marshal(gin.H{
+			"expiresIn":   300,
+			"mfaRequired": true,
+			"mfaToken":    "mfaToken",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, respBody, responseRecorder.Body.Bytes())
+		mockMFAService.AssertExpectations(t)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
+	})
+
 	t.Run("Failed Token Creation", func(t *testing.T) {
 		email := "cannotproducetoken@kostya.com"
 		password := "cannotproducetoken"
@@ -147,6 +243,7 @@ func TestSignIn(t *testing.T) {
 		mockUSArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			&model.User{Email: email, Password: password},
+			mock.AnythingOfType("string"),
 		}
 
 		mockUserService.On("SignIn", mockUSArgs...).Return(nil)
@@ -154,7 +251,8 @@ func TestSignIn(t *testing.T) {
 		mockTSArgs := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			&model.User{Email: email, Password: password},
-			"",
+			(*model.RefreshToken)(nil),
+			mock.AnythingOfType("*model.Session"),
 		}
 
 		mockError := apperrors.NewInternal()
@@ -9,6 +9,9 @@ import (
 )
 
 // SignOut handler.
+// Revokes the ID token the request was made with along with the refresh tokens,
+// so the token can't be used until it expires.
+// The cookies of the browser session mode are removed as well.
 func (h *Handler) SignOut(context *gin.Context) {
 	user := context.MustGet("user")
 
@@ -20,6 +23,17 @@ func (h *Handler) SignOut(context *gin.Context) {
 		return
 	}
 
+	if idToken := context.GetString("idToken"); idToken != "" {
+		if err := h.TokenService.RevokeIDToken(ctx, idToken); err != nil {
+			context.JSON(apperrors.Status(err), gin.H{
+				"error": err,
+			})
+			return
+		}
+	}
+
+	h.clearSessionCookies(context)
+
 	context.JSON(http.StatusOK, gin.H{
 		"message": "the user signed out successfully!",
 	})
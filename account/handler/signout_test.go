@@ -84,4 +84,102 @@ func TestSignOut(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
 	})
+	t.Run("Revokes the ID token", func(t *testing.T) {
+		userID, _ := uuid.NewRandom()
+
+		contextUser := &model.User{
+			UserID: userID,
+			Email:  "kostya3@kostya.com",
+		}
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context for setting a user and the token it was authenticated with.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+			context.Set("idToken", "someidtoken")
+		})
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("SignOut", mock.Anything, contextUser.UserID).Return(nil)
+		mockTokenService.On("RevokeIDToken", mock.Anything, "someidtoken").Return(nil)
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("RevokeIDToken Error", func(t *testing.T) {
+		userID, _ := uuid.NewRandom()
+
+		contextUser := &model.User{
+			UserID: userID,
+			Email:  "kostya4@kostya.com",
+		}
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context for setting a user and the token it was authenticated with.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+			context.Set("idToken", "someidtoken")
+		})
+
+		mockTokenService := new(mocks.MockTokenService)
+		mockTokenService.On("SignOut", mock.Anything, contextUser.UserID).Return(nil)
+		mockTokenService.On("RevokeIDToken", mock.Anything, "someidtoken").Return(apperrors.NewInternal())
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		userID, _ := uuid.NewRandom()
+
+		contextUser := &model.User{
+			UserID: userID,
+			Email:  "kostya5@kostya.com",
+		}
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// Creates a test context for setting a user authenticated with an API key.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set("user", contextUser)
+			context.Set("apiKey", &model.APIKey{UserID: userID})
+		})
+
+		mockTokenService := new(mocks.MockTokenService)
+
+		NewHandler(&Config{
+			Router:       router,
+			TokenService: mockTokenService,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/signout", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+		mockTokenService.AssertNotCalled(t, "SignOut", mock.Anything, mock.Anything)
+	})
 }
@@ -12,8 +12,9 @@ import (
 // signUpRequest is not exported, hence the lowercase name
 // is is used for validation and json marshalling.
 type signUpRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,gte=6,lte=30"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	DeviceName string `json:"deviceName" binding:"omitempty,max=100"`
 }
 
 // SignUp handler.
@@ -44,7 +45,10 @@ func (h *Handler) SignUp(context *gin.Context) {
 	}
 
 	// Create token pair as strings.
-	tokens, err := h.TokenService.NewPairFromUser(ctx, user, "")
+	session := sessionFromRequest(context, jsonRequest.DeviceName)
+	session.AMR = []string{model.AMRPassword}
+
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, nil, session)
 
 	if err != nil {
 		log.Printf("Failed to create tokens for user: %v\n", err.Error())
@@ -59,7 +63,5 @@ func (h *Handler) SignUp(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{
-		"tokens": tokens,
-	})
+	h.writeTokens(context, http.StatusCreated, tokens)
 }
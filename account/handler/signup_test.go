@@ -88,45 +88,19 @@ func TestSignUp(t *testing.T) {
 		mockUserService.AssertNotCalled(t, "SignUp")
 	})
 
-	t.Run("Password is too short", func(t *testing.T) {
-		// Show that it is not called in this case.
-		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("SignUp", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
-
-		// A response recorder for getting written http response.
-		responseRecorder := httptest.NewRecorder()
-
-		// Do not need a middleware as we don't yet have the authorized user.
-		router := gin.Default()
-
-		NewHandler(&Config{
-			Router:      router,
-			UserService: mockUserService,
-		})
+	t.Run("Password rejected by the policy", func(t *testing.T) {
+		user := &model.User{
+			Email:    "kostya@kostya.com",
+			Password: "secr",
+		}
 
-		// Create a request body with the wrong email and password.
-		reqBody, err := json.Marshal(gin.H{
-			"email":    "kostya@gmail.com",
-			"password": "secr",
+		mockError := apperrors.NewBadRequestWithReasons("The password does not meet the password policy. See reasons", []apperrors.Reason{
+			{Code: "PASSWORD_TOO_SHORT", Message: "The password must be at least 8 characters long"},
 		})
-		assert.NoError(t, err)
-
-		// Use bytes.NewBuffer to create a reader.
-		request, err := http.NewRequest(http.MethodPost, "/signup", bytes.NewBuffer(reqBody))
-		assert.NoError(t, err)
-
-		request.Header.Set("Content-Type", "application/json")
-
-		router.ServeHTTP(responseRecorder, request)
-
-		assert.Equal(t, 400, responseRecorder.Code)
-		mockUserService.AssertNotCalled(t, "SignUp")
-	})
 
-	t.Run("Password is too long", func(t *testing.T) {
-		// Show that it is not called in this case.
 		mockUserService := new(mocks.MockUserService)
-		mockUserService.On("SignUp", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("*model.User")).Return(nil)
+		mockUserService.On("SignUp", mock.Anything, user).Return(mockError)
+		mockTokenService := new(mocks.MockTokenService)
 
 		// A response recorder for getting written http response.
 		responseRecorder := httptest.NewRecorder()
@@ -135,14 +109,15 @@ func TestSignUp(t *testing.T) {
 		router := gin.Default()
 
 		NewHandler(&Config{
-			Router:      router,
-			UserService: mockUserService,
+			Router:       router,
+			UserService:  mockUserService,
+			TokenService: mockTokenService,
 		})
 
-		// Create a request body with the wrong email and password.
+		// Length is up to the password policy of the service.
 		reqBody, err := json.Marshal(gin.H{
-			"email":    "kostya@gmail.com",
-			"password": "secretpassworddasuhd89ydhuiasdajkdh792dyhuaksjdhnajdb78w",
+			"email":    user.Email,
+			"password": user.Password,
 		})
 		assert.NoError(t, err)
 
@@ -154,8 +129,10 @@ func TestSignUp(t *testing.T) {
 
 		router.ServeHTTP(responseRecorder, request)
 
-		assert.Equal(t, 400, responseRecorder.Code)
-		mockUserService.AssertNotCalled(t, "SignUp")
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+		assert.Contains(t, responseRecorder.Body.String(), `"reasons":[{"code":"PASSWORD_TOO_SHORT"`)
+		mockUserService.AssertExpectations(t)
+		mockTokenService.AssertNotCalled(t, "NewPairFromUser")
 	})
 
 	t.Run("Error returned from UserService", func(t *testing.T) {
@@ -212,7 +189,7 @@ func TestSignUp(t *testing.T) {
 		mockTokenService := new(mocks.MockTokenService)
 
 		mockUserService.On("SignUp", mock.AnythingOfType("*context.emptyCtx"), user).Return(nil)
-		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), user, "").Return(mockTokenResponse, nil)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), user, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).Return(mockTokenResponse, nil)
 
 		// A response recorder for getting a written http response.
 		responseRecorder := httptest.NewRecorder()
@@ -265,7 +242,7 @@ func TestSignUp(t *testing.T) {
 		mockTokenService := new(mocks.MockTokenService)
 
 		mockUserService.On("SignUp", mock.AnythingOfType("*context.emptyCtx"), user).Return(nil)
-		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), user, "").Return(nil, mockErrorResponse)
+		mockTokenService.On("NewPairFromUser", mock.AnythingOfType("*context.emptyCtx"), user, (*model.RefreshToken)(nil), mock.AnythingOfType("*model.Session")).Return(nil, mockErrorResponse)
 
 		// A response recorder for getting a written http response.
 		responseRecorder := httptest.NewRecorder()
@@ -13,11 +13,24 @@ type tokensRequest struct {
 }
 
 // Tokens handler.
+// In the browser session mode the refresh token is read from its cookie.
 func (h *Handler) Tokens(context *gin.Context) {
 	// Bind JSON to request of type tokensRequest.
 	var request tokensRequest
 
-	if ok := bindData(context, &request); !ok {
+	if h.SessionCookie != nil {
+		refreshToken, err := context.Cookie(refreshTokenCookieName)
+
+		if err != nil || refreshToken == "" {
+			err := apperrors.NewAuthorization("Missing refresh token cookie")
+			context.JSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		request.RefreshTokenString = refreshToken
+	} else if ok := bindData(context, &request); !ok {
 		return
 	}
 
@@ -44,7 +57,7 @@ func (h *Handler) Tokens(context *gin.Context) {
 	}
 
 	// Create fresh pair of tokens.
-	tokens, err := h.TokenService.NewPairFromUser(ctx, user, refreshToken.ID.String())
+	tokens, err := h.TokenService.NewPairFromUser(ctx, user, refreshToken, sessionFromRequest(context, ""))
 
 	if err != nil {
 		log.Printf("Failed to create tokens for the user: %+v. Error: %v\n", user, err.Error())
@@ -55,7 +68,5 @@ func (h *Handler) Tokens(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{
-		"tokens": tokens,
-	})
+	h.writeTokens(context, http.StatusOK, tokens)
 }
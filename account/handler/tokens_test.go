@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -106,7 +107,8 @@ func TestTokens(t *testing.T) {
 		newPairArguments := mock.Arguments{
 			mock.AnythingOfType("*context.emptyCtx"),
 			mockUserResponse,
-			mockRefreshTokenResponse.ID.String(),
+			mockRefreshTokenResponse,
+			mock.AnythingOfType("*model.Session"),
 		}
 
 		mockTokenService.On("NewPairFromUser", newPairArguments...).Return(nil, mockError)
@@ -168,7 +170,7 @@ func TestTokens(t *testing.T) {
 		}
 
 		newPairArguments := mock.Arguments{
-			mock.AnythingOfType("*context.emptyCtx"), mockUserResponse, mockRefreshTokenResponse.ID.String(),
+			mock.AnythingOfType("*context.emptyCtx"), mockUserResponse, mockRefreshTokenResponse, mock.AnythingOfType("*model.Session"),
 		}
 
 		mockTokenService.On("NewPairFromUser", newPairArguments...).Return(mockTokenPairResponse, nil)
@@ -199,3 +201,103 @@ func TestTokens(t *testing.T) {
 
 	// TODO - User not found (maybe in the furure).
 }
+
+func TestTokensSessionCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockTokenService := new(mocks.MockTokenService)
+	mockUserService := new(mocks.MockUserService)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		Router:       router,
+		TokenService: mockTokenService,
+		UserService:  mockUserService,
+		SessionCookie: &SessionCookieConfig{
+			Path:     "/",
+			SameSite: http.SameSiteStrictMode,
+		},
+	})
+
+	validTokenString := "valid token string"
+	mockUserID, _ := uuid.NewRandom()
+
+	mockRefreshTokenResponse := &model.RefreshToken{
+		SignedString: validTokenString,
+		UserID:       mockUserID,
+	}
+	mockUserResponse := &model.User{
+		UserID: mockUserID,
+	}
+	mockTokenPairResponse := &model.TokenPair{
+		IDToken:      model.IDToken{SignedString: "newIDToken"},
+		RefreshToken: model.RefreshToken{SignedString: "newRefreshToken", ExpiresIn: 90 * time.Minute},
+	}
+
+	mockTokenService.On("ValidateRefreshToken", validTokenString).Return(mockRefreshTokenResponse, nil)
+	mockUserService.On("Get", mock.Anything, mockUserID).Return(mockUserResponse, nil)
+	mockTokenService.On("NewPairFromUser", mock.Anything, mockUserResponse, mockRefreshTokenResponse, mock.AnythingOfType("*model.Session")).Return(mockTokenPairResponse, nil)
+
+	t.Run("Success", func(t *testing.T) {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", http.NoBody)
+		request.AddCookie(&http.Cookie{Name: "refreshToken", Value: validTokenString})
+		request.AddCookie(&http.Cookie{Name: "csrfToken", Value: "somecsrftoken"})
+		request.Header.Set("X-CSRF-Token", "somecsrftoken")
+		router.ServeHTTP(responseRecorder, request)
+
+		var responseBody struct {
+			Tokens    map[string]string `json:"tokens"`
+			CSRFToken string            `json:"csrfToken"`
+		}
+		json.Unmarshal(responseRecorder.Body.Bytes(), &responseBody)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, map[string]string{"idToken": "newIDToken"}, responseBody.Tokens)
+
+		cookies := map[string]*http.Cookie{}
+		for _, cookie := range responseRecorder.Result().Cookies() {
+			cookies[cookie.Name] = cookie
+		}
+
+		assert.Equal(t, "newRefreshToken", cookies["refreshToken"].Value)
+		assert.True(t, cookies["refreshToken"].HttpOnly)
+		assert.True(t, cookies["refreshToken"].Secure)
+		assert.Equal(t, http.SameSiteStrictMode, cookies["refreshToken"].SameSite)
+		assert.Equal(t, 5400, cookies["refreshToken"].MaxAge) // Expires with the refresh token.
+		assert.Equal(t, responseBody.CSRFToken, cookies["csrfToken"].Value)
+		assert.NotEqual(t, "somecsrftoken", responseBody.CSRFToken)
+		assert.False(t, cookies["csrfToken"].HttpOnly)
+	})
+
+	t.Run("Missing CSRF token", func(t *testing.T) {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", http.NoBody)
+		request.AddCookie(&http.Cookie{Name: "refreshToken", Value: validTokenString})
+		request.AddCookie(&http.Cookie{Name: "csrfToken", Value: "somecsrftoken"})
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+	})
+
+	t.Run("Missing refresh token cookie", func(t *testing.T) {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		// The refresh token in the body is not used in the browser session mode.
+		requestBody, _ := json.Marshal(gin.H{
+			"refreshToken": validTokenString,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/tokens", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	})
+}
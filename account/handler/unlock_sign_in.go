@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// adminScope is the scope machine clients need to administer users.
+const adminScope = "users:admin"
+
+// UnlockSignIn handler lets admin clients lift the sign in lock
+// of a user, which too many failed attempts caused.
+func (h *Handler) UnlockSignIn(context *gin.Context) {
+	// Only machine clients granted the admin scope are let through as a service.
+	if _, ok := context.Get("service"); !ok {
+		err := apperrors.NewForbidden("Only admin clients can unlock sign ins")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	userID, err := uuid.Parse(context.Param("id"))
+
+	if err != nil {
+		err := apperrors.NewBadRequest("User id must be a valid uuid")
+		context.JSON(err.Status(), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	if err := h.UserService.UnlockSignIn(context.Request.Context(), userID); err != nil {
+		log.Printf("Failed to unlock sign ins of the user: %v. Error: %v\n", userID, err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"message": "sign ins of the user were unlocked successfully!",
+	})
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestUnlockSignIn(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userID, _ := uuid.NewRandom()
+
+	newRouter := func(mockUserService *mocks.MockUserService, caller string, value interface{}) *gin.Engine {
+		// Creates a test context for setting the caller.
+		router := gin.Default()
+		router.Use(func(context *gin.Context) {
+			context.Set(caller, value)
+		})
+
+		NewHandler(&Config{
+			Router:      router,
+			UserService: mockUserService,
+		})
+
+		return router
+	}
+
+	adminClient := &model.ServicePrincipal{ClientID: "admin", Scopes: []string{"users:admin"}}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("UnlockSignIn", mock.Anything, userID).Return(nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, "service", adminClient)
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/signin-lock", userID), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"message": "sign ins of the user were unlocked successfully!",
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Users can't unlock", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, "user", &model.User{UserID: userID})
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/signin-lock", userID), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusForbidden, responseRecorder.Code)
+		mockUserService.AssertNotCalled(t, "UnlockSignIn", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		mockError := apperrors.NewNotFound("uid", userID.String())
+
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.On("UnlockSignIn", mock.Anything, userID).Return(mockError)
+
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockUserService, "service", adminClient)
+
+		request, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/%s/signin-lock", userID), nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusNotFound, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// UserInfo handler returns the claims about the user an access token
+// was issued to, as described in OpenID Connect Core section 5.3.
+// The token is sent in the Authorization header, or in the form
+// body of a POST for clients which cannot send the header.
+func (h *Handler) UserInfo(context *gin.Context) {
+	accessToken := context.PostForm("access_token")
+
+	if scheme, token, ok := strings.Cut(context.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		accessToken = token
+	}
+
+	if accessToken == "" {
+		oauthError(context, apperrors.NewOAuthError(apperrors.InvalidToken, "An access token is required"))
+		return
+	}
+
+	ctx := context.Request.Context()
+	userInfo, err := h.OAuthService.UserInfo(ctx, accessToken)
+
+	if err != nil {
+		log.Printf("Failed to get the user info: %v\n", err.Error())
+		oauthError(context, err)
+		return
+	}
+
+	context.Header("Cache-Control", "no-store")
+	context.JSON(http.StatusOK, userInfo)
+}
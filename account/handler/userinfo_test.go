@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestUserInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(mockOAuthService *mocks.MockOAuthService) *gin.Engine {
+		router := gin.Default()
+
+		NewHandler(&Config{
+			Router:       router,
+			OAuthService: mockOAuthService,
+		})
+
+		return router
+	}
+
+	userInfo := &model.UserInfo{
+		Subject: "someuserid",
+		Name:    "Kostya Kostyan",
+	}
+
+	t.Run("Bearer token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("UserInfo", mock.Anything, "sometoken").Return(userInfo, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/userinfo", nil)
+		request.Header.Set("Authorization", "Bearer sometoken")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(userInfo)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, "no-store", responseRecorder.Header().Get("Cache-Control"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Form token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("UserInfo", mock.Anything, "sometoken").Return(userInfo, nil)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		form := url.Values{"access_token": {"sometoken"}}
+		request, _ := http.NewRequest(http.MethodPost, "/userinfo", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		mockOAuthService.AssertExpectations(t)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		mockOAuthService := new(mocks.MockOAuthService)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/userinfo", nil)
+		router.ServeHTTP(responseRecorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, responseRecorder.Header().Get("WWW-Authenticate"))
+		mockOAuthService.AssertNotCalled(t, "UserInfo")
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockError := apperrors.NewOAuthError(apperrors.InvalidToken, "The access token is invalid")
+		mockOAuthService := new(mocks.MockOAuthService)
+		mockOAuthService.On("UserInfo", mock.Anything, "invalidtoken").Return(nil, mockError)
+
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+		router := newRouter(mockOAuthService)
+
+		request, _ := http.NewRequest(http.MethodGet, "/userinfo", nil)
+		request.Header.Set("Authorization", "Bearer invalidtoken")
+		router.ServeHTTP(responseRecorder, request)
+
+		responseBody, _ := json.Marshal(mockError)
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, `Bearer error="invalid_token"`, responseRecorder.Header().Get("WWW-Authenticate"))
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+		mockOAuthService.AssertExpectations(t)
+	})
+}
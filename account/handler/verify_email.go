@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail handler verifies the email a verification link was sent to.
+// The token of the link authenticates the request.
+func (h *Handler) VerifyEmail(context *gin.Context) {
+	var request verifyEmailRequest
+
+	if ok := bindData(context, &request); !ok {
+		return
+	}
+
+	ctx := context.Request.Context()
+	user, err := h.UserService.VerifyEmail(ctx, request.Token)
+
+	if err != nil {
+		log.Printf("Failed to verify the email: %v\n", err.Error())
+
+		context.JSON(apperrors.Status(err), gin.H{
+			"error": err,
+		})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"user": user,
+	})
+}
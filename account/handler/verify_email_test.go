@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockUser := &model.User{
+		UserID:        uuid.New(),
+		Email:         "kostya@kostya.com",
+		EmailVerified: true,
+	}
+	mockError := apperrors.NewAuthorization("Invalid or expired email verification link")
+
+	mockUserService := new(mocks.MockUserService)
+	mockUserService.On("VerifyEmail", mock.Anything, "somevalidtoken").Return(mockUser, nil)
+	mockUserService.On("VerifyEmail", mock.Anything, "someexpiredtoken").Return(nil, mockError)
+
+	router := gin.Default()
+
+	NewHandler(&Config{
+		Router:      router,
+		UserService: mockUserService,
+	})
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		// A response recorder for getting written an http response.
+		responseRecorder := httptest.NewRecorder()
+
+		requestBody, _ := json.Marshal(gin.H{
+			"token": token,
+		})
+
+		request, _ := http.NewRequest(http.MethodPost, "/email/verify", bytes.NewBuffer(requestBody))
+		request.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(responseRecorder, request)
+
+		return responseRecorder
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		responseRecorder := serve("somevalidtoken")
+
+		responseBody, _ := json.Marshal(gin.H{
+			"user": mockUser,
+		})
+
+		assert.Equal(t, http.StatusOK, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		responseRecorder := serve("someexpiredtoken")
+
+		responseBody, _ := json.Marshal(gin.H{
+			"error": mockError,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+		assert.Equal(t, responseBody, responseRecorder.Body.Bytes())
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		responseRecorder := serve("")
+
+		assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+	})
+}
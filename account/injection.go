@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/yachnytskyi/base-go/account/handler"
+	"github.com/yachnytskyi/base-go/account/model"
 	"github.com/yachnytskyi/base-go/account/repository"
 	"github.com/yachnytskyi/base-go/account/service"
 )
@@ -27,19 +31,163 @@ func inject(d *dataSources) (*gin.Engine, error) {
 	 */
 	userRepository := repository.NewUserRepository(d.DB)
 	tokenRepository := repository.NewTokenRepository(d.RedisClient)
+	securityEventRepository := repository.NewSecurityEventRepository(d.DB)
+	oauthClientRepository := repository.NewOAuthClientRepository(d.DB)
+	apiKeyRepository := repository.NewAPIKeyRepository(d.DB)
+	passkeyRepository := repository.NewPasskeyRepository(d.DB)
+	signInAttemptRepository := repository.NewSignInAttemptRepository(d.RedisClient)
 
 	bucketName := os.Getenv("GOOGLE_CLOUD_IMAGE_BUCKET")
 	imageRepository := repository.NewImageRepository(d.StorageClient, bucketName)
 
+	// Emails are only logged until a mail service is set up.
+	mailSender := repository.NewLogMailSender()
+
 	/*
 	 * service layer.
 	 */
+	// Load where password reset links lead and how long they are valid.
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+
+	if passwordResetURL == "" {
+		return nil, fmt.Errorf("PASSWORD_RESET_URL must be set")
+	}
+
+	passwordResetExpiration, err := strconv.ParseInt(os.Getenv("PASSWORD_RESET_EXPIRATION"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PASSWORD_RESET_EXPIRATION as int: %w", err)
+	}
+
+	// Load where email verification links lead, how long they are valid
+	// and the secret they are signed with.
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	emailVerificationSecret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+
+	if emailVerificationURL == "" || emailVerificationSecret == "" {
+		return nil, fmt.Errorf("EMAIL_VERIFICATION_URL and EMAIL_VERIFICATION_SECRET must be set")
+	}
+
+	emailVerificationExpiration, err := strconv.ParseInt(os.Getenv("EMAIL_VERIFICATION_EXPIRATION"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse EMAIL_VERIFICATION_EXPIRATION as int: %w", err)
+	}
+
+	magicLinkURL := os.Getenv("MAGIC_LINK_URL")
+	magicLinkSecret := os.Getenv("MAGIC_LINK_SECRET")
+
+	if magicLinkURL == "" || magicLinkSecret == "" {
+		return nil, fmt.Errorf("MAGIC_LINK_URL and MAGIC_LINK_SECRET must be set")
+	}
+
+	magicLinkExpiration, err := strconv.ParseInt(os.Getenv("MAGIC_LINK_EXPIRATION"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MAGIC_LINK_EXPIRATION as int: %w", err)
+	}
+
+	// Sign in links for unknown emails create the account when enabled.
+	magicLinkSignUp, err := strconv.ParseBool(os.Getenv("MAGIC_LINK_SIGNUP"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MAGIC_LINK_SIGNUP as bool: %w", err)
+	}
+
+	// Load the limits new passwords have to meet.
+	passwordMinLength, err := strconv.ParseInt(os.Getenv("PASSWORD_MIN_LENGTH"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PASSWORD_MIN_LENGTH as int: %w", err)
+	}
+
+	passwordMaxLength, err := strconv.ParseInt(os.Getenv("PASSWORD_MAX_LENGTH"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PASSWORD_MAX_LENGTH as int: %w", err)
+	}
+
+	passwordMinStrength, err := strconv.ParseInt(os.Getenv("PASSWORD_MIN_STRENGTH"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse PASSWORD_MIN_STRENGTH as int: %w", err)
+	}
+
+	if passwordMinLength > passwordMaxLength || passwordMinStrength < 0 || passwordMinStrength > 4 {
+		return nil, fmt.Errorf("PASSWORD_MIN_LENGTH must not exceed PASSWORD_MAX_LENGTH and PASSWORD_MIN_STRENGTH must be from 0 to 4")
+	}
+
+	// Load the local Have I Been Pwned dataset new passwords are screened against.
+	// Either a file sorted by hash or a directory of range buckets. Screening is off without it.
+	var breachedPasswordRepository model.BreachedPasswordRepository
+
+	if hibpDatasetPath := os.Getenv("HIBP_DATASET_PATH"); hibpDatasetPath != "" {
+		breachedPasswordRepository, err = repository.NewHIBPPasswordRepository(hibpDatasetPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not open the HIBP dataset: %w", err)
+		}
+	}
+
+	hibpBreachThreshold, err := strconv.ParseInt(os.Getenv("HIBP_BREACH_THRESHOLD"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse HIBP_BREACH_THRESHOLD as int: %w", err)
+	}
+
+	passwordPolicy := service.NewPasswordPolicy(&service.PasswordPolicyConfig{
+		MinLength:         int(passwordMinLength),
+		MaxLength:         int(passwordMaxLength),
+		MinStrength:       int(passwordMinStrength),
+		BreachedPasswords: breachedPasswordRepository,
+		BreachThreshold:   int(hibpBreachThreshold),
+	})
+
+	// Load how many sign in attempts may fail before they are locked, and for how long.
+	signInMaxFailures, err := strconv.ParseInt(os.Getenv("SIGNIN_MAX_FAILURES"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SIGNIN_MAX_FAILURES as int: %w", err)
+	}
+
+	signInIPMaxFailures, err := strconv.ParseInt(os.Getenv("SIGNIN_IP_MAX_FAILURES"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SIGNIN_IP_MAX_FAILURES as int: %w", err)
+	}
+
+	signInBackoffBase, err := strconv.ParseInt(os.Getenv("SIGNIN_BACKOFF_BASE"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SIGNIN_BACKOFF_BASE as int: %w", err)
+	}
+
+	signInLockoutMax, err := strconv.ParseInt(os.Getenv("SIGNIN_LOCKOUT_MAX"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SIGNIN_LOCKOUT_MAX as int: %w", err)
+	}
+
+	signInFailureWindow, err := strconv.ParseInt(os.Getenv("SIGNIN_FAILURE_WINDOW"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SIGNIN_FAILURE_WINDOW as int: %w", err)
+	}
+
 	userService := service.NewUserService(&service.UserConfig{
-		UserRepository:  userRepository,
-		ImageRepository: imageRepository,
+		UserRepository:              userRepository,
+		ImageRepository:             imageRepository,
+		TokenRepository:             tokenRepository,
+		MailSender:                  mailSender,
+		SignInAttemptRepository:     signInAttemptRepository,
+		SignInMaxFailures:           int(signInMaxFailures),
+		SignInIPMaxFailures:         int(signInIPMaxFailures),
+		SignInBackoffBase:           signInBackoffBase,
+		SignInLockoutMax:            signInLockoutMax,
+		SignInFailureWindow:         signInFailureWindow,
+		PasswordPolicy:              passwordPolicy,
+		PasswordResetURL:            passwordResetURL,
+		PasswordResetExpiration:     passwordResetExpiration,
+		EmailVerificationURL:        emailVerificationURL,
+		EmailVerificationSecret:     emailVerificationSecret,
+		EmailVerificationExpiration: emailVerificationExpiration,
+		MagicLinkURL:                magicLinkURL,
+		MagicLinkSecret:             magicLinkSecret,
+		MagicLinkExpiration:         magicLinkExpiration,
+		MagicLinkSignUp:             magicLinkSignUp,
 	})
 
-	// Load rsa keys.
+	// Load the algorithm ID tokens are signed with, such as RS256, PS256, ES256 or EdDSA.
+	idTokenAlgorithm := os.Getenv("ID_TOKEN_ALGORITHM")
+
+	// Load signing keys.
 	privateKeyFile := os.Getenv("PRIVATE_KEY_FILE")
 	private, err := ioutil.ReadFile(privateKeyFile)
 
@@ -47,7 +195,7 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not read private key pem file: %w", err)
 	}
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(private)
+	privateKey, err := service.ParsePrivateKeyPEM(private)
 
 	if err != nil {
 		return nil, fmt.Errorf("could not parse private key: %w", err)
@@ -60,14 +208,75 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not read public key pem file: %w", err)
 	}
 
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(public)
+	publicKey, err := service.ParsePublicKeyPEM(public)
 
 	if err != nil {
 		return nil, fmt.Errorf("could not parse public key: %w", err)
 	}
 
-	// Load refresh token secret from env variable.
-	refreshSecret := os.Getenv("REFRESH_SECRET")
+	// The private key signs new ID tokens. Other public keys are accepted
+	// for verification, which lets us publish an upcoming key or keep
+	// a retiring key around while its tokens expire.
+	keyRing, err := service.NewKeyRing(idTokenAlgorithm, privateKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create key ring: %w", err)
+	}
+
+	if _, err := keyRing.AddVerificationKey(idTokenAlgorithm, publicKey); err != nil {
+		return nil, fmt.Errorf("could not add public key to key ring: %w", err)
+	}
+
+	// Load comma separated verification keys from env variable.
+	// Entries may be prefixed with the algorithm of the key, as in ES256:./next_key.pem,
+	// and use the algorithm of the signing key otherwise.
+	verificationKeyFiles := os.Getenv("VERIFICATION_KEY_FILES")
+
+	for _, verificationKeyFile := range strings.Split(verificationKeyFiles, ",") {
+		verificationKeyFile = strings.TrimSpace(verificationKeyFile)
+
+		if verificationKeyFile == "" {
+			continue
+		}
+
+		verificationAlgorithm := idTokenAlgorithm
+
+		if algorithm, file, ok := strings.Cut(verificationKeyFile, ":"); ok {
+			verificationAlgorithm, verificationKeyFile = algorithm, file
+		}
+
+		verification, err := ioutil.ReadFile(verificationKeyFile)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read verification key pem file %s: %w", verificationKeyFile, err)
+		}
+
+		verificationKey, err := service.ParsePublicKeyPEM(verification)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not parse verification key %s: %w", verificationKeyFile, err)
+		}
+
+		if _, err := keyRing.AddVerificationKey(verificationAlgorithm, verificationKey); err != nil {
+			return nil, fmt.Errorf("could not add verification key %s to key ring: %w", verificationKeyFile, err)
+		}
+	}
+
+	// Load comma separated refresh token secrets from env variable, newest first.
+	// The newest secret signs new refresh tokens while the others keep
+	// validating the tokens they signed, so a secret can be rotated
+	// without signing everyone out.
+	var refreshSecrets []string
+
+	for _, refreshSecret := range strings.Split(os.Getenv("REFRESH_SECRETS"), ",") {
+		if refreshSecret = strings.TrimSpace(refreshSecret); refreshSecret != "" {
+			refreshSecrets = append(refreshSecrets, refreshSecret)
+		}
+	}
+
+	if len(refreshSecrets) == 0 {
+		return nil, fmt.Errorf("REFRESH_SECRETS must contain at least one secret")
+	}
 
 	// Load expiration lengts from env variables and parse as int.
 	idTokenExpiration := os.Getenv("ID_TOKEN_EXPIRATION")
@@ -83,13 +292,212 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse REFRESH_TOKEN_EXPIRATION as int: %w", err)
 	}
 
+	// Load the limits of a session across refresh token rotations, 0 for no limit.
+	// The maximum age counts from the sign in, the idle timeout from the last refresh.
+	sessionMaxAge, err := strconv.ParseInt(os.Getenv("SESSION_MAX_AGE"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SESSION_MAX_AGE as int: %w", err)
+	}
+
+	sessionIdleTimeout, err := strconv.ParseInt(os.Getenv("SESSION_IDLE_TIMEOUT"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SESSION_IDLE_TIMEOUT as int: %w", err)
+	}
+
+	// Decide whether a reused refresh token revokes all of the user's sessions
+	// or only the sessions of its token family.
+	refreshReuseRevokeAll := os.Getenv("REFRESH_REUSE_REVOKE_ALL")
+
+	revokeAllOnReuse, err := strconv.ParseBool(refreshReuseRevokeAll)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse REFRESH_REUSE_REVOKE_ALL as bool: %w", err)
+	}
+
+	// Load how long an ID token which is not revoked is remembered in process.
+	// Revocations made by other instances may go unnoticed for this long.
+	revocationCacheExpiration := os.Getenv("REVOCATION_CACHE_EXPIRATION")
+
+	revocationCacheExpirationInt, err := strconv.ParseInt(revocationCacheExpiration, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse REVOCATION_CACHE_EXPIRATION as int: %w", err)
+	}
+
+	// Load the issuer and audience ID tokens are issued with and verified against.
+	issuer := os.Getenv("ID_TOKEN_ISSUER")
+	audience := os.Getenv("ID_TOKEN_AUDIENCE")
+
+	if issuer == "" || audience == "" {
+		return nil, fmt.Errorf("ID_TOKEN_ISSUER and ID_TOKEN_AUDIENCE must be set")
+	}
+
+	// Load comma separated profile claims added to ID tokens from env variable.
+	var profileClaims []string
+
+	for _, profileClaim := range strings.Split(os.Getenv("ID_TOKEN_PROFILE_CLAIMS"), ",") {
+		profileClaim = strings.TrimSpace(profileClaim)
+
+		if profileClaim == "" {
+			continue
+		}
+
+		if !service.IsProfileClaim(profileClaim) {
+			return nil, fmt.Errorf("unsupported ID_TOKEN_PROFILE_CLAIMS entry: %s", profileClaim)
+		}
+
+		profileClaims = append(profileClaims, profileClaim)
+	}
+
+	clockSkew := os.Getenv("ID_TOKEN_CLOCK_SKEW")
+
+	clockSkewInt, err := strconv.ParseInt(clockSkew, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ID_TOKEN_CLOCK_SKEW as int: %w", err)
+	}
+
 	tokenService := service.NewTokenService(&service.TokenServiceConfig{
-		TokenRepository:          tokenRepository,
-		PrivateKey:               privateKey,
-		PublicKey:                publicKey,
-		RefreshSecret:            refreshSecret,
-		IDExpirationSecrets:      idExpiration,
-		RefreshExpirationSecrets: refreshExpiration,
+		TokenRepository:           tokenRepository,
+		SecurityEventRepository:   securityEventRepository,
+		APIKeyRepository:          apiKeyRepository,
+		KeyRing:                   keyRing,
+		RefreshSecrets:            refreshSecrets,
+		IDExpirationSecrets:       idExpiration,
+		RefreshExpirationSecrets:  refreshExpiration,
+		SessionMaxAge:             sessionMaxAge,
+		SessionIdleTimeout:        sessionIdleTimeout,
+		RevokeAllOnReuse:          revokeAllOnReuse,
+		RevocationCacheExpiration: revocationCacheExpirationInt,
+		Issuer:                    issuer,
+		Audience:                  audience,
+		ProfileClaims:             profileClaims,
+		ClockSkew:                 clockSkewInt,
+	})
+
+	// Load comma separated OAuth clients, in the clientID:secret:scopes format,
+	// and register them as machine clients, which may revoke their own tokens
+	// and get access tokens with the space separated scopes through the client
+	// credentials grant. The introspect scope registers a resource server, which
+	// may call the introspection endpoint. Clients which sign users in have
+	// redirect URIs and are registered in the oauth_clients table.
+	for _, oauthClient := range strings.Split(os.Getenv("OAUTH_CLIENTS"), ",") {
+		oauthClient = strings.TrimSpace(oauthClient)
+
+		if oauthClient == "" {
+			continue
+		}
+
+		clientID, credentials, ok := strings.Cut(oauthClient, ":")
+		clientSecret, scopes, _ := strings.Cut(credentials, ":")
+
+		if !ok || clientID == "" || clientSecret == "" {
+			return nil, fmt.Errorf("could not parse OAUTH_CLIENTS entry %q as clientID:secret:scopes", oauthClient)
+		}
+
+		secretHash, err := service.HashClientSecret(clientSecret)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not hash the secret of OAuth client %s: %w", clientID, err)
+		}
+
+		err = oauthClientRepository.Upsert(context.Background(), &model.OAuthClient{
+			ClientID:   clientID,
+			SecretHash: secretHash,
+			Name:       clientID,
+			Scopes:     strings.Fields(scopes),
+			GrantTypes: []string{model.ClientCredentialsGrant},
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("could not register OAuth client %s: %w", clientID, err)
+		}
+	}
+
+	// The authorization endpoint of the discovery document is the page
+	// of the frontend which signs the user in and asks for consent.
+	authorizationEndpoint := os.Getenv("OIDC_AUTHORIZATION_ENDPOINT")
+
+	if authorizationEndpoint == "" {
+		return nil, fmt.Errorf("OIDC_AUTHORIZATION_ENDPOINT must be set")
+	}
+
+	oauthService := service.NewOAuthService(&service.OAuthServiceConfig{
+		TokenService:          tokenService,
+		TokenRepository:       tokenRepository,
+		UserRepository:        userRepository,
+		OAuthClientRepository: oauthClientRepository,
+		Issuer:                issuer,
+		AuthorizationEndpoint: authorizationEndpoint,
+	})
+
+	apiKeyService := service.NewAPIKeyService(&service.APIKeyServiceConfig{
+		APIKeyRepository: apiKeyRepository,
+	})
+
+	// Load the key TOTP secrets are encrypted with, base64 encoded,
+	// the issuer authenticator apps show, and how long an MFA challenge
+	// of a password sign in can be answered for.
+	mfaEncryptionKey, err := base64.StdEncoding.DecodeString(os.Getenv("MFA_ENCRYPTION_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode MFA_ENCRYPTION_KEY as base64: %w", err)
+	}
+
+	if keyLength := len(mfaEncryptionKey); keyLength != 16 && keyLength != 24 && keyLength != 32 {
+		return nil, fmt.Errorf("MFA_ENCRYPTION_KEY must be 16, 24 or 32 bytes long")
+	}
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+
+	if totpIssuer == "" {
+		return nil, fmt.Errorf("TOTP_ISSUER must be set")
+	}
+
+	mfaChallengeExpiration, err := strconv.ParseInt(os.Getenv("MFA_CHALLENGE_EXPIRATION"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MFA_CHALLENGE_EXPIRATION as int: %w", err)
+	}
+
+	// Load the domain passkeys are scoped to, the name authenticators
+	// show for it, the origins of the pages allowed to use passkeys,
+	// and how long a WebAuthn ceremony can be finished for.
+	webAuthnRPID := os.Getenv("WEBAUTHN_RP_ID")
+
+	if webAuthnRPID == "" {
+		return nil, fmt.Errorf("WEBAUTHN_RP_ID must be set")
+	}
+
+	var webAuthnOrigins []string
+
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webAuthnOrigins = append(webAuthnOrigins, origin)
+		}
+	}
+
+	if len(webAuthnOrigins) == 0 {
+		return nil, fmt.Errorf("WEBAUTHN_ORIGINS must list at least one origin")
+	}
+
+	webAuthnChallengeExpiration, err := strconv.ParseInt(os.Getenv("WEBAUTHN_CHALLENGE_EXPIRATION"), 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse WEBAUTHN_CHALLENGE_EXPIRATION as int: %w", err)
+	}
+
+	passkeyService := service.NewPasskeyService(&service.PasskeyServiceConfig{
+		UserRepository:      userRepository,
+		PasskeyRepository:   passkeyRepository,
+		TokenRepository:     tokenRepository,
+		RPID:                webAuthnRPID,
+		RPName:              os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins:             webAuthnOrigins,
+		ChallengeExpiration: webAuthnChallengeExpiration,
+	})
+
+	mfaService := service.NewMFAService(&service.MFAServiceConfig{
+		UserRepository:      userRepository,
+		TokenRepository:     tokenRepository,
+		PasskeyService:      passkeyService,
+		Issuer:              totpIssuer,
+		EncryptionKey:       mfaEncryptionKey,
+		ChallengeExpiration: mfaChallengeExpiration,
 	})
 
 	// Initialize gin.Engine
@@ -111,13 +519,56 @@ func inject(d *dataSources) (*gin.Engine, error) {
 		return nil, fmt.Errorf("could not parse HANDLER_TIMEOUT as int: %w", err)
 	}
 
+	// Decide whether browsers keep the refresh token in a cookie
+	// instead of getting it in the response body.
+	sessionCookieMode, err := strconv.ParseBool(os.Getenv("SESSION_COOKIE_MODE"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SESSION_COOKIE_MODE as bool: %w", err)
+	}
+
+	var sessionCookie *handler.SessionCookieConfig
+
+	if sessionCookieMode {
+		sameSite := http.SameSiteStrictMode
+
+		switch sessionCookieSameSite := os.Getenv("SESSION_COOKIE_SAMESITE"); sessionCookieSameSite {
+		case "", "strict":
+		case "lax":
+			sameSite = http.SameSiteLaxMode
+		case "none":
+			sameSite = http.SameSiteNoneMode
+		default:
+			return nil, fmt.Errorf("unsupported SESSION_COOKIE_SAMESITE: %s", sessionCookieSameSite)
+		}
+
+		sessionCookie = &handler.SessionCookieConfig{
+			Domain:   os.Getenv("SESSION_COOKIE_DOMAIN"),
+			Path:     baseURL,
+			SameSite: sameSite,
+		}
+	}
+
+	// Load the rate limits of routes. Counts are kept in Redis,
+	// and in memory while Redis can't be reached.
+	rateLimits, err := handler.ParseRateLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse RATE_LIMITS: %w", err)
+	}
+
 	handler.NewHandler(&handler.Config{
 		Router:          router,
 		UserService:     userService,
 		TokenService:    tokenService,
+		OAuthService:    oauthService,
+		APIKeyService:   apiKeyService,
+		MFAService:      mfaService,
+		PasskeyService:  passkeyService,
+		SessionCookie:   sessionCookie,
 		BaseURL:         baseURL,
 		TimeoutDuration: time.Duration(time.Duration(handlerTimeoutInt) * time.Second),
 		MaxBodyBytes:    maxBodyBytesParsed,
+		RateLimiter:     repository.NewRateLimiter(d.RedisClient),
+		RateLimits:      rateLimits,
 	})
 
 	return router, nil
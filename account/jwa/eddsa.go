@@ -0,0 +1,58 @@
+package jwa
+
+import (
+	"crypto/ed25519"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningMethodEd25519 implements the EdDSA algorithm of RFC 8037
+// for Ed25519 keys, which jwt-go doesn't support.
+type SigningMethodEd25519 struct{}
+
+// SigningMethodEdDSA is registered with jwt-go, so tokens
+// with the EdDSA alg header can be parsed.
+var SigningMethodEdDSA = &SigningMethodEd25519{}
+
+func init() {
+	jwt.RegisterSigningMethod(EdDSA, func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+// Alg returns the name of the algorithm used in the alg header.
+func (m *SigningMethodEd25519) Alg() string {
+	return EdDSA
+}
+
+// Sign signs with an ed25519.PrivateKey.
+func (m *SigningMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+
+	if !ok || len(privateKey) != ed25519.PrivateKeySize {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// Verify verifies with an ed25519.PublicKey.
+func (m *SigningMethodEd25519) Verify(signingString string, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+
+	if !ok || len(publicKey) != ed25519.PublicKeySize {
+		return jwt.ErrInvalidKeyType
+	}
+
+	signatureBytes, err := jwt.DecodeSegment(signature)
+
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), signatureBytes) {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
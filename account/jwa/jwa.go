@@ -0,0 +1,262 @@
+// Package jwa holds the JSON Web Algorithms ID tokens are signed with.
+// It is shared by the service layer, which signs the tokens and publishes
+// the keys, and by the verifier package other services use to verify them.
+package jwa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// Algorithms ID tokens can be signed with.
+// EC and Ed25519 keys give much smaller tokens than RSA keys.
+const (
+	RS256 = "RS256" // RSA PKCS #1 v1.5 with SHA-256.
+	PS256 = "PS256" // RSA PSS with SHA-256.
+	ES256 = "ES256" // ECDSA on the P-256 curve with SHA-256.
+	EdDSA = "EdDSA" // Ed25519.
+)
+
+// algorithm signs and verifies tokens with one kind of key.
+type algorithm struct {
+	method jwt.SigningMethod
+
+	// publicJWK describes the public key as a JSON Web Key with only
+	// the key type specific members set. It returns false if the key
+	// can't be used with the algorithm.
+	publicJWK func(publicKey crypto.PublicKey) (*model.JSONWebKey, bool)
+
+	// publicKey is the reverse of publicJWK.
+	publicKey func(jwk *model.JSONWebKey) (crypto.PublicKey, error)
+}
+
+var algorithms = map[string]*algorithm{
+	RS256: {method: jwt.SigningMethodRS256, publicJWK: rsaJWK, publicKey: rsaPublicKey},
+	PS256: {method: jwt.SigningMethodPS256, publicJWK: rsaJWK, publicKey: rsaPublicKey},
+	ES256: {method: jwt.SigningMethodES256, publicJWK: p256JWK, publicKey: p256PublicKey},
+	EdDSA: {method: SigningMethodEdDSA, publicJWK: ed25519JWK, publicKey: ed25519PublicKey},
+}
+
+// Algorithms lists the names of the supported algorithms.
+func Algorithms() []string {
+	return []string{RS256, PS256, ES256, EdDSA}
+}
+
+// SigningMethod returns the jwt signing method of the named algorithm.
+func SigningMethod(name string) (jwt.SigningMethod, error) {
+	algorithm, ok := algorithms[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", name)
+	}
+
+	return algorithm.method, nil
+}
+
+// PublicJWK describes a public key used with the named algorithm as a
+// JSON Web Key. The kid and use members are left for the caller to set.
+func PublicJWK(name string, publicKey crypto.PublicKey) (*model.JSONWebKey, error) {
+	algorithm, ok := algorithms[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", name)
+	}
+
+	jwk, ok := algorithm.publicJWK(publicKey)
+
+	if !ok {
+		return nil, fmt.Errorf("key of type %T can't be used with %s", publicKey, name)
+	}
+
+	jwk.Algorithm = name
+
+	return jwk, nil
+}
+
+// PublicKey returns the public key a JSON Web Key describes.
+// The key must name one of the supported algorithms in its alg member.
+func PublicKey(jwk *model.JSONWebKey) (crypto.PublicKey, error) {
+	algorithm, ok := algorithms[jwk.Algorithm]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", jwk.Algorithm)
+	}
+
+	return algorithm.publicKey(jwk)
+}
+
+// Thumbprint computes the RFC 7638 thumbprint of a JSON Web Key,
+// so the same key always gets the same kid without extra configuration.
+func Thumbprint(jwk *model.JSONWebKey) (string, error) {
+	// Only the required members of the key type are included.
+	// Maps are marshalled with sorted keys, which gives the
+	// lexicographic order the thumbprint needs.
+	members := map[string]string{"kty": jwk.KeyType}
+
+	switch jwk.KeyType {
+	case "RSA":
+		members["e"] = jwk.E
+		members["n"] = jwk.N
+	case "EC":
+		members["crv"] = jwk.Curve
+		members["x"] = jwk.X
+		members["y"] = jwk.Y
+	case "OKP":
+		members["crv"] = jwk.Curve
+		members["x"] = jwk.X
+	default:
+		return "", fmt.Errorf("unsupported key type: %s", jwk.KeyType)
+	}
+
+	thumbprintInput, err := json.Marshal(members)
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(thumbprintInput)
+
+	return encode(sum[:]), nil
+}
+
+func rsaJWK(publicKey crypto.PublicKey) (*model.JSONWebKey, bool) {
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &model.JSONWebKey{
+		KeyType: "RSA",
+		N:       encode(rsaKey.N.Bytes()),
+		E:       encode(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}, true
+}
+
+func rsaPublicKey(jwk *model.JSONWebKey) (crypto.PublicKey, error) {
+	if jwk.KeyType != "RSA" {
+		return nil, fmt.Errorf("unexpected key type %s for %s", jwk.KeyType, jwk.Algorithm)
+	}
+
+	n, err := decode(jwk.N)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decode(jwk.E)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("rsa exponent is too large")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+func p256JWK(publicKey crypto.PublicKey) (*model.JSONWebKey, bool) {
+	ecKey, ok := publicKey.(*ecdsa.PublicKey)
+
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, false
+	}
+
+	// Coordinates are padded to the size of the curve as required by RFC 7518.
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+
+	return &model.JSONWebKey{
+		KeyType: "EC",
+		Curve:   "P-256",
+		X:       encode(ecKey.X.FillBytes(make([]byte, size))),
+		Y:       encode(ecKey.Y.FillBytes(make([]byte, size))),
+	}, true
+}
+
+func p256PublicKey(jwk *model.JSONWebKey) (crypto.PublicKey, error) {
+	if jwk.KeyType != "EC" || jwk.Curve != "P-256" {
+		return nil, fmt.Errorf("unexpected key type %s %s for %s", jwk.KeyType, jwk.Curve, jwk.Algorithm)
+	}
+
+	x, err := decode(jwk.X)
+
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := decode(jwk.Y)
+
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+		return nil, fmt.Errorf("ec point is not on the curve")
+	}
+
+	return publicKey, nil
+}
+
+func ed25519JWK(publicKey crypto.PublicKey) (*model.JSONWebKey, bool) {
+	edKey, ok := publicKey.(ed25519.PublicKey)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &model.JSONWebKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       encode(edKey),
+	}, true
+}
+
+func ed25519PublicKey(jwk *model.JSONWebKey) (crypto.PublicKey, error) {
+	if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" {
+		return nil, fmt.Errorf("unexpected key type %s %s for %s", jwk.KeyType, jwk.Curve, jwk.Algorithm)
+	}
+
+	x, err := decode(jwk.X)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key has the wrong size")
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+func decode(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,93 @@
+package jwa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+func TestJWA(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	privateKeys := map[string]crypto.Signer{
+		RS256: rsaKey,
+		PS256: rsaKey,
+		ES256: ecKey,
+		EdDSA: edKey,
+	}
+
+	t.Run("Public keys survive a round trip through JWK", func(t *testing.T) {
+		for algorithm, privateKey := range privateKeys {
+			jwk, err := PublicJWK(algorithm, privateKey.Public())
+			assert.NoError(t, err)
+			assert.Equal(t, algorithm, jwk.Algorithm)
+
+			publicKey, err := PublicKey(jwk)
+			assert.NoError(t, err, algorithm)
+			assert.Equal(t, privateKey.Public(), publicKey, algorithm)
+		}
+	})
+
+	t.Run("Signs and verifies with every algorithm", func(t *testing.T) {
+		for algorithm, privateKey := range privateKeys {
+			method, err := SigningMethod(algorithm)
+			assert.NoError(t, err)
+
+			signedString, err := jwt.NewWithClaims(method, jwt.StandardClaims{Subject: "someuser"}).SignedString(privateKey)
+			assert.NoError(t, err, algorithm)
+
+			token, err := jwt.Parse(signedString, func(token *jwt.Token) (interface{}, error) {
+				return privateKey.Public(), nil
+			})
+			assert.NoError(t, err, algorithm)
+			assert.Equal(t, algorithm, token.Method.Alg())
+		}
+	})
+
+	t.Run("Rejects keys the algorithm can't use", func(t *testing.T) {
+		p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+		_, err := PublicJWK(ES256, &p384Key.PublicKey)
+		assert.Error(t, err)
+
+		_, err = PublicJWK(EdDSA, &rsaKey.PublicKey)
+		assert.Error(t, err)
+
+		_, err = PublicJWK("HS256", &rsaKey.PublicKey)
+		assert.Error(t, err)
+
+		_, err = PublicKey(&model.JSONWebKey{KeyType: "RSA", Algorithm: ES256})
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects EC points which are not on the curve", func(t *testing.T) {
+		jwk, _ := PublicJWK(ES256, &ecKey.PublicKey)
+		jwk.Y = jwk.X
+
+		_, err := PublicKey(jwk)
+		assert.Error(t, err)
+	})
+
+	t.Run("Thumbprint", func(t *testing.T) {
+		// Example from RFC 7638 section 3.1.
+		jwk := &model.JSONWebKey{
+			KeyType: "RSA",
+			E:       "AQAB",
+			N:       "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		}
+
+		thumbprint, err := Thumbprint(jwk)
+		assert.NoError(t, err)
+		assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+	})
+}
@@ -0,0 +1 @@
+DROP TABLE security_events;
\ No newline at end of file
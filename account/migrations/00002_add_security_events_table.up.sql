@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS security_events (
+  event_id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+  type VARCHAR NOT NULL,
+  details VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS security_events_user_id_idx ON security_events (user_id, created_at);
\ No newline at end of file
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  client_id VARCHAR PRIMARY KEY,
+  secret_hash VARCHAR NOT NULL DEFAULT '',
+  name VARCHAR NOT NULL,
+  redirect_uris VARCHAR[] NOT NULL DEFAULT '{}',
+  scopes VARCHAR[] NOT NULL DEFAULT '{}',
+  first_party BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+  user_id uuid NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+  client_id VARCHAR NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+  scopes VARCHAR[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, client_id)
+);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS grant_types;
\ No newline at end of file
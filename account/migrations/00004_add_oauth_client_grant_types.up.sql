@@ -0,0 +1 @@
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS grant_types VARCHAR[] NOT NULL DEFAULT '{}';
\ No newline at end of file
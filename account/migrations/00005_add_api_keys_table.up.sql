@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  key_id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+  name VARCHAR NOT NULL,
+  prefix VARCHAR NOT NULL,
+  key_hash VARCHAR NOT NULL UNIQUE,
+  scopes VARCHAR[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
\ No newline at end of file
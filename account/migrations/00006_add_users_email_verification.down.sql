@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS pending_email,
+  DROP COLUMN IF EXISTS email_verified;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE users
+  DROP COLUMN IF EXISTS recovery_codes,
+  DROP COLUMN IF EXISTS totp_last_step,
+  DROP COLUMN IF EXISTS totp_enabled,
+  DROP COLUMN IF EXISTS totp_secret;
\ No newline at end of file
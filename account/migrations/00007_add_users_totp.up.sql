@@ -0,0 +1,5 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS totp_secret VARCHAR NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS recovery_codes VARCHAR[] NOT NULL DEFAULT '{}';
\ No newline at end of file
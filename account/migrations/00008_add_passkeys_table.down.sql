@@ -0,0 +1 @@
+DROP TABLE passkeys;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+  passkey_id uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  name VARCHAR NOT NULL,
+  public_key BYTEA NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  aaguid BYTEA NOT NULL,
+  transports VARCHAR[] NOT NULL DEFAULT '{}',
+  backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
\ No newline at end of file
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKeyPrefix starts every API key, so keys can be told apart from ID tokens
+// and found by secret scanners.
+const APIKeyPrefix = "bgo_"
+
+// Scopes an API key can be restricted to. A key without scopes
+// can do everything the user can.
+const (
+	APIKeyScopeRead  = "read"  // Safe requests, such as GET.
+	APIKeyScopeWrite = "write" // Requests which change something.
+)
+
+// APIKey is a long lived credential a user creates for scripts and CI.
+// Only the hash of the key is stored. The prefix is the start of the key,
+// which lets the user recognize it in the list of their keys.
+type APIKey struct {
+	KeyID      uuid.UUID      `db:"key_id" json:"id"`
+	UserID     uuid.UUID      `db:"user_id" json:"-"`
+	Name       string         `db:"name" json:"name"`
+	Prefix     string         `db:"prefix" json:"prefix"`
+	KeyHash    string         `db:"key_hash" json:"-"`
+	Scopes     pq.StringArray `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time     `db:"expires_at" json:"expiresAt"`
+	LastUsedAt *time.Time     `db:"last_used_at" json:"lastUsedAt"`
+	CreatedAt  time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// IsExpired reports whether the key has expired at the time.
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// AllowsMethod reports whether the key's scopes allow a request with the http method.
+func (k *APIKey) AllowsMethod(method string) bool {
+	if len(k.Scopes) == 0 {
+		return true
+	}
+
+	scope := APIKeyScopeWrite
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		scope = APIKeyScopeRead
+	}
+
+	return k.hasScope(scope)
+}
+
+// hasScope reports whether the key was restricted to the scope, among others.
+func (k *APIKey) hasScope(scope string) bool {
+	for _, value := range k.Scopes {
+		if value == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAPIKeyScope reports whether an API key can be restricted to the scope.
+func IsAPIKeyScope(scope string) bool {
+	return scope == APIKeyScopeRead || scope == APIKeyScopeWrite
+}
@@ -3,7 +3,9 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // Type holds a type string and integer code for the error.
@@ -14,10 +16,12 @@ const (
 	Authorization        Type = "AUTHORIZATION"          // Authentication Failures -.
 	BadRequest           Type = "BAD_REQUEST"            // Validation errors / BadInput.
 	Conflict             Type = "CONFLICT"               // Already exists (eg, create account with existent email) - 409.
+	Forbidden            Type = "FORBIDDEN"              // Authenticated, but not allowed to do this - 403.
 	Internal             Type = "INTERNAL"               // Server (500) and fallback errors.
 	NotFound             Type = "NOTFOUND"               // For not finding resource.
 	PayloadTooLarge      Type = "PAYLOAD_TOO_LARGE"      // For uploading tons of JSON, or an image over the limit - 413.
 	ServiceUnavailable   Type = "SERVICE_UNAVAILABLE"    // For long running handlers.
+	TooManyRequests      Type = "TOO_MANY_REQUESTS"      // For too many attempts or requests - 429.
 	UnsupportedMediaType Type = "UNSUPPORTED_MEDIA_TYPE" // For http 415.
 )
 
@@ -25,7 +29,16 @@ const (
 // which is helpful in returning a consistent
 // error type/message from API endpoints.
 type Error struct {
-	Type    Type   `json:"type"`
+	Type       Type     `json:"type"`
+	Message    string   `json:"message"`
+	Reasons    []Reason `json:"reasons,omitempty"`
+	RetryAfter int      `json:"retryAfter,omitempty"` // Seconds until a request can be retried.
+}
+
+// Reason tells clients one of the reasons a request was rejected
+// for, with a code they can show their own message for.
+type Reason struct {
+	Code    string `json:"code"`
 	Message string `json:"message"`
 }
 
@@ -47,6 +60,8 @@ func (e *Error) Status() int {
 		return http.StatusBadRequest
 	case Conflict:
 		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
 	case Internal:
 		return http.StatusInternalServerError
 	case NotFound:
@@ -55,6 +70,8 @@ func (e *Error) Status() int {
 		return http.StatusRequestEntityTooLarge
 	case ServiceUnavailable:
 		return http.StatusServiceUnavailable
+	case TooManyRequests:
+		return http.StatusTooManyRequests
 	case UnsupportedMediaType:
 		return http.StatusUnsupportedMediaType
 	default:
@@ -93,6 +110,16 @@ func NewBadRequest(reason string) *Error {
 	}
 }
 
+// NewBadRequestWithReasons to create 400 errors listing every reason
+// the request was rejected for.
+func NewBadRequestWithReasons(reason string, reasons []Reason) *Error {
+	return &Error{
+		Type:    BadRequest,
+		Message: fmt.Sprintf("Bad request. Reason: %v", reason),
+		Reasons: reasons,
+	}
+}
+
 // NewConflict to create an error for 409.
 func NewConflict(name string, value string) *Error {
 	return &Error{
@@ -101,6 +128,14 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewForbidden to create an error for 403.
+func NewForbidden(reason string) *Error {
+	return &Error{
+		Type:    Forbidden,
+		Message: reason,
+	}
+}
+
 // NewInternal for 500 errors and unknown errors.
 func NewInternal() *Error {
 	return &Error{
@@ -133,6 +168,16 @@ func NewServiceUnavailable() *Error {
 	}
 }
 
+// NewTooManyRequests to create an error for 429, which tells
+// after how many seconds the request can be retried.
+func NewTooManyRequests(reason string, retryAfter time.Duration) *Error {
+	return &Error{
+		Type:       TooManyRequests,
+		Message:    reason,
+		RetryAfter: int(math.Ceil(retryAfter.Seconds())),
+	}
+}
+
 // NewUnsupportedMediaType to create an error for 415.
 func NewUnsupportedMediaType(reason string) *Error {
 	return &Error{
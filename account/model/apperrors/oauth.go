@@ -0,0 +1,57 @@
+package apperrors
+
+import "net/http"
+
+// OAuthErrorCode is an error code defined by the OAuth 2.0 RFCs.
+type OAuthErrorCode string
+
+// "Set" of OAuth error codes we respond with.
+const (
+	AccessDenied            OAuthErrorCode = "access_denied"             // The user denied the authorization - 403.
+	InvalidClient           OAuthErrorCode = "invalid_client"            // Client authentication failed - 401.
+	InvalidGrant            OAuthErrorCode = "invalid_grant"             // Code or refresh token is invalid, expired or issued to another client - 400.
+	InvalidRequest          OAuthErrorCode = "invalid_request"           // Missing or malformed parameter - 400.
+	InvalidScope            OAuthErrorCode = "invalid_scope"             // Scope is not allowed for the client - 400.
+	InvalidToken            OAuthErrorCode = "invalid_token"             // Bearer token is invalid, expired or revoked, from RFC 6750 - 401.
+	ServerError             OAuthErrorCode = "server_error"              // Fallback for unexpected errors - 500.
+	UnauthorizedClient      OAuthErrorCode = "unauthorized_client"       // Client may not use the grant type - 400.
+	UnsupportedGrantType    OAuthErrorCode = "unsupported_grant_type"    // Grant type is not supported - 400.
+	UnsupportedResponseType OAuthErrorCode = "unsupported_response_type" // Response type is not supported - 400.
+	UnsupportedTokenType    OAuthErrorCode = "unsupported_token_type"    // Token type can't be revoked - 400.
+)
+
+// OAuthError holds an error in the format required by RFC 6749 section 5.2,
+// which standard OAuth clients and libraries expect from our OAuth endpoints.
+type OAuthError struct {
+	Code        OAuthErrorCode `json:"error"`
+	Description string         `json:"error_description,omitempty"`
+}
+
+// Error satisfies standard error interface.
+func (e *OAuthError) Error() string {
+	return e.Description
+}
+
+// Status maps OAuth error codes to http status codes.
+func (e *OAuthError) Status() int {
+	switch e.Code {
+	case AccessDenied:
+		return http.StatusForbidden
+	case InvalidClient, InvalidToken:
+		return http.StatusUnauthorized
+	case InvalidGrant, InvalidRequest, InvalidScope, UnauthorizedClient:
+		return http.StatusBadRequest
+	case UnsupportedGrantType, UnsupportedResponseType, UnsupportedTokenType:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewOAuthError to create an OAuth error response.
+func NewOAuthError(code OAuthErrorCode, description string) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+	}
+}
@@ -14,18 +14,77 @@ type UserService interface {
 	ClearProfileImage(ctx context.Context, userID uuid.UUID) error
 	Get(ctx context.Context, userID uuid.UUID) (*User, error)
 	SignUp(ctx context.Context, user *User) error
-	SignIn(ctx context.Context, user *User) error
+	SignIn(ctx context.Context, user *User, ipAddress string) error
+	UnlockSignIn(ctx context.Context, userID uuid.UUID) error
 	UpdateDetails(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) (*User, error)
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error)
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+	SendMagicLink(ctx context.Context, email string) error
+	SignInWithMagicLink(ctx context.Context, token string) (*User, error)
 	SetProfileImage(ctx context.Context, userID uuid.UUID, imageFileHeader *multipart.FileHeader) (*User, error)
 }
 
 // TokenService defines methods the handler layer expects to interact
 // with in regards to producting JWTs as string.
 type TokenService interface {
-	NewPairFromUser(ctx context.Context, user *User, refreshTokenID string) (*TokenPair, error)
+	NewPairFromUser(ctx context.Context, user *User, previousToken *RefreshToken, session *Session) (*TokenPair, error)
 	SignOut(ctx context.Context, userID uuid.UUID) error
-	ValidateIDToken(tokenString string) (*User, error)
+	Sessions(ctx context.Context, userID uuid.UUID) ([]*Session, error)
+	DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error
+	RevokeIDToken(ctx context.Context, tokenString string) error
+	ValidateIDToken(ctx context.Context, tokenString string) (*User, error)
+	ValidateAccessToken(ctx context.Context, tokenString string) (*User, error)
+	NewServiceToken(ctx context.Context, clientID string, scopes []string) (*AccessToken, error)
+	ValidateServiceToken(ctx context.Context, tokenString string) (*ServicePrincipal, error)
+	ValidateAPIKey(ctx context.Context, key string) (*APIKey, error)
 	ValidateRefreshToken(refreshTokenString string) (*RefreshToken, error)
+	JWKS() *JSONWebKeySet
+}
+
+// OAuthService defines methods the handler layer expects
+// for the standard OAuth endpoints.
+type OAuthService interface {
+	AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (*OAuthClient, error)
+	Introspect(ctx context.Context, client *OAuthClient, token string, tokenTypeHint string) (*TokenIntrospection, error)
+	Revoke(ctx context.Context, client *OAuthClient, token string, tokenTypeHint string) error
+	PrepareAuthorization(ctx context.Context, userID uuid.UUID, request *AuthorizationRequest) (*AuthorizationPrompt, error)
+	Authorize(ctx context.Context, user *User, request *AuthorizationRequest, approved bool) (string, error)
+	Token(ctx context.Context, request *TokenRequest) (*TokenResponse, error)
+	UserInfo(ctx context.Context, accessToken string) (*UserInfo, error)
+	Discovery() *OpenIDConfiguration
+}
+
+// APIKeyService defines methods the handler layer expects
+// for managing the API keys of a user.
+type APIKeyService interface {
+	Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error)
+	List(ctx context.Context, userID uuid.UUID) ([]*APIKey, error)
+	Revoke(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error
+}
+
+// MFAService defines methods the handler layer expects
+// for the two-factor authentication of users.
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error
+	NewChallenge(ctx context.Context, user *User) (*MFAChallenge, error)
+	VerifyChallenge(ctx context.Context, token string, code string) (*User, error)
+	PasskeyOptions(ctx context.Context, token string) (*PasskeyRequestOptions, error)
+	VerifyChallengeWithPasskey(ctx context.Context, token string, credential *PasskeyCredential) (*User, error)
+}
+
+// PasskeyService defines methods the handler layer expects
+// for registering passkeys and signing in with them.
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userID uuid.UUID) (*PasskeyCreationOptions, error)
+	FinishRegistration(ctx context.Context, userID uuid.UUID, name string, credential *PasskeyCredential) (*Passkey, error)
+	BeginSignIn(ctx context.Context, user *User) (*PasskeyRequestOptions, error)
+	FinishSignIn(ctx context.Context, user *User, credential *PasskeyCredential) (*User, error)
+	List(ctx context.Context, userID uuid.UUID) ([]*Passkey, error)
+	Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error
 }
 
 // UserRepository defines methods the service layer expects
@@ -36,14 +95,101 @@ type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
 	UpdateImage(ctx context.Context, userID uuid.UUID, imageURL string) (*User, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error
+	VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (*User, error)
+	SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodes []string, step int64) error
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
 }
 
 // TokenRepository defines methids if expects a repository
 // it interacts with to implement.
 type TokenRepository interface {
-	SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error
-	DeleteRefreshToken(ctx context.Context, userID string, previousTokenID string) error
+	SetRefreshToken(ctx context.Context, userID string, tokenID string, session *Session, expiresIn time.Duration) error
+	GetRefreshToken(ctx context.Context, userID string, tokenID string) (*Session, error)
+	DeleteRefreshToken(ctx context.Context, userID string, previousTokenID string) (*Session, error)
+	DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) (int64, error)
 	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+	GetUserSessions(ctx context.Context, userID string) ([]*Session, error)
+	RevokeIDToken(ctx context.Context, tokenID string, expiresIn time.Duration) error
+	IsIDTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	SetAuthorizationCode(ctx context.Context, code string, authorizationCode *AuthorizationCode, expiresIn time.Duration) error
+	GetAuthorizationCode(ctx context.Context, code string) (*AuthorizationCode, error)
+	ConsumeAuthorizationCode(ctx context.Context, code string) (*AuthorizationCode, error)
+	SetPasswordResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error
+	GetPasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	SetMFAChallenge(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error
+	AttemptMFAChallenge(ctx context.Context, tokenHash string) (string, int, error)
+	DeleteMFAChallenge(ctx context.Context, tokenHash string) error
+	SetPasskeyChallenge(ctx context.Context, challengeHash string, challenge *PasskeyChallenge, expiresIn time.Duration) error
+	ConsumePasskeyChallenge(ctx context.Context, challengeHash string) (*PasskeyChallenge, error)
+	UseMagicLinkToken(ctx context.Context, tokenID string, expiresIn time.Duration) (bool, error)
+}
+
+// OAuthClientRepository defines methods the service layer
+// expects for the registry of OAuth clients and the consents
+// users have given them.
+type OAuthClientRepository interface {
+	FindByID(ctx context.Context, clientID string) (*OAuthClient, error)
+	Upsert(ctx context.Context, client *OAuthClient) error
+	FindConsent(ctx context.Context, userID uuid.UUID, clientID string) (*OAuthConsent, error)
+	UpsertConsent(ctx context.Context, consent *OAuthConsent) error
+}
+
+// APIKeyRepository defines methods the service layer expects
+// any repository storing API keys to implement.
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey *APIKey) error
+	FindByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]*APIKey, error)
+	Delete(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error
+	UpdateLastUsed(ctx context.Context, keyID uuid.UUID, lastUsedAt time.Time) error
+}
+
+// PasskeyRepository defines methods the service layer expects
+// any repository storing passkeys to implement.
+type PasskeyRepository interface {
+	Create(ctx context.Context, passkey *Passkey) error
+	FindByCredentialID(ctx context.Context, credentialID []byte) (*Passkey, error)
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]*Passkey, error)
+	Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error
+	// UseSignCount records a use of the passkey, unless the sign count
+	// did not go up since the last use, which reveals a cloned authenticator.
+	UseSignCount(ctx context.Context, passkeyID uuid.UUID, signCount int64, usedAt time.Time) (bool, error)
+}
+
+// SecurityEventRepository defines methods the service layer
+// expects to record security relevant events with.
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *SecurityEvent) error
+}
+
+// SignInAttemptRepository defines methods the service layer expects
+// any repository counting failed sign in attempts to implement.
+// Keys identify who attempted to sign in, like an email or an IP address.
+type SignInAttemptRepository interface {
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// RateLimiter defines methods the handler layer expects
+// any store of request counts to implement.
+type RateLimiter interface {
+	// Allow counts a request of the key against the policy.
+	Allow(ctx context.Context, key string, policy *RateLimitPolicy) (*RateLimitResult, error)
+}
+
+// BreachedPasswordRepository defines methods the service layer
+// expects any collection of breached passwords to implement.
+type BreachedPasswordRepository interface {
+	// Count returns how many times the password with the SHA-1 hash,
+	// in hex, appeared in breaches.
+	Count(ctx context.Context, passwordHash string) (int, error)
 }
 
 // ImageRepository defines methods it expects a repository.
@@ -52,3 +198,9 @@ type ImageRepository interface {
 	DeleteProfile(ctx context.Context, objectName string) error
 	UpdateProfile(ctx context.Context, objectName string, imageFile multipart.File) (string, error)
 }
+
+// MailSender defines methods the service layer expects
+// any sender of emails to implement.
+type MailSender interface {
+	Send(ctx context.Context, mail *Mail) error
+}
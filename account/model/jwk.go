@@ -0,0 +1,22 @@
+package model
+
+// JSONWebKey is the public part of a signing key
+// as described in RFC 7517 and RFC 8037. Only the members
+// needed to verify our ID tokens are included.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus.
+	E         string `json:"e,omitempty"`   // RSA exponent.
+	Curve     string `json:"crv,omitempty"` // EC and OKP curve.
+	X         string `json:"x,omitempty"`   // EC x coordinate or OKP public key.
+	Y         string `json:"y,omitempty"`   // EC y coordinate.
+}
+
+// JSONWebKeySet is served from the jwks endpoint so other
+// services can verify ID tokens without sharing PEM files.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,8 @@
+package model
+
+// Mail is a plain text email to a user.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Authentication methods of the amr claim of ID tokens, as RFC 8176 names them.
+// RFC 8176 has no name for recovery codes, so they get one of their own,
+// along with "mfa" for relying parties which only know the standard names.
+const (
+	AMRPassword     = "pwd" // The user signed in with a password.
+	AMROTP          = "otp" // The user entered a TOTP code, or followed a sign in link.
+	AMRPasskey      = "hwk" // The user signed with the key of a passkey or security key.
+	AMRMFA          = "mfa" // The user signed in with more than one factor.
+	AMRRecoveryCode = "rc"  // The user entered one of the single use recovery codes instead of a second factor.
+)
+
+// TOTPEnrollment holds a new TOTP secret of a user. The URI is the
+// otpauth:// provisioning URI authenticator apps scan as a QR code.
+// Users who can't scan it enter the secret by hand.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// MFAChallenge is what a user with two-factor authentication enabled
+// gets instead of tokens after entering the password. The token proves
+// the password was right, and is exchanged for tokens along with a code.
+type MFAChallenge struct {
+	Token     string        `json:"mfaToken"`
+	ExpiresIn time.Duration `json:"-"`
+}
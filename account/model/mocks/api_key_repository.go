@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockAPIKeyRepository is a mock type for model.APIKeyRepository.
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of model.APIKeyRepository Create.
+func (m *MockAPIKeyRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
+	ret := m.Called(ctx, apiKey)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByHash is a mock of model.APIKeyRepository FindByHash.
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	ret := m.Called(ctx, keyHash)
+
+	var r0 *model.APIKey
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.APIKey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUser is a mock of model.APIKeyRepository FindByUser.
+func (m *MockAPIKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.APIKey
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.APIKey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete is a mock of model.APIKeyRepository Delete.
+func (m *MockAPIKeyRepository) Delete(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error {
+	ret := m.Called(ctx, userID, keyID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UpdateLastUsed is a mock of model.APIKeyRepository UpdateLastUsed.
+func (m *MockAPIKeyRepository) UpdateLastUsed(ctx context.Context, keyID uuid.UUID, lastUsedAt time.Time) error {
+	ret := m.Called(ctx, keyID, lastUsedAt)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
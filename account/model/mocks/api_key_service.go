@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockAPIKeyService is a mock type for model.APIKeyService.
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+// Create mocks concrete Create.
+func (m *MockAPIKeyService) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*model.APIKey, string, error) {
+	ret := m.Called(ctx, userID, name, scopes, expiresAt)
+
+	var r0 *model.APIKey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.APIKey)
+	}
+
+	r1 := ret.String(1)
+
+	var r2 error
+
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+// List mocks concrete List.
+func (m *MockAPIKeyService) List(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.APIKey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.APIKey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Revoke mocks concrete Revoke.
+func (m *MockAPIKeyService) Revoke(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error {
+	ret := m.Called(ctx, userID, keyID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
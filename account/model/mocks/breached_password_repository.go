@@ -0,0 +1,29 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBreachedPasswordRepository is a mock type for model.BreachedPasswordRepository.
+type MockBreachedPasswordRepository struct {
+	mock.Mock
+}
+
+// Count is a mock of BreachedPasswordRepository Count.
+func (m *MockBreachedPasswordRepository) Count(ctx context.Context, passwordHash string) (int, error) {
+	ret := m.Called(ctx, passwordHash)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
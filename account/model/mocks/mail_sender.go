@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockMailSender is a mock type for model.MailSender.
+type MockMailSender struct {
+	mock.Mock
+}
+
+// Send is a mock of MailSender Send.
+func (m *MockMailSender) Send(ctx context.Context, mail *model.Mail) error {
+	ret := m.Called(ctx, mail)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
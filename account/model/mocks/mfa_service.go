@@ -0,0 +1,134 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockMFAService is a mock type for model.MFAService.
+type MockMFAService struct {
+	mock.Mock
+}
+
+// EnrollTOTP mocks concrete EnrollTOTP.
+func (m *MockMFAService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 *model.TOTPEnrollment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TOTPEnrollment)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ConfirmTOTP mocks concrete ConfirmTOTP.
+func (m *MockMFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ret := m.Called(ctx, userID, code)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DisableTOTP mocks concrete DisableTOTP.
+func (m *MockMFAService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	ret := m.Called(ctx, userID, code)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// NewChallenge mocks concrete NewChallenge.
+func (m *MockMFAService) NewChallenge(ctx context.Context, user *model.User) (*model.MFAChallenge, error) {
+	ret := m.Called(ctx, user)
+
+	var r0 *model.MFAChallenge
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.MFAChallenge)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// VerifyChallenge mocks concrete VerifyChallenge.
+func (m *MockMFAService) VerifyChallenge(ctx context.Context, token string, code string) (*model.User, error) {
+	ret := m.Called(ctx, token, code)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// PasskeyOptions mocks concrete PasskeyOptions.
+func (m *MockMFAService) PasskeyOptions(ctx context.Context, token string) (*model.PasskeyRequestOptions, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.PasskeyRequestOptions
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyRequestOptions)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// VerifyChallengeWithPasskey mocks concrete VerifyChallengeWithPasskey.
+func (m *MockMFAService) VerifyChallengeWithPasskey(ctx context.Context, token string, credential *model.PasskeyCredential) (*model.User, error) {
+	ret := m.Called(ctx, token, credential)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
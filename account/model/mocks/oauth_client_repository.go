@@ -0,0 +1,78 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockOAuthClientRepository is a mock type for model.OAuthClientRepository.
+type MockOAuthClientRepository struct {
+	mock.Mock
+}
+
+// FindByID is a mock of model.OAuthClientRepository FindByID.
+func (m *MockOAuthClientRepository) FindByID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	ret := m.Called(ctx, clientID)
+
+	var r0 *model.OAuthClient
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OAuthClient)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Upsert is a mock of model.OAuthClientRepository Upsert.
+func (m *MockOAuthClientRepository) Upsert(ctx context.Context, client *model.OAuthClient) error {
+	ret := m.Called(ctx, client)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindConsent is a mock of model.OAuthClientRepository FindConsent.
+func (m *MockOAuthClientRepository) FindConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	ret := m.Called(ctx, userID, clientID)
+
+	var r0 *model.OAuthConsent
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OAuthConsent)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UpsertConsent is a mock of model.OAuthClientRepository UpsertConsent.
+func (m *MockOAuthClientRepository) UpsertConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	ret := m.Called(ctx, consent)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
@@ -0,0 +1,147 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockOAuthService is a mock type for model.OAuthService.
+type MockOAuthService struct {
+	mock.Mock
+}
+
+// AuthenticateClient mocks concrete AuthenticateClient.
+func (m *MockOAuthService) AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (*model.OAuthClient, error) {
+	ret := m.Called(ctx, clientID, clientSecret)
+
+	var r0 *model.OAuthClient
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OAuthClient)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Introspect mocks concrete Introspect.
+func (m *MockOAuthService) Introspect(ctx context.Context, client *model.OAuthClient, token string, tokenTypeHint string) (*model.TokenIntrospection, error) {
+	ret := m.Called(ctx, client, token, tokenTypeHint)
+
+	var r0 *model.TokenIntrospection
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TokenIntrospection)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Revoke mocks concrete Revoke.
+func (m *MockOAuthService) Revoke(ctx context.Context, client *model.OAuthClient, token string, tokenTypeHint string) error {
+	ret := m.Called(ctx, client, token, tokenTypeHint)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// PrepareAuthorization mocks concrete PrepareAuthorization.
+func (m *MockOAuthService) PrepareAuthorization(ctx context.Context, userID uuid.UUID, request *model.AuthorizationRequest) (*model.AuthorizationPrompt, error) {
+	ret := m.Called(ctx, userID, request)
+
+	var r0 *model.AuthorizationPrompt
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuthorizationPrompt)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Authorize mocks concrete Authorize.
+func (m *MockOAuthService) Authorize(ctx context.Context, user *model.User, request *model.AuthorizationRequest, approved bool) (string, error) {
+	ret := m.Called(ctx, user, request, approved)
+
+	var r0 string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Token mocks concrete Token.
+func (m *MockOAuthService) Token(ctx context.Context, request *model.TokenRequest) (*model.TokenResponse, error) {
+	ret := m.Called(ctx, request)
+
+	var r0 *model.TokenResponse
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TokenResponse)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UserInfo mocks concrete UserInfo.
+func (m *MockOAuthService) UserInfo(ctx context.Context, accessToken string) (*model.UserInfo, error) {
+	ret := m.Called(ctx, accessToken)
+
+	var r0 *model.UserInfo
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserInfo)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Discovery mocks concrete Discovery.
+func (m *MockOAuthService) Discovery() *model.OpenIDConfiguration {
+	ret := m.Called()
+
+	var r0 *model.OpenIDConfiguration
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.OpenIDConfiguration)
+	}
+
+	return r0
+}
@@ -0,0 +1,92 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockPasskeyRepository is a mock type for model.PasskeyRepository.
+type MockPasskeyRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of model.PasskeyRepository Create.
+func (m *MockPasskeyRepository) Create(ctx context.Context, passkey *model.Passkey) error {
+	ret := m.Called(ctx, passkey)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// FindByCredentialID is a mock of model.PasskeyRepository FindByCredentialID.
+func (m *MockPasskeyRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	ret := m.Called(ctx, credentialID)
+
+	var r0 *model.Passkey
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Passkey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FindByUser is a mock of model.PasskeyRepository FindByUser.
+func (m *MockPasskeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]*model.Passkey, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Passkey
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Passkey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete is a mock of model.PasskeyRepository Delete.
+func (m *MockPasskeyRepository) Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error {
+	ret := m.Called(ctx, userID, passkeyID)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UseSignCount is a mock of model.PasskeyRepository UseSignCount.
+func (m *MockPasskeyRepository) UseSignCount(ctx context.Context, passkeyID uuid.UUID, signCount int64, usedAt time.Time) (bool, error) {
+	ret := m.Called(ctx, passkeyID, signCount, usedAt)
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.Bool(0), r1
+}
@@ -0,0 +1,116 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockPasskeyService is a mock type for model.PasskeyService.
+type MockPasskeyService struct {
+	mock.Mock
+}
+
+// BeginRegistration mocks concrete BeginRegistration.
+func (m *MockPasskeyService) BeginRegistration(ctx context.Context, userID uuid.UUID) (*model.PasskeyCreationOptions, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 *model.PasskeyCreationOptions
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyCreationOptions)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FinishRegistration mocks concrete FinishRegistration.
+func (m *MockPasskeyService) FinishRegistration(ctx context.Context, userID uuid.UUID, name string, credential *model.PasskeyCredential) (*model.Passkey, error) {
+	ret := m.Called(ctx, userID, name, credential)
+
+	var r0 *model.Passkey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Passkey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// BeginSignIn mocks concrete BeginSignIn.
+func (m *MockPasskeyService) BeginSignIn(ctx context.Context, user *model.User) (*model.PasskeyRequestOptions, error) {
+	ret := m.Called(ctx, user)
+
+	var r0 *model.PasskeyRequestOptions
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyRequestOptions)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// FinishSignIn mocks concrete FinishSignIn.
+func (m *MockPasskeyService) FinishSignIn(ctx context.Context, user *model.User, credential *model.PasskeyCredential) (*model.User, error) {
+	ret := m.Called(ctx, user, credential)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// List mocks concrete List.
+func (m *MockPasskeyService) List(ctx context.Context, userID uuid.UUID) ([]*model.Passkey, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Passkey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Passkey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Delete mocks concrete Delete.
+func (m *MockPasskeyService) Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error {
+	ret := m.Called(ctx, userID, passkeyID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
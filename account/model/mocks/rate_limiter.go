@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockRateLimiter is a mock type for model.RateLimiter.
+type MockRateLimiter struct {
+	mock.Mock
+}
+
+// Allow is a mock of RateLimiter Allow.
+func (m *MockRateLimiter) Allow(ctx context.Context, key string, policy *model.RateLimitPolicy) (*model.RateLimitResult, error) {
+	ret := m.Called(ctx, key, policy)
+
+	var r0 *model.RateLimitResult
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.RateLimitResult)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
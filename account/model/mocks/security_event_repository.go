@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// MockSecurityEventRepository is a mock type for model.SecurityEventRepository.
+type MockSecurityEventRepository struct {
+	mock.Mock
+}
+
+// Create is a mock of model.SecurityEventRepository Create.
+func (m *MockSecurityEventRepository) Create(ctx context.Context, event *model.SecurityEvent) error {
+	ret := m.Called(ctx, event)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
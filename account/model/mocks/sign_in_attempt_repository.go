@@ -0,0 +1,71 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSignInAttemptRepository is a mock type for model.SignInAttemptRepository.
+type MockSignInAttemptRepository struct {
+	mock.Mock
+}
+
+// AddFailure is a mock of SignInAttemptRepository AddFailure.
+func (m *MockSignInAttemptRepository) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	ret := m.Called(ctx, key, window)
+
+	var r0 int
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Lock is a mock of SignInAttemptRepository Lock.
+func (m *MockSignInAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	ret := m.Called(ctx, key, duration)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// LockedFor is a mock of SignInAttemptRepository LockedFor.
+func (m *MockSignInAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 time.Duration
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// Reset is a mock of SignInAttemptRepository Reset.
+func (m *MockSignInAttemptRepository) Reset(ctx context.Context, key string) error {
+	ret := m.Called(ctx, key)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
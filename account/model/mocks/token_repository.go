@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
 )
 
 // MockTokenRepository is a mock type for model.TokenRepository
@@ -13,8 +14,8 @@ type MockTokenRepository struct {
 }
 
 // SetRefreshToken is a mock of model.TokenRepository SetRefreshToken.
-func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
-	ret := m.Called(ctx, userID, tokenID, expiresIn)
+func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, session *model.Session, expiresIn time.Duration) error {
+	ret := m.Called(ctx, userID, tokenID, session, expiresIn)
 
 	var r0 error
 
@@ -25,10 +26,67 @@ func (m *MockTokenRepository) SetRefreshToken(ctx context.Context, userID string
 	return r0
 }
 
+// GetRefreshToken is a mock of model.TokenRepository GetRefreshToken.
+func (m *MockTokenRepository) GetRefreshToken(ctx context.Context, userID string, tokenID string) (*model.Session, error) {
+	ret := m.Called(ctx, userID, tokenID)
+
+	var r0 *model.Session
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 // DeleteRefreshToken is a mock of model.TokenRepository DeleteRefreshToken.
-func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, previousTokenID string) error {
+func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, previousTokenID string) (*model.Session, error) {
 	ret := m.Called(ctx, userID, previousTokenID)
 
+	var r0 *model.Session
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteRefreshTokenFamily is a mock of model.TokenRepository DeleteRefreshTokenFamily.
+func (m *MockTokenRepository) DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) (int64, error) {
+	ret := m.Called(ctx, userID, familyID)
+
+	var r0 int64
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteUserRefreshTokens mocks concrete DeleteUserRefreshToken.
+func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	ret := m.Called(ctx, userID)
+
 	var r0 error
 
 	if ret.Get(0) != nil {
@@ -38,15 +96,226 @@ func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, userID str
 	return r0
 }
 
-// DeleteUserRefreshTokens mocks concrete DeleteUserRefreshToken.
-func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+// GetUserSessions is a mock of model.TokenRepository GetUserSessions.
+func (m *MockTokenRepository) GetUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
 	ret := m.Called(ctx, userID)
 
+	var r0 []*model.Session
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// RevokeIDToken is a mock of model.TokenRepository RevokeIDToken.
+func (m *MockTokenRepository) RevokeIDToken(ctx context.Context, tokenID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, tokenID, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// IsIDTokenRevoked is a mock of model.TokenRepository IsIDTokenRevoked.
+func (m *MockTokenRepository) IsIDTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	ret := m.Called(ctx, tokenID)
+
+	var r0 bool
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetAuthorizationCode is a mock of model.TokenRepository SetAuthorizationCode.
+func (m *MockTokenRepository) SetAuthorizationCode(ctx context.Context, code string, authorizationCode *model.AuthorizationCode, expiresIn time.Duration) error {
+	ret := m.Called(ctx, code, authorizationCode, expiresIn)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetAuthorizationCode is a mock of model.TokenRepository GetAuthorizationCode.
+func (m *MockTokenRepository) GetAuthorizationCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	ret := m.Called(ctx, code)
+
+	var r0 *model.AuthorizationCode
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuthorizationCode)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ConsumeAuthorizationCode is a mock of model.TokenRepository ConsumeAuthorizationCode.
+func (m *MockTokenRepository) ConsumeAuthorizationCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	ret := m.Called(ctx, code)
+
+	var r0 *model.AuthorizationCode
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AuthorizationCode)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetPasswordResetToken is a mock of TokenRepository SetPasswordResetToken.
+func (m *MockTokenRepository) SetPasswordResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, tokenHash, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// GetPasswordResetToken is a mock of TokenRepository GetPasswordResetToken.
+func (m *MockTokenRepository) GetPasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+// ConsumePasswordResetToken is a mock of TokenRepository ConsumePasswordResetToken.
+func (m *MockTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.String(0), r1
+}
+
+// SetMFAChallenge is a mock of TokenRepository SetMFAChallenge.
+func (m *MockTokenRepository) SetMFAChallenge(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	ret := m.Called(ctx, tokenHash, userID, expiresIn)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// AttemptMFAChallenge is a mock of TokenRepository AttemptMFAChallenge.
+func (m *MockTokenRepository) AttemptMFAChallenge(ctx context.Context, tokenHash string) (string, int, error) {
+	ret := m.Called(ctx, tokenHash)
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return ret.String(0), ret.Int(1), r2
+}
+
+// DeleteMFAChallenge is a mock of TokenRepository DeleteMFAChallenge.
+func (m *MockTokenRepository) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	ret := m.Called(ctx, tokenHash)
+
 	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SetPasskeyChallenge is a mock of TokenRepository SetPasskeyChallenge.
+func (m *MockTokenRepository) SetPasskeyChallenge(ctx context.Context, challengeHash string, challenge *model.PasskeyChallenge, expiresIn time.Duration) error {
+	ret := m.Called(ctx, challengeHash, challenge, expiresIn)
 
+	var r0 error
 	if ret.Get(0) != nil {
 		r0 = ret.Get(0).(error)
 	}
 
 	return r0
 }
+
+// ConsumePasskeyChallenge is a mock of TokenRepository ConsumePasskeyChallenge.
+func (m *MockTokenRepository) ConsumePasskeyChallenge(ctx context.Context, challengeHash string) (*model.PasskeyChallenge, error) {
+	ret := m.Called(ctx, challengeHash)
+
+	var r0 *model.PasskeyChallenge
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.PasskeyChallenge)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// UseMagicLinkToken is a mock of TokenRepository UseMagicLinkToken.
+func (m *MockTokenRepository) UseMagicLinkToken(ctx context.Context, tokenID string, expiresIn time.Duration) (bool, error) {
+	ret := m.Called(ctx, tokenID, expiresIn)
+
+	var r0 bool
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
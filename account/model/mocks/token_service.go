@@ -14,8 +14,8 @@ type MockTokenService struct {
 }
 
 // NewPairFromUser mocks concrete NewPairFromUser.
-func (m *MockTokenService) NewPairFromUser(ctx context.Context, user *model.User, refreshTokenID string) (*model.TokenPair, error) {
-	ret := m.Called(ctx, user, refreshTokenID)
+func (m *MockTokenService) NewPairFromUser(ctx context.Context, user *model.User, previousToken *model.RefreshToken, session *model.Session) (*model.TokenPair, error) {
+	ret := m.Called(ctx, user, previousToken, session)
 
 	// First value passed to "Return".
 	var r0 *model.TokenPair
@@ -45,9 +45,52 @@ func (m *MockTokenService) SignOut(ctx context.Context, userID uuid.UUID) error
 	return r0
 }
 
+// Sessions mocks concrete Sessions.
+func (m *MockTokenService) Sessions(ctx context.Context, userID uuid.UUID) ([]*model.Session, error) {
+	ret := m.Called(ctx, userID)
+
+	var r0 []*model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]*model.Session)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// DeleteSession mocks concrete DeleteSession.
+func (m *MockTokenService) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	ret := m.Called(ctx, userID, sessionID)
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// RevokeIDToken mocks concrete RevokeIDToken.
+func (m *MockTokenService) RevokeIDToken(ctx context.Context, tokenString string) error {
+	ret := m.Called(ctx, tokenString)
+
+	var r0 error
+
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
 // ValidateIDToken mocks concrete ValidateIDToken.
-func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	ret := m.Called(tokenString)
+func (m *MockTokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
+	ret := m.Called(ctx, tokenString)
 
 	// First value passed to "Return".
 	var r0 *model.User
@@ -65,6 +108,78 @@ func (m *MockTokenService) ValidateIDToken(tokenString string) (*model.User, err
 	return r0, r1
 }
 
+// ValidateAccessToken mocks concrete ValidateAccessToken.
+func (m *MockTokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*model.User, error) {
+	ret := m.Called(ctx, tokenString)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// NewServiceToken mocks concrete NewServiceToken.
+func (m *MockTokenService) NewServiceToken(ctx context.Context, clientID string, scopes []string) (*model.AccessToken, error) {
+	ret := m.Called(ctx, clientID, scopes)
+
+	var r0 *model.AccessToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.AccessToken)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ValidateAPIKey mocks concrete ValidateAPIKey.
+func (m *MockTokenService) ValidateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	ret := m.Called(ctx, key)
+
+	var r0 *model.APIKey
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.APIKey)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ValidateServiceToken mocks concrete ValidateServiceToken.
+func (m *MockTokenService) ValidateServiceToken(ctx context.Context, tokenString string) (*model.ServicePrincipal, error) {
+	ret := m.Called(ctx, tokenString)
+
+	var r0 *model.ServicePrincipal
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.ServicePrincipal)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 // ValidateRefreshToken mocks concrete ValidateRefreshToken.
 func (m *MockTokenService) ValidateRefreshToken(refreshTokenString string) (*model.RefreshToken, error) {
 	ret := m.Called(refreshTokenString)
@@ -82,3 +197,15 @@ func (m *MockTokenService) ValidateRefreshToken(refreshTokenString string) (*mod
 
 	return r0, r1
 }
+
+// JWKS mocks concrete JWKS.
+func (m *MockTokenService) JWKS() *model.JSONWebKeySet {
+	ret := m.Called()
+
+	var r0 *model.JSONWebKeySet
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.JSONWebKeySet)
+	}
+
+	return r0
+}
@@ -90,3 +90,93 @@ func (m *MockUserRepository) UpdateImage(ctx context.Context, userID uuid.UUID,
 
 	return r0, r1
 }
+
+// UpdatePassword is a mock of UserRepository UpdatePassword.
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error {
+	ret := m.Called(ctx, userID, password)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// VerifyEmail is a mock of UserRepository VerifyEmail.
+func (m *MockUserRepository) VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (*model.User, error) {
+	ret := m.Called(ctx, userID, email)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SetTOTPSecret is a mock of UserRepository SetTOTPSecret.
+func (m *MockUserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	ret := m.Called(ctx, userID, secret)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// EnableTOTP is a mock of UserRepository EnableTOTP.
+func (m *MockUserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodes []string, step int64) error {
+	ret := m.Called(ctx, userID, recoveryCodes, step)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// DisableTOTP is a mock of UserRepository DisableTOTP.
+func (m *MockUserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	ret := m.Called(ctx, userID)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UseTOTPStep is a mock of UserRepository UseTOTPStep.
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	ret := m.Called(ctx, userID, step)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.Bool(0), r1
+}
+
+// UseRecoveryCode is a mock of UserRepository UseRecoveryCode.
+func (m *MockUserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	ret := m.Called(ctx, userID, codeHash)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.Bool(0), r1
+}
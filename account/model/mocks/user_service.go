@@ -61,8 +61,20 @@ func (m *MockUserService) SignUp(ctx context.Context, user *model.User) error {
 }
 
 // SignIn is a mock for UserService.SignIn
-func (m *MockUserService) SignIn(ctx context.Context, user *model.User) error {
-	ret := m.Called(ctx, user)
+func (m *MockUserService) SignIn(ctx context.Context, user *model.User, ipAddress string) error {
+	ret := m.Called(ctx, user, ipAddress)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// UnlockSignIn is a mock for UserService.UnlockSignIn
+func (m *MockUserService) UnlockSignIn(ctx context.Context, userID uuid.UUID) error {
+	ret := m.Called(ctx, userID)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -102,3 +114,93 @@ func (m *MockUserService) SetProfileImage(ctx context.Context, userID uuid.UUID,
 
 	return r0, r1
 }
+
+// UpdatePassword is a mock of UserService.UpdatePassword
+func (m *MockUserService) UpdatePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) (*model.User, error) {
+	ret := m.Called(ctx, userID, currentPassword, newPassword)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// ForgotPassword is a mock of UserService.ForgotPassword
+func (m *MockUserService) ForgotPassword(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// ResetPassword is a mock of UserService.ResetPassword
+func (m *MockUserService) ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error) {
+	ret := m.Called(ctx, token, newPassword)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return ret.Get(0).(uuid.UUID), r1
+}
+
+// VerifyEmail is a mock of UserService.VerifyEmail
+func (m *MockUserService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+// SendMagicLink is a mock of UserService.SendMagicLink
+func (m *MockUserService) SendMagicLink(ctx context.Context, email string) error {
+	ret := m.Called(ctx, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+// SignInWithMagicLink is a mock of UserService.SignInWithMagicLink
+func (m *MockUserService) SignInWithMagicLink(ctx context.Context, token string) (*model.User, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
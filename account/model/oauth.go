@@ -0,0 +1,232 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Token types used by the OAuth endpoints. Our ID token
+// doubles as the access token for our APIs.
+const (
+	AccessTokenType  = "access_token"
+	RefreshTokenType = "refresh_token"
+)
+
+// TokenIntrospection is the response of the
+// introspection endpoint as described in RFC 7662.
+type TokenIntrospection struct {
+	Active    bool     `json:"active"`
+	TokenType string   `json:"token_type,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+}
+
+// Scopes of OpenID Connect. The profile and email scopes
+// decide which claims clients get about the user.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// ScopeIntrospect registers a client as a resource server,
+// which may ask the introspection endpoint about tokens.
+const ScopeIntrospect = "introspect"
+
+// Grant types supported by the token endpoint.
+const (
+	AuthorizationCodeGrant = "authorization_code"
+	RefreshTokenGrant      = "refresh_token"
+	ClientCredentialsGrant = "client_credentials"
+)
+
+// CodeChallengeMethodS256 is the only PKCE method we accept,
+// as the plain method doesn't protect a stolen code.
+const CodeChallengeMethodS256 = "S256"
+
+// OAuthClient is an application registered to sign users in through OAuth.
+// Public clients, such as single page and mobile apps, can't keep a secret
+// and have no SecretHash. First party clients are ours and skip the consent.
+// Machine clients, such as our backend jobs, use the client credentials
+// grant and act on their own behalf with the scopes they are registered with.
+type OAuthClient struct {
+	ClientID     string         `db:"client_id" json:"clientID"`
+	SecretHash   string         `db:"secret_hash" json:"-"`
+	Name         string         `db:"name" json:"name"`
+	RedirectURIs pq.StringArray `db:"redirect_uris" json:"redirectURIs"`
+	Scopes       pq.StringArray `db:"scopes" json:"scopes"`
+	GrantTypes   pq.StringArray `db:"grant_types" json:"grantTypes"`
+	FirstParty   bool           `db:"first_party" json:"firstParty"`
+	CreatedAt    time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// IsPublic reports whether the client has no secret.
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+// IsResourceServer reports whether the client may introspect tokens.
+func (c *OAuthClient) IsResourceServer() bool {
+	for _, scope := range c.Scopes {
+		if scope == ScopeIntrospect {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AllowsGrant reports whether the client may use the grant type.
+// Clients registered without grant types sign users in, so
+// they may use the authorization code and refresh token grants.
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grantType == AuthorizationCodeGrant || grantType == RefreshTokenGrant
+	}
+
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServicePrincipal is a machine client calling our APIs with an
+// access token of the client credentials grant, instead of a user.
+type ServicePrincipal struct {
+	ClientID string
+	Scopes   []string
+	TokenID  string
+}
+
+// HasScopes reports whether the client was granted all of the scopes.
+func (p *ServicePrincipal) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+
+		for _, value := range p.Scopes {
+			if value == scope {
+				granted = true
+				break
+			}
+		}
+
+		if !granted {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OAuthConsent records the scopes a user has allowed a client.
+type OAuthConsent struct {
+	UserID    uuid.UUID      `db:"user_id" json:"userID"`
+	ClientID  string         `db:"client_id" json:"clientID"`
+	Scopes    pq.StringArray `db:"scopes" json:"scopes"`
+	CreatedAt time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// AuthorizationRequest holds the parameters a client sends
+// to the authorization endpoint, as described in RFC 6749 and RFC 7636.
+type AuthorizationRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce"`
+}
+
+// AuthorizationPrompt is what the user is asked to allow.
+// Consent is not required for first party clients or
+// scopes the user has already allowed.
+type AuthorizationPrompt struct {
+	Client          *OAuthClient `json:"client"`
+	Scopes          []string     `json:"scopes"`
+	ConsentRequired bool         `json:"consentRequired"`
+}
+
+// AuthorizationCode is stored for the short time until
+// the client exchanges the code for tokens.
+type AuthorizationCode struct {
+	ClientID      string    `json:"clientID"`
+	RedirectURI   string    `json:"redirectURI"`
+	UserID        uuid.UUID `json:"userID"`
+	Scope         string    `json:"scope"`
+	CodeChallenge string    `json:"codeChallenge"`
+	Nonce         string    `json:"nonce,omitempty"`
+	// How and when the user signed in, so the tokens of the client tell the same.
+	AMR      []string  `json:"amr,omitempty"`
+	AuthTime time.Time `json:"authTime,omitempty"`
+}
+
+// TokenRequest holds the parameters of a request to the token endpoint.
+// Which of them are required depends on the grant type. The session
+// holds the client's metadata, which is stored with the refresh token.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	Session      *Session
+}
+
+// TokenResponse is the response of the token endpoint
+// as described in RFC 6749 section 5.1.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// UserInfo is the response of the UserInfo endpoint of OpenID Connect.
+// Clients only get the claims of the scopes the user allowed them.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Website       string `json:"website,omitempty"`
+}
+
+// OpenIDConfiguration is the discovery document of OpenID Connect,
+// which lets standard clients find our endpoints and capabilities.
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
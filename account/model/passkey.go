@@ -0,0 +1,118 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Ceremonies a WebAuthn challenge is issued for.
+const (
+	PasskeyRegistration   = "registration"
+	PasskeyAuthentication = "authentication"
+)
+
+// Passkey is a WebAuthn credential a user signs in with instead of a password,
+// or answers an MFA challenge with. Only the public key is stored.
+// The sign count of authenticators which count their signatures only goes up,
+// so a lower count reveals a cloned authenticator.
+type Passkey struct {
+	PasskeyID      uuid.UUID      `db:"passkey_id" json:"id"`
+	UserID         uuid.UUID      `db:"user_id" json:"-"`
+	CredentialID   []byte         `db:"credential_id" json:"-"`
+	Name           string         `db:"name" json:"name"`
+	PublicKey      []byte         `db:"public_key" json:"-"`
+	SignCount      int64          `db:"sign_count" json:"-"`
+	AAGUID         []byte         `db:"aaguid" json:"-"`
+	Transports     pq.StringArray `db:"transports" json:"transports"`
+	BackupEligible bool           `db:"backup_eligible" json:"synced"` // Synced passkeys live on all devices of the user.
+	LastUsedAt     *time.Time     `db:"last_used_at" json:"lastUsedAt"`
+	CreatedAt      time.Time      `db:"created_at" json:"createdAt"`
+}
+
+// PasskeyChallenge is the state of a WebAuthn ceremony kept until the response
+// comes back. Sign in challenges of passwordless sign ins have no user.
+type PasskeyChallenge struct {
+	Challenge        string    `json:"challenge"`
+	Ceremony         string    `json:"ceremony"`
+	UserID           uuid.UUID `json:"userID,omitempty"`
+	UserVerification bool      `json:"userVerification"` // Whether the user has to be verified, like with a PIN or fingerprint.
+}
+
+// PasskeyRelyingParty names the site passkeys are created for.
+type PasskeyRelyingParty struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// PasskeyUser is the account a passkey is created for. The ID is the
+// user handle authenticators return on sign in, which is the user ID.
+type PasskeyUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// PasskeyCredentialParameter is a kind of key the site accepts.
+type PasskeyCredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+// PasskeyCredentialDescriptor names a credential of the user.
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyAuthenticatorSelection tells which authenticators may be used.
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions are the options of navigator.credentials.create,
+// with binary values base64url encoded the way WebAuthn Level 3 serializes them.
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RelyingParty           PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	CredentialParameters   []PasskeyCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                         `json:"timeout"` // Milliseconds.
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+// PasskeyRequestOptions are the options of navigator.credentials.get.
+// Passwordless sign ins allow no credentials, so the authenticator
+// offers the passkeys it has for the site.
+type PasskeyRequestOptions struct {
+	Challenge        string                        `json:"challenge"`
+	Timeout          int64                         `json:"timeout"` // Milliseconds.
+	RelyingPartyID   string                        `json:"rpId"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                        `json:"userVerification"`
+}
+
+// PasskeyCredential is the response of an authenticator to either ceremony,
+// as PublicKeyCredential.toJSON() serializes it.
+type PasskeyCredential struct {
+	ID       string                    `json:"id" binding:"required"`
+	Type     string                    `json:"type" binding:"required,eq=public-key"`
+	Response PasskeyCredentialResponse `json:"response" binding:"required"`
+}
+
+// PasskeyCredentialResponse holds the base64url encoded response of the
+// authenticator. Registrations have an attestation object, and sign ins
+// have authenticator data and a signature.
+type PasskeyCredentialResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+	AttestationObject string   `json:"attestationObject,omitempty"`
+	Transports        []string `json:"transports,omitempty"`
+	AuthenticatorData string   `json:"authenticatorData,omitempty"`
+	Signature         string   `json:"signature,omitempty"`
+	UserHandle        string   `json:"userHandle,omitempty"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitAlgorithm decides how requests are counted against a limit.
+type RateLimitAlgorithm string
+
+const (
+	// TokenBucket allows bursts of up to the limit, and refills
+	// the bucket at the limit per period.
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+	// SlidingWindow allows the limit within any period.
+	SlidingWindow RateLimitAlgorithm = "sliding_window"
+)
+
+// RateLimitPolicy limits how many requests a key can make.
+// The name keeps the counts of different policies apart.
+type RateLimitPolicy struct {
+	Name      string
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Period    time.Duration
+}
+
+// String describes the policy the way the RateLimit-Policy header does.
+func (p *RateLimitPolicy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(p.Period.Seconds()))
+}
+
+// RateLimitResult tells whether a request is allowed
+// and how much of the limit is left.
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration // Until the whole limit is available again.
+	RetryAfter time.Duration // Until a request is allowed again, if it wasn't.
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType identifies what happened to an account.
+type SecurityEventType string
+
+// "Set" of recorded security events.
+const (
+	RefreshTokenReuse SecurityEventType = "REFRESH_TOKEN_REUSE" // An already rotated refresh token was presented.
+)
+
+// SecurityEvent is an audit record of something
+// suspicious or security relevant for a user.
+type SecurityEvent struct {
+	EventID   uuid.UUID         `db:"event_id" json:"eventID"`
+	UserID    uuid.UUID         `db:"user_id" json:"userID"`
+	Type      SecurityEventType `db:"type" json:"type"`
+	Details   string            `db:"details" json:"details"`
+	CreatedAt time.Time         `db:"created_at" json:"createdAt"`
+}
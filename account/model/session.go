@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session describes a signed in device. It is stored along with
+// each refresh token and lives as long as its refresh token family,
+// so ID is the family ID and survives token rotation.
+// Sessions started through OAuth keep the client they were
+// authorized for, so other clients can't refresh them.
+type Session struct {
+	ID              uuid.UUID `json:"id"`
+	UserAgent       string    `json:"userAgent"`
+	IP              string    `json:"ip"`
+	DeviceName      string    `json:"deviceName"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastRefreshedAt time.Time `json:"lastRefreshedAt"`
+	ClientID        string    `json:"clientID,omitempty"` // The OAuth client the tokens were issued to, if any.
+	Scope           string    `json:"scope,omitempty"`
+	Nonce           string    `json:"nonce,omitempty"` // Repeated in the ID tokens of the session as OpenID Connect requires.
+	AMR             []string  `json:"amr,omitempty"`   // How the user authenticated at the sign in, like the password and a TOTP code.
+}
@@ -1,13 +1,23 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // RefreshToken stores token properties that
 // are accessed in multiple application layers.
+// FamilyID and AuthTime, the time of the sign in,
+// are shared by all tokens rotated from the same sign in.
 type RefreshToken struct {
-	ID           uuid.UUID `json:"-"`
-	UserID       uuid.UUID `json:"-"`
-	SignedString string    `json:"refreshToken"`
+	ID           uuid.UUID     `json:"-"`
+	UserID       uuid.UUID     `json:"-"`
+	FamilyID     uuid.UUID     `json:"-"`
+	AuthTime     time.Time     `json:"-"` // Zero for tokens issued before it was recorded.
+	ExpiresIn    time.Duration `json:"-"` // How long a new token is valid for, within the limits of its session.
+	SignedString string        `json:"refreshToken,omitempty"`
 }
 
 // IDToken stores token properties that
@@ -16,8 +26,61 @@ type IDToken struct {
 	SignedString string `json:"idToken"`
 }
 
+// AccessToken is a token a machine client gets with the
+// client credentials grant. It has no refresh token, as
+// the client can authenticate again when it expires.
+type AccessToken struct {
+	SignedString string
+	ExpiresIn    time.Duration
+}
+
 // TokenPair used for returning pairs of id and refresh tokens.
 type TokenPair struct {
 	IDToken
 	RefreshToken
 }
+
+// Audience is the aud claim of a token, which RFC 7519 allows
+// to be a single string or an array of strings.
+type Audience []string
+
+// MarshalJSON writes a single audience as a string,
+// which is what most verifiers expect.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON reads either form of the claim.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+
+	*a = multiple
+
+	return nil
+}
+
+// Contains reports whether the token was issued for the audience.
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,11 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
 
 // User model.
 type User struct {
@@ -10,4 +15,20 @@ type User struct {
 	Username string    `db:"username" json:"username"`
 	ImageURL string    `db:"image_url" json:"imageURL"`
 	Website  string    `db:"website" json:"website"`
+	// Emails are verified by following a link mailed to them.
+	// A new email is pending until it is verified.
+	EmailVerified bool   `db:"email_verified" json:"emailVerified"`
+	PendingEmail  string `db:"pending_email" json:"pendingEmail,omitempty"`
+	// Users with TOTP enabled sign in with a code of their authenticator
+	// app after the password. The secret is stored encrypted, and until
+	// the user confirms it with a code, TOTP stays disabled.
+	// Only the hashes of the unused recovery codes are stored.
+	TOTPSecret    string         `db:"totp_secret" json:"-"`
+	TOTPEnabled   bool           `db:"totp_enabled" json:"totpEnabled"`
+	TOTPLastStep  int64          `db:"totp_last_step" json:"-"` // The time step of the last code used, so codes can't be replayed.
+	RecoveryCodes pq.StringArray `db:"recovery_codes" json:"-"`
+	// How and when the user signed in, for users read from an ID token.
+	// Users who just answered an MFA challenge have the methods of it.
+	AMR      []string  `db:"-" json:"-"`
+	AuthTime time.Time `db:"-" json:"-"`
 }
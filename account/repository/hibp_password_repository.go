@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// hibpPrefixLength is the length of the SHA-1 prefixes range buckets are named by.
+const hibpPrefixLength = 5
+
+// hibpMaxLineLength bounds the lines of the dataset, "HASH:COUNT" with a count of up to 20 digits.
+const hibpMaxLineLength = 64
+
+// hibpScanSize is the size of the part of a sorted file, which is scanned
+// line by line once the binary search narrowed the hash down to it.
+const hibpScanSize = 4096
+
+// hibpPasswordRepository is a BreachedPasswordRepository reading a local copy
+// of the Have I Been Pwned Pwned Passwords dataset in its SHA-1 format.
+// The dataset is either one file of "HASH:COUNT" lines sorted by hash,
+// or a directory of range buckets as the range API returns them, which
+// are files named by the first 5 characters of the hashes, holding
+// "SUFFIX:COUNT" lines of the rest of the hashes.
+type hibpPasswordRepository struct {
+	File      *os.File
+	Size      int64
+	BucketDir string
+}
+
+// NewHIBPPasswordRepository is a factory for initializing a Breached Password
+// Repository from the sorted file or the directory of range buckets at the path.
+func NewHIBPPasswordRepository(path string) (model.BreachedPasswordRepository, error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &hibpPasswordRepository{BucketDir: path}, nil
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &hibpPasswordRepository{
+		File: file,
+		Size: info.Size(),
+	}, nil
+}
+
+// Count returns how many times the password with the SHA-1 hash appeared in breaches.
+func (repository *hibpPasswordRepository) Count(ctx context.Context, passwordHash string) (int, error) {
+	passwordHash = strings.ToUpper(passwordHash)
+
+	var count int
+	var err error
+
+	if repository.File != nil {
+		count, err = repository.searchFile(passwordHash)
+	} else {
+		count, err = repository.searchBucket(passwordHash)
+	}
+
+	if err != nil {
+		log.Printf("Unable to search the breached passwords for the hash prefix: %v. Reason: %v\n", passwordHash[:hibpPrefixLength], err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return count, nil
+}
+
+// searchFile binary searches the sorted file for the hash.
+func (repository *hibpPasswordRepository) searchFile(passwordHash string) (int, error) {
+	// Lines starting before low are all smaller than the hash,
+	// and the line starting at high, if any, is not.
+	low, high := int64(0), repository.Size
+
+	for high-low > hibpScanSize {
+		start, err := repository.lineStart((low + high) / 2)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if start >= high {
+			break
+		}
+
+		line, err := repository.readLine(start)
+
+		if err != nil {
+			return 0, err
+		}
+
+		hash, count, err := parseHIBPLine(line)
+
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case hash == passwordHash:
+			return count, nil
+		case hash < passwordHash:
+			low = start + int64(len(line)) + 1
+		default:
+			high = start
+		}
+	}
+
+	part := make([]byte, high-low)
+
+	if _, err := repository.File.ReadAt(part, low); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	return searchHIBPLines(strings.Split(string(part), "\n"), passwordHash)
+}
+
+// lineStart returns the offset of the first line starting at or after the offset.
+func (repository *hibpPasswordRepository) lineStart(offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+
+	buffer := make([]byte, hibpMaxLineLength)
+	n, err := repository.File.ReadAt(buffer, offset-1)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	i := bytes.IndexByte(buffer[:n], '\n')
+
+	if i < 0 {
+		if errors.Is(err, io.EOF) {
+			return repository.Size, nil
+		}
+
+		return 0, fmt.Errorf("no line ends within %d bytes of the offset: %d", hibpMaxLineLength, offset)
+	}
+
+	return offset + int64(i), nil
+}
+
+// readLine returns the line starting at the offset, without its line ending.
+func (repository *hibpPasswordRepository) readLine(offset int64) (string, error) {
+	buffer := make([]byte, hibpMaxLineLength)
+	n, err := repository.File.ReadAt(buffer, offset)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	if i := bytes.IndexByte(buffer[:n], '\n'); i >= 0 {
+		n = i
+	}
+
+	return string(buffer[:n]), nil
+}
+
+// searchBucket searches the range bucket of the hash prefix for the rest of the hash.
+// Buckets may be named by the prefix alone or with a .txt extension.
+// A missing bucket holds no breached passwords.
+func (repository *hibpPasswordRepository) searchBucket(passwordHash string) (int, error) {
+	prefix, suffix := passwordHash[:hibpPrefixLength], passwordHash[hibpPrefixLength:]
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		bucket, err := ioutil.ReadFile(filepath.Join(repository.BucketDir, name))
+
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		return searchHIBPLines(strings.Split(string(bucket), "\n"), suffix)
+	}
+
+	return 0, nil
+}
+
+// searchHIBPLines binary searches sorted lines for the hash.
+func searchHIBPLines(lines []string, passwordHash string) (int, error) {
+	i := sort.Search(len(lines), func(i int) bool {
+		hash, _, _ := parseHIBPLine(lines[i])
+		return hash >= passwordHash
+	})
+
+	if i == len(lines) {
+		return 0, nil
+	}
+
+	hash, count, err := parseHIBPLine(lines[i])
+
+	if err != nil || hash != passwordHash {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// parseHIBPLine splits a "HASH:COUNT" line. Blank lines, like the one
+// after the final line ending, sort after every hash.
+func parseHIBPLine(line string) (string, int, error) {
+	line = strings.TrimSpace(line)
+
+	if line == "" {
+		return "\xff", 0, nil
+	}
+
+	hash, countString, ok := strings.Cut(line, ":")
+
+	if !ok {
+		return "", 0, fmt.Errorf("invalid line: %q", line)
+	}
+
+	count, err := strconv.Atoi(countString)
+
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid count of the line: %q", line)
+	}
+
+	return strings.ToUpper(hash), count, nil
+}
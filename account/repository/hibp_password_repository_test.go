@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sha1Hex(password string) string {
+	hash := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+func TestHIBPPasswordRepository(t *testing.T) {
+	// Enough hashes for the binary search to narrow down a large file.
+	counts := make(map[string]int)
+	for i := 0; i < 5000; i++ {
+		counts[sha1Hex(fmt.Sprintf("breachedpassword%d", i))] = i + 1
+	}
+
+	hashes := make([]string, 0, len(counts))
+	for hash := range counts {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	dir := t.TempDir()
+
+	t.Run("Sorted file", func(t *testing.T) {
+		var lines strings.Builder
+		for _, hash := range hashes {
+			fmt.Fprintf(&lines, "%s:%d\r\n", hash, counts[hash])
+		}
+
+		path := filepath.Join(dir, "pwned-passwords-sha1-ordered-by-hash.txt")
+		assert.NoError(t, os.WriteFile(path, []byte(lines.String()), 0600))
+
+		repository, err := NewHIBPPasswordRepository(path)
+		assert.NoError(t, err)
+
+		for _, i := range []int{0, 1, 2499, 4998, 4999} {
+			count, err := repository.Count(context.TODO(), sha1Hex(fmt.Sprintf("breachedpassword%d", i)))
+			assert.NoError(t, err)
+			assert.Equal(t, i+1, count)
+		}
+
+		// The first and the last lines.
+		count, err := repository.Count(context.TODO(), hashes[0])
+		assert.NoError(t, err)
+		assert.Equal(t, counts[hashes[0]], count)
+
+		count, err = repository.Count(context.TODO(), strings.ToLower(hashes[len(hashes)-1]))
+		assert.NoError(t, err)
+		assert.Equal(t, counts[hashes[len(hashes)-1]], count)
+
+		count, err = repository.Count(context.TODO(), sha1Hex("somerandomunbreachedpassword"))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Range buckets", func(t *testing.T) {
+		bucketDir := filepath.Join(dir, "range")
+		assert.NoError(t, os.Mkdir(bucketDir, 0700))
+
+		buckets := make(map[string]*strings.Builder)
+		for _, hash := range hashes {
+			if buckets[hash[:5]] == nil {
+				buckets[hash[:5]] = &strings.Builder{}
+			}
+			fmt.Fprintf(buckets[hash[:5]], "%s:%d\n", hash[5:], counts[hash])
+		}
+
+		for prefix, lines := range buckets {
+			assert.NoError(t, os.WriteFile(filepath.Join(bucketDir, prefix+".txt"), []byte(lines.String()), 0600))
+		}
+
+		repository, err := NewHIBPPasswordRepository(bucketDir)
+		assert.NoError(t, err)
+
+		for _, i := range []int{0, 2499, 4999} {
+			count, err := repository.Count(context.TODO(), sha1Hex(fmt.Sprintf("breachedpassword%d", i)))
+			assert.NoError(t, err)
+			assert.Equal(t, i+1, count)
+		}
+
+		count, err := repository.Count(context.TODO(), sha1Hex("somerandomunbreachedpassword"))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("Missing dataset", func(t *testing.T) {
+		_, err := NewHIBPPasswordRepository(filepath.Join(dir, "missing"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// logMailSender is a MailSender for local development,
+// which writes emails to the log instead of sending them.
+type logMailSender struct{}
+
+// NewLogMailSender is a factory for initializing a Mail Sender which logs emails.
+func NewLogMailSender() model.MailSender {
+	return &logMailSender{}
+}
+
+// Send writes the email to the log.
+func (sender *logMailSender) Send(ctx context.Context, mail *model.Mail) error {
+	log.Printf("Mail to: %s\nSubject: %s\n\n%s\n", mail.To, mail.Subject, mail.Body)
+
+	return nil
+}
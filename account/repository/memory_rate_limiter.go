@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// memorySweepInterval is how often counts which expired are dropped.
+const memorySweepInterval = time.Minute
+
+// memoryRateLimiter is a RateLimiter keeping the counts in memory,
+// so they are neither shared between replicas nor kept across restarts.
+type memoryRateLimiter struct {
+	mutex     sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// memoryRateLimitEntry holds the count of a key, which is
+// either a token bucket or a log of requests in the window.
+type memoryRateLimitEntry struct {
+	Tokens   float64
+	Updated  time.Time
+	Requests []time.Time
+	Expires  time.Time
+}
+
+// NewMemoryRateLimiter is a factory for initializing a Rate Limiter
+// which keeps the counts in memory.
+func NewMemoryRateLimiter() model.RateLimiter {
+	return newMemoryRateLimiter()
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	return &memoryRateLimiter{
+		entries:   make(map[string]*memoryRateLimitEntry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow counts a request of the key against the policy.
+func (limiter *memoryRateLimiter) Allow(ctx context.Context, key string, policy *model.RateLimitPolicy) (*model.RateLimitResult, error) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	entryKey := fmt.Sprintf("%s:%s", policy.Name, key)
+	entry, ok := limiter.entries[entryKey]
+
+	if !ok {
+		entry = &memoryRateLimitEntry{Tokens: float64(policy.Limit), Updated: now}
+		limiter.entries[entryKey] = entry
+	}
+
+	// Either way the count is back at the full limit after a period without requests.
+	entry.Expires = now.Add(policy.Period)
+
+	if policy.Algorithm == model.SlidingWindow {
+		return entry.slideWindow(now, policy), nil
+	}
+
+	return entry.takeToken(now, policy), nil
+}
+
+// takeToken refills the bucket for the time since it was last
+// updated, and takes a token out of it if there is one.
+func (entry *memoryRateLimitEntry) takeToken(now time.Time, policy *model.RateLimitPolicy) *model.RateLimitResult {
+	rate := float64(policy.Limit) / float64(policy.Period)
+
+	entry.Tokens = math.Min(float64(policy.Limit), entry.Tokens+float64(now.Sub(entry.Updated))*rate)
+	entry.Updated = now
+
+	result := &model.RateLimitResult{}
+
+	if entry.Tokens >= 1 {
+		entry.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - entry.Tokens) / rate))
+	}
+
+	result.Remaining = int(entry.Tokens)
+	result.Reset = time.Duration(math.Ceil((float64(policy.Limit) - entry.Tokens) / rate))
+
+	return result
+}
+
+// slideWindow drops the requests which left the window,
+// and logs the request if the window has room for it.
+func (entry *memoryRateLimitEntry) slideWindow(now time.Time, policy *model.RateLimitPolicy) *model.RateLimitResult {
+	windowStart := now.Add(-policy.Period)
+
+	kept := entry.Requests[:0]
+	for _, request := range entry.Requests {
+		if request.After(windowStart) {
+			kept = append(kept, request)
+		}
+	}
+	entry.Requests = kept
+
+	result := &model.RateLimitResult{}
+
+	if len(entry.Requests) < policy.Limit {
+		entry.Requests = append(entry.Requests, now)
+		result.Allowed = true
+	} else {
+		result.RetryAfter = entry.Requests[0].Add(policy.Period).Sub(now)
+	}
+
+	result.Remaining = policy.Limit - len(entry.Requests)
+	result.Reset = entry.Requests[len(entry.Requests)-1].Add(policy.Period).Sub(now)
+
+	return result
+}
+
+// sweep drops the counts which expired, once in a while.
+func (limiter *memoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, entry := range limiter.entries {
+		if now.After(entry.Expires) {
+			delete(limiter.entries, key)
+		}
+	}
+
+	limiter.lastSweep = now
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	now := time.Now()
+
+	newLimiter := func() *memoryRateLimiter {
+		limiter := newMemoryRateLimiter()
+		limiter.now = func() time.Time { return now }
+		return limiter
+	}
+
+	t.Run("Token bucket", func(t *testing.T) {
+		limiter := newLimiter()
+		policy := &model.RateLimitPolicy{Name: "POST /tokens", Algorithm: model.TokenBucket, Limit: 3, Period: 3 * time.Second}
+
+		for i := 2; i >= 0; i-- {
+			result, err := limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+			assert.NoError(t, err)
+
+			assert.True(t, result.Allowed)
+			assert.Equal(t, i, result.Remaining)
+		}
+
+		result, _ := limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, time.Second, result.RetryAfter)
+		assert.Equal(t, 3*time.Second, result.Reset)
+
+		// Other keys and policies have their own buckets.
+		result, _ = limiter.Allow(context.TODO(), "ip:203.0.113.8", policy)
+		assert.True(t, result.Allowed)
+
+		// A token is refilled every second.
+		now = now.Add(time.Second)
+
+		result, _ = limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+
+	t.Run("Sliding window", func(t *testing.T) {
+		limiter := newLimiter()
+		policy := &model.RateLimitPolicy{Name: "POST /signup", Algorithm: model.SlidingWindow, Limit: 2, Period: time.Minute}
+
+		result, _ := limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 1, result.Remaining)
+
+		now = now.Add(30 * time.Second)
+
+		result, _ = limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+
+		result, _ = limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 30*time.Second, result.RetryAfter)
+		assert.Equal(t, time.Minute, result.Reset)
+
+		// The first request leaves the window.
+		now = now.Add(30*time.Second + time.Millisecond)
+
+		result, _ = limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+	})
+
+	t.Run("Expired counts are swept", func(t *testing.T) {
+		limiter := newLimiter()
+		policy := &model.RateLimitPolicy{Name: "POST /signup", Algorithm: model.SlidingWindow, Limit: 2, Period: time.Minute}
+
+		limiter.Allow(context.TODO(), "ip:203.0.113.7", policy)
+
+		now = now.Add(2 * time.Minute)
+		limiter.Allow(context.TODO(), "ip:203.0.113.8", policy)
+
+		assert.Len(t, limiter.entries, 1)
+	})
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// pgAPIKeyRepository is data/repository implementation
+// of the service layer APIKeyRepository.
+type pgAPIKeyRepository struct {
+	DB *sqlx.DB
+}
+
+// NewAPIKeyRepository is a factory for initializing API Key Repositories.
+func NewAPIKeyRepository(db *sqlx.DB) model.APIKeyRepository {
+	return &pgAPIKeyRepository{
+		DB: db,
+	}
+}
+
+// Create stores a new API key.
+func (repository *pgAPIKeyRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, COALESCE($5, '{}'::VARCHAR[]), $6)
+		RETURNING *;
+	`
+
+	if err := repository.DB.GetContext(ctx, apiKey, query, apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, apiKey.ExpiresAt); err != nil {
+		log.Printf("Could not create an API key for userID: %v. Reason: %v\n", apiKey.UserID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByHash fetches the API key with the hash.
+func (repository *pgAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	apiKey := &model.APIKey{}
+
+	query := "SELECT * FROM api_keys WHERE key_hash=$1"
+
+	if err := repository.DB.GetContext(ctx, apiKey, query, keyHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("apiKey", "")
+		}
+
+		log.Printf("Unable to get the API key. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return apiKey, nil
+}
+
+// FindByUser fetches the API keys of a user, newest first.
+func (repository *pgAPIKeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	apiKeys := []*model.APIKey{}
+
+	query := "SELECT * FROM api_keys WHERE user_id=$1 ORDER BY created_at DESC"
+
+	if err := repository.DB.SelectContext(ctx, &apiKeys, query, userID); err != nil {
+		log.Printf("Unable to get the API keys of userID: %v. Err: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return apiKeys, nil
+}
+
+// Delete removes an API key of a user.
+func (repository *pgAPIKeyRepository) Delete(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error {
+	query := "DELETE FROM api_keys WHERE user_id=$1 AND key_id=$2"
+
+	result, err := repository.DB.ExecContext(ctx, query, userID, keyID)
+
+	if err != nil {
+		log.Printf("Could not delete the API key: %v of userID: %v. Reason: %v\n", keyID, userID, err)
+		return apperrors.NewInternal()
+	}
+
+	if deletedCount, err := result.RowsAffected(); err == nil && deletedCount == 0 {
+		return apperrors.NewNotFound("apiKey", keyID.String())
+	}
+
+	return nil
+}
+
+// UpdateLastUsed records when an API key was used.
+func (repository *pgAPIKeyRepository) UpdateLastUsed(ctx context.Context, keyID uuid.UUID, lastUsedAt time.Time) error {
+	query := "UPDATE api_keys SET last_used_at=$2 WHERE key_id=$1"
+
+	if _, err := repository.DB.ExecContext(ctx, query, keyID, lastUsedAt); err != nil {
+		log.Printf("Could not update when the API key: %v was last used. Reason: %v\n", keyID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
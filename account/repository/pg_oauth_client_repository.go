@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// pgOAuthClientRepository is data/repository implementation
+// of the service layer OAuthClientRepository.
+type pgOAuthClientRepository struct {
+	DB *sqlx.DB
+}
+
+// NewOAuthClientRepository is a factory for initializing OAuth Client Repositories.
+func NewOAuthClientRepository(db *sqlx.DB) model.OAuthClientRepository {
+	return &pgOAuthClientRepository{
+		DB: db,
+	}
+}
+
+// FindByID fetches a registered client.
+func (repository *pgOAuthClientRepository) FindByID(ctx context.Context, clientID string) (*model.OAuthClient, error) {
+	client := &model.OAuthClient{}
+
+	query := "SELECT * FROM oauth_clients WHERE client_id=$1"
+
+	if err := repository.DB.GetContext(ctx, client, query, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("clientID", clientID)
+		}
+
+		log.Printf("Unable to get the OAuth client: %v. Err: %v\n", clientID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return client, nil
+}
+
+// Upsert registers a client, or updates it if it is already registered.
+// Lists which are not set are stored empty, as the columns are not nullable.
+func (repository *pgOAuthClientRepository) Upsert(ctx context.Context, client *model.OAuthClient) error {
+	query := `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, scopes, grant_types, first_party)
+		VALUES ($1, $2, $3, COALESCE($4, '{}'::VARCHAR[]), COALESCE($5, '{}'::VARCHAR[]), COALESCE($6, '{}'::VARCHAR[]), $7)
+		ON CONFLICT (client_id) DO UPDATE
+		SET secret_hash=EXCLUDED.secret_hash, name=EXCLUDED.name, redirect_uris=EXCLUDED.redirect_uris,
+			scopes=EXCLUDED.scopes, grant_types=EXCLUDED.grant_types, first_party=EXCLUDED.first_party
+		RETURNING *;
+	`
+
+	if err := repository.DB.GetContext(ctx, client, query, client.ClientID, client.SecretHash, client.Name, client.RedirectURIs, client.Scopes, client.GrantTypes, client.FirstParty); err != nil {
+		log.Printf("Could not register the OAuth client: %v. Reason: %v\n", client.ClientID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindConsent fetches the scopes a user has allowed a client.
+func (repository *pgOAuthClientRepository) FindConsent(ctx context.Context, userID uuid.UUID, clientID string) (*model.OAuthConsent, error) {
+	consent := &model.OAuthConsent{}
+
+	query := "SELECT * FROM oauth_consents WHERE user_id=$1 AND client_id=$2"
+
+	if err := repository.DB.GetContext(ctx, consent, query, userID, clientID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("consent", clientID)
+		}
+
+		log.Printf("Unable to get the consent of userID: %v for clientID: %v. Err: %v\n", userID, clientID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return consent, nil
+}
+
+// UpsertConsent stores the scopes a user has allowed a client,
+// replacing the scopes allowed before.
+func (repository *pgOAuthClientRepository) UpsertConsent(ctx context.Context, consent *model.OAuthConsent) error {
+	query := `
+		INSERT INTO oauth_consents (user_id, client_id, scopes)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE
+		SET scopes=EXCLUDED.scopes
+		RETURNING *;
+	`
+
+	if err := repository.DB.GetContext(ctx, consent, query, consent.UserID, consent.ClientID, consent.Scopes); err != nil {
+		log.Printf("Could not store the consent of userID: %v for clientID: %v. Reason: %v\n", consent.UserID, consent.ClientID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
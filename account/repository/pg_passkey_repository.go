@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// pgPasskeyRepository is data/repository implementation
+// of the service layer PasskeyRepository.
+type pgPasskeyRepository struct {
+	DB *sqlx.DB
+}
+
+// NewPasskeyRepository is a factory for initializing Passkey Repositories.
+func NewPasskeyRepository(db *sqlx.DB) model.PasskeyRepository {
+	return &pgPasskeyRepository{
+		DB: db,
+	}
+}
+
+// Create stores a new passkey.
+func (repository *pgPasskeyRepository) Create(ctx context.Context, passkey *model.Passkey) error {
+	query := `
+		INSERT INTO passkeys (user_id, credential_id, name, public_key, sign_count, aaguid, transports, backup_eligible)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, '{}'::VARCHAR[]), $8)
+		RETURNING *;
+	`
+
+	err := repository.DB.GetContext(ctx, passkey, query, passkey.UserID, passkey.CredentialID, passkey.Name, passkey.PublicKey, passkey.SignCount, passkey.AAGUID, passkey.Transports, passkey.BackupEligible)
+
+	if err != nil {
+		// Check for a unique violation, which means the credential is registered already.
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not create a passkey for userID: %v. Reason: %v\n", passkey.UserID, err.Code.Name())
+			return apperrors.NewConflict("passkey", "credential")
+		}
+
+		log.Printf("Could not create a passkey for userID: %v. Reason: %v\n", passkey.UserID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// FindByCredentialID fetches the passkey with the WebAuthn credential ID.
+func (repository *pgPasskeyRepository) FindByCredentialID(ctx context.Context, credentialID []byte) (*model.Passkey, error) {
+	passkey := &model.Passkey{}
+
+	query := "SELECT * FROM passkeys WHERE credential_id=$1"
+
+	if err := repository.DB.GetContext(ctx, passkey, query, credentialID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFound("passkey", "")
+		}
+
+		log.Printf("Unable to get the passkey. Err: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return passkey, nil
+}
+
+// FindByUser fetches the passkeys of a user, newest first.
+func (repository *pgPasskeyRepository) FindByUser(ctx context.Context, userID uuid.UUID) ([]*model.Passkey, error) {
+	passkeys := []*model.Passkey{}
+
+	query := "SELECT * FROM passkeys WHERE user_id=$1 ORDER BY created_at DESC"
+
+	if err := repository.DB.SelectContext(ctx, &passkeys, query, userID); err != nil {
+		log.Printf("Unable to get the passkeys of userID: %v. Err: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return passkeys, nil
+}
+
+// Delete removes a passkey of a user.
+func (repository *pgPasskeyRepository) Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error {
+	query := "DELETE FROM passkeys WHERE user_id=$1 AND passkey_id=$2"
+
+	result, err := repository.DB.ExecContext(ctx, query, userID, passkeyID)
+
+	if err != nil {
+		log.Printf("Could not delete the passkey: %v of userID: %v. Reason: %v\n", passkeyID, userID, err)
+		return apperrors.NewInternal()
+	}
+
+	if deletedCount, err := result.RowsAffected(); err == nil && deletedCount == 0 {
+		return apperrors.NewNotFound("passkey", passkeyID.String())
+	}
+
+	return nil
+}
+
+// UseSignCount stores the sign count of a use of the passkey, in the same
+// statement that checks it went up, so concurrent sign ins with a cloned
+// authenticator can't both pass. Authenticators which don't count their
+// signatures always report zero.
+func (repository *pgPasskeyRepository) UseSignCount(ctx context.Context, passkeyID uuid.UUID, signCount int64, usedAt time.Time) (bool, error) {
+	query := `
+		UPDATE passkeys SET sign_count=$2, last_used_at=$3
+		WHERE passkey_id=$1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0));
+	`
+
+	result, err := repository.DB.ExecContext(ctx, query, passkeyID, signCount, usedAt)
+
+	if err != nil {
+		log.Printf("Could not update the sign count of the passkey: %v. Reason: %v\n", passkeyID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	updatedCount, err := result.RowsAffected()
+
+	if err != nil {
+		log.Printf("Could not update the sign count of the passkey: %v. Reason: %v\n", passkeyID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return updatedCount == 1, nil
+}
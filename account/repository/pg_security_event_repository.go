@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// pgSecurityEventRepository is data/repository implementation
+// of the service layer SecurityEventRepository.
+type pgSecurityEventRepository struct {
+	DB *sqlx.DB
+}
+
+// NewSecurityEventRepository is a factory for initializing Security Event Repositories.
+func NewSecurityEventRepository(db *sqlx.DB) model.SecurityEventRepository {
+	return &pgSecurityEventRepository{
+		DB: db,
+	}
+}
+
+// Create stores a security event for a user.
+func (repository *pgSecurityEventRepository) Create(ctx context.Context, event *model.SecurityEvent) error {
+	query := "INSERT INTO security_events (user_id, type, details) VALUES ($1, $2, $3) RETURNING *"
+
+	if err := repository.DB.GetContext(ctx, event, query, event.UserID, event.Type, event.Details); err != nil {
+		log.Printf("Could not record security event: %v for userID: %v. Reason: %v\n", event.Type, event.UserID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
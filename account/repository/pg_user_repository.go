@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 
 	"github.com/google/uuid"
@@ -61,18 +63,23 @@ func (repository *pgUserRepository) FindByEmail(ctx context.Context, email strin
 	query := "SELECT * FROM users WHERE email=$1"
 
 	if err := repository.DB.GetContext(ctx, user, query, email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, apperrors.NewNotFound("email", email)
+		}
+
 		log.Printf("Unable to get the user with email adress: %v. Err: %v\n", email, err)
-		return user, apperrors.NewNotFound("email", email)
+		return user, apperrors.NewInternal()
 	}
 
 	return user, nil
 }
 
-// Update updates a user's properties.
+// Update updates a user's properties. The email only changes once
+// the pending email is verified.
 func (repository *pgUserRepository) Update(ctx context.Context, user *model.User) error {
 	query := `
 		UPDATE users 
-		SET username=:username, email=:email, website=:website
+		SET username=:username, website=:website, pending_email=:pending_email
 		WHERE user_id=:user_id
 		RETURNING *;
 	`
@@ -113,3 +120,135 @@ func (repository *pgUserRepository) UpdateImage(ctx context.Context, userID uuid
 
 	return user, nil
 }
+
+// UpdatePassword stores a new password hash of a user.
+func (repository *pgUserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, password string) error {
+	query := "UPDATE users SET password=$2 WHERE user_id=$1"
+
+	if _, err := repository.DB.ExecContext(ctx, query, userID, password); err != nil {
+		log.Printf("Error updating the password of userID: %v. Reason: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the email of a user as verified. A verified
+// pending email replaces the email of the user.
+func (repository *pgUserRepository) VerifyEmail(ctx context.Context, userID uuid.UUID, email string) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET email=$2, email_verified=TRUE,
+			pending_email=CASE WHEN pending_email=$2 THEN '' ELSE pending_email END
+		WHERE user_id=$1
+		RETURNING *;
+	`
+
+	user := &model.User{}
+
+	if err := repository.DB.GetContext(ctx, user, query, userID, email); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code.Name() == "unique_violation" {
+			log.Printf("Could not verify the email: %v of userID: %v. Reason: %v\n", email, userID, err.Code.Name())
+			return nil, apperrors.NewConflict("email", email)
+		}
+
+		log.Printf("Could not verify the email: %v of userID: %v. Reason: %v\n", email, userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return user, nil
+}
+
+// SetTOTPSecret stores the encrypted TOTP secret a user is enrolling with.
+// TOTP stays disabled until the user confirms the secret.
+func (repository *pgUserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := "UPDATE users SET totp_secret=$2 WHERE user_id=$1 AND NOT totp_enabled"
+
+	if _, err := repository.DB.ExecContext(ctx, query, userID, secret); err != nil {
+		log.Printf("Error setting the TOTP secret of userID: %v. Reason: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// EnableTOTP enables TOTP with the hashes of new recovery codes.
+// The step of the code which confirmed the secret is used up.
+func (repository *pgUserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodes []string, step int64) error {
+	query := `
+		UPDATE users
+		SET totp_enabled=TRUE, totp_last_step=$3, recovery_codes=$2
+		WHERE user_id=$1 AND totp_secret<>''
+	`
+
+	if _, err := repository.DB.ExecContext(ctx, query, userID, pq.StringArray(recoveryCodes), step); err != nil {
+		log.Printf("Error enabling TOTP of userID: %v. Reason: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// DisableTOTP disables TOTP and forgets the secret and recovery codes.
+func (repository *pgUserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		UPDATE users
+		SET totp_enabled=FALSE, totp_secret='', totp_last_step=0, recovery_codes='{}'
+		WHERE user_id=$1
+	`
+
+	if _, err := repository.DB.ExecContext(ctx, query, userID); err != nil {
+		log.Printf("Error disabling TOTP of userID: %v. Reason: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that a code of the time step was used. It reports false
+// if a code of the step or a later one was used before, so codes can't be replayed.
+func (repository *pgUserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := "UPDATE users SET totp_last_step=$2 WHERE user_id=$1 AND totp_last_step<$2"
+
+	result, err := repository.DB.ExecContext(ctx, query, userID, step)
+
+	if err != nil {
+		log.Printf("Error using a TOTP step of userID: %v. Reason: %v\n", userID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+
+	if err != nil {
+		log.Printf("Error using a TOTP step of userID: %v. Reason: %v\n", userID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return rows == 1, nil
+}
+
+// UseRecoveryCode removes the hash of a recovery code of the user.
+// It reports false if the user has no such code, so each code works once.
+func (repository *pgUserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE users
+		SET recovery_codes=array_remove(recovery_codes, $2)
+		WHERE user_id=$1 AND $2=ANY(recovery_codes)
+	`
+
+	result, err := repository.DB.ExecContext(ctx, query, userID, codeHash)
+
+	if err != nil {
+		log.Printf("Error using a recovery code of userID: %v. Reason: %v\n", userID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	rows, err := result.RowsAffected()
+
+	if err != nil {
+		log.Printf("Error using a recovery code of userID: %v. Reason: %v\n", userID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return rows == 1, nil
+}
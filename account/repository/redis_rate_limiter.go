@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// tokenBucketScript refills the bucket of KEYS[1] for the time since it was
+// last updated, and takes a token out of it if there is one. ARGV holds the
+// limit and the period in milliseconds. The time of Redis is used, so the
+// clocks of replicas don't matter. It returns whether the request is allowed,
+// the remaining tokens and the milliseconds until the bucket is full and until
+// a token is available.
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or limit
+local updated = tonumber(bucket[2]) or now
+local rate = limit / period
+
+tokens = math.min(limit, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+local retryAfter = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retryAfter = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], period)
+
+return {allowed, math.floor(tokens), math.ceil((limit - tokens) / rate), retryAfter}
+`)
+
+// slidingWindowScript drops the requests which left the window of KEYS[1],
+// and logs the request as ARGV[3] if the window has room for it. ARGV holds
+// the limit and the period in milliseconds. It returns whether the request
+// is allowed, the remaining requests and the milliseconds until the window
+// is empty and until a request is allowed.
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+local retryAfter = 0
+
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], period)
+	count = count + 1
+	allowed = 1
+else
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	retryAfter = tonumber(oldest[2]) + period - now
+end
+
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+
+return {allowed, limit - count, tonumber(newest[2]) + period - now, retryAfter}
+`)
+
+// redisRateLimiter is a RateLimiter keeping the counts in Redis,
+// so limits hold across replicas. It falls back to counting
+// in memory while Redis can't be reached.
+type redisRateLimiter struct {
+	Redis    *redis.Client
+	Fallback model.RateLimiter
+}
+
+// NewRateLimiter is a factory for initializing a Rate Limiter
+// which keeps the counts in Redis, or in memory without it.
+func NewRateLimiter(redisClient *redis.Client) model.RateLimiter {
+	if redisClient == nil {
+		return NewMemoryRateLimiter()
+	}
+
+	return &redisRateLimiter{
+		Redis:    redisClient,
+		Fallback: NewMemoryRateLimiter(),
+	}
+}
+
+// Allow counts a request of the key against the policy.
+func (limiter *redisRateLimiter) Allow(ctx context.Context, key string, policy *model.RateLimitPolicy) (*model.RateLimitResult, error) {
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", policy.Name, key)
+	args := []interface{}{policy.Limit, policy.Period.Milliseconds()}
+
+	script := tokenBucketScript
+	if policy.Algorithm == model.SlidingWindow {
+		script = slidingWindowScript
+		args = append(args, uuid.NewString())
+	}
+
+	values, err := script.Run(ctx, limiter.Redis, []string{redisKey}, args...).Int64Slice()
+
+	if err != nil || len(values) != 4 {
+		log.Printf("Could not count the request of: %s against the rate limit: %s in Redis, counting it in memory: %v\n", key, policy.Name, err)
+		return limiter.Fallback.Allow(ctx, key, policy)
+	}
+
+	return &model.RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		Reset:      time.Duration(values[2]) * time.Millisecond,
+		RetryAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
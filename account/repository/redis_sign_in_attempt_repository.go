@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// redisSignInAttemptRepository is data/repository implementation
+// of the service layer SignInAttemptRepository.
+type redisSignInAttemptRepository struct {
+	Redis *redis.Client
+}
+
+// NewSignInAttemptRepository is a factory for initializing Sign In Attempt Repositories.
+func NewSignInAttemptRepository(redisClient *redis.Client) model.SignInAttemptRepository {
+	return &redisSignInAttemptRepository{
+		Redis: redisClient,
+	}
+}
+
+// AddFailure counts a failed sign in of the key and returns how many failed
+// in a row. The count is forgotten once no attempt failed for the window.
+func (repository *redisSignInAttemptRepository) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	failuresKey := fmt.Sprintf("signin_failures:%s", key)
+
+	pipe := repository.Redis.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey)
+	pipe.Expire(ctx, failuresKey, window)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Could not count the failed sign in of: %s in Redis: %v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	return int(failures.Val()), nil
+}
+
+// Lock blocks sign ins of the key for the duration.
+func (repository *redisSignInAttemptRepository) Lock(ctx context.Context, key string, duration time.Duration) error {
+	lockKey := fmt.Sprintf("signin_lock:%s", key)
+
+	if err := repository.Redis.Set(ctx, lockKey, time.Now().Add(duration).Unix(), duration).Err(); err != nil {
+		log.Printf("Could not lock sign ins of: %s in Redis: %v\n", key, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// LockedFor returns how long sign ins of the key stay blocked, or zero.
+func (repository *redisSignInAttemptRepository) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	lockKey := fmt.Sprintf("signin_lock:%s", key)
+
+	ttl, err := repository.Redis.PTTL(ctx, lockKey).Result()
+
+	if err != nil {
+		log.Printf("Could not get the sign in lock of: %s from Redis: %v\n", key, err)
+		return 0, apperrors.NewInternal()
+	}
+
+	// Missing keys have a negative TTL.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Reset forgets the failed sign ins and the lock of the key.
+func (repository *redisSignInAttemptRepository) Reset(ctx context.Context, key string) error {
+	failuresKey := fmt.Sprintf("signin_failures:%s", key)
+	lockKey := fmt.Sprintf("signin_lock:%s", key)
+
+	if err := repository.Redis.Del(ctx, failuresKey, lockKey).Err(); err != nil {
+		log.Printf("Could not reset the failed sign ins of: %s in Redis: %v\n", key, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
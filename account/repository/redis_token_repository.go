@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
@@ -11,6 +12,17 @@ import (
 	"github.com/yachnytskyi/base-go/account/model/apperrors"
 )
 
+// attemptMFAChallengeScript counts an attempt at the MFA challenge of KEYS[1]
+// and returns the user it was issued to along with the attempts so far.
+// Missing challenges return nil, so no challenge is created without an expiry.
+var attemptMFAChallengeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+  return false
+end
+local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+return {redis.call('HGET', KEYS[1], 'user_id'), attempts}
+`)
+
 // redisTokenRepository is data/repository implementation
 // of the service layer TokenRepository.
 type redisTokenRepository struct {
@@ -25,37 +37,148 @@ func NewTokenRepository(redisClient *redis.Client) model.TokenRepository {
 }
 
 // SetRefreshToken stores a refresh token with an expiry time.
-func (repository *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, expiresIn time.Duration) error {
+// The value is the session the token belongs to, so the session
+// metadata survives rotation and a whole family can be revoked.
+func (repository *redisTokenRepository) SetRefreshToken(ctx context.Context, userID string, tokenID string, session *model.Session, expiresIn time.Duration) error {
 	// We will store userID with token id so we can scan (non-blocking)
 	// over the user's tokens and delete them in case of token leakage.
 	key := fmt.Sprintf("%s:%s", userID, tokenID)
-	if err := repository.Redis.Set(ctx, key, 0, expiresIn).Err(); err != nil {
+
+	value, err := json.Marshal(session)
+
+	if err != nil {
+		log.Printf("Could not marshal session for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := repository.Redis.Set(ctx, key, value, expiresIn).Err(); err != nil {
 		log.Printf("Could not SET refresh token to Redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
 		return apperrors.NewInternal()
 	}
 	return nil
 }
 
+// GetRefreshToken checks a refresh token is still valid without rotating it.
+// It returns the session stored with the token.
+func (repository *redisTokenRepository) GetRefreshToken(ctx context.Context, userID string, tokenID string) (*model.Session, error) {
+	key := fmt.Sprintf("%s:%s", userID, tokenID)
+
+	value, err := repository.Redis.Get(ctx, key).Result()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid refresh token")
+	}
+
+	if err != nil {
+		log.Printf("Could not get refresh token from redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return decodeSession(value), nil
+}
+
 // DeleteRefreshToken used to delete old refresh tokens.
 // Services my access this to revolve tokens.
-func (repository *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) error {
+// It returns the session stored with the token, which is nil
+// for tokens stored before sessions were introduced.
+func (repository *redisTokenRepository) DeleteRefreshToken(ctx context.Context, userID string, tokenID string) (*model.Session, error) {
 	key := fmt.Sprintf("%s:%s", userID, tokenID)
 
-	result := repository.Redis.Del(ctx, key)
+	value, err := repository.Redis.GetDel(ctx, key).Result()
+
+	// If no key was deleted, the refresh token is invalid.
+	if err == redis.Nil {
+		log.Printf("Refresh token to redis for userID/tokenID: %s/%s does not exist\n", userID, tokenID)
+		return nil, apperrors.NewAuthorization("Invalid refresh token")
+	}
 
-	if err := result.Err(); err != nil {
+	if err != nil {
 		log.Printf("Could not delete refresh token to redis for userID/tokenID: %s/%s: %v\n", userID, tokenID, err)
-		return apperrors.NewInternal()
+		return nil, apperrors.NewInternal()
 	}
 
-	// Val returns count of deleted keys.
-	// If no key was deleted, the refresh token is invalid.
-	if result.Val() < 1 {
-		log.Printf("Refresh token to redis for userID/tokenID: %s/%s does not exist\n", userID, tokenID)
-		return apperrors.NewAuthorization("Invalid refresh token")
+	return decodeSession(value), nil
+}
+
+// DeleteRefreshTokenFamily scans the user's tokens and deletes the ones
+// which belong to the family. It returns the count of deleted tokens.
+func (repository *redisTokenRepository) DeleteRefreshTokenFamily(ctx context.Context, userID string, familyID string) (int64, error) {
+	pattern := fmt.Sprintf("%s:*", userID)
+
+	scanIterator := repository.Redis.Scan(ctx, 0, pattern, 5).Iterator()
+	var deletedCount int64
+
+	for scanIterator.Next(ctx) {
+		key := scanIterator.Val()
+		value, err := repository.Redis.Get(ctx, key).Result()
+
+		// The token may have expired in between.
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Failed to read the refresh token: %s: %v\n", key, err)
+			return deletedCount, apperrors.NewInternal()
+		}
+
+		session := decodeSession(value)
+
+		if session == nil || session.ID.String() != familyID {
+			continue
+		}
+
+		result := repository.Redis.Del(ctx, key)
+
+		if err := result.Err(); err != nil {
+			log.Printf("Failed to delete the refresh token: %s: %v\n", key, err)
+			return deletedCount, apperrors.NewInternal()
+		}
+
+		deletedCount += result.Val()
 	}
 
-	return nil
+	if err := scanIterator.Err(); err != nil {
+		log.Printf("Failed to scan refresh tokens for userID/familyID: %s/%s: %v\n", userID, familyID, err)
+		return deletedCount, apperrors.NewInternal()
+	}
+
+	return deletedCount, nil
+}
+
+// GetUserSessions scans the user's tokens and returns their sessions.
+// There is one valid token per session, so every session is listed once.
+func (repository *redisTokenRepository) GetUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	pattern := fmt.Sprintf("%s:*", userID)
+
+	scanIterator := repository.Redis.Scan(ctx, 0, pattern, 5).Iterator()
+	sessions := []*model.Session{}
+
+	for scanIterator.Next(ctx) {
+		key := scanIterator.Val()
+		value, err := repository.Redis.Get(ctx, key).Result()
+
+		// The token may have expired in between.
+		if err == redis.Nil {
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Failed to read the refresh token: %s: %v\n", key, err)
+			return nil, apperrors.NewInternal()
+		}
+
+		if session := decodeSession(value); session != nil {
+			sessions = append(sessions, session)
+		}
+	}
+
+	if err := scanIterator.Err(); err != nil {
+		log.Printf("Failed to scan refresh tokens for userID: %s: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return sessions, nil
 }
 
 // DeleteUserRefreshTokens looks for all tokens beginning with
@@ -84,3 +207,287 @@ func (repository *redisTokenRepository) DeleteUserRefreshTokens(ctx context.Cont
 
 	return nil
 }
+
+// RevokeIDToken adds an ID token to the denylist. The entry expires
+// with the token, since an expired token is rejected anyway.
+func (repository *redisTokenRepository) RevokeIDToken(ctx context.Context, tokenID string, expiresIn time.Duration) error {
+	key := fmt.Sprintf("revoked_id_token:%s", tokenID)
+
+	if err := repository.Redis.Set(ctx, key, 1, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET revoked ID token to Redis for tokenID: %s: %v\n", tokenID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// IsIDTokenRevoked checks whether an ID token is on the denylist.
+func (repository *redisTokenRepository) IsIDTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	key := fmt.Sprintf("revoked_id_token:%s", tokenID)
+
+	count, err := repository.Redis.Exists(ctx, key).Result()
+
+	if err != nil {
+		log.Printf("Could not check revoked ID token in Redis for tokenID: %s: %v\n", tokenID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return count > 0, nil
+}
+
+// SetAuthorizationCode stores an authorization code until the client
+// exchanges it for tokens or it expires.
+func (repository *redisTokenRepository) SetAuthorizationCode(ctx context.Context, code string, authorizationCode *model.AuthorizationCode, expiresIn time.Duration) error {
+	key := fmt.Sprintf("authorization_code:%s", code)
+
+	value, err := json.Marshal(authorizationCode)
+
+	if err != nil {
+		log.Printf("Could not marshal authorization code for clientID: %s: %v\n", authorizationCode.ClientID, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := repository.Redis.Set(ctx, key, value, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET authorization code to Redis for clientID: %s: %v\n", authorizationCode.ClientID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetAuthorizationCode returns an authorization code without using it up,
+// so the client presenting it can be checked first.
+func (repository *redisTokenRepository) GetAuthorizationCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	key := fmt.Sprintf("authorization_code:%s", code)
+
+	value, err := repository.Redis.Get(ctx, key).Result()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid authorization code")
+	}
+
+	if err != nil {
+		log.Printf("Could not get authorization code from Redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return decodeAuthorizationCode(value)
+}
+
+// ConsumeAuthorizationCode deletes an authorization code and returns it,
+// so a code can only be exchanged once.
+func (repository *redisTokenRepository) ConsumeAuthorizationCode(ctx context.Context, code string) (*model.AuthorizationCode, error) {
+	key := fmt.Sprintf("authorization_code:%s", code)
+
+	value, err := repository.Redis.GetDel(ctx, key).Result()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid authorization code")
+	}
+
+	if err != nil {
+		log.Printf("Could not delete authorization code from Redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return decodeAuthorizationCode(value)
+}
+
+// decodeAuthorizationCode unmarshals a stored authorization code.
+func decodeAuthorizationCode(value string) (*model.AuthorizationCode, error) {
+	authorizationCode := &model.AuthorizationCode{}
+
+	if err := json.Unmarshal([]byte(value), authorizationCode); err != nil {
+		log.Printf("Could not unmarshal authorization code: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return authorizationCode, nil
+}
+
+// SetPasswordResetToken stores the hash of a password reset token
+// with the user it resets the password of, until it expires.
+func (repository *redisTokenRepository) SetPasswordResetToken(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	key := fmt.Sprintf("password_reset:%s", tokenHash)
+
+	if err := repository.Redis.Set(ctx, key, userID, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET password reset token to Redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// GetPasswordResetToken returns the ID of the user of a password reset
+// token without using it up.
+func (repository *redisTokenRepository) GetPasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	key := fmt.Sprintf("password_reset:%s", tokenHash)
+
+	userID, err := repository.Redis.Get(ctx, key).Result()
+
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset token")
+	}
+
+	if err != nil {
+		log.Printf("Could not get password reset token from Redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return userID, nil
+}
+
+// ConsumePasswordResetToken deletes a password reset token and returns
+// the ID of its user, so a token can only be used once.
+func (repository *redisTokenRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	key := fmt.Sprintf("password_reset:%s", tokenHash)
+
+	userID, err := repository.Redis.GetDel(ctx, key).Result()
+
+	if err == redis.Nil {
+		return "", apperrors.NewAuthorization("Invalid or expired password reset token")
+	}
+
+	if err != nil {
+		log.Printf("Could not delete password reset token from Redis: %v\n", err)
+		return "", apperrors.NewInternal()
+	}
+
+	return userID, nil
+}
+
+// SetMFAChallenge stores the hash of an MFA challenge token
+// with the user who entered the password, until it expires.
+func (repository *redisTokenRepository) SetMFAChallenge(ctx context.Context, tokenHash string, userID string, expiresIn time.Duration) error {
+	key := fmt.Sprintf("mfa_challenge:%s", tokenHash)
+
+	pipe := repository.Redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "attempts", 0)
+	pipe.Expire(ctx, key, expiresIn)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("Could not SET MFA challenge to Redis for userID: %s: %v\n", userID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// AttemptMFAChallenge counts an attempt to answer an MFA challenge and returns
+// the ID of its user along with how many attempts were made, this one included.
+func (repository *redisTokenRepository) AttemptMFAChallenge(ctx context.Context, tokenHash string) (string, int, error) {
+	key := fmt.Sprintf("mfa_challenge:%s", tokenHash)
+
+	values, err := attemptMFAChallengeScript.Run(ctx, repository.Redis, []string{key}).Slice()
+
+	if err == redis.Nil {
+		return "", 0, apperrors.NewAuthorization("Invalid or expired MFA token")
+	}
+
+	if err != nil {
+		log.Printf("Could not count the attempt at an MFA challenge in Redis: %v\n", err)
+		return "", 0, apperrors.NewInternal()
+	}
+
+	if len(values) == 2 {
+		userID, isString := values[0].(string)
+		attempts, isInt := values[1].(int64)
+
+		if isString && isInt {
+			return userID, int(attempts), nil
+		}
+	}
+
+	log.Printf("Unexpected reply to the attempt at an MFA challenge from Redis: %v\n", values)
+	return "", 0, apperrors.NewInternal()
+}
+
+// DeleteMFAChallenge deletes an MFA challenge, which can't be answered anymore.
+func (repository *redisTokenRepository) DeleteMFAChallenge(ctx context.Context, tokenHash string) error {
+	key := fmt.Sprintf("mfa_challenge:%s", tokenHash)
+
+	if err := repository.Redis.Del(ctx, key).Err(); err != nil {
+		log.Printf("Could not delete MFA challenge from Redis: %v\n", err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// SetPasskeyChallenge stores the state of a WebAuthn ceremony under
+// the hash of its challenge, until the response comes back or it expires.
+func (repository *redisTokenRepository) SetPasskeyChallenge(ctx context.Context, challengeHash string, challenge *model.PasskeyChallenge, expiresIn time.Duration) error {
+	key := fmt.Sprintf("passkey_challenge:%s", challengeHash)
+
+	value, err := json.Marshal(challenge)
+
+	if err != nil {
+		log.Printf("Could not marshal passkey challenge for userID: %s: %v\n", challenge.UserID, err)
+		return apperrors.NewInternal()
+	}
+
+	if err := repository.Redis.Set(ctx, key, value, expiresIn).Err(); err != nil {
+		log.Printf("Could not SET passkey challenge to Redis for userID: %s: %v\n", challenge.UserID, err)
+		return apperrors.NewInternal()
+	}
+
+	return nil
+}
+
+// ConsumePasskeyChallenge deletes the state of a WebAuthn ceremony and
+// returns it, so a challenge can only be answered once.
+func (repository *redisTokenRepository) ConsumePasskeyChallenge(ctx context.Context, challengeHash string) (*model.PasskeyChallenge, error) {
+	key := fmt.Sprintf("passkey_challenge:%s", challengeHash)
+
+	value, err := repository.Redis.GetDel(ctx, key).Result()
+
+	if err == redis.Nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey challenge")
+	}
+
+	if err != nil {
+		log.Printf("Could not delete passkey challenge from Redis: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	challenge := &model.PasskeyChallenge{}
+
+	if err := json.Unmarshal([]byte(value), challenge); err != nil {
+		log.Printf("Could not unmarshal passkey challenge: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	return challenge, nil
+}
+
+// UseMagicLinkToken marks the token of a sign in link as used until
+// the link expires. It reports whether the token was unused.
+func (repository *redisTokenRepository) UseMagicLinkToken(ctx context.Context, tokenID string, expiresIn time.Duration) (bool, error) {
+	key := fmt.Sprintf("magic_link:%s", tokenID)
+
+	// An expired link is rejected before this, but it can expire right now.
+	if expiresIn <= 0 {
+		return false, nil
+	}
+
+	unused, err := repository.Redis.SetNX(ctx, key, 1, expiresIn).Result()
+
+	if err != nil {
+		log.Printf("Could not SETNX used magic link token to Redis for tokenID: %s: %v\n", tokenID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	return unused, nil
+}
+
+// decodeSession returns nil for values which are not a session,
+// such as tokens stored before sessions were introduced.
+func decodeSession(value string) *model.Session {
+	session := &model.Session{}
+
+	if err := json.Unmarshal([]byte(value), session); err != nil {
+		return nil
+	}
+
+	return session
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"time"
+
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// apiKeyPrefixLength is how much of a key is stored in the clear,
+// which is enough for the user to recognize the key.
+const apiKeyPrefixLength = len(model.APIKeyPrefix) + 8
+
+// apiKeyLastUsedResolution is how precisely the last use of a key is recorded.
+const apiKeyLastUsedResolution = time.Minute
+
+// generateAPIKey returns a new API key along with its visible prefix.
+func generateAPIKey() (string, string, error) {
+	secret, err := randomToken(32)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	key := model.APIKeyPrefix + secret
+
+	return key, key[:apiKeyPrefixLength], nil
+}
+
+// hashAPIKey hashes an API key for storage. API keys are long random
+// strings, so they are hashed like other tokens, which
+// lets a key be looked up by its hash.
+func hashAPIKey(key string) string {
+	return hashToken(key)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// apiKeyService acts as a struct for injecting an implementation
+// of APIKeyRepository for use in service methods.
+type apiKeyService struct {
+	APIKeyRepository model.APIKeyRepository
+}
+
+// APIKeyServiceConfig will hold repositories that
+// will eventually be injected into this service layer.
+type APIKeyServiceConfig struct {
+	APIKeyRepository model.APIKeyRepository
+}
+
+// NewAPIKeyService is a factory function for
+// initializing an APIKeyService with its
+// repository layer dependencies.
+func NewAPIKeyService(c *APIKeyServiceConfig) model.APIKeyService {
+	return &apiKeyService{
+		APIKeyRepository: c.APIKeyRepository,
+	}
+}
+
+// Create creates a named API key for the user. The key is returned
+// only this once, as just its hash is stored.
+// Keys without scopes or expiry can do everything the user can until they are revoked.
+func (s *apiKeyService) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*model.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !model.IsAPIKeyScope(scope) {
+			return nil, "", apperrors.NewBadRequest("Unsupported API key scope: " + scope)
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", apperrors.NewBadRequest("The expiry of the API key must be in the future")
+	}
+
+	key, prefix, err := generateAPIKey()
+
+	if err != nil {
+		log.Printf("Error generating API key for userID: %v. Error: %v\n", userID, err)
+		return nil, "", apperrors.NewInternal()
+	}
+
+	apiKey := &model.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := s.APIKeyRepository.Create(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+// List returns the API keys of the user.
+func (s *apiKeyService) List(ctx context.Context, userID uuid.UUID) ([]*model.APIKey, error) {
+	return s.APIKeyRepository.FindByUser(ctx, userID)
+}
+
+// Revoke deletes an API key of the user, which stops working right away.
+func (s *apiKeyService) Revoke(ctx context.Context, userID uuid.UUID, keyID uuid.UUID) error {
+	return s.APIKeyRepository.Delete(ctx, userID, keyID)
+}
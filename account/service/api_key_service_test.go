@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestAPIKeyCreate(t *testing.T) {
+	userID, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		apiKeyService := NewAPIKeyService(&APIKeyServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		var storedAPIKey *model.APIKey
+		mockAPIKeyRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.APIKey")).
+			Run(func(args mock.Arguments) {
+				storedAPIKey = args.Get(1).(*model.APIKey)
+			}).Return(nil)
+
+		scopes := []string{model.APIKeyScopeRead}
+		apiKey, key, err := apiKeyService.Create(context.Background(), userID, "CI", scopes, nil)
+		assert.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(key, model.APIKeyPrefix))
+		assert.True(t, strings.HasPrefix(key, apiKey.Prefix))
+		assert.Len(t, apiKey.Prefix, apiKeyPrefixLength)
+		assert.Equal(t, storedAPIKey, apiKey)
+		assert.Equal(t, userID, storedAPIKey.UserID)
+		assert.Equal(t, "CI", storedAPIKey.Name)
+		assert.Equal(t, hashAPIKey(key), storedAPIKey.KeyHash)
+		assert.NotContains(t, storedAPIKey.KeyHash, key)
+		mockAPIKeyRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		apiKeyService := NewAPIKeyService(&APIKeyServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		_, _, err := apiKeyService.Create(context.Background(), userID, "CI", []string{"admin"}, nil)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		expiredAt := time.Now().Add(-time.Hour)
+		_, _, err = apiKeyService.Create(context.Background(), userID, "CI", nil, &expiredAt)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		mockAPIKeyRepository.AssertNotCalled(t, "Create")
+	})
+
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	userID, _ := uuid.NewRandom()
+	keyID, _ := uuid.NewRandom()
+
+	key := model.APIKeyPrefix + "somerandomtestkey"
+
+	t.Run("Records the use of a valid key", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		mockAPIKey := &model.APIKey{
+			KeyID:  keyID,
+			UserID: userID,
+		}
+
+		mockAPIKeyRepository.On("FindByHash", mock.Anything, hashAPIKey(key)).Return(mockAPIKey, nil)
+		mockAPIKeyRepository.On("UpdateLastUsed", mock.Anything, keyID, mock.AnythingOfType("time.Time")).Return(nil)
+
+		apiKey, err := tokenService.ValidateAPIKey(context.Background(), key)
+		assert.NoError(t, err)
+
+		assert.Equal(t, userID, apiKey.UserID)
+		assert.WithinDuration(t, time.Now(), *apiKey.LastUsedAt, 5*time.Second)
+		mockAPIKeyRepository.AssertExpectations(t)
+	})
+
+	t.Run("Recently used key", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		lastUsedAt := time.Now().Add(-10 * time.Second)
+		mockAPIKey := &model.APIKey{
+			KeyID:      keyID,
+			UserID:     userID,
+			LastUsedAt: &lastUsedAt,
+		}
+
+		mockAPIKeyRepository.On("FindByHash", mock.Anything, hashAPIKey(key)).Return(mockAPIKey, nil)
+
+		_, err := tokenService.ValidateAPIKey(context.Background(), key)
+		assert.NoError(t, err)
+
+		mockAPIKeyRepository.AssertNotCalled(t, "UpdateLastUsed")
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		mockAPIKeyRepository.On("FindByHash", mock.Anything, hashAPIKey(key)).Return(nil, apperrors.NewNotFound("apiKey", ""))
+
+		apiKey, err := tokenService.ValidateAPIKey(context.Background(), key)
+
+		assert.Nil(t, apiKey)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Expired key", func(t *testing.T) {
+		mockAPIKeyRepository := new(mocks.MockAPIKeyRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			APIKeyRepository: mockAPIKeyRepository,
+		})
+
+		expiresAt := time.Now().Add(-time.Minute)
+		mockAPIKey := &model.APIKey{
+			KeyID:     keyID,
+			UserID:    userID,
+			ExpiresAt: &expiresAt,
+		}
+
+		mockAPIKeyRepository.On("FindByHash", mock.Anything, hashAPIKey(key)).Return(mockAPIKey, nil)
+
+		apiKey, err := tokenService.ValidateAPIKey(context.Background(), key)
+
+		assert.Nil(t, apiKey)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockAPIKeyRepository.AssertNotCalled(t, "UpdateLastUsed")
+	})
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// emailVerificationAudience keeps other tokens signed with
+// the same secret from passing as verification tokens.
+const emailVerificationAudience = "email_verification"
+
+// emailVerificationClaims holds the payload of an email verification token.
+// The token only verifies the address it was sent to.
+type emailVerificationClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+// generateEmailVerificationToken signs a token which verifies the email of the user.
+func generateEmailVerificationToken(userID uuid.UUID, email string, secret string, expiration time.Duration) (string, error) {
+	currentTime := time.Now()
+
+	claims := emailVerificationClaims{
+		Email: email,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   userID.String(),
+			Audience:  emailVerificationAudience,
+			IssuedAt:  currentTime.Unix(),
+			ExpiresAt: currentTime.Add(expiration).Unix(),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// validateEmailVerificationToken returns the claims of a valid email verification token.
+func validateEmailVerificationToken(tokenString string, secret string) (*emailVerificationClaims, error) {
+	claims := &emailVerificationClaims{}
+	parser := &jwt.Parser{
+		ValidMethods: []string{jwt.SigningMethodHS256.Alg()},
+	}
+
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || !claims.VerifyAudience(emailVerificationAudience, true) {
+		return nil, fmt.Errorf("email verification token is invalid")
+	}
+
+	return claims, nil
+}
+
+// linkWithToken adds a token to a page of the frontend as the token query parameter.
+func linkWithToken(link string, token string) (string, error) {
+	tokenURL, err := url.Parse(link)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := tokenURL.Query()
+	query.Set("token", token)
+	tokenURL.RawQuery = query.Encode()
+
+	return tokenURL.String(), nil
+}
+
+// sendEmailVerification mails a verification link for the email to the email.
+// Failures are only logged, the user can ask for the link again.
+func (s *userService) sendEmailVerification(ctx context.Context, userID uuid.UUID, email string) {
+	token, err := generateEmailVerificationToken(userID, email, s.EmailVerificationSecret, s.EmailVerificationExpiration)
+
+	if err != nil {
+		log.Printf("Unable to generate an email verification token for userID: %v. Error: %v\n", userID, err)
+		return
+	}
+
+	link, err := linkWithToken(s.EmailVerificationURL, token)
+
+	if err != nil {
+		log.Printf("Unable to parse the email verification URL: %v. Error: %v\n", s.EmailVerificationURL, err)
+		return
+	}
+
+	mail := &model.Mail{
+		To:      email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Follow the link within %d hours to verify the email of your account:\n\n%s\n\nIf you didn't sign up, you can ignore this email.",
+			int(s.EmailVerificationExpiration.Hours()), link),
+	}
+
+	if err := s.MailSender.Send(ctx, mail); err != nil {
+		log.Printf("Unable to send the email verification to userID: %v. Error: %v\n", userID, err)
+	}
+}
+
+// sendEmailChangeNotice tells the current address of the user
+// that the email of the account is about to change.
+func (s *userService) sendEmailChangeNotice(ctx context.Context, user *model.User, pendingEmail string) {
+	mail := &model.Mail{
+		To:      user.Email,
+		Subject: "Your email is about to change",
+		Body: fmt.Sprintf("Someone asked to change the email of your account to %s. The change takes effect once the new address is verified.\n\nIf it wasn't you, change your password right away.",
+			pendingEmail),
+	}
+
+	if err := s.MailSender.Send(ctx, mail); err != nil {
+		log.Printf("Unable to send the email change notice to userID: %v. Error: %v\n", user.UserID, err)
+	}
+}
+
+// VerifyEmail verifies the email a verification token was sent to.
+// Verifying the pending email of the user makes it the user's email.
+func (s *userService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	claims, err := validateEmailVerificationToken(token, s.EmailVerificationSecret)
+
+	if err != nil {
+		log.Printf("Unable to validate the email verification token. Error: %v\n", err)
+		return nil, apperrors.NewAuthorization("Invalid or expired email verification link")
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+
+	if err != nil {
+		return nil, apperrors.NewAuthorization("Invalid or expired email verification link")
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Links to addresses the user has moved on from no longer work.
+	if claims.Email != user.Email && claims.Email != user.PendingEmail {
+		return nil, apperrors.NewAuthorization("Invalid or expired email verification link")
+	}
+
+	return s.UserRepository.VerifyEmail(ctx, userID, claims.Email)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"crypto"
+	"fmt"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// signingKey holds a key identified by its kid along with
+// the algorithm it is used with. Pinning the algorithm to the key
+// keeps tokens from being verified with an algorithm we didn't choose.
+// PrivateKey is nil for keys that are only used for verification.
+type signingKey struct {
+	ID         string
+	Algorithm  string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	jwk        *model.JSONWebKey
+}
+
+// KeyRing holds the key used for signing new ID tokens along with
+// upcoming and retiring keys which are still accepted for verification.
+// This lets us rotate keys without signing everyone out: a new signing key
+// is deployed with the previous one as a verification key, which is dropped
+// once its tokens have expired.
+type KeyRing struct {
+	mu        sync.RWMutex
+	currentID string
+	keys      map[string]*signingKey
+	order     []string // Keeps the jwks output stable.
+}
+
+// NewKeyRing creates a key ring which signs with the provided
+// private key using the named algorithm, such as jwa.RS256 or jwa.ES256.
+func NewKeyRing(algorithm string, privateKey crypto.Signer) (*KeyRing, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("private key must not be nil")
+	}
+
+	key, err := newSigningKey(algorithm, privateKey.Public())
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.PrivateKey = privateKey
+
+	keyRing := &KeyRing{
+		keys: make(map[string]*signingKey),
+	}
+
+	keyRing.add(key)
+	keyRing.currentID = key.ID
+
+	return keyRing, nil
+}
+
+// AddVerificationKey adds a public key which is accepted when validating
+// tokens signed with the named algorithm but is never used for signing.
+// It returns the kid of the key.
+func (k *KeyRing) AddVerificationKey(algorithm string, publicKey crypto.PublicKey) (string, error) {
+	if publicKey == nil {
+		return "", fmt.Errorf("public key must not be nil")
+	}
+
+	key, err := newSigningKey(algorithm, publicKey)
+
+	if err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, exists := k.keys[key.ID]; !exists {
+		k.add(key)
+	}
+
+	return key.ID, nil
+}
+
+// JWKS returns the public keys of the ring as a JSON Web Key Set.
+func (k *KeyRing) JWKS() *model.JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keySet := &model.JSONWebKeySet{
+		Keys: make([]model.JSONWebKey, 0, len(k.order)),
+	}
+
+	for _, kid := range k.order {
+		key := k.keys[kid]
+
+		jwk := *key.jwk
+		jwk.KeyID = kid
+		jwk.Use = "sig"
+
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+
+	return keySet
+}
+
+// signingKey returns the key new tokens are signed with.
+func (k *KeyRing) signingKey() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.keys[k.currentID]
+}
+
+// verificationKey looks up a key by its kid.
+func (k *KeyRing) verificationKey(kid string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+
+	return key, ok
+}
+
+// add must be called with the lock held.
+func (k *KeyRing) add(key *signingKey) {
+	if _, exists := k.keys[key.ID]; !exists {
+		k.order = append(k.order, key.ID)
+	}
+
+	k.keys[key.ID] = key
+}
+
+// newSigningKey checks the public key can be used with the algorithm
+// and identifies it by its thumbprint.
+func newSigningKey(algorithm string, publicKey crypto.PublicKey) (*signingKey, error) {
+	method, err := jwa.SigningMethod(algorithm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := jwa.PublicJWK(algorithm, publicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := jwa.Thumbprint(jwk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		ID:        kid,
+		Algorithm: algorithm,
+		Method:    method,
+		PublicKey: publicKey,
+		jwk:       jwk,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+func TestKeyRing(t *testing.T) {
+	firstKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	secondKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	t.Run("Signs with the provided key", func(t *testing.T) {
+		keyRing, err := NewKeyRing(jwa.RS256, firstKey)
+		assert.NoError(t, err)
+
+		signingKey := keyRing.signingKey()
+		assert.Equal(t, firstKey, signingKey.PrivateKey)
+		assert.NotEmpty(t, signingKey.ID)
+
+		jwks := keyRing.JWKS()
+		assert.Len(t, jwks.Keys, 1)
+		assert.Equal(t, signingKey.ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, "RSA", jwks.Keys[0].KeyType)
+		assert.Equal(t, "RS256", jwks.Keys[0].Algorithm)
+		assert.Equal(t, "sig", jwks.Keys[0].Use)
+	})
+
+	t.Run("Key ID is stable for the same key", func(t *testing.T) {
+		keyRing, _ := NewKeyRing(jwa.RS256, firstKey)
+
+		kid, err := keyRing.AddVerificationKey(jwa.RS256, &firstKey.PublicKey)
+		assert.NoError(t, err)
+		assert.Equal(t, keyRing.signingKey().ID, kid)
+		assert.Len(t, keyRing.JWKS().Keys, 1)
+	})
+
+	t.Run("Keeps the previous key for verification", func(t *testing.T) {
+		previousRing, _ := NewKeyRing(jwa.RS256, firstKey)
+		previousID := previousRing.signingKey().ID
+
+		keyRing, _ := NewKeyRing(jwa.RS256, secondKey)
+		kid, err := keyRing.AddVerificationKey(jwa.RS256, &firstKey.PublicKey)
+		assert.NoError(t, err)
+		assert.Equal(t, previousID, kid)
+		assert.NotEqual(t, kid, keyRing.signingKey().ID)
+
+		previousKey, ok := keyRing.verificationKey(previousID)
+		assert.True(t, ok)
+		assert.Nil(t, previousKey.PrivateKey)
+		assert.Equal(t, &firstKey.PublicKey, previousKey.PublicKey)
+		assert.Len(t, keyRing.JWKS().Keys, 2)
+	})
+
+	t.Run("Nil private key", func(t *testing.T) {
+		_, err := NewKeyRing(jwa.RS256, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Signs and verifies with every algorithm", func(t *testing.T) {
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+		user := &model.User{UserID: uuid.New(), Email: "kostya@kostya.com"}
+		settings := &idTokenSettings{}
+
+		for algorithm, privateKey := range map[string]crypto.Signer{
+			jwa.RS256: firstKey,
+			jwa.PS256: firstKey,
+			jwa.ES256: ecKey,
+			jwa.EdDSA: edKey,
+		} {
+			keyRing, err := NewKeyRing(algorithm, privateKey)
+			assert.NoError(t, err)
+
+			signedString, err := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), settings, 60)
+			assert.NoError(t, err)
+
+			claims, err := validateIDToken(signedString, keyRing, settings)
+			assert.NoError(t, err, algorithm)
+			assert.Equal(t, user.UserID.String(), claims.Subject)
+
+			jwk := keyRing.JWKS().Keys[0]
+			assert.Equal(t, algorithm, jwk.Algorithm)
+			assert.Equal(t, keyRing.signingKey().ID, jwk.KeyID)
+		}
+	})
+
+	t.Run("Publishes EC and OKP keys", func(t *testing.T) {
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		edPublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+		keyRing, _ := NewKeyRing(jwa.ES256, ecKey)
+		_, err := keyRing.AddVerificationKey(jwa.EdDSA, edPublicKey)
+		assert.NoError(t, err)
+
+		jwks := keyRing.JWKS()
+		assert.Len(t, jwks.Keys, 2)
+
+		assert.Equal(t, "EC", jwks.Keys[0].KeyType)
+		assert.Equal(t, "P-256", jwks.Keys[0].Curve)
+		assert.Len(t, jwks.Keys[0].X, 43) // 32 bytes, base64url encoded.
+		assert.Len(t, jwks.Keys[0].Y, 43)
+
+		assert.Equal(t, "OKP", jwks.Keys[1].KeyType)
+		assert.Equal(t, "Ed25519", jwks.Keys[1].Curve)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(edPublicKey), jwks.Keys[1].X)
+	})
+
+	t.Run("Rejects a key the algorithm can't use", func(t *testing.T) {
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+		_, err := NewKeyRing(jwa.ES256, ecKey)
+		assert.Error(t, err)
+
+		_, err = NewKeyRing(jwa.EdDSA, firstKey)
+		assert.Error(t, err)
+
+		_, err = NewKeyRing("HS256", firstKey)
+		assert.Error(t, err)
+	})
+
+	t.Run("Rejects tokens signed with another algorithm than the key's", func(t *testing.T) {
+		keyRing, _ := NewKeyRing(jwa.RS256, firstKey)
+		pssKeyRing, _ := NewKeyRing(jwa.PS256, firstKey)
+
+		user := &model.User{UserID: uuid.New()}
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, pssKeyRing.signingKey(), &idTokenSettings{}, 60)
+
+		// Same key and kid, but the key ring only accepts RS256 for it.
+		_, err := validateIDToken(signedString, keyRing, &idTokenSettings{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// magicLinkAudience keeps other tokens signed with
+// the same secret from passing as sign in links.
+const magicLinkAudience = "magic_link"
+
+// magicLinkClaims holds the payload of a sign in link token. The ID of
+// the token is random, so each link can be used up on its own.
+type magicLinkClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+// generateMagicLinkToken signs a token which signs in the owner of the email.
+func generateMagicLinkToken(email string, secret string, expiration time.Duration) (string, error) {
+	tokenID, err := randomToken(16)
+
+	if err != nil {
+		return "", err
+	}
+
+	currentTime := time.Now()
+
+	claims := magicLinkClaims{
+		Email: email,
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID,
+			Audience:  magicLinkAudience,
+			IssuedAt:  currentTime.Unix(),
+			ExpiresAt: currentTime.Add(expiration).Unix(),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// validateMagicLinkToken returns the claims of a valid sign in link token.
+func validateMagicLinkToken(tokenString string, secret string) (*magicLinkClaims, error) {
+	claims := &magicLinkClaims{}
+	parser := &jwt.Parser{
+		ValidMethods: []string{jwt.SigningMethodHS256.Alg()},
+	}
+
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid || !claims.VerifyAudience(magicLinkAudience, true) || claims.Id == "" {
+		return nil, fmt.Errorf("magic link token is invalid")
+	}
+
+	return claims, nil
+}
+
+// SendMagicLink mails a link to the email, which signs its owner in
+// without a password. Unknown emails only get a link when links may
+// sign up new users, and users with two-factor authentication are told
+// to sign in with their password instead. Nothing tells the caller
+// whether the email is registered.
+func (s *userService) SendMagicLink(ctx context.Context, email string) error {
+	user, err := s.UserRepository.FindByEmail(ctx, email)
+
+	if err != nil && !hasErrorType(err, apperrors.NotFound) {
+		return err
+	}
+
+	if err != nil {
+		if !s.MagicLinkSignUp {
+			return nil
+		}
+
+		user = nil
+	}
+
+	if user != nil && user.TOTPEnabled {
+		mail := &model.Mail{
+			To:      email,
+			Subject: "Sign in to your account",
+			Body:    "Someone asked for a sign in link for your account. As two-factor authentication is enabled, sign in with your password and authenticator instead.\n\nIf it wasn't you, you can ignore this email.",
+		}
+
+		if err := s.MailSender.Send(ctx, mail); err != nil {
+			log.Printf("Unable to send the sign in link notice to userID: %v. Error: %v\n", user.UserID, err)
+		}
+
+		return nil
+	}
+
+	token, err := generateMagicLinkToken(email, s.MagicLinkSecret, s.MagicLinkExpiration)
+
+	if err != nil {
+		log.Printf("Unable to generate a sign in link token for email: %v. Error: %v\n", email, err)
+		return nil
+	}
+
+	link, err := linkWithToken(s.MagicLinkURL, token)
+
+	if err != nil {
+		log.Printf("Unable to parse the sign in link URL: %v. Error: %v\n", s.MagicLinkURL, err)
+		return nil
+	}
+
+	action := "sign in"
+
+	if user == nil {
+		action = "create your account and sign in"
+	}
+
+	mail := &model.Mail{
+		To:      email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Follow the link within %d minutes to %s. It works once:\n\n%s\n\nIf you didn't ask for it, you can ignore this email.",
+			int(s.MagicLinkExpiration.Minutes()), action, link),
+	}
+
+	if err := s.MailSender.Send(ctx, mail); err != nil {
+		log.Printf("Unable to send the sign in link to email: %v. Error: %v\n", email, err)
+	}
+
+	return nil
+}
+
+// SignInWithMagicLink signs in the owner of the email a sign in link
+// was sent to, and verifies the email, as following the link proves it
+// is the user's. The link can't be used again. Without an account for
+// the email, one without a password is created when links may sign up
+// new users.
+func (s *userService) SignInWithMagicLink(ctx context.Context, token string) (*model.User, error) {
+	claims, err := validateMagicLinkToken(token, s.MagicLinkSecret)
+
+	if err != nil {
+		log.Printf("Unable to validate the sign in link token. Error: %v\n", err)
+		return nil, apperrors.NewAuthorization("Invalid or expired sign in link")
+	}
+
+	user, err := s.UserRepository.FindByEmail(ctx, claims.Email)
+
+	// Only a missing account may be created, not one which couldn't be read.
+	if err != nil && !hasErrorType(err, apperrors.NotFound) {
+		return nil, err
+	}
+
+	if err != nil {
+		if !s.MagicLinkSignUp {
+			return nil, apperrors.NewAuthorization("Invalid or expired sign in link")
+		}
+
+		user = nil
+	}
+
+	// The link only replaces the password, so it doesn't get
+	// past two-factor authentication. The link isn't used up.
+	if user != nil && user.TOTPEnabled {
+		return nil, apperrors.NewForbidden("Two-factor authentication is enabled. Sign in with your password")
+	}
+
+	unused, err := s.TokenRepository.UseMagicLinkToken(ctx, claims.Id, time.Until(time.Unix(claims.ExpiresAt, 0)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !unused {
+		return nil, apperrors.NewAuthorization("Invalid or expired sign in link")
+	}
+
+	if user == nil {
+		user = &model.User{Email: claims.Email}
+
+		if err := s.UserRepository.Create(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.EmailVerified {
+		return user, nil
+	}
+
+	return s.UserRepository.VerifyEmail(ctx, user.UserID, claims.Email)
+}
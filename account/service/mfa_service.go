@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// mfaChallengeMaxAttempts is how many codes may be tried against
+// an MFA challenge, before the user has to enter the password again.
+const mfaChallengeMaxAttempts = 5
+
+// mfaService acts as a struct for injecting implementations
+// of UserRepository and TokenRepository for use in service methods.
+// The PasskeyService lets users answer challenges with a passkey.
+type mfaService struct {
+	UserRepository      model.UserRepository
+	TokenRepository     model.TokenRepository
+	PasskeyService      model.PasskeyService
+	Issuer              string
+	EncryptionKey       []byte
+	ChallengeExpiration time.Duration
+}
+
+// MFAServiceConfig will hold repositories that
+// will eventually be injected into this service layer.
+type MFAServiceConfig struct {
+	UserRepository      model.UserRepository
+	TokenRepository     model.TokenRepository
+	PasskeyService      model.PasskeyService
+	Issuer              string // Names the account in authenticator apps.
+	EncryptionKey       []byte // Encrypts the TOTP secrets with AES-GCM. 16, 24 or 32 bytes long.
+	ChallengeExpiration int64  // Seconds an MFA challenge can be answered for.
+}
+
+// NewMFAService is a factory function for
+// initializing an MFAService with its
+// repository layer dependencies.
+func NewMFAService(c *MFAServiceConfig) model.MFAService {
+	return &mfaService{
+		UserRepository:      c.UserRepository,
+		TokenRepository:     c.TokenRepository,
+		PasskeyService:      c.PasskeyService,
+		Issuer:              c.Issuer,
+		EncryptionKey:       c.EncryptionKey,
+		ChallengeExpiration: time.Duration(c.ChallengeExpiration) * time.Second,
+	}
+}
+
+// EnrollTOTP creates a new TOTP secret for the user, which replaces
+// any secret of an earlier enrollment which wasn't confirmed.
+// TOTP is enabled once the user confirms it with a code.
+func (s *mfaService) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*model.TOTPEnrollment, error) {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("TOTP is already enabled. Disable it before enrolling again")
+	}
+
+	secret, err := generateTOTPSecret()
+
+	if err != nil {
+		log.Printf("Unable to generate a TOTP secret for userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	encrypted, err := encryptTOTPSecret(s.EncryptionKey, userID, secret)
+
+	if err != nil {
+		log.Printf("Unable to encrypt the TOTP secret of userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.SetTOTPSecret(ctx, userID, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP once the user proves the authenticator
+// works with a code of the enrolled secret. It returns the recovery
+// codes, which are shown only this once, as just their hashes are stored.
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, apperrors.NewBadRequest("TOTP is already enabled")
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, apperrors.NewBadRequest("Enroll in TOTP before confirming it")
+	}
+
+	secret, err := decryptTOTPSecret(s.EncryptionKey, userID, user.TOTPSecret)
+
+	if err != nil {
+		log.Printf("Unable to decrypt the TOTP secret of userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	step, ok := matchTOTPCode(secret, normalizeMFACode(code), time.Now())
+
+	if !ok {
+		return nil, apperrors.NewAuthorization("Invalid code")
+	}
+
+	recoveryCodes, err := generateRecoveryCodes()
+
+	if err != nil {
+		log.Printf("Unable to generate recovery codes for userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	hashes := make([]string, len(recoveryCodes))
+	for i, recoveryCode := range recoveryCodes {
+		hashes[i] = hashRecoveryCode(recoveryCode)
+	}
+
+	if err := s.UserRepository.EnableTOTP(ctx, userID, hashes, step); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTOTP disables TOTP, which takes a TOTP or recovery code,
+// so a stolen ID token alone can't turn off the second factor.
+func (s *mfaService) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return apperrors.NewBadRequest("TOTP is not enabled")
+	}
+
+	if _, err := s.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	return s.UserRepository.DisableTOTP(ctx, userID)
+}
+
+// NewChallenge issues an MFA challenge to a user who entered the right
+// password. Only the hash of the token is stored.
+func (s *mfaService) NewChallenge(ctx context.Context, user *model.User) (*model.MFAChallenge, error) {
+	token, err := randomToken(32)
+
+	if err != nil {
+		log.Printf("Unable to generate an MFA challenge for userID: %v. Error: %v\n", user.UserID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.TokenRepository.SetMFAChallenge(ctx, hashToken(token), user.UserID.String(), s.ChallengeExpiration); err != nil {
+		return nil, err
+	}
+
+	return &model.MFAChallenge{
+		Token:     token,
+		ExpiresIn: s.ChallengeExpiration,
+	}, nil
+}
+
+// VerifyChallenge answers an MFA challenge with a TOTP or recovery code,
+// and returns the user who is then signed in, with the AMR of the
+// password and the code. A challenge can only be answered once,
+// and only a few codes may be tried against it.
+func (s *mfaService) VerifyChallenge(ctx context.Context, token string, code string) (*model.User, error) {
+	tokenHash := hashToken(token)
+
+	user, err := s.challengeUser(ctx, tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	amr, err := s.verifyCode(ctx, user, code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The code is used up either way, so a failure only leaves
+	// the challenge around until it expires.
+	s.TokenRepository.DeleteMFAChallenge(ctx, tokenHash)
+
+	user.AMR = append([]string{model.AMRPassword}, amr...)
+
+	return user, nil
+}
+
+// PasskeyOptions starts answering an MFA challenge with a passkey
+// of the user, instead of a code. Fetching the options counts
+// as an attempt, so they can't be fetched endlessly.
+func (s *mfaService) PasskeyOptions(ctx context.Context, token string) (*model.PasskeyRequestOptions, error) {
+	user, err := s.challengeUser(ctx, hashToken(token))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.PasskeyService.BeginSignIn(ctx, user)
+}
+
+// VerifyChallengeWithPasskey answers an MFA challenge with a passkey of
+// the user, and returns the user who is then signed in, with the AMR
+// of the password and the passkey.
+func (s *mfaService) VerifyChallengeWithPasskey(ctx context.Context, token string, credential *model.PasskeyCredential) (*model.User, error) {
+	tokenHash := hashToken(token)
+
+	user, err := s.challengeUser(ctx, tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The passkey has to be one of the user who entered the password.
+	user, err = s.PasskeyService.FinishSignIn(ctx, user, credential)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.TokenRepository.DeleteMFAChallenge(ctx, tokenHash)
+
+	user.AMR = []string{model.AMRPassword, model.AMRPasskey}
+
+	return user, nil
+}
+
+// challengeUser counts an attempt to answer the MFA challenge,
+// and returns the user it was issued to.
+func (s *mfaService) challengeUser(ctx context.Context, tokenHash string) (*model.User, error) {
+	userIDString, attempts, err := s.TokenRepository.AttemptMFAChallenge(ctx, tokenHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if attempts > mfaChallengeMaxAttempts {
+		s.TokenRepository.DeleteMFAChallenge(ctx, tokenHash)
+		return nil, apperrors.NewAuthorization("Too many invalid codes. Sign in again")
+	}
+
+	userID, err := uuid.Parse(userIDString)
+
+	if err != nil {
+		log.Printf("Invalid userID: %v of an MFA challenge. Error: %v\n", userIDString, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// TOTP may have been disabled since the password was entered.
+	if !user.TOTPEnabled {
+		return nil, apperrors.NewAuthorization("Invalid or expired MFA token")
+	}
+
+	return user, nil
+}
+
+// verifyCode checks a TOTP or recovery code of the user, uses it up,
+// and returns the AMR of the kind of code. Codes of a time step which
+// was used before are rejected, so a code seen over someone's shoulder
+// can't be replayed.
+func (s *mfaService) verifyCode(ctx context.Context, user *model.User, code string) ([]string, error) {
+	code = normalizeMFACode(code)
+
+	var ok bool
+	var err error
+	var amr []string
+
+	if isTOTPCode(code) {
+		ok, err = s.useTOTPCode(ctx, user, code)
+		amr = []string{model.AMROTP}
+	} else {
+		ok, err = s.UserRepository.UseRecoveryCode(ctx, user.UserID, hashRecoveryCode(code))
+		amr = []string{model.AMRRecoveryCode, model.AMRMFA}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, apperrors.NewAuthorization("Invalid code")
+	}
+
+	return amr, nil
+}
+
+// useTOTPCode reports whether the TOTP code is valid and wasn't used before.
+func (s *mfaService) useTOTPCode(ctx context.Context, user *model.User, code string) (bool, error) {
+	secret, err := decryptTOTPSecret(s.EncryptionKey, user.UserID, user.TOTPSecret)
+
+	if err != nil {
+		log.Printf("Unable to decrypt the TOTP secret of userID: %v. Error: %v\n", user.UserID, err)
+		return false, apperrors.NewInternal()
+	}
+
+	step, ok := matchTOTPCode(secret, code, time.Now())
+
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+
+	return s.UserRepository.UseTOTPStep(ctx, user.UserID, step)
+}
+
+// normalizeMFACode drops the spaces users type into codes, like "123 456".
+func normalizeMFACode(code string) string {
+	return strings.Join(strings.Fields(code), "")
+}
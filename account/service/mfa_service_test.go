@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+// mfaTestKey encrypts the TOTP secrets of the tests.
+var mfaTestKey = []byte("somemfaencryptionkeyof32bytes!!!")
+
+// newTOTPUser returns a user with TOTP enabled along with the current code.
+func newTOTPUser(t *testing.T) (*model.User, string) {
+	userID, _ := uuid.NewRandom()
+
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+
+	encrypted, err := encryptTOTPSecret(mfaTestKey, userID, secret)
+	assert.NoError(t, err)
+
+	key, _ := totpEncoding.DecodeString(secret)
+
+	return &model.User{
+		UserID:      userID,
+		Email:       "kostya@kostya.com",
+		TOTPSecret:  encrypted,
+		TOTPEnabled: true,
+	}, totpCode(key, totpStep(time.Now()))
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	userID, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			Issuer:         "base-go",
+			EncryptionKey:  mfaTestKey,
+		})
+
+		var storedSecret string
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID, Email: "kostya@kostya.com"}, nil)
+		mockUserRepository.On("SetTOTPSecret", mock.Anything, userID, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				storedSecret = args.String(2)
+			}).Return(nil)
+
+		enrollment, err := mfaService.EnrollTOTP(context.Background(), userID)
+		assert.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/base-go:kostya@kostya.com?"))
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+		// Only the encrypted secret is stored.
+		assert.NotContains(t, storedSecret, enrollment.Secret)
+		secret, err := decryptTOTPSecret(mfaTestKey, userID, storedSecret)
+		assert.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, secret)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Already enabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID, TOTPEnabled: true}, nil)
+
+		_, err := mfaService.EnrollTOTP(context.Background(), userID)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "SetTOTPSecret")
+	})
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		user, code := newTOTPUser(t)
+		user.TOTPEnabled = false
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		var storedHashes []string
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockUserRepository.On("EnableTOTP", mock.Anything, user.UserID, mock.AnythingOfType("[]string"), mock.AnythingOfType("int64")).
+			Run(func(args mock.Arguments) {
+				storedHashes = args.Get(2).([]string)
+			}).Return(nil)
+
+		recoveryCodes, err := mfaService.ConfirmTOTP(context.Background(), user.UserID, code[:3]+" "+code[3:])
+		assert.NoError(t, err)
+
+		assert.Len(t, recoveryCodes, recoveryCodeCount)
+		assert.Len(t, storedHashes, recoveryCodeCount)
+		assert.Equal(t, hashRecoveryCode(recoveryCodes[0]), storedHashes[0])
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+		user.TOTPEnabled = false
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+
+		_, err := mfaService.ConfirmTOTP(context.Background(), user.UserID, "000000x")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "EnableTOTP")
+	})
+
+	t.Run("Not enrolled", func(t *testing.T) {
+		userID, _ := uuid.NewRandom()
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID}, nil)
+
+		_, err := mfaService.ConfirmTOTP(context.Background(), userID, "123456")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestDisableTOTP(t *testing.T) {
+	t.Run("Success with a recovery code", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockUserRepository.On("UseRecoveryCode", mock.Anything, user.UserID, hashRecoveryCode("ABCD-EFGH-IJKL-MNOP")).Return(true, nil)
+		mockUserRepository.On("DisableTOTP", mock.Anything, user.UserID).Return(nil)
+
+		err := mfaService.DisableTOTP(context.Background(), user.UserID, "abcd-efgh-ijkl-mnop")
+		assert.NoError(t, err)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository: mockUserRepository,
+			EncryptionKey:  mfaTestKey,
+		})
+
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockUserRepository.On("UseRecoveryCode", mock.Anything, user.UserID, mock.AnythingOfType("string")).Return(false, nil)
+
+		err := mfaService.DisableTOTP(context.Background(), user.UserID, "abcd-efgh-ijkl-mnop")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "DisableTOTP")
+	})
+}
+
+func TestMFAChallenge(t *testing.T) {
+	t.Run("Issues a challenge", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			ChallengeExpiration: 300,
+		})
+
+		var storedHash string
+		mockTokenRepository.On("SetMFAChallenge", mock.Anything, mock.AnythingOfType("string"), user.UserID.String(), 5*time.Minute).
+			Run(func(args mock.Arguments) {
+				storedHash = args.String(1)
+			}).Return(nil)
+
+		challenge, err := mfaService.NewChallenge(context.Background(), user)
+		assert.NoError(t, err)
+
+		assert.Equal(t, 5*time.Minute, challenge.ExpiresIn)
+		assert.Equal(t, hashToken(challenge.Token), storedHash)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Answered with a TOTP code", func(t *testing.T) {
+		user, code := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			EncryptionKey:   mfaTestKey,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 1, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, tokenHash).Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockUserRepository.On("UseTOTPStep", mock.Anything, user.UserID, mock.AnythingOfType("int64")).Return(true, nil)
+
+		signedInUser, err := mfaService.VerifyChallenge(context.Background(), "sometoken", code)
+		assert.NoError(t, err)
+		assert.Equal(t, user, signedInUser)
+		assert.Equal(t, []string{model.AMRPassword, model.AMROTP}, signedInUser.AMR)
+		mockUserRepository.AssertExpectations(t)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Replayed TOTP code", func(t *testing.T) {
+		user, code := newTOTPUser(t)
+		user.TOTPLastStep = totpStep(time.Now())
+		tokenHash := hashToken("sometoken")
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			EncryptionKey:   mfaTestKey,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 1, nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+
+		_, err := mfaService.VerifyChallenge(context.Background(), "sometoken", code)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockUserRepository.AssertNotCalled(t, "UseTOTPStep")
+		mockTokenRepository.AssertNotCalled(t, "DeleteMFAChallenge")
+	})
+
+	t.Run("Answered with a recovery code", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			EncryptionKey:   mfaTestKey,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 2, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, tokenHash).Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockUserRepository.On("UseRecoveryCode", mock.Anything, user.UserID, hashRecoveryCode("ABCD-EFGH-IJKL-MNOP")).Return(true, nil)
+
+		signedInUser, err := mfaService.VerifyChallenge(context.Background(), "sometoken", "ABCD-EFGH-IJKL-MNOP")
+		assert.NoError(t, err)
+
+		// Relying parties can tell a recovery code from a TOTP code.
+		assert.Equal(t, []string{model.AMRPassword, model.AMRRecoveryCode, model.AMRMFA}, signedInUser.AMR)
+		mockUserRepository.AssertExpectations(t)
+	})
+
+	t.Run("Too many attempts", func(t *testing.T) {
+		user, code := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			EncryptionKey:   mfaTestKey,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), mfaChallengeMaxAttempts+1, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, tokenHash).Return(nil)
+
+		_, err := mfaService.VerifyChallenge(context.Background(), "sometoken", code)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockTokenRepository.AssertCalled(t, "DeleteMFAChallenge", mock.Anything, tokenHash)
+		mockUserRepository.AssertNotCalled(t, "FindByID")
+	})
+
+	t.Run("Invalid or expired token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			TokenRepository: mockTokenRepository,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, mock.AnythingOfType("string")).
+			Return("", 0, apperrors.NewAuthorization("Invalid or expired MFA token"))
+
+		_, err := mfaService.VerifyChallenge(context.Background(), "expiredtoken", "123456")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
+
+func TestMFAChallengeWithPasskey(t *testing.T) {
+	t.Run("Passkey options", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+		options := &model.PasskeyRequestOptions{Challenge: "somechallenge"}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			PasskeyService:  mockPasskeyService,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 1, nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockPasskeyService.On("BeginSignIn", mock.Anything, user).Return(options, nil)
+
+		fetched, err := mfaService.PasskeyOptions(context.Background(), "sometoken")
+		assert.NoError(t, err)
+		assert.Equal(t, options, fetched)
+		mockTokenRepository.AssertNotCalled(t, "DeleteMFAChallenge")
+	})
+
+	t.Run("Answered with a passkey", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+		credential := &model.PasskeyCredential{ID: "somecredential"}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			PasskeyService:  mockPasskeyService,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 2, nil)
+		mockTokenRepository.On("DeleteMFAChallenge", mock.Anything, tokenHash).Return(nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockPasskeyService.On("FinishSignIn", mock.Anything, user, credential).Return(user, nil)
+
+		signedInUser, err := mfaService.VerifyChallengeWithPasskey(context.Background(), "sometoken", credential)
+		assert.NoError(t, err)
+		assert.Equal(t, user, signedInUser)
+		assert.Equal(t, []string{model.AMRPassword, model.AMRPasskey}, signedInUser.AMR)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Passkey which doesn't verify", func(t *testing.T) {
+		user, _ := newTOTPUser(t)
+		tokenHash := hashToken("sometoken")
+		credential := &model.PasskeyCredential{ID: "somecredential"}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockPasskeyService := new(mocks.MockPasskeyService)
+		mfaService := NewMFAService(&MFAServiceConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			PasskeyService:  mockPasskeyService,
+		})
+
+		mockTokenRepository.On("AttemptMFAChallenge", mock.Anything, tokenHash).Return(user.UserID.String(), 2, nil)
+		mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+		mockPasskeyService.On("FinishSignIn", mock.Anything, user, credential).
+			Return(nil, apperrors.NewAuthorization("The passkey could not be verified"))
+
+		_, err := mfaService.VerifyChallengeWithPasskey(context.Background(), "sometoken", credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "DeleteMFAChallenge")
+	})
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// hashToken hashes a random token, like a reset token or a challenge,
+// so only the hash is stored. Tokens are long random strings rather
+// than passwords, so a fast unsalted hash is enough to keep them from leaking.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashClientSecret hashes a client secret for the client registry.
+// Client secrets are chosen by hand rather than generated,
+// so they are hashed like passwords rather than like tokens.
+func HashClientSecret(secret string) (string, error) {
+	return hashPassword(secret)
+}
+
+// clientSecretMatches compares a secret with a stored hash in constant time.
+func clientSecretMatches(secretHash string, secret string) bool {
+	matches, err := comparePasswords(secretHash, secret)
+	return err == nil && matches
+}
+
+// randomToken returns a url safe random string of
+// the length of the base64 encoded bytes.
+func randomToken(bytes int) (string, error) {
+	token := make([]byte, bytes)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// validCodeChallenge checks that a PKCE challenge is a base64url
+// encoded SHA-256 digest as required by the S256 method.
+func validCodeChallenge(codeChallenge string) bool {
+	digest, err := base64.RawURLEncoding.DecodeString(codeChallenge)
+	return err == nil && len(digest) == sha256.Size
+}
+
+// verifyCodeVerifier checks a PKCE verifier against
+// the challenge sent with the authorization request.
+func verifyCodeVerifier(codeVerifier string, codeChallenge string) bool {
+	// RFC 7636 requires between 43 and 128 characters.
+	if len(codeVerifier) < 43 || len(codeVerifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(codeVerifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(codeChallenge)) == 1
+}
+
+// redirectURL adds the non-empty parameters to the query of a redirect URI.
+func redirectURL(redirectURI string, parameters map[string]string) (string, error) {
+	u, err := url.Parse(redirectURI)
+
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+
+	for key, value := range parameters {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// hasErrorType reports whether err is an application error of the type.
+func hasErrorType(err error, errorType apperrors.Type) bool {
+	var appError *apperrors.Error
+	return errors.As(err, &appError) && appError.Type == errorType
+}
+
+// containsAll reports whether all of the values are in the list.
+func containsAll(list []string, values []string) bool {
+	for _, value := range values {
+		if !contains(list, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,799 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// authorizationCodeExpiration is how long a client has
+// to exchange an authorization code for tokens.
+const authorizationCodeExpiration = time.Minute
+
+// oauthService acts as a struct for injecting the services and
+// repositories used by the OAuth authorization server endpoints.
+type oauthService struct {
+	TokenService          model.TokenService
+	TokenRepository       model.TokenRepository
+	UserRepository        model.UserRepository
+	OAuthClientRepository model.OAuthClientRepository
+	Issuer                string
+	AuthorizationEndpoint string
+}
+
+// OAuthServiceConfig will hold services and repositories
+// that will eventually be injected into this service layer.
+// The authorization endpoint is the page of our frontend which
+// signs the user in and asks for consent, so it is configured
+// separately from the endpoints below the issuer.
+type OAuthServiceConfig struct {
+	TokenService          model.TokenService
+	TokenRepository       model.TokenRepository
+	UserRepository        model.UserRepository
+	OAuthClientRepository model.OAuthClientRepository
+	Issuer                string
+	AuthorizationEndpoint string
+}
+
+// NewOAuthService is a factory function for
+// initializing an OAuthService with its
+// service and repository layer dependencies.
+func NewOAuthService(c *OAuthServiceConfig) model.OAuthService {
+	return &oauthService{
+		TokenService:          c.TokenService,
+		TokenRepository:       c.TokenRepository,
+		UserRepository:        c.UserRepository,
+		OAuthClientRepository: c.OAuthClientRepository,
+		Issuer:                c.Issuer,
+		AuthorizationEndpoint: c.AuthorizationEndpoint,
+	}
+}
+
+// AuthenticateClient checks the credentials of a confidential
+// client calling the revocation or introspection endpoint.
+// It returns the client, which the endpoints act on behalf of.
+func (s *oauthService) AuthenticateClient(ctx context.Context, clientID string, clientSecret string) (*model.OAuthClient, error) {
+	return s.authenticateClient(ctx, clientID, clientSecret, false)
+}
+
+// authenticateClient looks the client up in the registry and checks its secret.
+// Public clients have no secret to check and are only accepted where allowPublic,
+// which is the token endpoint, where PKCE protects their authorization codes.
+func (s *oauthService) authenticateClient(ctx context.Context, clientID string, clientSecret string, allowPublic bool) (*model.OAuthClient, error) {
+	invalidClient := apperrors.NewOAuthError(apperrors.InvalidClient, "Client authentication failed")
+
+	if clientID == "" {
+		return nil, invalidClient
+	}
+
+	client, err := s.OAuthClientRepository.FindByID(ctx, clientID)
+
+	if hasErrorType(err, apperrors.NotFound) {
+		log.Printf("Failed to authenticate unknown OAuth client: %v\n", clientID)
+		return nil, invalidClient
+	}
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to authenticate the client")
+	}
+
+	if client.IsPublic() {
+		if !allowPublic || clientSecret != "" {
+			log.Printf("Failed to authenticate public OAuth client: %v\n", clientID)
+			return nil, invalidClient
+		}
+
+		return client, nil
+	}
+
+	if !clientSecretMatches(client.SecretHash, clientSecret) {
+		log.Printf("Failed to authenticate OAuth client: %v\n", clientID)
+		return nil, invalidClient
+	}
+
+	return client, nil
+}
+
+// Introspect reports whether a token is currently active.
+// Only resource servers may ask, as the answer describes the user.
+// The hint only decides which token type is tried first.
+// Invalid tokens are not an error, they are simply inactive.
+func (s *oauthService) Introspect(ctx context.Context, client *model.OAuthClient, token string, tokenTypeHint string) (*model.TokenIntrospection, error) {
+	if !client.IsResourceServer() {
+		log.Printf("OAuth client which is not a resource server tried to introspect a token: %v\n", client.ClientID)
+		return nil, apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The client is not registered as a resource server")
+	}
+
+	if tokenTypeHint == model.RefreshTokenType {
+		if introspection := s.introspectRefreshToken(ctx, token); introspection.Active {
+			return introspection, nil
+		}
+
+		return s.introspectAccessToken(ctx, token), nil
+	}
+
+	if introspection := s.introspectAccessToken(ctx, token); introspection.Active {
+		return introspection, nil
+	}
+
+	return s.introspectRefreshToken(ctx, token), nil
+}
+
+// Revoke invalidates a refresh token along with its session,
+// or adds an access token to the denylist. A client may only
+// revoke the tokens which were issued to it.
+// As required by RFC 7009, invalid or unknown tokens are not an error.
+func (s *oauthService) Revoke(ctx context.Context, client *model.OAuthClient, token string, tokenTypeHint string) error {
+	refreshToken, err := s.TokenService.ValidateRefreshToken(token)
+
+	if err != nil {
+		return s.revokeAccessToken(ctx, client, token)
+	}
+
+	userID := refreshToken.UserID.String()
+	session, err := s.TokenRepository.GetRefreshToken(ctx, userID, refreshToken.ID.String())
+
+	// The token was already rotated or revoked.
+	if hasErrorType(err, apperrors.Authorization) {
+		return nil
+	}
+
+	if err != nil {
+		log.Printf("Failed to read refresh token for userID: %v. Error: %v\n", userID, err)
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to revoke the token")
+	}
+
+	// Tokens of our own sign in, and tokens stored
+	// before sessions were introduced, have no client.
+	if session == nil || session.ClientID != client.ClientID {
+		log.Printf("OAuth client tried to revoke a refresh token of another client: %v\n", client.ClientID)
+		return apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The token was not issued to the client")
+	}
+
+	if _, err := s.TokenRepository.DeleteRefreshTokenFamily(ctx, userID, session.ID.String()); err != nil {
+		log.Printf("Failed to revoke refresh token for userID: %v. Error: %v\n", userID, err)
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to revoke the token")
+	}
+
+	return nil
+}
+
+func (s *oauthService) revokeAccessToken(ctx context.Context, client *model.OAuthClient, token string) error {
+	clientID, valid, err := s.accessTokenClient(ctx, token)
+
+	if err != nil {
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to revoke the token")
+	}
+
+	// Not a valid token of any type, or already revoked.
+	if !valid {
+		return nil
+	}
+
+	if clientID != client.ClientID {
+		log.Printf("OAuth client tried to revoke an access token of another client: %v\n", client.ClientID)
+		return apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The token was not issued to the client")
+	}
+
+	err = s.TokenService.RevokeIDToken(ctx, token)
+
+	if err == nil {
+		return nil
+	}
+
+	var appError *apperrors.Error
+
+	if !errors.As(err, &appError) {
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to revoke the token")
+	}
+
+	switch appError.Type {
+	case apperrors.Authorization:
+		// Not a valid token of any type.
+		return nil
+	case apperrors.BadRequest:
+		return apperrors.NewOAuthError(apperrors.UnsupportedTokenType, appError.Message)
+	default:
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to revoke the token")
+	}
+}
+
+// accessTokenClient returns the client an access token was issued to, which is
+// the authorized party of a user's token and the subject of a machine client's.
+// Tokens of our own sign in have no client. It reports whether the token is valid.
+func (s *oauthService) accessTokenClient(ctx context.Context, token string) (string, bool, error) {
+	_, err := s.TokenService.ValidateAccessToken(ctx, token)
+
+	if err == nil {
+		// The signature was verified above, so reading the
+		// claims without verifying again is safe.
+		return unverifiedIDTokenClaims(token).AuthorizedParty, true, nil
+	}
+
+	principal, serviceErr := s.TokenService.ValidateServiceToken(ctx, token)
+
+	if serviceErr == nil {
+		return principal.ClientID, true, nil
+	}
+
+	for _, err := range []error{err, serviceErr} {
+		if !hasErrorType(err, apperrors.Authorization) {
+			return "", false, err
+		}
+	}
+
+	return "", false, nil
+}
+
+func (s *oauthService) introspectAccessToken(ctx context.Context, token string) *model.TokenIntrospection {
+	user, err := s.TokenService.ValidateAccessToken(ctx, token)
+
+	if err != nil {
+		return s.introspectServiceToken(ctx, token)
+	}
+
+	// The signature was verified above, so reading the
+	// claims without verifying again is safe.
+	claims := unverifiedIDTokenClaims(token)
+
+	return &model.TokenIntrospection{
+		Active:    true,
+		TokenType: model.AccessTokenType,
+		Subject:   user.UserID.String(),
+		Username:  user.Email,
+		ClientID:  claims.AuthorizedParty,
+		Scope:     claims.Scope,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		TokenID:   claims.Id,
+	}
+}
+
+// introspectServiceToken describes an access token of a machine client.
+func (s *oauthService) introspectServiceToken(ctx context.Context, token string) *model.TokenIntrospection {
+	principal, err := s.TokenService.ValidateServiceToken(ctx, token)
+
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}
+	}
+
+	claims := unverifiedIDTokenClaims(token)
+
+	return &model.TokenIntrospection{
+		Active:    true,
+		TokenType: model.AccessTokenType,
+		Subject:   principal.ClientID,
+		ClientID:  principal.ClientID,
+		Scope:     strings.Join(principal.Scopes, " "),
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		TokenID:   principal.TokenID,
+	}
+}
+
+func (s *oauthService) introspectRefreshToken(ctx context.Context, token string) *model.TokenIntrospection {
+	refreshToken, err := s.TokenService.ValidateRefreshToken(token)
+
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}
+	}
+
+	// A validly signed refresh token is only active
+	// until it is rotated or its session is signed out.
+	session, err := s.TokenRepository.GetRefreshToken(ctx, refreshToken.UserID.String(), refreshToken.ID.String())
+
+	if err != nil {
+		return &model.TokenIntrospection{Active: false}
+	}
+
+	claims := unverifiedStandardClaims(token)
+	introspection := &model.TokenIntrospection{
+		Active:    true,
+		TokenType: model.RefreshTokenType,
+		Subject:   refreshToken.UserID.String(),
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		TokenID:   claims.Id,
+	}
+
+	if session != nil {
+		introspection.ClientID = session.ClientID
+		introspection.Scope = session.Scope
+	}
+
+	return introspection
+}
+
+// unverifiedStandardClaims reads the standard claims of a token
+// which has already been validated.
+func unverifiedStandardClaims(tokenString string) *jwt.StandardClaims {
+	claims := &jwt.StandardClaims{}
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		log.Printf("Unable to read claims of a validated token: %v\n", err)
+	}
+
+	return claims
+}
+
+// unverifiedIDTokenClaims reads the claims of an ID token
+// which has already been validated.
+func unverifiedIDTokenClaims(tokenString string) *idTokenCustomClaims {
+	claims := &idTokenCustomClaims{}
+
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims); err != nil {
+		log.Printf("Unable to read claims of a validated token: %v\n", err)
+	}
+
+	return claims
+}
+
+// PrepareAuthorization validates an authorization request for a signed in
+// user and tells whether the user has to be asked for consent.
+func (s *oauthService) PrepareAuthorization(ctx context.Context, userID uuid.UUID, request *model.AuthorizationRequest) (*model.AuthorizationPrompt, error) {
+	client, scopes, err := s.validateAuthorizationRequest(ctx, request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.findConsent(ctx, userID, client)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.AuthorizationPrompt{
+		Client:          client,
+		Scopes:          scopes,
+		ConsentRequired: !client.FirstParty && !containsAll(consent, scopes),
+	}, nil
+}
+
+// Authorize records the user's decision on an authorization request
+// and returns the URL the user is redirected back to the client with.
+// If the user approved, the URL holds a single use authorization code,
+// which remembers how and when the user signed in.
+func (s *oauthService) Authorize(ctx context.Context, user *model.User, request *model.AuthorizationRequest, approved bool) (string, error) {
+	userID := user.UserID
+	client, scopes, err := s.validateAuthorizationRequest(ctx, request)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !approved {
+		return s.redirectToClient(request.RedirectURI, map[string]string{
+			"error": string(apperrors.AccessDenied),
+			"state": request.State,
+		})
+	}
+
+	if !client.FirstParty {
+		if err := s.grantConsent(ctx, userID, client, scopes); err != nil {
+			return "", err
+		}
+	}
+
+	code, err := randomToken(32)
+
+	if err != nil {
+		log.Printf("Error generating authorization code for userID: %v. Error: %v\n", userID, err)
+		return "", apperrors.NewOAuthError(apperrors.ServerError, "Unable to authorize the client")
+	}
+
+	authorizationCode := &model.AuthorizationCode{
+		ClientID:      client.ClientID,
+		RedirectURI:   request.RedirectURI,
+		UserID:        userID,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		AMR:           user.AMR,
+		AuthTime:      user.AuthTime,
+	}
+
+	if err := s.TokenRepository.SetAuthorizationCode(ctx, code, authorizationCode, authorizationCodeExpiration); err != nil {
+		return "", apperrors.NewOAuthError(apperrors.ServerError, "Unable to authorize the client")
+	}
+
+	return s.redirectToClient(request.RedirectURI, map[string]string{
+		"code":  code,
+		"state": request.State,
+	})
+}
+
+// Token issues tokens for the authorization_code, refresh_token and client_credentials grants.
+func (s *oauthService) Token(ctx context.Context, request *model.TokenRequest) (*model.TokenResponse, error) {
+	switch request.GrantType {
+	case model.AuthorizationCodeGrant:
+		return s.exchangeAuthorizationCode(ctx, request)
+	case model.RefreshTokenGrant:
+		return s.refreshTokens(ctx, request)
+	case model.ClientCredentialsGrant:
+		return s.issueServiceToken(ctx, request)
+	case "":
+		return nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The grant_type parameter is required")
+	default:
+		return nil, apperrors.NewOAuthError(apperrors.UnsupportedGrantType, "The grant type is not supported")
+	}
+}
+
+// UserInfo returns the claims about the user an access token was issued to.
+// Tokens of OAuth clients only get the claims of the scopes the user allowed.
+func (s *oauthService) UserInfo(ctx context.Context, accessToken string) (*model.UserInfo, error) {
+	tokenUser, err := s.TokenService.ValidateAccessToken(ctx, accessToken)
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidToken, "The access token is invalid")
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, tokenUser.UserID)
+
+	if err != nil {
+		log.Printf("Unable to find user of access token: %v. Error: %v\n", tokenUser.UserID, err)
+		return nil, apperrors.NewOAuthError(apperrors.InvalidToken, "The user of the access token no longer exists")
+	}
+
+	// Our own sign in gets all of the claims.
+	scopes := []string{model.ScopeEmail, model.ScopeProfile}
+
+	if claims := unverifiedIDTokenClaims(accessToken); claims.AuthorizedParty != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+
+	userInfo := &model.UserInfo{Subject: user.UserID.String()}
+
+	if contains(scopes, model.ScopeEmail) {
+		userInfo.Email = user.Email
+		userInfo.EmailVerified = &user.EmailVerified
+	}
+
+	if contains(scopes, model.ScopeProfile) {
+		userInfo.Name = user.Username
+		userInfo.Picture = user.ImageURL
+		userInfo.Website = user.Website
+	}
+
+	return userInfo, nil
+}
+
+// Discovery returns the OpenID Connect discovery document. The signing
+// algorithms are the ones of the published keys, so the document
+// follows key rotation.
+func (s *oauthService) Discovery() *model.OpenIDConfiguration {
+	var algorithms []string
+
+	for _, key := range s.TokenService.JWKS().Keys {
+		if !contains(algorithms, key.Algorithm) {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+
+	return &model.OpenIDConfiguration{
+		Issuer:                            s.Issuer,
+		AuthorizationEndpoint:             s.AuthorizationEndpoint,
+		TokenEndpoint:                     s.Issuer + "/oauth/token",
+		UserInfoEndpoint:                  s.Issuer + "/userinfo",
+		JWKSURI:                           s.Issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:                s.Issuer + "/oauth/revoke",
+		IntrospectionEndpoint:             s.Issuer + "/oauth/introspect",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{model.AuthorizationCodeGrant, model.RefreshTokenGrant, model.ClientCredentialsGrant},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		ScopesSupported:                   []string{model.ScopeOpenID, model.ScopeProfile, model.ScopeEmail},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "amr", "nonce", "azp", "email", "email_verified", "name", "picture", "website"},
+		CodeChallengeMethodsSupported:     []string{model.CodeChallengeMethodS256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+	}
+}
+
+// validateAuthorizationRequest checks an authorization request against
+// the client registry and returns the client with the requested scopes.
+func (s *oauthService) validateAuthorizationRequest(ctx context.Context, request *model.AuthorizationRequest) (*model.OAuthClient, []string, error) {
+	if request.ClientID == "" {
+		return nil, nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The client_id parameter is required")
+	}
+
+	client, err := s.OAuthClientRepository.FindByID(ctx, request.ClientID)
+
+	if hasErrorType(err, apperrors.NotFound) {
+		return nil, nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The client is not registered")
+	}
+
+	if err != nil {
+		return nil, nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to find the client")
+	}
+
+	if !client.AllowsGrant(model.AuthorizationCodeGrant) {
+		return nil, nil, apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The client may not sign users in")
+	}
+
+	// Redirect URIs must match exactly, so codes can't be sent anywhere else.
+	if !contains(client.RedirectURIs, request.RedirectURI) {
+		return nil, nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The redirect_uri is not registered for the client")
+	}
+
+	if request.ResponseType != "code" {
+		return nil, nil, apperrors.NewOAuthError(apperrors.UnsupportedResponseType, "Only the code response type is supported")
+	}
+
+	// PKCE is required from all clients, as recommended for OAuth 2.1.
+	if request.CodeChallengeMethod != model.CodeChallengeMethodS256 || !validCodeChallenge(request.CodeChallenge) {
+		return nil, nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "A code_challenge with the S256 code_challenge_method is required")
+	}
+
+	scopes := strings.Fields(request.Scope)
+
+	if !containsAll(client.Scopes, scopes) {
+		return nil, nil, apperrors.NewOAuthError(apperrors.InvalidScope, "The scope is not allowed for the client")
+	}
+
+	return client, scopes, nil
+}
+
+// findConsent returns the scopes the user has already allowed the client.
+func (s *oauthService) findConsent(ctx context.Context, userID uuid.UUID, client *model.OAuthClient) ([]string, error) {
+	if client.FirstParty {
+		return nil, nil
+	}
+
+	consent, err := s.OAuthClientRepository.FindConsent(ctx, userID, client.ClientID)
+
+	if hasErrorType(err, apperrors.NotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to find the consent")
+	}
+
+	return consent.Scopes, nil
+}
+
+// grantConsent adds the scopes to the ones the user has allowed the client.
+func (s *oauthService) grantConsent(ctx context.Context, userID uuid.UUID, client *model.OAuthClient, scopes []string) error {
+	allowed, err := s.findConsent(ctx, userID, client)
+
+	if err != nil {
+		return err
+	}
+
+	for _, scope := range scopes {
+		if !contains(allowed, scope) {
+			allowed = append(allowed, scope)
+		}
+	}
+
+	consent := &model.OAuthConsent{
+		UserID:   userID,
+		ClientID: client.ClientID,
+		Scopes:   allowed,
+	}
+
+	if err := s.OAuthClientRepository.UpsertConsent(ctx, consent); err != nil {
+		return apperrors.NewOAuthError(apperrors.ServerError, "Unable to store the consent")
+	}
+
+	return nil
+}
+
+// exchangeAuthorizationCode issues tokens for an authorization code.
+// The session of the tokens is bound to the client.
+func (s *oauthService) exchangeAuthorizationCode(ctx context.Context, request *model.TokenRequest) (*model.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The code and code_verifier parameters are required")
+	}
+
+	// The code is only used up once the client proves it was issued the code,
+	// so presenting a stolen or guessed code doesn't destroy it.
+	authorizationCode, err := s.TokenRepository.GetAuthorizationCode(ctx, request.Code)
+
+	if hasErrorType(err, apperrors.Authorization) {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The authorization code is invalid or expired")
+	}
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to exchange the authorization code")
+	}
+
+	if authorizationCode.ClientID != client.ClientID || authorizationCode.RedirectURI != request.RedirectURI {
+		log.Printf("Authorization code of clientID: %v presented by clientID: %v\n", authorizationCode.ClientID, client.ClientID)
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The authorization code was issued to another client or redirect_uri")
+	}
+
+	if !verifyCodeVerifier(request.CodeVerifier, authorizationCode.CodeChallenge) {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The code_verifier does not match the code_challenge")
+	}
+
+	// Deleting the code makes sure only one of concurrent exchanges gets tokens.
+	if _, err := s.TokenRepository.ConsumeAuthorizationCode(ctx, request.Code); err != nil {
+		if hasErrorType(err, apperrors.Authorization) {
+			return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The authorization code is invalid or expired")
+		}
+
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to exchange the authorization code")
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, authorizationCode.UserID)
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The user of the authorization code no longer exists")
+	}
+
+	session := &model.Session{DeviceName: client.Name}
+
+	if request.Session != nil {
+		session.UserAgent = request.Session.UserAgent
+		session.IP = request.Session.IP
+	}
+
+	session.ClientID = client.ClientID
+	session.Scope = authorizationCode.Scope
+	session.Nonce = authorizationCode.Nonce
+	session.AMR = authorizationCode.AMR
+	session.CreatedAt = authorizationCode.AuthTime
+
+	tokens, err := s.TokenService.NewPairFromUser(ctx, user, nil, session)
+
+	// The sign in the user authorized the client in may have reached the maximum session age.
+	if hasErrorType(err, apperrors.Authorization) {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The sign in of the authorization code has expired")
+	}
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to issue tokens")
+	}
+
+	return tokenResponse(tokens, authorizationCode.Scope), nil
+}
+
+// refreshTokens rotates a refresh token which was issued to the client.
+func (s *oauthService) refreshTokens(ctx context.Context, request *model.TokenRequest) (*model.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.RefreshToken == "" {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidRequest, "The refresh_token parameter is required")
+	}
+
+	refreshToken, err := s.TokenService.ValidateRefreshToken(request.RefreshToken)
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The refresh token is invalid")
+	}
+
+	// A token which is no longer stored may be replayed after rotation,
+	// which NewPairFromUser detects, so it is passed on below.
+	session, err := s.TokenRepository.GetRefreshToken(ctx, refreshToken.UserID.String(), refreshToken.ID.String())
+
+	if err != nil && !hasErrorType(err, apperrors.Authorization) {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to refresh the tokens")
+	}
+
+	scope := ""
+
+	if err == nil {
+		clientID := ""
+
+		// Tokens stored before sessions were introduced were issued by the sign in.
+		if session != nil {
+			clientID, scope = session.ClientID, session.Scope
+		}
+
+		if clientID != client.ClientID {
+			log.Printf("Refresh token of clientID: %v presented by clientID: %v\n", clientID, client.ClientID)
+			return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The refresh token was issued to another client")
+		}
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, refreshToken.UserID)
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The user of the refresh token no longer exists")
+	}
+
+	tokens, err := s.TokenService.NewPairFromUser(ctx, user, refreshToken, request.Session)
+
+	if hasErrorType(err, apperrors.Authorization) {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidGrant, "The refresh token is invalid")
+	}
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to refresh the tokens")
+	}
+
+	return tokenResponse(tokens, scope), nil
+}
+
+// issueServiceToken issues an access token to a machine client acting on its own behalf.
+// The client gets the requested scopes, or all of its scopes if none were requested.
+func (s *oauthService) issueServiceToken(ctx context.Context, request *model.TokenRequest) (*model.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.AllowsGrant(model.ClientCredentialsGrant) {
+		return nil, apperrors.NewOAuthError(apperrors.UnauthorizedClient, "The client may not use the client_credentials grant")
+	}
+
+	scopes := strings.Fields(request.Scope)
+
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	if !containsAll(client.Scopes, scopes) {
+		return nil, apperrors.NewOAuthError(apperrors.InvalidScope, "The scope is not allowed for the client")
+	}
+
+	accessToken, err := s.TokenService.NewServiceToken(ctx, client.ClientID, scopes)
+
+	if err != nil {
+		return nil, apperrors.NewOAuthError(apperrors.ServerError, "Unable to issue tokens")
+	}
+
+	return &model.TokenResponse{
+		AccessToken: accessToken.SignedString,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(accessToken.ExpiresIn / time.Second),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+func (s *oauthService) redirectToClient(redirectURI string, parameters map[string]string) (string, error) {
+	location, err := redirectURL(redirectURI, parameters)
+
+	if err != nil {
+		log.Printf("Unable to build redirect URL from: %v. Error: %v\n", redirectURI, err)
+		return "", apperrors.NewOAuthError(apperrors.ServerError, "Unable to redirect to the client")
+	}
+
+	return location, nil
+}
+
+// tokenResponse describes the tokens in the format of the token endpoint.
+// The ID token is the access token of the client, and is returned as the
+// id_token too if the openid scope was allowed. Its audience is the client,
+// so only the client can use it, like at the userinfo endpoint, and it
+// can't call the account API.
+func tokenResponse(tokens *model.TokenPair, scope string) *model.TokenResponse {
+	claims := unverifiedIDTokenClaims(tokens.IDToken.SignedString)
+
+	response := &model.TokenResponse{
+		AccessToken:  tokens.IDToken.SignedString,
+		TokenType:    "Bearer",
+		ExpiresIn:    claims.ExpiresAt - time.Now().Unix(),
+		RefreshToken: tokens.RefreshToken.SignedString,
+		Scope:        scope,
+	}
+
+	if contains(strings.Fields(scope), model.ScopeOpenID) {
+		response.IDToken = tokens.IDToken.SignedString
+	}
+
+	return response
+}
@@ -0,0 +1,939 @@
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestOAuthService(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+	secret := "anothersomerandomtestsecret"
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+	familyID, _ := uuid.NewRandom()
+
+	idToken, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, 15*60)
+	clientIDToken, _ := generateIDToken(user, &model.Session{ClientID: "gateway", CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, 15*60)
+	refreshToken, _ := generateRefreshToken(userID, familyID, time.Now(), secret, 3*24*60*60)
+
+	gatewaySecretHash, _ := HashClientSecret("gatewaysecret")
+	grafanaSecretHash, _ := HashClientSecret("grafanasecret")
+
+	gateway := &model.OAuthClient{
+		ClientID:   "gateway",
+		SecretHash: gatewaySecretHash,
+		Scopes:     []string{model.ScopeIntrospect},
+	}
+	grafana := &model.OAuthClient{
+		ClientID:   "grafana",
+		SecretHash: grafanaSecretHash,
+	}
+
+	newService := func(mockTokenRepository *mocks.MockTokenRepository) model.OAuthService {
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+			KeyRing:         keyRing,
+			RefreshSecrets:  []string{secret},
+		})
+
+		mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+		mockOAuthClientRepository.On("FindByID", mock.Anything, "gateway").Return(gateway, nil)
+		mockOAuthClientRepository.On("FindByID", mock.Anything, "spa").Return(&model.OAuthClient{
+			ClientID: "spa",
+		}, nil)
+		mockOAuthClientRepository.On("FindByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, apperrors.NewNotFound("clientID", "unknown"))
+
+		return NewOAuthService(&OAuthServiceConfig{
+			TokenService:          tokenService,
+			TokenRepository:       mockTokenRepository,
+			OAuthClientRepository: mockOAuthClientRepository,
+		})
+	}
+
+	t.Run("Authenticates a known client", func(t *testing.T) {
+		oauthService := newService(new(mocks.MockTokenRepository))
+
+		client, err := oauthService.AuthenticateClient(context.Background(), "gateway", "gatewaysecret")
+		assert.NoError(t, err)
+		assert.Equal(t, gateway, client)
+	})
+
+	t.Run("Rejects invalid client credentials", func(t *testing.T) {
+		oauthService := newService(new(mocks.MockTokenRepository))
+
+		for _, credentials := range [][2]string{
+			{"gateway", "wrongsecret"},
+			{"unknown", "gatewaysecret"},
+			{"spa", ""},
+			{"", ""},
+		} {
+			_, err := oauthService.AuthenticateClient(context.Background(), credentials[0], credentials[1])
+
+			oauthErr, ok := err.(*apperrors.OAuthError)
+			assert.True(t, ok)
+			assert.Equal(t, apperrors.InvalidClient, oauthErr.Code)
+		}
+	})
+
+	t.Run("Introspects an access token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+		oauthService := newService(mockTokenRepository)
+
+		introspection, err := oauthService.Introspect(context.Background(), gateway, idToken, "")
+		assert.NoError(t, err)
+
+		assert.True(t, introspection.Active)
+		assert.Equal(t, model.AccessTokenType, introspection.TokenType)
+		assert.Equal(t, userID.String(), introspection.Subject)
+		assert.Equal(t, user.Email, introspection.Username)
+		assert.NotZero(t, introspection.ExpiresAt)
+		assert.NotEmpty(t, introspection.TokenID)
+	})
+
+	t.Run("Revoked access token is inactive", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(true, nil)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, mock.Anything, mock.Anything).Return(nil, apperrors.NewAuthorization("Invalid refresh token"))
+		oauthService := newService(mockTokenRepository)
+
+		introspection, err := oauthService.Introspect(context.Background(), gateway, idToken, model.AccessTokenType)
+		assert.NoError(t, err)
+		assert.False(t, introspection.Active)
+	})
+
+	t.Run("Introspects a stored refresh token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(&model.Session{ID: familyID}, nil)
+		oauthService := newService(mockTokenRepository)
+
+		introspection, err := oauthService.Introspect(context.Background(), gateway, refreshToken.SignedString, model.RefreshTokenType)
+		assert.NoError(t, err)
+
+		assert.True(t, introspection.Active)
+		assert.Equal(t, model.RefreshTokenType, introspection.TokenType)
+		assert.Equal(t, userID.String(), introspection.Subject)
+		assert.Equal(t, refreshToken.ID.String(), introspection.TokenID)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Rotated refresh token is inactive", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(nil, apperrors.NewAuthorization("Invalid refresh token"))
+		oauthService := newService(mockTokenRepository)
+
+		introspection, err := oauthService.Introspect(context.Background(), gateway, refreshToken.SignedString, model.RefreshTokenType)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.TokenIntrospection{Active: false}, introspection)
+	})
+
+	t.Run("Invalid token is inactive", func(t *testing.T) {
+		oauthService := newService(new(mocks.MockTokenRepository))
+
+		introspection, err := oauthService.Introspect(context.Background(), gateway, "notatoken", "")
+		assert.NoError(t, err)
+		assert.False(t, introspection.Active)
+	})
+
+	t.Run("Client which is not a resource server can't introspect", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		oauthService := newService(mockTokenRepository)
+
+		introspection, err := oauthService.Introspect(context.Background(), grafana, idToken, "")
+		assert.Nil(t, introspection)
+
+		oauthErr, ok := err.(*apperrors.OAuthError)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.UnauthorizedClient, oauthErr.Code)
+		mockTokenRepository.AssertNotCalled(t, "IsIDTokenRevoked")
+	})
+
+	t.Run("Revokes the session of a refresh token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(&model.Session{ID: familyID, ClientID: "gateway"}, nil)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), familyID.String()).Return(int64(1), nil)
+		oauthService := newService(mockTokenRepository)
+
+		err := oauthService.Revoke(context.Background(), gateway, refreshToken.SignedString, model.RefreshTokenType)
+		assert.NoError(t, err)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Refresh token of another client", func(t *testing.T) {
+		for _, session := range []*model.Session{
+			{ID: familyID, ClientID: "grafana"},
+			{ID: familyID}, // Our own sign in.
+			nil,            // Stored before sessions were introduced.
+		} {
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(session, nil)
+			oauthService := newService(mockTokenRepository)
+
+			err := oauthService.Revoke(context.Background(), gateway, refreshToken.SignedString, model.RefreshTokenType)
+
+			oauthErr, ok := err.(*apperrors.OAuthError)
+			assert.True(t, ok)
+			assert.Equal(t, apperrors.UnauthorizedClient, oauthErr.Code)
+			mockTokenRepository.AssertNotCalled(t, "DeleteRefreshTokenFamily")
+		}
+	})
+
+	t.Run("Revoking a rotated refresh token succeeds", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(nil, apperrors.NewAuthorization("Invalid refresh token"))
+		oauthService := newService(mockTokenRepository)
+
+		err := oauthService.Revoke(context.Background(), gateway, refreshToken.SignedString, model.RefreshTokenType)
+		assert.NoError(t, err)
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshTokenFamily")
+	})
+
+	t.Run("Revoking an invalid token succeeds", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		oauthService := newService(mockTokenRepository)
+
+		err := oauthService.Revoke(context.Background(), gateway, "notatoken", "")
+		assert.NoError(t, err)
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshTokenFamily")
+	})
+
+	t.Run("Revokes an access token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+		mockTokenRepository.On("RevokeIDToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+		oauthService := newService(mockTokenRepository)
+
+		err := oauthService.Revoke(context.Background(), gateway, clientIDToken, model.AccessTokenType)
+		assert.NoError(t, err)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Access token of another client", func(t *testing.T) {
+		for _, request := range []struct {
+			client *model.OAuthClient
+			token  string
+		}{
+			{client: grafana, token: clientIDToken},
+			{client: gateway, token: idToken}, // Tokens of our own sign in belong to no client.
+		} {
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+			oauthService := newService(mockTokenRepository)
+
+			err := oauthService.Revoke(context.Background(), request.client, request.token, model.AccessTokenType)
+
+			oauthErr, ok := err.(*apperrors.OAuthError)
+			assert.True(t, ok)
+			assert.Equal(t, apperrors.UnauthorizedClient, oauthErr.Code)
+			mockTokenRepository.AssertNotCalled(t, "RevokeIDToken")
+		}
+	})
+
+	t.Run("Repository failure", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(&model.Session{ID: familyID, ClientID: "gateway"}, nil)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), familyID.String()).Return(int64(0), apperrors.NewInternal())
+		oauthService := newService(mockTokenRepository)
+
+		err := oauthService.Revoke(context.Background(), gateway, refreshToken.SignedString, "")
+
+		oauthErr, ok := err.(*apperrors.OAuthError)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.ServerError, oauthErr.Code)
+	})
+}
+
+func TestOAuthAuthorizationCode(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+	secret := "anothersomerandomtestsecret"
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	secretHash, _ := HashClientSecret("grafanasecret")
+
+	thirdPartyClient := &model.OAuthClient{
+		ClientID:     "grafana",
+		SecretHash:   secretHash,
+		Name:         "Grafana",
+		RedirectURIs: []string{"https://grafana.example.com/login/generic_oauth"},
+		Scopes:       []string{"profile", "email"},
+	}
+	firstPartyClient := &model.OAuthClient{
+		ClientID:     "spa",
+		Name:         "Base Go",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		Scopes:       []string{"profile"},
+		FirstParty:   true,
+	}
+
+	codeVerifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	newRequest := func(client *model.OAuthClient) *model.AuthorizationRequest {
+		return &model.AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            client.ClientID,
+			RedirectURI:         client.RedirectURIs[0],
+			Scope:               "profile",
+			State:               "somestate",
+			CodeChallenge:       codeChallenge,
+			CodeChallengeMethod: "S256",
+		}
+	}
+
+	newService := func(mockTokenRepository *mocks.MockTokenRepository, mockOAuthClientRepository *mocks.MockOAuthClientRepository) model.OAuthService {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+
+		mockOAuthClientRepository.On("FindByID", mock.Anything, thirdPartyClient.ClientID).Return(thirdPartyClient, nil)
+		mockOAuthClientRepository.On("FindByID", mock.Anything, firstPartyClient.ClientID).Return(firstPartyClient, nil)
+		mockOAuthClientRepository.On("FindByID", mock.Anything, mock.AnythingOfType("string")).Return(nil, apperrors.NewNotFound("clientID", "unknown"))
+
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			KeyRing:             keyRing,
+			RefreshSecrets:      []string{secret},
+			IDExpirationSecrets: 15 * 60,
+		})
+
+		return NewOAuthService(&OAuthServiceConfig{
+			TokenService:          tokenService,
+			TokenRepository:       mockTokenRepository,
+			UserRepository:        mockUserRepository,
+			OAuthClientRepository: mockOAuthClientRepository,
+		})
+	}
+
+	assertOAuthError := func(t *testing.T, code apperrors.OAuthErrorCode, err error) {
+		oauthErr, ok := err.(*apperrors.OAuthError)
+		assert.True(t, ok)
+
+		if ok {
+			assert.Equal(t, code, oauthErr.Code)
+		}
+	}
+
+	t.Run("Consent is required from a third party client", func(t *testing.T) {
+		mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+		mockOAuthClientRepository.On("FindConsent", mock.Anything, userID, thirdPartyClient.ClientID).Return(nil, apperrors.NewNotFound("consent", thirdPartyClient.ClientID))
+		oauthService := newService(new(mocks.MockTokenRepository), mockOAuthClientRepository)
+
+		prompt, err := oauthService.PrepareAuthorization(context.Background(), userID, newRequest(thirdPartyClient))
+		assert.NoError(t, err)
+		assert.Equal(t, thirdPartyClient, prompt.Client)
+		assert.Equal(t, []string{"profile"}, prompt.Scopes)
+		assert.True(t, prompt.ConsentRequired)
+	})
+
+	t.Run("Consent is remembered", func(t *testing.T) {
+		mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+		mockOAuthClientRepository.On("FindConsent", mock.Anything, userID, thirdPartyClient.ClientID).Return(&model.OAuthConsent{
+			UserID:   userID,
+			ClientID: thirdPartyClient.ClientID,
+			Scopes:   []string{"profile"},
+		}, nil)
+		oauthService := newService(new(mocks.MockTokenRepository), mockOAuthClientRepository)
+
+		prompt, err := oauthService.PrepareAuthorization(context.Background(), userID, newRequest(thirdPartyClient))
+		assert.NoError(t, err)
+		assert.False(t, prompt.ConsentRequired)
+
+		// A new scope needs consent again.
+		request := newRequest(thirdPartyClient)
+		request.Scope = "profile email"
+
+		prompt, err = oauthService.PrepareAuthorization(context.Background(), userID, request)
+		assert.NoError(t, err)
+		assert.True(t, prompt.ConsentRequired)
+	})
+
+	t.Run("First party client needs no consent", func(t *testing.T) {
+		mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+		oauthService := newService(new(mocks.MockTokenRepository), mockOAuthClientRepository)
+
+		prompt, err := oauthService.PrepareAuthorization(context.Background(), userID, newRequest(firstPartyClient))
+		assert.NoError(t, err)
+		assert.False(t, prompt.ConsentRequired)
+		mockOAuthClientRepository.AssertNotCalled(t, "FindConsent")
+	})
+
+	t.Run("Invalid authorization requests", func(t *testing.T) {
+		oauthService := newService(new(mocks.MockTokenRepository), new(mocks.MockOAuthClientRepository))
+
+		unknownClient := newRequest(firstPartyClient)
+		unknownClient.ClientID = "unknown"
+
+		unregisteredRedirect := newRequest(firstPartyClient)
+		unregisteredRedirect.RedirectURI = "https://evil.com/callback"
+
+		implicitFlow := newRequest(firstPartyClient)
+		implicitFlow.ResponseType = "token"
+
+		missingChallenge := newRequest(firstPartyClient)
+		missingChallenge.CodeChallenge = ""
+
+		plainChallenge := newRequest(firstPartyClient)
+		plainChallenge.CodeChallengeMethod = "plain"
+
+		disallowedScope := newRequest(firstPartyClient)
+		disallowedScope.Scope = "profile admin"
+
+		for code, requests := range map[apperrors.OAuthErrorCode][]*model.AuthorizationRequest{
+			apperrors.InvalidRequest:          {unknownClient, unregisteredRedirect, missingChallenge, plainChallenge},
+			apperrors.UnsupportedResponseType: {implicitFlow},
+			apperrors.InvalidScope:            {disallowedScope},
+		} {
+			for _, request := range requests {
+				_, err := oauthService.PrepareAuthorization(context.Background(), userID, request)
+				assertOAuthError(t, code, err)
+
+				_, err = oauthService.Authorize(context.Background(), &model.User{UserID: userID}, request, true)
+				assertOAuthError(t, code, err)
+			}
+		}
+	})
+
+	t.Run("Denied authorization redirects with an error", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		location, err := oauthService.Authorize(context.Background(), &model.User{UserID: userID}, newRequest(thirdPartyClient), false)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://grafana.example.com/login/generic_oauth?error=access_denied&state=somestate", location)
+		mockTokenRepository.AssertNotCalled(t, "SetAuthorizationCode")
+	})
+
+	t.Run("Approved authorization stores the consent and a code", func(t *testing.T) {
+		signedInUser := &model.User{
+			UserID:   userID,
+			AMR:      []string{model.AMRPassword, model.AMROTP},
+			AuthTime: time.Now().Add(-time.Hour),
+		}
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+		mockOAuthClientRepository.On("FindConsent", mock.Anything, userID, thirdPartyClient.ClientID).Return(&model.OAuthConsent{
+			Scopes: []string{"email"},
+		}, nil)
+		consent := &model.OAuthConsent{
+			UserID:   userID,
+			ClientID: thirdPartyClient.ClientID,
+			Scopes:   []string{"email", "profile"},
+		}
+		mockOAuthClientRepository.On("UpsertConsent", mock.Anything, consent).Return(nil)
+
+		var code string
+		mockTokenRepository.On("SetAuthorizationCode", mock.Anything, mock.AnythingOfType("string"), &model.AuthorizationCode{
+			ClientID:      thirdPartyClient.ClientID,
+			RedirectURI:   thirdPartyClient.RedirectURIs[0],
+			UserID:        userID,
+			Scope:         "profile",
+			CodeChallenge: codeChallenge,
+			AMR:           signedInUser.AMR,
+			AuthTime:      signedInUser.AuthTime,
+		}, authorizationCodeExpiration).Run(func(args mock.Arguments) {
+			code = args.String(1)
+		}).Return(nil)
+
+		oauthService := newService(mockTokenRepository, mockOAuthClientRepository)
+
+		location, err := oauthService.Authorize(context.Background(), signedInUser, newRequest(thirdPartyClient), true)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://grafana.example.com/login/generic_oauth?code="+code+"&state=somestate", location)
+		assert.NotEmpty(t, code)
+		mockTokenRepository.AssertExpectations(t)
+		mockOAuthClientRepository.AssertCalled(t, "UpsertConsent", mock.Anything, consent)
+	})
+
+	authorizationCode := &model.AuthorizationCode{
+		ClientID:      thirdPartyClient.ClientID,
+		RedirectURI:   thirdPartyClient.RedirectURIs[0],
+		UserID:        userID,
+		Scope:         "profile",
+		CodeChallenge: codeChallenge,
+	}
+
+	newTokenRequest := func() *model.TokenRequest {
+		return &model.TokenRequest{
+			GrantType:    model.AuthorizationCodeGrant,
+			ClientID:     thirdPartyClient.ClientID,
+			ClientSecret: "grafanasecret",
+			Code:         "somecode",
+			RedirectURI:  thirdPartyClient.RedirectURIs[0],
+			CodeVerifier: codeVerifier,
+			Session: &model.Session{
+				UserAgent: "Grafana",
+				IP:        "10.0.0.1",
+			},
+		}
+	}
+
+	t.Run("Exchanges a code for tokens bound to the client", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(authorizationCode, nil)
+		mockTokenRepository.On("ConsumeAuthorizationCode", mock.Anything, "somecode").Return(authorizationCode, nil)
+
+		var storedSession *model.Session
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				storedSession = args.Get(3).(*model.Session)
+			}).Return(nil)
+
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		response, err := oauthService.Token(context.Background(), newTokenRequest())
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+		assert.Equal(t, "Bearer", response.TokenType)
+		assert.Equal(t, "profile", response.Scope)
+		assert.InDelta(t, 15*60, response.ExpiresIn, 5)
+
+		assert.Equal(t, thirdPartyClient.ClientID, storedSession.ClientID)
+		assert.Equal(t, "profile", storedSession.Scope)
+		assert.Equal(t, "Grafana", storedSession.DeviceName)
+		assert.Equal(t, "10.0.0.1", storedSession.IP)
+	})
+
+	t.Run("Exchanges an openid code for an ID token", func(t *testing.T) {
+		openIDCode := *authorizationCode
+		openIDCode.Scope = "openid email"
+		openIDCode.Nonce = "somenonce"
+		openIDCode.AMR = []string{model.AMRPassword, model.AMROTP}
+		openIDCode.AuthTime = time.Now().Add(-time.Hour)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(&openIDCode, nil)
+		mockTokenRepository.On("ConsumeAuthorizationCode", mock.Anything, "somecode").Return(&openIDCode, nil)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).Return(nil)
+
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		response, err := oauthService.Token(context.Background(), newTokenRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, response.AccessToken, response.IDToken)
+
+		claims := unverifiedIDTokenClaims(response.IDToken)
+		assert.Equal(t, "somenonce", claims.Nonce)
+		assert.Equal(t, thirdPartyClient.ClientID, claims.AuthorizedParty)
+		assert.Equal(t, user.Email, claims.Email)
+
+		// The client learns how and when the user signed in, not when the code was exchanged.
+		assert.Equal(t, openIDCode.AMR, claims.AMR)
+		assert.Equal(t, openIDCode.AuthTime.Unix(), claims.AuthTime)
+	})
+
+	t.Run("Invalid code exchanges", func(t *testing.T) {
+		wrongVerifier := newTokenRequest()
+		wrongVerifier.CodeVerifier = "Rs5IvMuDsZJIbEF5aHWQx3-k8VNb7RSS6hYMWjUYn5Q"
+
+		wrongRedirect := newTokenRequest()
+		wrongRedirect.RedirectURI = "https://grafana.example.com/other"
+
+		for _, request := range []*model.TokenRequest{wrongVerifier, wrongRedirect} {
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(authorizationCode, nil)
+			oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+			_, err := oauthService.Token(context.Background(), request)
+			assertOAuthError(t, apperrors.InvalidGrant, err)
+			mockTokenRepository.AssertNotCalled(t, "ConsumeAuthorizationCode", mock.Anything, mock.Anything)
+			mockTokenRepository.AssertNotCalled(t, "SetRefreshToken")
+		}
+	})
+
+	t.Run("Code issued to another client", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(authorizationCode, nil)
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		request := newTokenRequest()
+		request.ClientID = firstPartyClient.ClientID
+		request.ClientSecret = ""
+		request.RedirectURI = firstPartyClient.RedirectURIs[0]
+
+		_, err := oauthService.Token(context.Background(), request)
+		assertOAuthError(t, apperrors.InvalidGrant, err)
+
+		// The code still works for the client it was issued to.
+		mockTokenRepository.AssertNotCalled(t, "ConsumeAuthorizationCode", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Used or expired code", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(nil, apperrors.NewAuthorization("Invalid authorization code"))
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		_, err := oauthService.Token(context.Background(), newTokenRequest())
+		assertOAuthError(t, apperrors.InvalidGrant, err)
+	})
+
+	t.Run("Code exchanged concurrently", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetAuthorizationCode", mock.Anything, "somecode").Return(authorizationCode, nil)
+		mockTokenRepository.On("ConsumeAuthorizationCode", mock.Anything, "somecode").Return(nil, apperrors.NewAuthorization("Invalid authorization code"))
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		_, err := oauthService.Token(context.Background(), newTokenRequest())
+		assertOAuthError(t, apperrors.InvalidGrant, err)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken")
+	})
+
+	t.Run("Client authentication", func(t *testing.T) {
+		wrongSecret := newTokenRequest()
+		wrongSecret.ClientSecret = "wrongsecret"
+
+		publicWithSecret := newTokenRequest()
+		publicWithSecret.ClientID = firstPartyClient.ClientID
+
+		for _, request := range []*model.TokenRequest{wrongSecret, publicWithSecret} {
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+			_, err := oauthService.Token(context.Background(), request)
+			assertOAuthError(t, apperrors.InvalidClient, err)
+			mockTokenRepository.AssertNotCalled(t, "GetAuthorizationCode")
+		}
+	})
+
+	t.Run("Unsupported grant type", func(t *testing.T) {
+		oauthService := newService(new(mocks.MockTokenRepository), new(mocks.MockOAuthClientRepository))
+
+		request := newTokenRequest()
+		request.GrantType = "password"
+
+		_, err := oauthService.Token(context.Background(), request)
+		assertOAuthError(t, apperrors.UnsupportedGrantType, err)
+	})
+
+	familyID, _ := uuid.NewRandom()
+	refreshToken, _ := generateRefreshToken(userID, familyID, time.Now(), secret, 3*24*60*60)
+
+	newRefreshRequest := func() *model.TokenRequest {
+		return &model.TokenRequest{
+			GrantType:    model.RefreshTokenGrant,
+			ClientID:     thirdPartyClient.ClientID,
+			ClientSecret: "grafanasecret",
+			RefreshToken: refreshToken.SignedString,
+		}
+	}
+
+	t.Run("Refreshes tokens of the client", func(t *testing.T) {
+		session := &model.Session{
+			ID:        familyID,
+			CreatedAt: time.Now().Add(-time.Hour),
+			ClientID:  thirdPartyClient.ClientID,
+			Scope:     "profile",
+		}
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(session, nil)
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(session, nil)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).Return(nil)
+		oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+		response, err := oauthService.Token(context.Background(), newRefreshRequest())
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEqual(t, refreshToken.SignedString, response.RefreshToken)
+		assert.Equal(t, "profile", response.Scope)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Refresh token of another client", func(t *testing.T) {
+		for _, session := range []*model.Session{
+			{ID: familyID, ClientID: "another"},
+			{ID: familyID}, // Issued by the sign in.
+		} {
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			mockTokenRepository.On("GetRefreshToken", mock.Anything, userID.String(), refreshToken.ID.String()).Return(session, nil)
+			oauthService := newService(mockTokenRepository, new(mocks.MockOAuthClientRepository))
+
+			_, err := oauthService.Token(context.Background(), newRefreshRequest())
+			assertOAuthError(t, apperrors.InvalidGrant, err)
+			mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
+		}
+	})
+}
+
+func TestOpenIDConnect(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+	issuer := "http://localhost:8080/api/account"
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID:   userID,
+		Email:    "kostya@kostya.com",
+		Username: "Kostya Kostyan",
+		Website:  "https://kostya.com",
+	}
+	settings := &idTokenSettings{Issuer: issuer}
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+
+	oauthService := NewOAuthService(&OAuthServiceConfig{
+		TokenService: NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+			KeyRing:         keyRing,
+			Issuer:          issuer,
+		}),
+		TokenRepository:       mockTokenRepository,
+		UserRepository:        mockUserRepository,
+		Issuer:                issuer,
+		AuthorizationEndpoint: "http://localhost:8080/authorize",
+	})
+
+	t.Run("UserInfo of our own sign in", func(t *testing.T) {
+		idToken, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), settings, 15*60)
+
+		userInfo, err := oauthService.UserInfo(context.Background(), idToken)
+		assert.NoError(t, err)
+		assert.Equal(t, userID.String(), userInfo.Subject)
+		assert.Equal(t, user.Email, userInfo.Email)
+		assert.NotNil(t, userInfo.EmailVerified)
+		assert.Equal(t, user.Username, userInfo.Name)
+		assert.Equal(t, user.Website, userInfo.Website)
+	})
+
+	t.Run("UserInfo of a client only holds the allowed scopes", func(t *testing.T) {
+		session := &model.Session{
+			CreatedAt: time.Now(),
+			ClientID:  "grafana",
+			Scope:     "openid profile",
+		}
+		idToken, _ := generateIDToken(user, session, keyRing.signingKey(), settings, 15*60)
+
+		userInfo, err := oauthService.UserInfo(context.Background(), idToken)
+		assert.NoError(t, err)
+		assert.Equal(t, userID.String(), userInfo.Subject)
+		assert.Equal(t, user.Username, userInfo.Name)
+		assert.Empty(t, userInfo.Email)
+		assert.Nil(t, userInfo.EmailVerified)
+	})
+
+	t.Run("UserInfo of an invalid token", func(t *testing.T) {
+		_, err := oauthService.UserInfo(context.Background(), "invalidtoken")
+
+		oauthErr, ok := err.(*apperrors.OAuthError)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.InvalidToken, oauthErr.Code)
+	})
+
+	t.Run("Discovery", func(t *testing.T) {
+		configuration := oauthService.Discovery()
+
+		assert.Equal(t, issuer, configuration.Issuer)
+		assert.Equal(t, "http://localhost:8080/authorize", configuration.AuthorizationEndpoint)
+		assert.Equal(t, issuer+"/oauth/token", configuration.TokenEndpoint)
+		assert.Equal(t, issuer+"/userinfo", configuration.UserInfoEndpoint)
+		assert.Equal(t, issuer+"/.well-known/jwks.json", configuration.JWKSURI)
+		assert.Equal(t, []string{jwa.RS256}, configuration.IDTokenSigningAlgValuesSupported)
+		assert.Contains(t, configuration.ScopesSupported, model.ScopeOpenID)
+		assert.Contains(t, configuration.ClaimsSupported, "amr")
+	})
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	machineSecretHash, _ := HashClientSecret("billingsecret")
+	signInSecretHash, _ := HashClientSecret("grafanasecret")
+
+	machineClient := &model.OAuthClient{
+		ClientID:   "billing",
+		SecretHash: machineSecretHash,
+		Name:       "Billing",
+		Scopes:     []string{"users:read", "users:write"},
+		GrantTypes: []string{model.ClientCredentialsGrant},
+	}
+	signInClient := &model.OAuthClient{
+		ClientID:     "grafana",
+		SecretHash:   signInSecretHash,
+		RedirectURIs: []string{"https://grafana.example.com/login/generic_oauth"},
+		Scopes:       []string{"profile"},
+	}
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+	mockOAuthClientRepository := new(mocks.MockOAuthClientRepository)
+	mockOAuthClientRepository.On("FindByID", mock.Anything, machineClient.ClientID).Return(machineClient, nil)
+	mockOAuthClientRepository.On("FindByID", mock.Anything, signInClient.ClientID).Return(signInClient, nil)
+
+	tokenService := NewTokenService(&TokenServiceConfig{
+		TokenRepository:     mockTokenRepository,
+		KeyRing:             keyRing,
+		IDExpirationSecrets: 15 * 60,
+		Issuer:              "https://accounts.test",
+		Audience:            "web",
+	})
+
+	oauthService := NewOAuthService(&OAuthServiceConfig{
+		TokenService:          tokenService,
+		TokenRepository:       mockTokenRepository,
+		OAuthClientRepository: mockOAuthClientRepository,
+	})
+
+	newRequest := func(scope string) *model.TokenRequest {
+		return &model.TokenRequest{
+			GrantType:    model.ClientCredentialsGrant,
+			ClientID:     machineClient.ClientID,
+			ClientSecret: "billingsecret",
+			Scope:        scope,
+		}
+	}
+
+	assertOAuthError := func(t *testing.T, code apperrors.OAuthErrorCode, err error) {
+		oauthErr, ok := err.(*apperrors.OAuthError)
+		assert.True(t, ok)
+
+		if ok {
+			assert.Equal(t, code, oauthErr.Code)
+		}
+	}
+
+	t.Run("Issues an access token with the requested scopes", func(t *testing.T) {
+		response, err := oauthService.Token(context.Background(), newRequest("users:read"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", response.TokenType)
+		assert.Equal(t, "users:read", response.Scope)
+		assert.Equal(t, int64(15*60), response.ExpiresIn)
+		assert.Empty(t, response.RefreshToken)
+
+		principal, err := tokenService.ValidateServiceToken(context.Background(), response.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, machineClient.ClientID, principal.ClientID)
+		assert.Equal(t, []string{"users:read"}, principal.Scopes)
+
+		claims := unverifiedIDTokenClaims(response.AccessToken)
+		assert.Equal(t, machineClient.ClientID, claims.Subject)
+
+		// The token of a client is not the ID token of a user, nor the other way around.
+		_, err = tokenService.ValidateIDToken(context.Background(), response.AccessToken)
+		assert.Error(t, err)
+
+		user := &model.User{UserID: uuid.New()}
+		idToken, _ := generateIDToken(user, &model.Session{}, keyRing.signingKey(), &idTokenSettings{Issuer: "https://accounts.test", Audience: "web"}, 15*60)
+
+		_, err = tokenService.ValidateServiceToken(context.Background(), idToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("Access token without a jti", func(t *testing.T) {
+		unixTime := time.Now().Unix()
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, &serviceTokenCustomClaims{
+			ClientID: machineClient.ClientID,
+			Scope:    "users:read",
+			Audience: model.Audience{"web"},
+			StandardClaims: jwt.StandardClaims{
+				Subject:   machineClient.ClientID,
+				Issuer:    "https://accounts.test",
+				IssuedAt:  unixTime,
+				ExpiresAt: unixTime + 15*60,
+			},
+		})
+		token.Header["kid"] = keyRing.signingKey().ID
+		token.Header["typ"] = serviceTokenType
+		accessToken, _ := token.SignedString(keyRing.signingKey().PrivateKey)
+
+		_, err := tokenService.ValidateServiceToken(context.Background(), accessToken)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "IsIDTokenRevoked", mock.Anything, "")
+	})
+
+	t.Run("Defaults to all of the client's scopes", func(t *testing.T) {
+		response, err := oauthService.Token(context.Background(), newRequest(""))
+		assert.NoError(t, err)
+		assert.Equal(t, "users:read users:write", response.Scope)
+	})
+
+	t.Run("Introspects an access token of a client", func(t *testing.T) {
+		response, _ := oauthService.Token(context.Background(), newRequest("users:read"))
+
+		resourceServer := &model.OAuthClient{
+			ClientID: "gateway",
+			Scopes:   []string{model.ScopeIntrospect},
+		}
+
+		introspection, err := oauthService.Introspect(context.Background(), resourceServer, response.AccessToken, "")
+		assert.NoError(t, err)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, machineClient.ClientID, introspection.Subject)
+		assert.Equal(t, machineClient.ClientID, introspection.ClientID)
+		assert.Equal(t, "users:read", introspection.Scope)
+	})
+
+	t.Run("Revokes its own access token", func(t *testing.T) {
+		mockTokenRepository.On("RevokeIDToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil).Once()
+
+		response, _ := oauthService.Token(context.Background(), newRequest("users:read"))
+
+		err := oauthService.Revoke(context.Background(), signInClient, response.AccessToken, "")
+		assertOAuthError(t, apperrors.UnauthorizedClient, err)
+
+		err = oauthService.Revoke(context.Background(), machineClient, response.AccessToken, "")
+		assert.NoError(t, err)
+		mockTokenRepository.AssertNumberOfCalls(t, "RevokeIDToken", 1)
+	})
+
+	t.Run("Scope not allowed for the client", func(t *testing.T) {
+		_, err := oauthService.Token(context.Background(), newRequest("users:delete"))
+		assertOAuthError(t, apperrors.InvalidScope, err)
+	})
+
+	t.Run("Client which signs users in", func(t *testing.T) {
+		_, err := oauthService.Token(context.Background(), &model.TokenRequest{
+			GrantType:    model.ClientCredentialsGrant,
+			ClientID:     signInClient.ClientID,
+			ClientSecret: "grafanasecret",
+		})
+		assertOAuthError(t, apperrors.UnauthorizedClient, err)
+	})
+
+	t.Run("Machine client can't sign users in", func(t *testing.T) {
+		_, err := oauthService.PrepareAuthorization(context.Background(), uuid.New(), &model.AuthorizationRequest{
+			ResponseType: "code",
+			ClientID:     machineClient.ClientID,
+		})
+		assertOAuthError(t, apperrors.UnauthorizedClient, err)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		request := newRequest("")
+		request.ClientSecret = "wrongsecret"
+
+		_, err := oauthService.Token(context.Background(), request)
+		assertOAuthError(t, apperrors.InvalidClient, err)
+	})
+}
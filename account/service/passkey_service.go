@@ -0,0 +1,391 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/webauthn"
+)
+
+// defaultPasskeyName names passkeys the user didn't name.
+const defaultPasskeyName = "Passkey"
+
+// passkeyService acts as a struct for injecting implementations
+// of UserRepository, PasskeyRepository and TokenRepository
+// for use in service methods.
+type passkeyService struct {
+	UserRepository      model.UserRepository
+	PasskeyRepository   model.PasskeyRepository
+	TokenRepository     model.TokenRepository
+	RelyingParty        *webauthn.RelyingParty
+	ChallengeExpiration time.Duration
+}
+
+// PasskeyServiceConfig will hold repositories that
+// will eventually be injected into this service layer.
+type PasskeyServiceConfig struct {
+	UserRepository      model.UserRepository
+	PasskeyRepository   model.PasskeyRepository
+	TokenRepository     model.TokenRepository
+	RPID                string   // The domain passkeys are scoped to, like example.com.
+	RPName              string   // Names the site in the prompts of authenticators.
+	Origins             []string // The pages allowed to run the ceremonies, like https://example.com.
+	ChallengeExpiration int64    // Seconds a ceremony can be finished for.
+}
+
+// NewPasskeyService is a factory function for
+// initializing a PasskeyService with its
+// repository layer dependencies.
+func NewPasskeyService(c *PasskeyServiceConfig) model.PasskeyService {
+	return &passkeyService{
+		UserRepository:    c.UserRepository,
+		PasskeyRepository: c.PasskeyRepository,
+		TokenRepository:   c.TokenRepository,
+		RelyingParty: &webauthn.RelyingParty{
+			ID:      c.RPID,
+			Name:    c.RPName,
+			Origins: c.Origins,
+		},
+		ChallengeExpiration: time.Duration(c.ChallengeExpiration) * time.Second,
+	}
+}
+
+// BeginRegistration starts the registration of a passkey for the user,
+// and returns the options for navigator.credentials.create. The passkeys
+// the user already has are excluded, so an authenticator holds one at most.
+func (s *passkeyService) BeginRegistration(ctx context.Context, userID uuid.UUID) (*model.PasskeyCreationOptions, error) {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys, err := s.PasskeyRepository.FindByUser(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.newChallenge(ctx, model.PasskeyRegistration, userID, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := []model.PasskeyCredentialParameter{}
+	for _, algorithm := range webauthn.Algorithms() {
+		parameters = append(parameters, model.PasskeyCredentialParameter{Type: "public-key", Algorithm: algorithm})
+	}
+
+	displayName := user.Username
+	if displayName == "" {
+		displayName = user.Email
+	}
+
+	return &model.PasskeyCreationOptions{
+		Challenge: challenge,
+		RelyingParty: model.PasskeyRelyingParty{
+			ID:   s.RelyingParty.ID,
+			Name: s.RelyingParty.Name,
+		},
+		User: model.PasskeyUser{
+			ID:          base64.RawURLEncoding.EncodeToString(userID[:]),
+			Name:        user.Email,
+			DisplayName: displayName,
+		},
+		CredentialParameters: parameters,
+		Timeout:              s.ChallengeExpiration.Milliseconds(),
+		ExcludeCredentials:   credentialDescriptors(passkeys),
+		AuthenticatorSelection: model.PasskeyAuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration verifies the response of the authenticator to the
+// registration the user began, and stores the new passkey.
+func (s *passkeyService) FinishRegistration(ctx context.Context, userID uuid.UUID, name string, credential *model.PasskeyCredential) (*model.Passkey, error) {
+	clientDataJSON, err := decodeCredentialField(credential.Response.ClientDataJSON)
+
+	if err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := decodeCredentialField(credential.Response.AttestationObject)
+
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.consumeChallenge(ctx, clientDataJSON, model.PasskeyRegistration)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.UserID != userID {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey challenge")
+	}
+
+	registered, err := s.RelyingParty.VerifyRegistration(challenge.Challenge, clientDataJSON, attestationObject, challenge.UserVerification)
+
+	if err != nil {
+		return nil, verificationError(userID, err)
+	}
+
+	if credentialID, err := decodeCredentialField(credential.ID); err != nil || !bytes.Equal(credentialID, registered.ID) {
+		return nil, apperrors.NewBadRequest("The credential ID does not match the authenticator data")
+	}
+
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	passkey := &model.Passkey{
+		UserID:         userID,
+		CredentialID:   registered.ID,
+		Name:           name,
+		PublicKey:      registered.PublicKey,
+		SignCount:      int64(registered.SignCount),
+		AAGUID:         registered.AAGUID,
+		Transports:     credential.Response.Transports,
+		BackupEligible: registered.BackupEligible,
+	}
+
+	if err := s.PasskeyRepository.Create(ctx, passkey); err != nil {
+		return nil, err
+	}
+
+	return passkey, nil
+}
+
+// BeginSignIn starts a sign in with a passkey, and returns the options for
+// navigator.credentials.get. Passwordless sign ins have no user yet, so the
+// authenticator offers any passkey of the site and has to verify the user.
+// With a user, who entered the password already, only the passkeys of the
+// user are allowed, and security keys without a PIN will do.
+func (s *passkeyService) BeginSignIn(ctx context.Context, user *model.User) (*model.PasskeyRequestOptions, error) {
+	userID := uuid.Nil
+	allowCredentials := []model.PasskeyCredentialDescriptor{}
+	userVerification := "required"
+
+	if user != nil {
+		passkeys, err := s.PasskeyRepository.FindByUser(ctx, user.UserID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(passkeys) == 0 {
+			return nil, apperrors.NewBadRequest("No passkeys are registered")
+		}
+
+		userID = user.UserID
+		allowCredentials = credentialDescriptors(passkeys)
+		userVerification = "preferred"
+	}
+
+	challenge, err := s.newChallenge(ctx, model.PasskeyAuthentication, userID, user == nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PasskeyRequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.ChallengeExpiration.Milliseconds(),
+		RelyingPartyID:   s.RelyingParty.ID,
+		AllowCredentials: allowCredentials,
+		UserVerification: userVerification,
+	}, nil
+}
+
+// FinishSignIn verifies the response of the authenticator to a sign in the
+// user began, or a passwordless one without a user, and returns the user of
+// the passkey. The sign count has to go up with every use, unless the
+// authenticator doesn't count, so a cloned authenticator is rejected once
+// either copy was used.
+func (s *passkeyService) FinishSignIn(ctx context.Context, user *model.User, credential *model.PasskeyCredential) (*model.User, error) {
+	clientDataJSON, err := decodeCredentialField(credential.Response.ClientDataJSON)
+
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := decodeCredentialField(credential.Response.AuthenticatorData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeCredentialField(credential.Response.Signature)
+
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID, err := decodeCredentialField(credential.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.consumeChallenge(ctx, clientDataJSON, model.PasskeyAuthentication)
+
+	if err != nil {
+		return nil, err
+	}
+
+	passkey, err := s.PasskeyRepository.FindByCredentialID(ctx, credentialID)
+
+	if err != nil {
+		return nil, apperrors.NewAuthorization("Unknown passkey")
+	}
+
+	// Challenges of passwordless sign ins and second factors
+	// can't stand in for each other.
+	userID := uuid.Nil
+	if user != nil {
+		userID = user.UserID
+	}
+
+	if challenge.UserID != userID {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey challenge")
+	}
+
+	if user != nil && passkey.UserID != user.UserID {
+		return nil, apperrors.NewAuthorization("Unknown passkey")
+	}
+
+	// Discoverable credentials return the user handle, which is the user ID.
+	if credential.Response.UserHandle != "" {
+		userHandle, err := decodeCredentialField(credential.Response.UserHandle)
+
+		if err != nil || !bytes.Equal(userHandle, passkey.UserID[:]) {
+			return nil, apperrors.NewAuthorization("Unknown passkey")
+		}
+	}
+
+	assertion, err := s.RelyingParty.VerifyAssertion(challenge.Challenge, clientDataJSON, authenticatorData, signature, passkey.PublicKey, challenge.UserVerification)
+
+	if err != nil {
+		return nil, verificationError(passkey.UserID, err)
+	}
+
+	ok, err := s.PasskeyRepository.UseSignCount(ctx, passkey.PasskeyID, int64(assertion.SignCount), time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		log.Printf("The sign count of passkey: %v of userID: %v went from %d to %d. The authenticator may be cloned\n", passkey.PasskeyID, passkey.UserID, passkey.SignCount, assertion.SignCount)
+		return nil, apperrors.NewAuthorization("The passkey could not be verified")
+	}
+
+	return s.UserRepository.FindByID(ctx, passkey.UserID)
+}
+
+// List returns the passkeys of a user.
+func (s *passkeyService) List(ctx context.Context, userID uuid.UUID) ([]*model.Passkey, error) {
+	return s.PasskeyRepository.FindByUser(ctx, userID)
+}
+
+// Delete removes a passkey of a user. The authenticator keeps the
+// credential, but it can't sign in anymore.
+func (s *passkeyService) Delete(ctx context.Context, userID uuid.UUID, passkeyID uuid.UUID) error {
+	return s.PasskeyRepository.Delete(ctx, userID, passkeyID)
+}
+
+// newChallenge stores the state of a new ceremony and returns its challenge.
+// Only the hash of the challenge is used as the key.
+func (s *passkeyService) newChallenge(ctx context.Context, ceremony string, userID uuid.UUID, userVerification bool) (string, error) {
+	challenge, err := randomToken(32)
+
+	if err != nil {
+		log.Printf("Unable to generate a passkey challenge for userID: %v. Error: %v\n", userID, err)
+		return "", apperrors.NewInternal()
+	}
+
+	state := &model.PasskeyChallenge{
+		Challenge:        challenge,
+		Ceremony:         ceremony,
+		UserID:           userID,
+		UserVerification: userVerification,
+	}
+
+	if err := s.TokenRepository.SetPasskeyChallenge(ctx, hashToken(challenge), state, s.ChallengeExpiration); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge looks up the ceremony by the challenge of the client data
+// and uses it up, so a response can't be replayed.
+func (s *passkeyService) consumeChallenge(ctx context.Context, clientDataJSON []byte, ceremony string) (*model.PasskeyChallenge, error) {
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Invalid client data")
+	}
+
+	challenge, err := s.TokenRepository.ConsumePasskeyChallenge(ctx, hashToken(clientData.Challenge))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.Ceremony != ceremony {
+		return nil, apperrors.NewAuthorization("Invalid or expired passkey challenge")
+	}
+
+	return challenge, nil
+}
+
+// credentialDescriptors names the credentials of the passkeys.
+func credentialDescriptors(passkeys []*model.Passkey) []model.PasskeyCredentialDescriptor {
+	descriptors := []model.PasskeyCredentialDescriptor{}
+
+	for _, passkey := range passkeys {
+		descriptors = append(descriptors, model.PasskeyCredentialDescriptor{
+			Type:       "public-key",
+			ID:         base64.RawURLEncoding.EncodeToString(passkey.CredentialID),
+			Transports: passkey.Transports,
+		})
+	}
+
+	return descriptors
+}
+
+// decodeCredentialField decodes a base64url encoded field of a credential.
+func decodeCredentialField(value string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil || len(decoded) == 0 {
+		return nil, apperrors.NewBadRequest("Invalid passkey credential")
+	}
+
+	return decoded, nil
+}
+
+// verificationError tells responses which don't verify from
+// responses which can't be parsed.
+func verificationError(userID uuid.UUID, err error) error {
+	log.Printf("Unable to verify the passkey of userID: %v. Error: %v\n", userID, err)
+
+	if errors.Is(err, webauthn.ErrVerification) {
+		return apperrors.NewAuthorization("The passkey could not be verified")
+	}
+
+	return apperrors.NewBadRequest("Invalid passkey credential")
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+	"github.com/yachnytskyi/base-go/account/webauthn/webauthntest"
+)
+
+// passkeyTestOrigin is the page the ceremonies of the tests run on.
+const passkeyTestOrigin = "http://localhost:8080"
+
+// newPasskeyTestService returns a passkey service for localhost along with its mocks.
+// Challenges it stores can be consumed once, like Redis would.
+func newPasskeyTestService() (model.PasskeyService, *mocks.MockUserRepository, *mocks.MockPasskeyRepository, *mocks.MockTokenRepository) {
+	mockUserRepository := new(mocks.MockUserRepository)
+	mockPasskeyRepository := new(mocks.MockPasskeyRepository)
+	mockTokenRepository := new(mocks.MockTokenRepository)
+
+	passkeyService := NewPasskeyService(&PasskeyServiceConfig{
+		UserRepository:      mockUserRepository,
+		PasskeyRepository:   mockPasskeyRepository,
+		TokenRepository:     mockTokenRepository,
+		RPID:                "localhost",
+		RPName:              "base-go",
+		Origins:             []string{passkeyTestOrigin},
+		ChallengeExpiration: 300,
+	})
+
+	mockTokenRepository.On("SetPasskeyChallenge", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*model.PasskeyChallenge"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			mockTokenRepository.On("ConsumePasskeyChallenge", mock.Anything, args.String(1)).Return(args.Get(2), nil).Once()
+		}).Return(nil)
+
+	return passkeyService, mockUserRepository, mockPasskeyRepository, mockTokenRepository
+}
+
+// registerPasskey registers a passkey of the authenticator for the user,
+// and returns it as it would be stored.
+func registerPasskey(t *testing.T, authenticator *webauthntest.Authenticator, user *model.User) *model.Passkey {
+	passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+
+	mockUserRepository.On("FindByID", mock.Anything, user.UserID).Return(user, nil)
+	mockPasskeyRepository.On("FindByUser", mock.Anything, user.UserID).Return([]*model.Passkey{}, nil)
+	mockPasskeyRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Passkey")).Return(nil)
+
+	options, err := passkeyService.BeginRegistration(context.Background(), user.UserID)
+	assert.NoError(t, err)
+
+	credential, err := authenticator.Create(options)
+	assert.NoError(t, err)
+
+	passkey, err := passkeyService.FinishRegistration(context.Background(), user.UserID, "", credential)
+	assert.NoError(t, err)
+
+	passkey.PasskeyID, _ = uuid.NewRandom()
+
+	return passkey
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	userID, _ := uuid.NewRandom()
+	user := &model.User{UserID: userID, Email: "kostya@kostya.com"}
+
+	t.Run("Registers a passkey", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+		authenticator := webauthntest.NewAuthenticator(passkeyTestOrigin)
+
+		existing := &model.Passkey{UserID: userID, CredentialID: []byte("existing"), Transports: []string{"usb"}}
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{existing}, nil)
+		mockPasskeyRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Passkey")).Return(nil)
+
+		options, err := passkeyService.BeginRegistration(context.Background(), userID)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "localhost", options.RelyingParty.ID)
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(userID[:]), options.User.ID)
+		assert.Equal(t, "kostya@kostya.com", options.User.DisplayName)
+		assert.Equal(t, int64(300000), options.Timeout)
+		assert.Equal(t, "required", options.AuthenticatorSelection.UserVerification)
+		assert.Equal(t, []model.PasskeyCredentialDescriptor{
+			{Type: "public-key", ID: "ZXhpc3Rpbmc", Transports: []string{"usb"}},
+		}, options.ExcludeCredentials)
+
+		credential, err := authenticator.Create(options)
+		assert.NoError(t, err)
+
+		passkey, err := passkeyService.FinishRegistration(context.Background(), userID, "Laptop", credential)
+		assert.NoError(t, err)
+
+		assert.Equal(t, userID, passkey.UserID)
+		assert.Equal(t, "Laptop", passkey.Name)
+		assert.Equal(t, credential.ID, base64.RawURLEncoding.EncodeToString(passkey.CredentialID))
+		assert.Equal(t, []string{"internal"}, []string(passkey.Transports))
+		assert.True(t, passkey.BackupEligible)
+		mockPasskeyRepository.AssertCalled(t, "Create", mock.Anything, passkey)
+	})
+
+	t.Run("Challenge of another user", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+		authenticator := webauthntest.NewAuthenticator(passkeyTestOrigin)
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{}, nil)
+
+		options, err := passkeyService.BeginRegistration(context.Background(), userID)
+		assert.NoError(t, err)
+
+		credential, err := authenticator.Create(options)
+		assert.NoError(t, err)
+
+		otherUserID, _ := uuid.NewRandom()
+
+		_, err = passkeyService.FinishRegistration(context.Background(), otherUserID, "", credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockPasskeyRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Response from another origin", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+		authenticator := webauthntest.NewAuthenticator("https://evil.com")
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{}, nil)
+
+		options, err := passkeyService.BeginRegistration(context.Background(), userID)
+		assert.NoError(t, err)
+
+		credential, err := authenticator.Create(options)
+		assert.NoError(t, err)
+
+		_, err = passkeyService.FinishRegistration(context.Background(), userID, "", credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockPasskeyRepository.AssertNotCalled(t, "Create")
+	})
+
+	t.Run("Replayed response", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, mockTokenRepository := newPasskeyTestService()
+		authenticator := webauthntest.NewAuthenticator(passkeyTestOrigin)
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{}, nil)
+		mockPasskeyRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.Passkey")).Return(nil)
+
+		options, err := passkeyService.BeginRegistration(context.Background(), userID)
+		assert.NoError(t, err)
+
+		credential, err := authenticator.Create(options)
+		assert.NoError(t, err)
+
+		_, err = passkeyService.FinishRegistration(context.Background(), userID, "", credential)
+		assert.NoError(t, err)
+
+		mockTokenRepository.On("ConsumePasskeyChallenge", mock.Anything, hashToken(options.Challenge)).
+			Return(nil, apperrors.NewAuthorization("Invalid or expired passkey challenge"))
+
+		_, err = passkeyService.FinishRegistration(context.Background(), userID, "", credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockPasskeyRepository.AssertNumberOfCalls(t, "Create", 1)
+	})
+}
+
+func TestPasskeySignIn(t *testing.T) {
+	userID, _ := uuid.NewRandom()
+	user := &model.User{UserID: userID, Email: "kostya@kostya.com"}
+
+	authenticator := webauthntest.NewAuthenticator(passkeyTestOrigin)
+	authenticator.CountSignatures = true
+
+	passkey := registerPasskey(t, authenticator, user)
+
+	t.Run("Passwordless", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+		mockPasskeyRepository.On("UseSignCount", mock.Anything, passkey.PasskeyID, int64(2), mock.AnythingOfType("time.Time")).Return(true, nil)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+
+		options, err := passkeyService.BeginSignIn(context.Background(), nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "localhost", options.RelyingPartyID)
+		assert.Empty(t, options.AllowCredentials)
+		assert.Equal(t, "required", options.UserVerification)
+
+		credential, err := authenticator.Get(options)
+		assert.NoError(t, err)
+
+		signedIn, err := passkeyService.FinishSignIn(context.Background(), nil, credential)
+		assert.NoError(t, err)
+		assert.Equal(t, user, signedIn)
+		mockPasskeyRepository.AssertExpectations(t)
+	})
+
+	t.Run("Second factor", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{passkey}, nil)
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+		mockPasskeyRepository.On("UseSignCount", mock.Anything, passkey.PasskeyID, int64(3), mock.AnythingOfType("time.Time")).Return(true, nil)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+
+		options, err := passkeyService.BeginSignIn(context.Background(), user)
+		assert.NoError(t, err)
+
+		assert.Len(t, options.AllowCredentials, 1)
+		assert.Equal(t, "preferred", options.UserVerification)
+
+		credential, err := authenticator.Get(options)
+		assert.NoError(t, err)
+
+		signedIn, err := passkeyService.FinishSignIn(context.Background(), user, credential)
+		assert.NoError(t, err)
+		assert.Equal(t, user, signedIn)
+		mockPasskeyRepository.AssertExpectations(t)
+	})
+
+	t.Run("No passkeys registered", func(t *testing.T) {
+		passkeyService, _, mockPasskeyRepository, mockTokenRepository := newPasskeyTestService()
+
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{}, nil)
+
+		_, err := passkeyService.BeginSignIn(context.Background(), user)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		mockTokenRepository.AssertNotCalled(t, "SetPasskeyChallenge")
+	})
+
+	t.Run("Cloned authenticator", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+		clone := authenticator.Clone()
+
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+		mockPasskeyRepository.On("UseSignCount", mock.Anything, passkey.PasskeyID, int64(4), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		mockPasskeyRepository.On("UseSignCount", mock.Anything, passkey.PasskeyID, int64(4), mock.AnythingOfType("time.Time")).Return(false, nil)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(user, nil)
+
+		for i, a := range []*webauthntest.Authenticator{authenticator, clone} {
+			options, err := passkeyService.BeginSignIn(context.Background(), nil)
+			assert.NoError(t, err)
+
+			credential, err := a.Get(options)
+			assert.NoError(t, err)
+
+			_, err = passkeyService.FinishSignIn(context.Background(), nil, credential)
+
+			if i == 0 {
+				assert.NoError(t, err)
+			} else {
+				// The clone reports the same sign count again.
+				assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+			}
+		}
+
+		mockUserRepository.AssertNumberOfCalls(t, "FindByID", 1)
+	})
+
+	t.Run("Challenge of a second factor", func(t *testing.T) {
+		passkeyService, mockUserRepository, mockPasskeyRepository, _ := newPasskeyTestService()
+
+		mockPasskeyRepository.On("FindByUser", mock.Anything, userID).Return([]*model.Passkey{passkey}, nil)
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+
+		options, err := passkeyService.BeginSignIn(context.Background(), user)
+		assert.NoError(t, err)
+
+		credential, err := authenticator.Get(options)
+		assert.NoError(t, err)
+
+		_, err = passkeyService.FinishSignIn(context.Background(), nil, credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockPasskeyRepository.AssertNotCalled(t, "UseSignCount")
+		mockUserRepository.AssertNotCalled(t, "FindByID")
+	})
+
+	t.Run("Passwordless without user verification", func(t *testing.T) {
+		passkeyService, _, mockPasskeyRepository, _ := newPasskeyTestService()
+
+		securityKey := authenticator.Clone()
+		securityKey.SkipUserVerification = true
+
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, passkey.CredentialID).Return(passkey, nil)
+
+		options, err := passkeyService.BeginSignIn(context.Background(), nil)
+		assert.NoError(t, err)
+
+		credential, err := securityKey.Get(options)
+		assert.NoError(t, err)
+
+		_, err = passkeyService.FinishSignIn(context.Background(), nil, credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		mockPasskeyRepository.AssertNotCalled(t, "UseSignCount")
+	})
+
+	t.Run("Unknown passkey", func(t *testing.T) {
+		passkeyService, _, mockPasskeyRepository, _ := newPasskeyTestService()
+
+		stranger := webauthntest.NewAuthenticator(passkeyTestOrigin)
+		registerPasskey(t, stranger, &model.User{UserID: uuid.New(), Email: "stranger@kostya.com"})
+
+		mockPasskeyRepository.On("FindByCredentialID", mock.Anything, mock.Anything).Return(nil, apperrors.NewNotFound("passkey", ""))
+
+		options, err := passkeyService.BeginSignIn(context.Background(), nil)
+		assert.NoError(t, err)
+
+		credential, err := stranger.Get(options)
+		assert.NoError(t, err)
+
+		_, err = passkeyService.FinishSignIn(context.Background(), nil, credential)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
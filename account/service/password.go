@@ -2,44 +2,351 @@ package service
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/scrypt"
 )
 
+// passwordHasher hashes passwords into self describing strings in the PHC
+// format, $id$parameters$salt$hash, so a stored hash can always be verified
+// with the algorithm and cost parameters it was made with.
+type passwordHasher interface {
+	// hash returns the encoded hash of the password with the current parameters.
+	hash(password string) (string, error)
+	// verify compares the password with an encoded hash in constant time.
+	verify(encoded string, password string) (bool, error)
+	// isCurrent reports whether the encoded hash was made with the current parameters.
+	isCurrent(encoded string) bool
+}
+
+// defaultPasswordHasher hashes new passwords. Raising its parameters
+// upgrades stored hashes as their users sign in.
+// The parameters are the minimum OWASP recommends for argon2id.
+var defaultPasswordHasher passwordHasher = &argon2idHasher{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// passwordHashers holds the hashers stored hashes are verified with,
+// by the id of their PHC string.
+var passwordHashers = map[string]passwordHasher{
+	"argon2id": defaultPasswordHasher,
+	"scrypt":   &scryptHasher{LogN: 15, R: 8, P: 1, SaltLength: 16, KeyLength: 32},
+	"2a":       &bcryptHasher{Cost: bcrypt.DefaultCost},
+	"2b":       &bcryptHasher{Cost: bcrypt.DefaultCost},
+	"2y":       &bcryptHasher{Cost: bcrypt.DefaultCost},
+}
+
+// hashPassword hashes a new password with the default hasher.
 func hashPassword(password string) (string, error) {
-	// Example for making salt - https://play.golang.org/p/_Aw6WeWC42I
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
+	return defaultPasswordHasher.hash(password)
+}
+
+// comparePasswords checks the supplied password against the stored hash.
+// An error means the stored hash could not be read.
+func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
+	hasher, err := passwordHasherOf(storedPassword)
+
+	if err != nil {
+		return false, err
+	}
+
+	return hasher.verify(storedPassword, suppliedPassword)
+}
+
+// passwordNeedsRehash reports whether the stored hash was not made
+// by the default hasher with its current parameters.
+func passwordNeedsRehash(storedPassword string) bool {
+	hasher, err := passwordHasherOf(storedPassword)
+
+	return err != nil || hasher != defaultPasswordHasher || !hasher.isCurrent(storedPassword)
+}
+
+// passwordHasherOf picks the hasher of a stored hash by its id.
+// Hashes stored before the PHC format are hex encoded scrypt hashes in the hash.salt format.
+func passwordHasherOf(storedPassword string) (passwordHasher, error) {
+	if !strings.HasPrefix(storedPassword, "$") {
+		return legacyScryptHasher{}, nil
+	}
+
+	fields := strings.SplitN(storedPassword, "$", 3)
+
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("malformed password hash")
+	}
+
+	hasher, ok := passwordHashers[fields[1]]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported password hash: %s", fields[1])
+	}
+
+	return hasher, nil
+}
+
+// randomSalt returns a random salt of the length.
+func randomSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// encodePHC formats a hash as $id$parameters$salt$hash.
+func encodePHC(id string, parameters string, salt []byte, key []byte) string {
+	return fmt.Sprintf("$%s$%s$%s$%s", id, parameters, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// decodePHC splits an encoded hash into the parameters, salt and hash.
+// The version, if there is one, is returned as the first parameter.
+func decodePHC(encoded string, id string) ([]string, []byte, []byte, error) {
+	fields := strings.Split(encoded, "$")
+
+	if len(fields) < 5 || fields[0] != "" || fields[1] != id {
+		return nil, nil, nil, fmt.Errorf("malformed %s hash", id)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[len(fields)-2])
+
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed %s salt: %w", id, err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(fields[len(fields)-1])
+
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, fmt.Errorf("malformed %s hash", id)
+	}
+
+	return fields[2 : len(fields)-2], salt, key, nil
+}
+
+// argon2idHasher is the argon2id hasher, which is encoded as
+// $argon2id$v=19$m=19456,t=2,p=1$salt$hash with the memory in KiB.
+type argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+func (h *argon2idHasher) hash(password string) (string, error) {
+	salt, err := randomSalt(h.SaltLength)
+
 	if err != nil {
 		return "", err
 	}
 
-	// Using recommended cost parameters from - https://godoc.org/golang.org/x/crypto/scrypt
-	shash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return encodePHC("argon2id", fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, h.Memory, h.Iterations, h.Parallelism), salt, key), nil
+}
+
+func (h *argon2idHasher) verify(encoded string, password string) (bool, error) {
+	parameters, salt, key, err := h.decode(encoded)
+
+	if err != nil {
+		return false, err
+	}
+
+	suppliedKey := argon2.IDKey([]byte(password), salt, parameters.Iterations, parameters.Memory, parameters.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
+}
+
+func (h *argon2idHasher) isCurrent(encoded string) bool {
+	parameters, salt, key, err := h.decode(encoded)
+
+	return err == nil &&
+		parameters.Memory == h.Memory &&
+		parameters.Iterations == h.Iterations &&
+		parameters.Parallelism == h.Parallelism &&
+		len(salt) == h.SaltLength &&
+		uint32(len(key)) == h.KeyLength
+}
+
+// decode reads the parameters, salt and hash of an encoded argon2id hash.
+func (h *argon2idHasher) decode(encoded string) (*argon2idHasher, []byte, []byte, error) {
+	fields, salt, key, err := decodePHC(encoded, "argon2id")
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if len(fields) != 2 || fields[0] != fmt.Sprintf("v=%d", argon2.Version) {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+
+	parameters := &argon2idHasher{}
+
+	if _, err := fmt.Sscanf(fields[1], "m=%d,t=%d,p=%d", &parameters.Memory, &parameters.Iterations, &parameters.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	if parameters.Iterations == 0 || parameters.Parallelism == 0 {
+		return nil, nil, nil, fmt.Errorf("malformed argon2id parameters")
+	}
+
+	return parameters, salt, key, nil
+}
+
+// scryptHasher is the scrypt hasher, which is encoded as
+// $scrypt$ln=15,r=8,p=1$salt$hash with the log2 of the cost.
+type scryptHasher struct {
+	LogN       uint
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+func (h *scryptHasher) hash(password string) (string, error) {
+	salt, err := randomSalt(h.SaltLength)
+
 	if err != nil {
 		return "", err
 	}
 
-	// Return hex-encoded string with salt appended to password.
-	hashedPassword := fmt.Sprintf("%s.%s", hex.EncodeToString(shash), hex.EncodeToString(salt))
+	key, err := scrypt.Key([]byte(password), salt, 1<<h.LogN, h.R, h.P, h.KeyLength)
+
+	if err != nil {
+		return "", err
+	}
 
-	return hashedPassword, nil
+	return encodePHC("scrypt", fmt.Sprintf("ln=%d,r=%d,p=%d", h.LogN, h.R, h.P), salt, key), nil
 }
 
-func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
-	passwordSalt := strings.Split(storedPassword, ".")
+func (h *scryptHasher) verify(encoded string, password string) (bool, error) {
+	parameters, salt, key, err := h.decode(encoded)
+
+	if err != nil {
+		return false, err
+	}
+
+	suppliedKey, err := scrypt.Key([]byte(password), salt, 1<<parameters.LogN, parameters.R, parameters.P, len(key))
+
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
+}
+
+func (h *scryptHasher) isCurrent(encoded string) bool {
+	parameters, salt, key, err := h.decode(encoded)
+
+	return err == nil &&
+		parameters.LogN == h.LogN &&
+		parameters.R == h.R &&
+		parameters.P == h.P &&
+		len(salt) == h.SaltLength &&
+		len(key) == h.KeyLength
+}
+
+// decode reads the parameters, salt and hash of an encoded scrypt hash.
+func (h *scryptHasher) decode(encoded string) (*scryptHasher, []byte, []byte, error) {
+	fields, salt, key, err := decodePHC(encoded, "scrypt")
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	parameters := &scryptHasher{}
+
+	if len(fields) != 1 {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt parameters")
+	}
 
-	// Check supplied password salted with hash.
-	salt, err := hex.DecodeString(passwordSalt[1])
+	if _, err := fmt.Sscanf(fields[0], "ln=%d,r=%d,p=%d", &parameters.LogN, &parameters.R, &parameters.P); err != nil {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt parameters: %w", err)
+	}
+
+	// Larger costs would shift out of range.
+	if parameters.LogN == 0 || parameters.LogN > 30 {
+		return nil, nil, nil, fmt.Errorf("malformed scrypt parameters")
+	}
+
+	return parameters, salt, key, nil
+}
+
+// bcryptHasher is the bcrypt hasher. Bcrypt hashes are already
+// self describing, as $2b$cost$ followed by the salt and hash.
+type bcryptHasher struct {
+	Cost int
+}
+
+func (h *bcryptHasher) hash(password string) (string, error) {
+	key, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+
+	return string(key), err
+}
+
+func (h *bcryptHasher) verify(encoded string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (h *bcryptHasher) isCurrent(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err == nil && cost == h.Cost
+}
+
+// legacyScryptHasher verifies the hashes stored before the PHC format,
+// which are the hex encoded hash and salt joined by a dot.
+type legacyScryptHasher struct{}
+
+func (h legacyScryptHasher) hash(password string) (string, error) {
+	return "", fmt.Errorf("the legacy scrypt format is only verified")
+}
+
+func (h legacyScryptHasher) verify(encoded string, password string) (bool, error) {
+	hashSalt := strings.Split(encoded, ".")
+
+	if len(hashSalt) != 2 {
+		return false, fmt.Errorf("malformed legacy password hash")
+	}
+
+	key, err := hex.DecodeString(hashSalt[0])
 
 	if err != nil {
-		return false, fmt.Errorf("unable to verify user password")
+		return false, fmt.Errorf("malformed legacy password hash: %w", err)
 	}
 
-	shash, err := scrypt.Key([]byte(suppliedPassword), salt, 32768, 8, 1, 32)
+	salt, err := hex.DecodeString(hashSalt[1])
+
+	if err != nil {
+		return false, fmt.Errorf("malformed legacy password salt: %w", err)
+	}
+
+	// The parameters were fixed before the PHC format.
+	suppliedKey, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
+}
 
-	return hex.EncodeToString(shash) == passwordSalt[0], nil
+func (h legacyScryptHasher) isCurrent(encoded string) bool {
+	return false
 }
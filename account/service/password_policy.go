@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Default limits of a password policy. NIST recommends at least 8 characters
+// and allowing at least 64, so passphrases fit.
+const (
+	defaultPasswordMinLength   = 8
+	defaultPasswordMaxLength   = 128
+	defaultPasswordMinStrength = 2
+)
+
+// Codes of the reasons a password is rejected for.
+const (
+	PasswordTooShort = "PASSWORD_TOO_SHORT"
+	PasswordTooLong  = "PASSWORD_TOO_LONG"
+	PasswordCommon   = "PASSWORD_COMMON"
+	PasswordWeak     = "PASSWORD_WEAK"
+	PasswordBreached = "PASSWORD_BREACHED"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords ranks the most common passwords, starting with 1 for the most common.
+var commonPasswords = rankWords(commonPasswordsFile)
+
+// PasswordPolicy decides which new passwords are accepted.
+// Passwords are normalized before they are checked and hashed,
+// so the same password typed on different keyboards matches.
+type PasswordPolicy struct {
+	MinLength         int
+	MaxLength         int
+	MinStrength       int
+	BreachedPasswords model.BreachedPasswordRepository
+	BreachThreshold   int
+}
+
+// PasswordPolicyConfig holds the limits of a password policy.
+// Lengths are counted in characters. Zero lengths fall back to the defaults.
+type PasswordPolicyConfig struct {
+	MinLength         int
+	MaxLength         int
+	MinStrength       int                              // The minimum strength score from 0 to 4. Zero accepts any password.
+	BreachedPasswords model.BreachedPasswordRepository // Passwords found in it are rejected. Optional.
+	BreachThreshold   int                              // How many breaches a password has to appear in to be rejected. Defaults to 1.
+}
+
+// NewPasswordPolicy is a factory function for initializing a PasswordPolicy.
+func NewPasswordPolicy(c *PasswordPolicyConfig) *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:         c.MinLength,
+		MaxLength:         c.MaxLength,
+		MinStrength:       c.MinStrength,
+		BreachedPasswords: c.BreachedPasswords,
+		BreachThreshold:   c.BreachThreshold,
+	}
+
+	if policy.MinLength <= 0 {
+		policy.MinLength = defaultPasswordMinLength
+	}
+
+	if policy.MaxLength <= 0 {
+		policy.MaxLength = defaultPasswordMaxLength
+	}
+
+	if policy.BreachThreshold <= 0 {
+		policy.BreachThreshold = 1
+	}
+
+	return policy
+}
+
+// Check returns the normalized password to hash if the policy accepts it.
+// Otherwise it returns a bad request listing every reason it was rejected for.
+// User inputs, such as the email, make passwords built from them weaker.
+// Passwords which appeared in breaches are rejected too, since attackers
+// try them first.
+func (p *PasswordPolicy) Check(ctx context.Context, password string, userInputs ...string) (string, error) {
+	password = normalizePassword(password)
+	length := utf8.RuneCountInString(password)
+
+	var reasons []apperrors.Reason
+
+	if length < p.MinLength {
+		reasons = append(reasons, apperrors.Reason{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("The password must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if length > p.MaxLength {
+		reasons = append(reasons, apperrors.Reason{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("The password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+		reasons = append(reasons, apperrors.Reason{
+			Code:    PasswordCommon,
+			Message: "The password is one of the most common passwords",
+		})
+	} else if length <= p.MaxLength && p.Strength(password, userInputs...) < p.MinStrength {
+		reasons = append(reasons, apperrors.Reason{
+			Code:    PasswordWeak,
+			Message: "The password is too easy to guess. Add more words or characters which don't follow a pattern",
+		})
+	}
+
+	// Common passwords are breached too, so there's no need to look them up.
+	if len(reasons) == 0 && p.BreachedPasswords != nil {
+		breached, err := p.isBreached(ctx, password)
+
+		if err != nil {
+			return "", err
+		}
+
+		if breached {
+			reasons = append(reasons, apperrors.Reason{
+				Code:    PasswordBreached,
+				Message: "The password appeared in a data breach, so attackers try it first",
+			})
+		}
+	}
+
+	if len(reasons) > 0 {
+		return "", apperrors.NewBadRequestWithReasons("The password does not meet the password policy. See reasons", reasons)
+	}
+
+	return password, nil
+}
+
+// Strength estimates how hard the password is to guess,
+// from 0 for too guessable to 4 for very unguessable.
+func (p *PasswordPolicy) Strength(password string, userInputs ...string) int {
+	return estimatePasswordStrength(normalizePassword(password), userInputs)
+}
+
+// isBreached tells whether the password appeared in at least
+// as many breaches as the threshold.
+func (p *PasswordPolicy) isBreached(ctx context.Context, password string) (bool, error) {
+	hash := sha1.Sum([]byte(password))
+
+	count, err := p.BreachedPasswords.Count(ctx, strings.ToUpper(hex.EncodeToString(hash[:])))
+
+	if err != nil {
+		return false, err
+	}
+
+	return count >= p.BreachThreshold, nil
+}
+
+// normalizePassword applies the NFKC normalization form,
+// so composed and decomposed characters become the same.
+func normalizePassword(password string) string {
+	return norm.NFKC.String(password)
+}
+
+// matchPassword checks the supplied password against the stored hash.
+// Hashes are made of normalized passwords, but hashes of passwords set
+// before normalization match the password as typed, and report that
+// they should be rehashed. Users who signed up with a sign in link
+// have no password, which no password matches.
+func matchPassword(storedPassword string, suppliedPassword string) (match bool, needsRehash bool, err error) {
+	if storedPassword == "" {
+		return false, false, nil
+	}
+
+	normalized := normalizePassword(suppliedPassword)
+
+	match, err = comparePasswords(storedPassword, normalized)
+
+	if err != nil || match || normalized == suppliedPassword {
+		return match, match && passwordNeedsRehash(storedPassword), err
+	}
+
+	match, err = comparePasswords(storedPassword, suppliedPassword)
+
+	return match, match, err
+}
+
+// rankWords ranks the lines of a word list by their order.
+func rankWords(list string) map[string]int {
+	ranks := make(map[string]int)
+
+	for _, word := range strings.Split(list, "\n") {
+		word = strings.TrimSpace(word)
+
+		if _, ok := ranks[word]; word != "" && !ok {
+			ranks[word] = len(ranks) + 1
+		}
+	}
+
+	return ranks
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+	"github.com/yachnytskyi/base-go/account/model/mocks"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(&PasswordPolicyConfig{
+		MinLength:   8,
+		MaxLength:   64,
+		MinStrength: 2,
+	})
+
+	reasonCodes := func(err error) []string {
+		var codes []string
+		for _, reason := range err.(*apperrors.Error).Reasons {
+			codes = append(codes, reason.Code)
+		}
+		return codes
+	}
+
+	t.Run("Accepts long passphrases", func(t *testing.T) {
+		password, err := policy.Check(context.TODO(), "correct horse battery staple")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "correct horse battery staple", password)
+	})
+
+	t.Run("Too short", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "x7#kLp")
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		assert.Equal(t, []string{PasswordTooShort}, reasonCodes(err))
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), strings.Repeat("x7#kLp9!zQ", 7))
+
+		assert.Equal(t, []string{PasswordTooLong}, reasonCodes(err))
+	})
+
+	t.Run("Common password", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "Password1")
+
+		assert.Equal(t, []string{PasswordCommon}, reasonCodes(err))
+	})
+
+	t.Run("Weak password", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "abcdefgh1234")
+
+		assert.Equal(t, []string{PasswordWeak}, reasonCodes(err))
+	})
+
+	t.Run("Built from user inputs", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "kostyakostya")
+		assert.NoError(t, err)
+
+		_, err = policy.Check(context.TODO(), "kostyakostya", "kostya@kostya.com")
+		assert.Equal(t, []string{PasswordWeak}, reasonCodes(err))
+	})
+
+	t.Run("Lists every reason", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "qwerty")
+
+		assert.Equal(t, []string{PasswordTooShort, PasswordCommon}, reasonCodes(err))
+	})
+
+	t.Run("Normalizes passwords", func(t *testing.T) {
+		// Accents typed as combining characters are composed.
+		password, err := policy.Check(context.TODO(), "cafe\u0301 au lait, s'il vous plai\u0302t")
+		assert.NoError(t, err)
+
+		assert.Equal(t, "caf\u00e9 au lait, s'il vous pla\u00eet", password)
+	})
+
+	t.Run("Counts characters instead of bytes", func(t *testing.T) {
+		_, err := policy.Check(context.TODO(), "пароль")
+
+		assert.Equal(t, PasswordTooShort, reasonCodes(err)[0])
+	})
+}
+
+func TestPasswordStrength(t *testing.T) {
+	policy := NewPasswordPolicy(&PasswordPolicyConfig{})
+
+	assert.Equal(t, 0, policy.Strength("password"))
+	assert.Equal(t, 0, policy.Strength("P@ssw0rd"))
+	assert.Equal(t, 0, policy.Strength("aaaaaaaaaaaa"))
+	assert.Equal(t, 0, policy.Strength("qwertyuiop123"))
+	assert.Equal(t, 4, policy.Strength("x7#kLp9!zQ"))
+	assert.Equal(t, 4, policy.Strength("correct horse battery staple"))
+}
+
+func TestMatchPassword(t *testing.T) {
+	composed := "caf\u00e9 au lait"
+	decomposed := "cafe\u0301 au lait"
+
+	t.Run("Normalized hash", func(t *testing.T) {
+		hashedPassword, _ := hashPassword(composed)
+
+		match, needsRehash, err := matchPassword(hashedPassword, decomposed)
+		assert.NoError(t, err)
+
+		assert.True(t, match)
+		assert.False(t, needsRehash)
+	})
+
+	t.Run("Hash of a password set before normalization", func(t *testing.T) {
+		hashedPassword, _ := hashPassword(decomposed)
+
+		match, needsRehash, err := matchPassword(hashedPassword, decomposed)
+		assert.NoError(t, err)
+
+		assert.True(t, match)
+		assert.True(t, needsRehash)
+	})
+
+	t.Run("Invalid password", func(t *testing.T) {
+		hashedPassword, _ := hashPassword(composed)
+
+		match, needsRehash, err := matchPassword(hashedPassword, "somerandominvalidpassword")
+		assert.NoError(t, err)
+
+		assert.False(t, match)
+		assert.False(t, needsRehash)
+	})
+}
+
+func TestPasswordPolicyBreaches(t *testing.T) {
+	password := "Tr0ub4dor&3"
+	hash := "874572E7A5AE6A49466A6AC578B98ADBA78C6AA6"
+
+	mockBreachedPasswordRepository := new(mocks.MockBreachedPasswordRepository)
+	mockBreachedPasswordRepository.On("Count", mock.Anything, hash).Return(3, nil)
+	mockBreachedPasswordRepository.On("Count", mock.Anything, mock.AnythingOfType("string")).Return(0, nil)
+
+	t.Run("Breached password", func(t *testing.T) {
+		policy := NewPasswordPolicy(&PasswordPolicyConfig{
+			BreachedPasswords: mockBreachedPasswordRepository,
+		})
+
+		_, err := policy.Check(context.TODO(), password)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		assert.Equal(t, PasswordBreached, err.(*apperrors.Error).Reasons[0].Code)
+	})
+
+	t.Run("Breached fewer times than the threshold", func(t *testing.T) {
+		policy := NewPasswordPolicy(&PasswordPolicyConfig{
+			BreachedPasswords: mockBreachedPasswordRepository,
+			BreachThreshold:   10,
+		})
+
+		_, err := policy.Check(context.TODO(), password)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Unbreached password", func(t *testing.T) {
+		policy := NewPasswordPolicy(&PasswordPolicyConfig{
+			BreachedPasswords: mockBreachedPasswordRepository,
+		})
+
+		_, err := policy.Check(context.TODO(), "correct horse battery staple")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Dataset error", func(t *testing.T) {
+		mockBreachedPasswordRepository := new(mocks.MockBreachedPasswordRepository)
+		mockBreachedPasswordRepository.On("Count", mock.Anything, hash).Return(0, apperrors.NewInternal())
+
+		policy := NewPasswordPolicy(&PasswordPolicyConfig{
+			BreachedPasswords: mockBreachedPasswordRepository,
+		})
+
+		_, err := policy.Check(context.TODO(), password)
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+	})
+}
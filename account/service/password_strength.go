@@ -0,0 +1,278 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// The strength of a password is estimated the way zxcvbn does it: the password
+// is split into parts which follow patterns, like common passwords, repeated
+// characters or sequences, and the split which takes the fewest guesses to
+// crack decides the strength. Guess counts are kept as their base 10 logarithms.
+
+// maxStrengthEstimateLength limits how many characters of a password
+// are estimated, as the estimate grows with the cube of the length.
+const maxStrengthEstimateLength = 100
+
+// minWordLength is the length of the shortest word matched in passwords.
+const minWordLength = 3
+
+// strengthScoreGuesses holds the logarithms of the guess counts
+// a password needs to reach for each score above 0.
+var strengthScoreGuesses = []float64{3, 6, 8, 10}
+
+// leetSubstitutions maps the characters which replace letters in l33t speak.
+var leetSubstitutions = map[rune]rune{
+	'4': 'a',
+	'@': 'a',
+	'8': 'b',
+	'(': 'c',
+	'3': 'e',
+	'6': 'g',
+	'1': 'i',
+	'!': 'i',
+	'0': 'o',
+	'$': 's',
+	'5': 's',
+	'7': 't',
+	'2': 'z',
+}
+
+// passwordMatch is the part of a password from start up to end, which
+// follows a pattern and takes the logarithm of guesses to crack.
+type passwordMatch struct {
+	start   int
+	end     int
+	guesses float64
+}
+
+// estimatePasswordStrength scores the password from 0 to 4.
+func estimatePasswordStrength(password string, userInputs []string) int {
+	runes := []rune(password)
+
+	if len(runes) > maxStrengthEstimateLength {
+		runes = runes[:maxStrengthEstimateLength]
+	}
+
+	guesses := minimumGuesses(len(runes), passwordMatches(runes, userInputWords(userInputs)))
+
+	score := 0
+	for _, threshold := range strengthScoreGuesses {
+		if guesses < threshold {
+			break
+		}
+		score++
+	}
+
+	return score
+}
+
+// minimumGuesses finds the split of a password of the length into matches
+// and brute forced parts which takes the fewest guesses. Attackers try the
+// patterns in any order, so a split of k parts takes k! times the product
+// of the guesses of its parts.
+func minimumGuesses(length int, matches []passwordMatch) float64 {
+	matchesByEnd := make([][]passwordMatch, length+1)
+
+	for _, match := range matches {
+		matchesByEnd[match.end] = append(matchesByEnd[match.end], match)
+	}
+
+	// best[k][i] holds the fewest guesses of the first i characters split into k parts.
+	best := make([][]float64, length+1)
+
+	for k := range best {
+		best[k] = make([]float64, length+1)
+
+		for i := range best[k] {
+			best[k][i] = math.Inf(1)
+		}
+	}
+
+	best[0][0] = 0
+
+	for i := 1; i <= length; i++ {
+		for k := 1; k <= i; k++ {
+			// Brute forcing takes 10 guesses per character.
+			for j := 0; j < i; j++ {
+				best[k][i] = math.Min(best[k][i], best[k-1][j]+float64(i-j))
+			}
+
+			for _, match := range matchesByEnd[i] {
+				best[k][i] = math.Min(best[k][i], best[k-1][match.start]+match.guesses)
+			}
+		}
+	}
+
+	guesses := best[0][length]
+
+	for k := 1; k <= length; k++ {
+		factorial, _ := math.Lgamma(float64(k + 1))
+		guesses = math.Min(guesses, best[k][length]+factorial/math.Ln10)
+	}
+
+	return guesses
+}
+
+// passwordMatches finds the parts of the password which follow a pattern.
+func passwordMatches(runes []rune, userWords map[string]int) []passwordMatch {
+	var matches []passwordMatch
+
+	matches = append(matches, dictionaryMatches(runes, commonPasswords)...)
+	matches = append(matches, dictionaryMatches(runes, userWords)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+
+	return matches
+}
+
+// dictionaryMatches finds ranked words in the password, also when they are
+// capitalized or written in l33t speak. A word takes as many guesses as its rank.
+func dictionaryMatches(runes []rune, words map[string]int) []passwordMatch {
+	lower := make([]rune, len(runes))
+	unleet := make([]rune, len(runes))
+
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+		unleet[i] = lower[i]
+
+		if letter, ok := leetSubstitutions[r]; ok {
+			unleet[i] = letter
+		}
+	}
+
+	var matches []passwordMatch
+
+	for start := range runes {
+		for end := start + minWordLength; end <= len(runes); end++ {
+			guesses := uppercaseVariations(runes[start:end])
+
+			if rank, ok := words[string(lower[start:end])]; ok {
+				matches = append(matches, passwordMatch{start, end, math.Log10(float64(rank)) + guesses})
+			} else if rank, ok := words[string(unleet[start:end])]; ok {
+				matches = append(matches, passwordMatch{start, end, math.Log10(float64(rank)) + guesses + math.Log10(2)})
+			}
+		}
+	}
+
+	return matches
+}
+
+// uppercaseVariations returns the logarithm of the ways
+// the uppercase letters of a word could have been placed.
+// Capitalized and all uppercase words only double the guesses.
+func uppercaseVariations(word []rune) float64 {
+	upper, lower := 0, 0
+
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 0
+	}
+
+	if lower == 0 || (upper == 1 && unicode.IsUpper(word[0])) {
+		return math.Log10(2)
+	}
+
+	variations, binomial := 0.0, 1.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		binomial = binomial * float64(upper+lower-i+1) / float64(i)
+		variations += binomial
+	}
+
+	return math.Log10(variations)
+}
+
+// repeatMatches finds runs of the same character, like "aaaa".
+func repeatMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && runes[end] == runes[start] {
+			end++
+		}
+
+		if end-start >= minWordLength {
+			matches = append(matches, passwordMatch{start, end, math.Log10(characterCardinality(runes[start]) * float64(end-start))})
+		}
+
+		start = end
+	}
+
+	return matches
+}
+
+// sequenceMatches finds runs of consecutive characters, like "abcd" or "9876".
+func sequenceMatches(runes []rune) []passwordMatch {
+	var matches []passwordMatch
+
+	for start := 0; start+1 < len(runes); {
+		delta := runes[start+1] - runes[start]
+		end := start + 1
+
+		for end < len(runes) && runes[end]-runes[end-1] == delta {
+			end++
+		}
+
+		if (delta == 1 || delta == -1) && end-start >= minWordLength {
+			// Sequences starting at either end of the alphabet or digits are tried first.
+			base := characterCardinality(runes[start])
+			if strings.ContainsRune("aAzZ019", runes[start]) {
+				base = 4
+			}
+
+			guesses := base * float64(end-start)
+			if delta < 0 {
+				guesses *= 2
+			}
+
+			matches = append(matches, passwordMatch{start, end, math.Log10(guesses)})
+		}
+
+		// The last character of a run can start the next one.
+		start = end - 1
+	}
+
+	return matches
+}
+
+// characterCardinality returns the size of the class of characters of the rune.
+func characterCardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	default:
+		return 33
+	}
+}
+
+// userInputWords ranks the words of user inputs, like the parts of
+// an email, as the most likely words of a password.
+func userInputWords(userInputs []string) map[string]int {
+	words := make(map[string]int)
+
+	for _, input := range userInputs {
+		input = strings.ToLower(normalizePassword(input))
+		words[input] = 1
+
+		for _, word := range strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len([]rune(word)) >= minWordLength {
+				words[word] = 1
+			}
+		}
+	}
+
+	return words
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// legacyHashPassword hashes a password the way it was stored before the PHC format.
+func legacyHashPassword(password string) string {
+	salt := make([]byte, 32)
+	rand.Read(salt)
+
+	key, _ := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+
+	return fmt.Sprintf("%s.%s", hex.EncodeToString(key), hex.EncodeToString(salt))
+}
+
+func TestPasswordHashing(t *testing.T) {
+	password := "somerandomvalidpassword"
+
+	t.Run("Hashes with argon2id by default", func(t *testing.T) {
+		hashedPassword, err := hashPassword(password)
+		assert.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+		match, err := comparePasswords(hashedPassword, password)
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		match, err = comparePasswords(hashedPassword, "somerandominvalidpassword")
+		assert.NoError(t, err)
+		assert.False(t, match)
+
+		assert.False(t, passwordNeedsRehash(hashedPassword))
+	})
+
+	t.Run("Verifies the other hashers", func(t *testing.T) {
+		scryptPassword, _ := passwordHashers["scrypt"].hash(password)
+		bcryptPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+
+		for _, hashedPassword := range []string{scryptPassword, string(bcryptPassword), legacyHashPassword(password)} {
+			match, err := comparePasswords(hashedPassword, password)
+			assert.NoError(t, err)
+			assert.True(t, match)
+
+			match, err = comparePasswords(hashedPassword, "somerandominvalidpassword")
+			assert.NoError(t, err)
+			assert.False(t, match)
+
+			assert.True(t, passwordNeedsRehash(hashedPassword))
+		}
+	})
+
+	t.Run("Hashes with outdated parameters need a rehash", func(t *testing.T) {
+		outdatedHasher := &argon2idHasher{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+		hashedPassword, _ := outdatedHasher.hash(password)
+
+		match, err := comparePasswords(hashedPassword, password)
+		assert.NoError(t, err)
+		assert.True(t, match)
+
+		assert.True(t, passwordNeedsRehash(hashedPassword))
+	})
+
+	t.Run("Malformed hashes", func(t *testing.T) {
+		for _, hashedPassword := range []string{
+			"",
+			"nodot",
+			"nothex.nothex",
+			"$argon2id",
+			"$argon2id$v=19$m=19456,t=2,p=1$notbase64!$notbase64!",
+			"$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+			"$scrypt$ln=64,r=8,p=1$c2FsdA$aGFzaA",
+			"$md5$c2FsdA$aGFzaA",
+		} {
+			match, err := comparePasswords(hashedPassword, password)
+
+			assert.Error(t, err, hashedPassword)
+			assert.False(t, match)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyPEM(t *testing.T) {
+	t.Run("RSA key pair", func(t *testing.T) {
+		private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+		public, _ := ioutil.ReadFile("../rsa_public_test.pem")
+
+		privateKey, err := ParsePrivateKeyPEM(private)
+		assert.NoError(t, err)
+		assert.IsType(t, &rsa.PrivateKey{}, privateKey)
+
+		publicKey, err := ParsePublicKeyPEM(public)
+		assert.NoError(t, err)
+		assert.Equal(t, privateKey.Public(), publicKey)
+	})
+
+	t.Run("EC key pair", func(t *testing.T) {
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		privateBytes, _ := x509.MarshalECPrivateKey(ecKey)
+		publicBytes, _ := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+
+		privateKey, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateBytes}))
+		assert.NoError(t, err)
+		assert.True(t, ecKey.Equal(privateKey))
+
+		publicKey, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes}))
+		assert.NoError(t, err)
+		assert.True(t, ecKey.PublicKey.Equal(publicKey))
+	})
+
+	t.Run("Ed25519 key pair", func(t *testing.T) {
+		edPublicKey, edKey, _ := ed25519.GenerateKey(rand.Reader)
+		privateBytes, _ := x509.MarshalPKCS8PrivateKey(edKey)
+		publicBytes, _ := x509.MarshalPKIXPublicKey(edPublicKey)
+
+		privateKey, err := ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes}))
+		assert.NoError(t, err)
+		assert.Equal(t, edKey, privateKey)
+
+		publicKey, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes}))
+		assert.NoError(t, err)
+		assert.Equal(t, edPublicKey, publicKey)
+	})
+
+	t.Run("Not a PEM file", func(t *testing.T) {
+		_, err := ParsePrivateKeyPEM([]byte("notapemfile"))
+		assert.Error(t, err)
+
+		_, err = ParsePublicKeyPEM([]byte("notapemfile"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRevocationCacheEntries bounds the memory used by the cache.
+// It is roughly the number of distinct ID tokens seen per cache expiration.
+const maxRevocationCacheEntries = 10000
+
+// minRevocationExpiration is the shortest time a revocation is kept on
+// the denylist, for tokens revoked right before they stop being accepted.
+const minRevocationExpiration = time.Second
+
+// revocationCacheEntry remembers whether an ID token was revoked.
+type revocationCacheEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+// revocationCache keeps denylist lookups for ID tokens in process,
+// so validating a token does not hit Redis on every request.
+// Revoked tokens are remembered until they expire, since a revocation
+// is never undone. Tokens which are not revoked are only remembered
+// for a short time, which bounds how long a revocation made by another
+// instance of the service can go unnoticed.
+type revocationCache struct {
+	mu         sync.Mutex
+	expiration time.Duration
+	entries    map[string]revocationCacheEntry
+}
+
+// newRevocationCache creates a cache which remembers tokens that are not
+// revoked for the provided expiration. A zero expiration disables that part.
+func newRevocationCache(expiration time.Duration) *revocationCache {
+	return &revocationCache{
+		expiration: expiration,
+		entries:    make(map[string]revocationCacheEntry),
+	}
+}
+
+// get returns whether the token is revoked and
+// whether the answer was found in the cache.
+func (c *revocationCache) get(tokenID string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[tokenID]
+
+	if !ok {
+		return false, false
+	}
+
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, tokenID)
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+// set remembers the denylist state of a token
+// which expires at tokenExpiresAt.
+func (c *revocationCache) set(tokenID string, revoked bool, tokenExpiresAt time.Time) {
+	expiresAt := tokenExpiresAt
+
+	if !revoked {
+		if c.expiration <= 0 {
+			return
+		}
+
+		if cacheExpiresAt := time.Now().Add(c.expiration); cacheExpiresAt.Before(expiresAt) {
+			expiresAt = cacheExpiresAt
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxRevocationCacheEntries {
+		c.evict()
+	}
+
+	c.entries[tokenID] = revocationCacheEntry{
+		revoked:   revoked,
+		expiresAt: expiresAt,
+	}
+}
+
+// evict must be called with the lock held. It removes expired entries
+// and, if the cache is still full, the entries closest to expiry until
+// a tenth of the cache is free, whether or not their tokens are revoked.
+// Either answer is on the denylist, so an evicted entry only costs
+// a Redis lookup to get back.
+func (c *revocationCache) evict() {
+	now := time.Now()
+
+	for tokenID, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, tokenID)
+		}
+	}
+
+	if len(c.entries) < maxRevocationCacheEntries {
+		return
+	}
+
+	tokenIDs := make([]string, 0, len(c.entries))
+
+	for tokenID := range c.entries {
+		tokenIDs = append(tokenIDs, tokenID)
+	}
+
+	sort.Slice(tokenIDs, func(i, j int) bool {
+		return c.entries[tokenIDs[i]].expiresAt.Before(c.entries[tokenIDs[j]].expiresAt)
+	})
+
+	for _, tokenID := range tokenIDs[:len(tokenIDs)-maxRevocationCacheEntries*9/10] {
+		delete(c.entries, tokenID)
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationCacheEviction(t *testing.T) {
+	cache := newRevocationCache(time.Minute)
+	now := time.Now()
+
+	// Revocations alone fill the cache, the first ones closest to expiry.
+	for i := 0; i < maxRevocationCacheEntries; i++ {
+		cache.set(fmt.Sprint(i), true, now.Add(time.Hour+time.Duration(i)*time.Second))
+	}
+
+	cache.set("latest", true, now.Add(2*time.Hour))
+
+	assert.LessOrEqual(t, len(cache.entries), maxRevocationCacheEntries)
+
+	_, ok := cache.get("0")
+	assert.False(t, ok)
+
+	revoked, ok := cache.get("latest")
+	assert.True(t, ok)
+	assert.True(t, revoked)
+
+	revoked, ok = cache.get(fmt.Sprint(maxRevocationCacheEntries - 1))
+	assert.True(t, ok)
+	assert.True(t, revoked)
+}
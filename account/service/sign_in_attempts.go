@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+// signInAttemptKey identifies who attempted to sign in
+// and how many attempts in a row may fail before a lock.
+type signInAttemptKey struct {
+	Key         string
+	MaxFailures int
+}
+
+// signInAttemptKeys returns the keys failed sign ins are counted by.
+// Emails are counted apart from IP addresses, so guessing the password of
+// one account from many addresses, and of many accounts from one address,
+// are both slowed down.
+func (s *userService) signInAttemptKeys(email string, ipAddress string) []signInAttemptKey {
+	keys := []signInAttemptKey{
+		{Key: emailAttemptKey(email), MaxFailures: s.SignInMaxFailures},
+	}
+
+	if ipAddress != "" {
+		keys = append(keys, signInAttemptKey{Key: fmt.Sprintf("ip:%s", ipAddress), MaxFailures: s.SignInIPMaxFailures})
+	}
+
+	return keys
+}
+
+// checkSignInLock returns a too many requests error with the longest
+// lock of the keys. It is checked before the password is hashed,
+// so locked attempts don't cost a hash computation.
+func (s *userService) checkSignInLock(ctx context.Context, keys []signInAttemptKey) error {
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		lockedFor, err := s.SignInAttemptRepository.LockedFor(ctx, key.Key)
+
+		if err != nil {
+			return err
+		}
+
+		if lockedFor > retryAfter {
+			retryAfter = lockedFor
+		}
+	}
+
+	if retryAfter > 0 {
+		return apperrors.NewTooManyRequests("Too many failed sign in attempts. Try again later", retryAfter)
+	}
+
+	return nil
+}
+
+// recordSignInFailure counts a failed sign in for the keys. Once more
+// attempts of a key failed than it may, its sign ins are locked for
+// a backoff which doubles with every further failure.
+// The sign in failed anyway, so errors are only logged.
+func (s *userService) recordSignInFailure(ctx context.Context, keys []signInAttemptKey) {
+	for _, key := range keys {
+		failures, err := s.SignInAttemptRepository.AddFailure(ctx, key.Key, s.SignInFailureWindow)
+
+		if err != nil || failures <= key.MaxFailures {
+			continue
+		}
+
+		lockout := s.signInBackoff(failures - key.MaxFailures)
+
+		if err := s.SignInAttemptRepository.Lock(ctx, key.Key, lockout); err == nil {
+			log.Printf("Locked sign ins of: %v for %v after %d failed attempts\n", key.Key, lockout, failures)
+		}
+	}
+}
+
+// resetSignInFailures forgets the failed sign ins of the email which
+// signed in. Failures of the IP address are left to expire, or signing
+// in to an account of one's own would lift the lock of an address
+// guessing the passwords of others.
+func (s *userService) resetSignInFailures(ctx context.Context, email string) {
+	if s.SignInAttemptRepository == nil {
+		return
+	}
+
+	s.SignInAttemptRepository.Reset(ctx, emailAttemptKey(email))
+}
+
+// signInBackoff returns how long sign ins are locked for
+// after failing the number of times over the limit.
+func (s *userService) signInBackoff(excessFailures int) time.Duration {
+	backoff := s.SignInBackoffBase
+
+	for i := 1; i < excessFailures && backoff < s.SignInLockoutMax; i++ {
+		backoff *= 2
+	}
+
+	if backoff > s.SignInLockoutMax {
+		return s.SignInLockoutMax
+	}
+
+	return backoff
+}
+
+// UnlockSignIn lets admins forget the failed sign ins of a user's
+// email and lift its lock.
+func (s *userService) UnlockSignIn(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return err
+	}
+
+	if s.SignInAttemptRepository == nil {
+		return nil
+	}
+
+	return s.SignInAttemptRepository.Reset(ctx, emailAttemptKey(user.Email))
+}
+
+// emailAttemptKey counts attempts of differently cased emails together.
+func emailAttemptKey(email string) string {
+	return fmt.Sprintf("email:%s", strings.ToLower(strings.TrimSpace(email)))
+}
@@ -2,9 +2,13 @@ package service
 
 import (
 	"context"
-	"crypto/rsa"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 	"github.com/yachnytskyi/base-go/account/model"
 	"github.com/yachnytskyi/base-go/account/model/apperrors"
@@ -15,23 +19,38 @@ import (
 // along with keys and secrets forsigning JWTs.
 type tokenService struct {
 	TokenRepository          model.TokenRepository
-	PrivateKey               *rsa.PrivateKey
-	PublicKey                *rsa.PublicKey
-	RefreshSecret            string
+	SecurityEventRepository  model.SecurityEventRepository
+	APIKeyRepository         model.APIKeyRepository
+	KeyRing                  *KeyRing
+	RefreshSecrets           []string
 	IDExpirationSecrets      int64
 	RefreshExpirationSecrets int64
+	SessionMaxAge            time.Duration
+	SessionIdleTimeout       time.Duration
+	RevokeAllOnReuse         bool
+	RevocationCache          *revocationCache
+	IDTokenSettings          *idTokenSettings
 }
 
 // TokenServiceConfig will hold repositories
 // that will eventually be injected
 // into this service layer.
 type TokenServiceConfig struct {
-	TokenRepository          model.TokenRepository
-	PrivateKey               *rsa.PrivateKey
-	PublicKey                *rsa.PublicKey
-	RefreshSecret            string
-	IDExpirationSecrets      int64
-	RefreshExpirationSecrets int64
+	TokenRepository           model.TokenRepository
+	SecurityEventRepository   model.SecurityEventRepository
+	APIKeyRepository          model.APIKeyRepository
+	KeyRing                   *KeyRing
+	RefreshSecrets            []string // Newest first. The first secret signs new refresh tokens.
+	IDExpirationSecrets       int64
+	RefreshExpirationSecrets  int64
+	SessionMaxAge             int64 // Seconds after the sign in a session can no longer be refreshed, 0 for no limit.
+	SessionIdleTimeout        int64 // Seconds after which a session which was not refreshed expires, 0 for no limit.
+	RevokeAllOnReuse          bool  // Revoke all of the user's sessions instead of the token family on reuse.
+	RevocationCacheExpiration int64 // Seconds to remember that an ID token is not revoked.
+	Issuer                    string
+	Audience                  string
+	ProfileClaims             []string // Names of the profile claims added to ID tokens.
+	ClockSkew                 int64    // Seconds of clock difference tolerated when verifying ID tokens.
 }
 
 // NewTokenService is a factory function
@@ -40,35 +59,138 @@ type TokenServiceConfig struct {
 func NewTokenService(c *TokenServiceConfig) model.TokenService {
 	return &tokenService{
 		TokenRepository:          c.TokenRepository,
-		PrivateKey:               c.PrivateKey,
-		PublicKey:                c.PublicKey,
-		RefreshSecret:            c.RefreshSecret,
+		SecurityEventRepository:  c.SecurityEventRepository,
+		APIKeyRepository:         c.APIKeyRepository,
+		KeyRing:                  c.KeyRing,
+		RefreshSecrets:           c.RefreshSecrets,
 		IDExpirationSecrets:      c.IDExpirationSecrets,
 		RefreshExpirationSecrets: c.RefreshExpirationSecrets,
+		SessionMaxAge:            time.Duration(c.SessionMaxAge) * time.Second,
+		SessionIdleTimeout:       time.Duration(c.SessionIdleTimeout) * time.Second,
+		RevokeAllOnReuse:         c.RevokeAllOnReuse,
+		RevocationCache:          newRevocationCache(time.Duration(c.RevocationCacheExpiration) * time.Second),
+		IDTokenSettings: &idTokenSettings{
+			Issuer:        c.Issuer,
+			Audience:      c.Audience,
+			ProfileClaims: c.ProfileClaims,
+			ClockSkew:     time.Duration(c.ClockSkew) * time.Second,
+		},
 	}
 }
 
 // NewPairFromUser creates fresh id and refresh tokens for the current user.
 // If a previous token is included, the previous token
-// is removed from the tokens repository.
-func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, previousTokenID string) (*model.TokenPair, error) {
-	if previousTokenID != "" {
-		if err := s.TokenRepository.DeleteRefreshToken(ctx, user.UserID.String(), previousTokenID); err != nil {
-			log.Printf("Could not delete previous refreshToken for userID: %v, tokenID: %v\n", user.UserID.String(), previousTokenID)
+// is removed from the tokens repository and the new refresh
+// token joins its family. The session holds the client's current
+// metadata which is stored along with the refresh token.
+// A session is not refreshed past its maximum age or after being idle
+// for longer than the idle timeout, so the user must sign in again.
+// A new session which continues an earlier sign in, like the one a user
+// authorized a client in, has the time of that sign in as its CreatedAt.
+func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, previousToken *model.RefreshToken, session *model.Session) (*model.TokenPair, error) {
+	if session == nil {
+		session = &model.Session{}
+	}
+
+	currentTime := time.Now()
+	authTime := currentTime
+
+	if previousToken == nil && !session.CreatedAt.IsZero() {
+		authTime = session.CreatedAt
+
+		if s.SessionMaxAge > 0 && currentTime.Sub(authTime) >= s.SessionMaxAge {
+			log.Printf("Sign in of userID: %v reached the maximum session age\n", user.UserID)
+			return nil, apperrors.NewAuthorization("The session has expired. Please sign in again")
+		}
+	}
+
+	familyID := uuid.Nil
+	storedSession := &model.Session{
+		UserAgent:       session.UserAgent,
+		IP:              session.IP,
+		DeviceName:      session.DeviceName,
+		CreatedAt:       authTime,
+		LastRefreshedAt: currentTime,
+		ClientID:        session.ClientID,
+		Scope:           session.Scope,
+		Nonce:           session.Nonce,
+		AMR:             session.AMR,
+	}
+
+	if previousToken != nil {
+		previousSession, err := s.TokenRepository.DeleteRefreshToken(ctx, user.UserID.String(), previousToken.ID.String())
+
+		if err != nil {
+			log.Printf("Could not delete previous refreshToken for userID: %v, tokenID: %v\n", user.UserID.String(), previousToken.ID)
+
+			var appError *apperrors.Error
+			if errors.As(err, &appError) && appError.Type == apperrors.Authorization {
+				s.handleRefreshTokenReuse(ctx, previousToken)
+			}
 
 			return nil, err
 		}
+
+		familyID = previousToken.FamilyID
+
+		// Keep what we know about the device from the sign in.
+		if previousSession != nil && !previousSession.CreatedAt.IsZero() {
+			storedSession.CreatedAt = previousSession.CreatedAt
+
+			if storedSession.DeviceName == "" {
+				storedSession.DeviceName = previousSession.DeviceName
+			}
+		}
+
+		// What a client was authorized for can't change on refresh.
+		if previousSession != nil {
+			storedSession.ClientID = previousSession.ClientID
+			storedSession.Scope = previousSession.Scope
+			storedSession.Nonce = previousSession.Nonce
+			storedSession.AMR = previousSession.AMR
+		}
+
+		// The time of the sign in is carried by the token itself,
+		// so the session limits hold even without the metadata.
+		if !previousToken.AuthTime.IsZero() {
+			storedSession.CreatedAt = previousToken.AuthTime
+		}
+
+		if err := s.checkSessionLimits(previousToken, storedSession.CreatedAt, previousSession, currentTime); err != nil {
+			return nil, err
+		}
 	}
 
+	// Tokens issued before families were introduced start a new family.
+	if familyID == uuid.Nil {
+		newFamilyID, err := uuid.NewRandom()
+
+		if err != nil {
+			log.Printf("Error generating refresh token familyID for userID: %v. Error: %v\n", user.UserID, err.Error())
+			return nil, apperrors.NewInternal()
+		}
+
+		familyID = newFamilyID
+	}
+
+	storedSession.ID = familyID
+
 	// No need to use a repository for idToken as it is unrelated to any data source.
-	idToken, err := generateIDToken(user, s.PrivateKey, s.IDExpirationSecrets)
+	// The session was created when the user signed in.
+	idToken, err := generateIDToken(user, storedSession, s.KeyRing.signingKey(), s.IDTokenSettings, s.IDExpirationSecrets)
 
 	if err != nil {
 		log.Printf("Error generating idToken for userID: %v. Error: %v\n", user.UserID, err.Error())
 		return nil, apperrors.NewInternal()
 	}
 
-	refreshToken, err := generateRefreshToken(user.UserID, s.RefreshSecret, s.RefreshExpirationSecrets)
+	if len(s.RefreshSecrets) == 0 {
+		log.Printf("Error generating refreshToken for userID: %v. Error: no refresh secret configured\n", user.UserID)
+		return nil, apperrors.NewInternal()
+	}
+
+	// The newest secret signs, the others are only used for verification.
+	refreshToken, err := generateRefreshToken(user.UserID, familyID, storedSession.CreatedAt, s.RefreshSecrets[0], s.refreshExpiration(storedSession.CreatedAt, currentTime))
 
 	if err != nil {
 		log.Printf("Error generating refreshToken for userID: %v. Error: %v\n", user.UserID, err.Error())
@@ -76,41 +198,339 @@ func (s *tokenService) NewPairFromUser(ctx context.Context, user *model.User, pr
 	}
 
 	// Set freshly minted refresh token to valid list.
-	if err := s.TokenRepository.SetRefreshToken(ctx, user.UserID.String(), refreshToken.ID.String(), refreshToken.ExpiresIn); err != nil {
+	if err := s.TokenRepository.SetRefreshToken(ctx, user.UserID.String(), refreshToken.ID.String(), storedSession, refreshToken.ExpiresIn); err != nil {
 		log.Printf("Error storing tokenID for userID: %v. Error: %v\n", user.UserID, err.Error())
 		return nil, apperrors.NewInternal()
 	}
 
 	return &model.TokenPair{
 		IDToken:      model.IDToken{SignedString: idToken},
-		RefreshToken: model.RefreshToken{SignedString: refreshToken.SignedString, ID: refreshToken.ID, UserID: user.UserID},
+		RefreshToken: model.RefreshToken{SignedString: refreshToken.SignedString, ID: refreshToken.ID, UserID: user.UserID, FamilyID: familyID, AuthTime: storedSession.CreatedAt, ExpiresIn: refreshToken.ExpiresIn},
 	}, nil
 }
 
+// checkSessionLimits rejects refreshing a session which is older than the maximum
+// session age, or which was not refreshed within the idle timeout.
+func (s *tokenService) checkSessionLimits(previousToken *model.RefreshToken, authTime time.Time, previousSession *model.Session, currentTime time.Time) error {
+	if s.SessionMaxAge > 0 && currentTime.Sub(authTime) >= s.SessionMaxAge {
+		log.Printf("Session of userID: %v, familyID: %v reached its maximum age\n", previousToken.UserID, previousToken.FamilyID)
+		return apperrors.NewAuthorization("The session has expired. Please sign in again")
+	}
+
+	if s.SessionIdleTimeout > 0 && previousSession != nil && !previousSession.LastRefreshedAt.IsZero() &&
+		currentTime.Sub(previousSession.LastRefreshedAt) >= s.SessionIdleTimeout {
+		log.Printf("Session of userID: %v, familyID: %v expired after being idle\n", previousToken.UserID, previousToken.FamilyID)
+		return apperrors.NewAuthorization("The session has expired. Please sign in again")
+	}
+
+	return nil
+}
+
+// refreshExpiration returns the seconds a new refresh token of a session is valid for.
+// Each rotation starts a new window, which is no longer than the idle timeout
+// and ends no later than the session's maximum age.
+func (s *tokenService) refreshExpiration(authTime time.Time, currentTime time.Time) int64 {
+	expiration := time.Duration(s.RefreshExpirationSecrets) * time.Second
+
+	if s.SessionIdleTimeout > 0 && s.SessionIdleTimeout < expiration {
+		expiration = s.SessionIdleTimeout
+	}
+
+	if s.SessionMaxAge > 0 {
+		if remaining := authTime.Add(s.SessionMaxAge).Sub(currentTime); remaining < expiration {
+			expiration = remaining
+		}
+	}
+
+	// Round up, so a session which is about to end still gets a valid token.
+	return int64((expiration + time.Second - 1) / time.Second)
+}
+
+// handleRefreshTokenReuse is called when a validly signed refresh token
+// is no longer in the repository. If other tokens of its family are still
+// active, the token was already rotated and is being replayed, so we treat
+// it as stolen and revoke the family (or all of the user's sessions).
+func (s *tokenService) handleRefreshTokenReuse(ctx context.Context, previousToken *model.RefreshToken) {
+	// Tokens issued before families were introduced can't be traced.
+	if previousToken.FamilyID == uuid.Nil {
+		return
+	}
+
+	userID := previousToken.UserID.String()
+	revokedCount, err := s.TokenRepository.DeleteRefreshTokenFamily(ctx, userID, previousToken.FamilyID.String())
+
+	if err != nil {
+		log.Printf("Could not revoke refresh token family for userID: %v, familyID: %v. Error: %v\n", userID, previousToken.FamilyID, err)
+		return
+	}
+
+	// Nothing left of the family means the user signed out or the
+	// session expired, which is not a sign of theft.
+	if revokedCount == 0 {
+		return
+	}
+
+	log.Printf("Refresh token reuse detected for userID: %v, familyID: %v, tokenID: %v\n", userID, previousToken.FamilyID, previousToken.ID)
+
+	details := fmt.Sprintf("Refresh token %v of family %v was presented after rotation.", previousToken.ID, previousToken.FamilyID)
+
+	if s.RevokeAllOnReuse {
+		if err := s.TokenRepository.DeleteUserRefreshTokens(ctx, userID); err != nil {
+			log.Printf("Could not revoke refresh tokens for userID: %v. Error: %v\n", userID, err)
+		}
+
+		details += " All sessions were revoked."
+	}
+
+	event := &model.SecurityEvent{
+		UserID:  previousToken.UserID,
+		Type:    model.RefreshTokenReuse,
+		Details: details,
+	}
+
+	if err := s.SecurityEventRepository.Create(ctx, event); err != nil {
+		log.Printf("Could not record security event for userID: %v. Error: %v\n", userID, err)
+	}
+}
+
 // SignOut reaches out to the repository layer to delete all valid tokens for a user.
 func (s *tokenService) SignOut(ctx context.Context, userID uuid.UUID) error {
 	return s.TokenRepository.DeleteUserRefreshTokens(ctx, userID.String())
 }
 
-// ValidateIDToken validates the id token jwt string.
+// Sessions returns the user's signed in devices.
+func (s *tokenService) Sessions(ctx context.Context, userID uuid.UUID) ([]*model.Session, error) {
+	return s.TokenRepository.GetUserSessions(ctx, userID.String())
+}
+
+// DeleteSession signs a single device out by revoking
+// the refresh token family of the session.
+func (s *tokenService) DeleteSession(ctx context.Context, userID uuid.UUID, sessionID uuid.UUID) error {
+	deletedCount, err := s.TokenRepository.DeleteRefreshTokenFamily(ctx, userID.String(), sessionID.String())
+
+	if err != nil {
+		return err
+	}
+
+	if deletedCount == 0 {
+		return apperrors.NewNotFound("session", sessionID.String())
+	}
+
+	return nil
+}
+
+// RevokeIDToken adds a valid ID token, or access token
+// of a machine client, to the denylist, so it is rejected before it expires.
+func (s *tokenService) RevokeIDToken(ctx context.Context, tokenString string) error {
+	var claims *jwt.StandardClaims
+
+	if idTokenClaims, err := validateIDToken(tokenString, s.KeyRing, s.IDTokenSettings); err == nil {
+		claims = &idTokenClaims.StandardClaims
+	} else if serviceTokenClaims, serviceErr := validateServiceToken(tokenString, s.KeyRing, s.IDTokenSettings); serviceErr == nil {
+		claims = &serviceTokenClaims.StandardClaims
+	} else {
+		log.Printf("Unable to validate or parse idToken - Error: %v\n", err)
+		return apperrors.NewAuthorization("Unable to verify the user from the idToken")
+	}
+
+	// Tokens issued before revocation was introduced have no ID.
+	if claims.Id == "" {
+		return apperrors.NewBadRequest("The idToken has no ID and cannot be revoked")
+	}
+
+	acceptedUntil := s.acceptedUntil(claims)
+	expiresIn := time.Until(acceptedUntil)
+
+	// A token revoked within the clock skew after its expiry is still
+	// accepted, and Redis would keep a key with a negative TTL forever.
+	if expiresIn < minRevocationExpiration {
+		expiresIn = minRevocationExpiration
+	}
+
+	if err := s.TokenRepository.RevokeIDToken(ctx, claims.Id, expiresIn); err != nil {
+		log.Printf("Failed to revoke idToken for userID: %v. Error: %v\n", claims.Subject, err)
+		return err
+	}
+
+	s.RevocationCache.set(claims.Id, true, acceptedUntil)
+
+	return nil
+}
+
+// acceptedUntil returns when a token stops being accepted, which is
+// its expiry plus the clock skew tolerated when verifying it.
+func (s *tokenService) acceptedUntil(claims *jwt.StandardClaims) time.Time {
+	return time.Unix(claims.ExpiresAt, 0).Add(s.IDTokenSettings.ClockSkew)
+}
+
+// ValidateIDToken validates the id token jwt string
+// and checks that it has not been revoked.
 // It returns the user extract from the IDTokenCustomClaims.
-func (s *tokenService) ValidateIDToken(tokenString string) (*model.User, error) {
-	claims, err := validateIDToken(tokenString, s.PublicKey) // Uses public RSA key.
+// Only tokens of our own sign in are accepted, as tokens of OAuth
+// clients don't carry our audience, so clients can't call our APIs.
+func (s *tokenService) ValidateIDToken(ctx context.Context, tokenString string) (*model.User, error) {
+	claims, user, err := s.validateUserToken(ctx, tokenString)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.AuthorizedParty != "" {
+		log.Printf("Token of OAuth client: %v used as an ID token for userID: %v\n", claims.AuthorizedParty, user.UserID)
+		return nil, apperrors.NewAuthorization("Unable to verify the user from the idToken")
+	}
+
+	return user, nil
+}
+
+// ValidateAccessToken validates an ID token of our own sign in, or one
+// issued to an OAuth client, which is also the client's access token,
+// and checks that it has not been revoked. The userinfo and
+// introspection endpoints accept both.
+func (s *tokenService) ValidateAccessToken(ctx context.Context, tokenString string) (*model.User, error) {
+	_, user, err := s.validateUserToken(ctx, tokenString)
+
+	return user, err
+}
+
+// validateUserToken returns the claims and the user of a valid token
+// of a user, which has not been revoked.
+func (s *tokenService) validateUserToken(ctx context.Context, tokenString string) (*idTokenCustomClaims, *model.User, error) {
+	claims, err := validateIDToken(tokenString, s.KeyRing, s.IDTokenSettings) // Uses public RSA keys.
 
 	// We will just return unauthorized error in all instances of failing to verify the user.
 	if err != nil {
 		log.Printf("Unable to validate or parse idToken - Error: %v\n", err)
-		return nil, apperrors.NewAuthorization("Unable to verify the user from the idToken")
+		return nil, nil, apperrors.NewAuthorization("Unable to verify the user from the idToken")
+	}
+
+	user, err := claims.user()
+
+	if err != nil {
+		log.Printf("Unable to read the user from idToken - Error: %v\n", err)
+		return nil, nil, apperrors.NewAuthorization("Unable to verify the user from the idToken")
+	}
+
+	// Tokens issued before revocation was introduced have no ID
+	// and can't be on the denylist.
+	if claims.Id == "" {
+		return claims, user, nil
+	}
+
+	revoked, err := s.isIDTokenRevoked(ctx, claims.Id, s.acceptedUntil(&claims.StandardClaims))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if revoked {
+		log.Printf("Revoked idToken used for userID: %v\n", user.UserID)
+		return nil, nil, apperrors.NewAuthorization("The idToken has been revoked")
+	}
+
+	return claims, user, nil
+}
+
+// NewServiceToken issues a short lived access token to a machine client.
+// It expires like ID tokens do, and the client authenticates again for a new one.
+func (s *tokenService) NewServiceToken(ctx context.Context, clientID string, scopes []string) (*model.AccessToken, error) {
+	signedString, err := generateServiceToken(clientID, scopes, s.KeyRing.signingKey(), s.IDTokenSettings, s.IDExpirationSecrets)
+
+	if err != nil {
+		log.Printf("Error generating service token for clientID: %v. Error: %v\n", clientID, err.Error())
+		return nil, apperrors.NewInternal()
+	}
+
+	return &model.AccessToken{
+		SignedString: signedString,
+		ExpiresIn:    time.Duration(s.IDExpirationSecrets) * time.Second,
+	}, nil
+}
+
+// ValidateServiceToken validates the access token of a machine client
+// and checks that it has not been revoked, like ValidateIDToken.
+func (s *tokenService) ValidateServiceToken(ctx context.Context, tokenString string) (*model.ServicePrincipal, error) {
+	claims, err := validateServiceToken(tokenString, s.KeyRing, s.IDTokenSettings)
+
+	if err != nil {
+		log.Printf("Unable to validate or parse service token - Error: %v\n", err)
+		return nil, apperrors.NewAuthorization("Unable to verify the client from the access token")
+	}
+
+	revoked, err := s.isIDTokenRevoked(ctx, claims.Id, s.acceptedUntil(&claims.StandardClaims))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		log.Printf("Revoked service token used for clientID: %v\n", claims.ClientID)
+		return nil, apperrors.NewAuthorization("The access token has been revoked")
+	}
+
+	return &model.ServicePrincipal{
+		ClientID: claims.ClientID,
+		Scopes:   strings.Fields(claims.Scope),
+		TokenID:  claims.Id,
+	}, nil
+}
+
+// ValidateAPIKey returns the API key if it exists and has not expired.
+// When the key was last used is recorded at most once a minute,
+// so requests made with a key don't all write to the database.
+func (s *tokenService) ValidateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	apiKey, err := s.APIKeyRepository.FindByHash(ctx, hashAPIKey(key))
+
+	var appError *apperrors.Error
+	if errors.As(err, &appError) && appError.Type == apperrors.NotFound {
+		return nil, apperrors.NewAuthorization("The API key is invalid")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if apiKey.IsExpired(now) {
+		log.Printf("Expired API key: %v used for userID: %v\n", apiKey.KeyID, apiKey.UserID)
+		return nil, apperrors.NewAuthorization("The API key has expired")
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedResolution {
+		// Not being able to record the use shouldn't fail the request.
+		if err := s.APIKeyRepository.UpdateLastUsed(ctx, apiKey.KeyID, now); err == nil {
+			apiKey.LastUsedAt = &now
+		}
 	}
 
-	return claims.User, nil
+	return apiKey, nil
+}
+
+// isIDTokenRevoked checks the denylist, asking the repository
+// only when the answer isn't in the in-process cache.
+func (s *tokenService) isIDTokenRevoked(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	if revoked, ok := s.RevocationCache.get(tokenID); ok {
+		return revoked, nil
+	}
+
+	revoked, err := s.TokenRepository.IsIDTokenRevoked(ctx, tokenID)
+
+	if err != nil {
+		log.Printf("Unable to check the denylist for idToken: %v. Error: %v\n", tokenID, err)
+		return false, err
+	}
+
+	s.RevocationCache.set(tokenID, revoked, expiresAt)
+
+	return revoked, nil
 }
 
 // ValidateRefreshToken checks to make sure the JWT provided by a string is valid
 // and returns a RefreshToken if valid.
 func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshToken, error) {
 	// Validate actual JWT with string a secret.
-	claims, err := validateRefreshToken(tokenString, s.RefreshSecret)
+	claims, err := validateRefreshToken(tokenString, s.RefreshSecrets)
 
 	// We will just return unauthorized error in all instances of failing to verify the user.
 	if err != nil {
@@ -127,9 +547,21 @@ func (s *tokenService) ValidateRefreshToken(tokenString string) (*model.RefreshT
 		return nil, apperrors.NewAuthorization("Unable to verify user from refresh token")
 	}
 
-	return &model.RefreshToken{
+	refreshToken := &model.RefreshToken{
 		SignedString: tokenString,
 		ID:           tokenUUID,
 		UserID:       claims.UserID,
-	}, nil
+		FamilyID:     claims.FamilyID,
+	}
+
+	if claims.AuthTime != 0 {
+		refreshToken.AuthTime = time.Unix(claims.AuthTime, 0)
+	}
+
+	return refreshToken, nil
+}
+
+// JWKS returns the public keys used to verify ID tokens.
+func (s *tokenService) JWKS() *model.JSONWebKeySet {
+	return s.KeyRing.JWKS()
 }
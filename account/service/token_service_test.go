@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"testing"
@@ -10,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/yachnytskyi/base-go/account/jwa"
 	"github.com/yachnytskyi/base-go/account/model"
 	"github.com/yachnytskyi/base-go/account/model/apperrors"
 	"github.com/yachnytskyi/base-go/account/model/mocks"
@@ -24,6 +28,7 @@ func TestNewPairFromUser(t *testing.T) {
 	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
 	public, _ := ioutil.ReadFile("../rsa_public_test.pem")
 	publicKey, _ := jwt.ParseRSAPublicKeyFromPEM(public)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
 	secret := "anothersomerandomtestsecret"
 
 	mockTokenRepository := new(mocks.MockTokenRepository)
@@ -31,20 +36,23 @@ func TestNewPairFromUser(t *testing.T) {
 	// Instantiate a common token service to be used by all tests.
 	tokenService := NewTokenService(&TokenServiceConfig{
 		TokenRepository:          mockTokenRepository,
-		PrivateKey:               privateKey,
-		PublicKey:                publicKey,
-		RefreshSecret:            secret,
+		KeyRing:                  keyRing,
+		RefreshSecrets:           []string{secret},
 		IDExpirationSecrets:      idExpiration,
 		RefreshExpirationSecrets: refreshExpiration,
+		Issuer:                   "https://accounts.test",
+		Audience:                 "web",
+		ProfileClaims:            []string{"name", "website"},
 	})
 
 	// Include password to make sure it is not serialized
 	// since json tag is "-".
 	userID, _ := uuid.NewRandom()
 	user := &model.User{
-		UserID:   userID,
-		Email:    "kostya@kostya.com",
-		Password: "somerandompassword",
+		UserID:        userID,
+		Email:         "kostya@kostya.com",
+		EmailVerified: true,
+		Password:      "somerandompassword",
 	}
 
 	// Setup mock call responses in setup before t.Run statements.
@@ -54,12 +62,20 @@ func TestNewPairFromUser(t *testing.T) {
 		Email:    "failed@failed.com",
 		Password: "somerfailedpassword",
 	}
-	previousID := "a_previous_tokenID"
+	previousTokenID, _ := uuid.NewRandom()
+	previousFamilyID, _ := uuid.NewRandom()
+	previousToken := &model.RefreshToken{
+		ID:       previousTokenID,
+		UserID:   user.UserID,
+		FamilyID: previousFamilyID,
+	}
+	previousID := previousTokenID.String()
 
 	setSuccessArguments := mock.Arguments{
 		mock.AnythingOfType("*context.emptyCtx"),
 		user.UserID.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.Session"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
@@ -67,6 +83,7 @@ func TestNewPairFromUser(t *testing.T) {
 		mock.AnythingOfType("*context.emptyCtx"),
 		userIDErrorCase.String(),
 		mock.AnythingOfType("string"),
+		mock.AnythingOfType("*model.Session"),
 		mock.AnythingOfType("time.Duration"),
 	}
 
@@ -79,11 +96,11 @@ func TestNewPairFromUser(t *testing.T) {
 	// Mock call argument/responses.
 	mockTokenRepository.On("SetRefreshToken", setSuccessArguments...).Return(nil)
 	mockTokenRepository.On("SetRefreshToken", setErrorArguments...).Return(fmt.Errorf("Error setting refresh token"))
-	mockTokenRepository.On("DeleteRefreshToken", deleteWithPreviousIDArguments...).Return(nil)
+	mockTokenRepository.On("DeleteRefreshToken", deleteWithPreviousIDArguments...).Return(nil, nil)
 
 	t.Run("Returns a token pair with proper values", func(t *testing.T) {
-		ctx := context.Background()                                           // Updated from context.TODO().
-		tokenPair, err := tokenService.NewPairFromUser(ctx, user, previousID) // Replaced "" with previousID from setup.
+		ctx := context.Background()                                                   // Updated from context.TODO().
+		tokenPair, err := tokenService.NewPairFromUser(ctx, user, previousToken, nil) // Replaced nil with previousToken from setup.
 		assert.NoError(t, err)
 
 		// SetRefreshToken should be called with setSuccessArguments.
@@ -98,30 +115,23 @@ func TestNewPairFromUser(t *testing.T) {
 		// simpler to use jwt library which is already imported.
 		idTokenClaims := &idTokenCustomClaims{}
 
-		_, err = jwt.ParseWithClaims(tokenPair.IDToken.SignedString, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
+		idToken, err := jwt.ParseWithClaims(tokenPair.IDToken.SignedString, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
 			return publicKey, nil
 		})
 
 		assert.NoError(t, err)
+		assert.Equal(t, keyRing.signingKey().ID, idToken.Header["kid"])
 
 		// Assert claims on idToken.
-		expectedClaims := []interface{}{
-			user.UserID,
-			user.Email,
-			user.Username,
-			user.ImageURL,
-			user.Website,
-		}
-		actualIDClaims := []interface{}{
-			idTokenClaims.User.UserID,
-			idTokenClaims.User.Email,
-			idTokenClaims.User.Username,
-			idTokenClaims.User.ImageURL,
-			idTokenClaims.User.Website,
-		}
-
-		assert.ElementsMatch(t, expectedClaims, actualIDClaims)
-		assert.Empty(t, idTokenClaims.User.Password) // Password should never be encoded to json.
+		assert.Equal(t, user.UserID.String(), idTokenClaims.Subject)
+		assert.Equal(t, "https://accounts.test", idTokenClaims.Issuer)
+		assert.Equal(t, model.Audience{"web"}, idTokenClaims.Audience)
+		assert.Equal(t, user.Email, idTokenClaims.Email)
+		assert.True(t, idTokenClaims.EmailVerified)
+		assert.Equal(t, user.Username, idTokenClaims.Name)
+		assert.Equal(t, user.Website, idTokenClaims.Website)
+		assert.Empty(t, idTokenClaims.Picture) // Only configured profile claims are included.
+		assert.WithinDuration(t, time.Now(), time.Unix(idTokenClaims.AuthTime, 0), 5*time.Second)
 
 		expiresAt := time.Unix(idTokenClaims.StandardClaims.ExpiresAt, 0)
 		expectedExpiresAt := time.Now().Add(time.Duration(idExpiration) * time.Second)
@@ -137,6 +147,8 @@ func TestNewPairFromUser(t *testing.T) {
 		// Assert claims on a refresh token.
 		assert.NoError(t, err)
 		assert.Equal(t, user.UserID, refreshTokenClaims.UserID)
+		assert.Equal(t, previousFamilyID, refreshTokenClaims.FamilyID) // Rotated tokens stay in the same family.
+		assert.Equal(t, previousFamilyID, tokenPair.RefreshToken.FamilyID)
 
 		expiresAt = time.Unix(refreshTokenClaims.StandardClaims.ExpiresAt, 0)
 		expectedExpiresAt = time.Now().Add(time.Duration(refreshExpiration) * time.Second)
@@ -145,7 +157,7 @@ func TestNewPairFromUser(t *testing.T) {
 
 	t.Run("Error setting refresh token", func(t *testing.T) {
 		ctx := context.Background()
-		_, err := tokenService.NewPairFromUser(ctx, userErrorCase, "")
+		_, err := tokenService.NewPairFromUser(ctx, userErrorCase, nil, nil)
 		assert.Error(t, err) // Should return an error.
 
 		// SetRefreshToken should be called with setErrorArguments.
@@ -154,14 +166,15 @@ func TestNewPairFromUser(t *testing.T) {
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
 
-	t.Run("Empty string provided for previousID", func(t *testing.T) {
+	t.Run("Nil provided for previousToken", func(t *testing.T) {
 		ctx := context.Background()
-		_, err := tokenService.NewPairFromUser(ctx, user, "")
+		tokenPair, err := tokenService.NewPairFromUser(ctx, user, nil, nil)
 		assert.NoError(t, err)
+		assert.NotEqual(t, uuid.Nil, tokenPair.RefreshToken.FamilyID) // A new sign in starts a new family.
 
 		// SetRefreshToken should be called with setSuccessArguments.
 		mockTokenRepository.AssertCalled(t, "SetRefreshToken", setSuccessArguments...)
-		// DeleteRefreshToken should not be called since previousID is "".
+		// DeleteRefreshToken should not be called since previousToken is nil.
 		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshToken")
 	})
 
@@ -172,18 +185,22 @@ func TestNewPairFromUser(t *testing.T) {
 			UserID: userID,
 		}
 
-		tokenIDNotInRepo := "not_in_token_repo"
+		tokenIDNotInRepo, _ := uuid.NewRandom()
+		tokenNotInRepo := &model.RefreshToken{
+			ID:     tokenIDNotInRepo,
+			UserID: user.UserID,
+		}
 
 		deleteArgs := mock.Arguments{
 			ctx,
 			user.UserID.String(),
-			tokenIDNotInRepo,
+			tokenIDNotInRepo.String(),
 		}
 
 		mockError := apperrors.NewAuthorization("Invalid refresh token")
-		mockTokenRepository.On("DeleteRefreshToken", deleteArgs...).Return(mockError)
+		mockTokenRepository.On("DeleteRefreshToken", deleteArgs...).Return(nil, mockError)
 
-		_, err := tokenService.NewPairFromUser(ctx, user, tokenIDNotInRepo)
+		_, err := tokenService.NewPairFromUser(ctx, user, tokenNotInRepo, nil)
 		assert.Error(t, err)
 
 		appError, ok := err.(*apperrors.Error)
@@ -195,6 +212,318 @@ func TestNewPairFromUser(t *testing.T) {
 	})
 }
 
+func TestRefreshTokenReuse(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newRotatedToken := func() *model.RefreshToken {
+		tokenID, _ := uuid.NewRandom()
+		familyID, _ := uuid.NewRandom()
+
+		return &model.RefreshToken{
+			ID:       tokenID,
+			UserID:   userID,
+			FamilyID: familyID,
+		}
+	}
+
+	mockError := apperrors.NewAuthorization("Invalid refresh token")
+
+	t.Run("Revokes the family of a reused token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockSecurityEventRepository := new(mocks.MockSecurityEventRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:         mockTokenRepository,
+			SecurityEventRepository: mockSecurityEventRepository,
+			KeyRing:                 keyRing,
+		})
+
+		reusedToken := newRotatedToken()
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), reusedToken.ID.String()).Return(nil, mockError)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), reusedToken.FamilyID.String()).Return(int64(1), nil)
+		mockSecurityEventRepository.On("Create", mock.Anything, mock.MatchedBy(func(event *model.SecurityEvent) bool {
+			return event.UserID == userID && event.Type == model.RefreshTokenReuse
+		})).Return(nil)
+
+		_, err := tokenService.NewPairFromUser(context.Background(), user, reusedToken, nil)
+		assert.EqualError(t, err, mockError.Error())
+
+		mockTokenRepository.AssertExpectations(t)
+		mockSecurityEventRepository.AssertExpectations(t)
+		mockTokenRepository.AssertNotCalled(t, "DeleteUserRefreshTokens", mock.Anything, mock.Anything)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revokes all sessions when configured", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockSecurityEventRepository := new(mocks.MockSecurityEventRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:         mockTokenRepository,
+			SecurityEventRepository: mockSecurityEventRepository,
+			KeyRing:                 keyRing,
+			RevokeAllOnReuse:        true,
+		})
+
+		reusedToken := newRotatedToken()
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), reusedToken.ID.String()).Return(nil, mockError)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), reusedToken.FamilyID.String()).Return(int64(1), nil)
+		mockTokenRepository.On("DeleteUserRefreshTokens", mock.Anything, userID.String()).Return(nil)
+		mockSecurityEventRepository.On("Create", mock.Anything, mock.AnythingOfType("*model.SecurityEvent")).Return(nil)
+
+		_, err := tokenService.NewPairFromUser(context.Background(), user, reusedToken, nil)
+		assert.EqualError(t, err, mockError.Error())
+
+		mockTokenRepository.AssertExpectations(t)
+		mockSecurityEventRepository.AssertExpectations(t)
+	})
+
+	t.Run("Signed out family is not reuse", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockSecurityEventRepository := new(mocks.MockSecurityEventRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:         mockTokenRepository,
+			SecurityEventRepository: mockSecurityEventRepository,
+			KeyRing:                 keyRing,
+		})
+
+		signedOutToken := newRotatedToken()
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), signedOutToken.ID.String()).Return(nil, mockError)
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), signedOutToken.FamilyID.String()).Return(int64(0), nil)
+
+		_, err := tokenService.NewPairFromUser(context.Background(), user, signedOutToken, nil)
+		assert.EqualError(t, err, mockError.Error())
+
+		mockTokenRepository.AssertExpectations(t)
+		mockSecurityEventRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Repository failure is not reuse", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockSecurityEventRepository := new(mocks.MockSecurityEventRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:         mockTokenRepository,
+			SecurityEventRepository: mockSecurityEventRepository,
+			KeyRing:                 keyRing,
+		})
+
+		token := newRotatedToken()
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), token.ID.String()).Return(nil, apperrors.NewInternal())
+
+		_, err := tokenService.NewPairFromUser(context.Background(), user, token, nil)
+		assert.Error(t, err)
+
+		mockTokenRepository.AssertNotCalled(t, "DeleteRefreshTokenFamily", mock.Anything, mock.Anything, mock.Anything)
+		mockSecurityEventRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	t.Run("Stores client metadata with a new sign in", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+			KeyRing:         keyRing,
+			RefreshSecrets:  []string{"anothersomerandomtestsecret"},
+		})
+
+		client := &model.Session{
+			UserAgent:  "Mozilla/5.0",
+			IP:         "10.0.0.1",
+			DeviceName: "Kostya's phone",
+		}
+
+		var storedSession *model.Session
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				storedSession = args.Get(3).(*model.Session)
+			}).Return(nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, nil, client)
+		assert.NoError(t, err)
+
+		assert.Equal(t, tokenPair.RefreshToken.FamilyID, storedSession.ID)
+		assert.Equal(t, client.UserAgent, storedSession.UserAgent)
+		assert.Equal(t, client.IP, storedSession.IP)
+		assert.Equal(t, client.DeviceName, storedSession.DeviceName)
+		assert.WithinDuration(t, time.Now(), storedSession.CreatedAt, 5*time.Second)
+		assert.Equal(t, storedSession.CreatedAt, storedSession.LastRefreshedAt)
+	})
+
+	t.Run("Keeps sign in metadata across rotation", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+			KeyRing:         keyRing,
+			RefreshSecrets:  []string{"anothersomerandomtestsecret"},
+		})
+
+		previousTokenID, _ := uuid.NewRandom()
+		familyID, _ := uuid.NewRandom()
+		previousToken := &model.RefreshToken{
+			ID:       previousTokenID,
+			UserID:   userID,
+			FamilyID: familyID,
+		}
+		previousSession := &model.Session{
+			ID:              familyID,
+			UserAgent:       "Mozilla/5.0",
+			IP:              "10.0.0.1",
+			DeviceName:      "Kostya's phone",
+			CreatedAt:       time.Now().Add(-time.Hour),
+			LastRefreshedAt: time.Now().Add(-time.Minute),
+			ClientID:        "spa",
+			Scope:           "profile",
+			AMR:             []string{model.AMRPassword, model.AMROTP},
+		}
+
+		var storedSession *model.Session
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), previousTokenID.String()).Return(previousSession, nil)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				storedSession = args.Get(3).(*model.Session)
+			}).Return(nil)
+
+		_, err := tokenService.NewPairFromUser(context.Background(), user, previousToken, &model.Session{
+			UserAgent: "Mozilla/5.0",
+			IP:        "10.0.0.2",
+			ClientID:  "another",
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(t, familyID, storedSession.ID)
+		assert.Equal(t, previousSession.ClientID, storedSession.ClientID)
+		assert.Equal(t, previousSession.Scope, storedSession.Scope)
+		assert.Equal(t, previousSession.AMR, storedSession.AMR)
+		assert.Equal(t, "10.0.0.2", storedSession.IP)
+		assert.Equal(t, previousSession.DeviceName, storedSession.DeviceName)
+		assert.Equal(t, previousSession.CreatedAt, storedSession.CreatedAt)
+		assert.WithinDuration(t, time.Now(), storedSession.LastRefreshedAt, 5*time.Second)
+	})
+
+	t.Run("ID token of an OAuth client holds the allowed claims", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+			KeyRing:         keyRing,
+			RefreshSecrets:  []string{"anothersomerandomtestsecret"},
+			Issuer:          "https://accounts.test",
+			Audience:        "web",
+			ProfileClaims:   []string{"name"},
+		})
+
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).Return(nil)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+		profileUser := &model.User{
+			UserID:   userID,
+			Email:    "kostya@kostya.com",
+			Username: "Kostya Kostyan",
+		}
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), profileUser, nil, &model.Session{
+			ClientID: "grafana",
+			Scope:    "openid profile",
+			Nonce:    "somenonce",
+		})
+		assert.NoError(t, err)
+
+		claims := unverifiedIDTokenClaims(tokenPair.IDToken.SignedString)
+		assert.Equal(t, model.Audience{"grafana"}, claims.Audience)
+		assert.Equal(t, "grafana", claims.AuthorizedParty)
+		assert.Equal(t, "openid profile", claims.Scope)
+		assert.Equal(t, "somenonce", claims.Nonce)
+		assert.Equal(t, profileUser.Username, claims.Name)
+		assert.Empty(t, claims.Email) // The email scope was not allowed.
+
+		// Userinfo and introspection accept the token, our APIs don't.
+		validatedUser, err := tokenService.ValidateAccessToken(context.Background(), tokenPair.IDToken.SignedString)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, validatedUser.UserID)
+
+		_, err = tokenService.ValidateIDToken(context.Background(), tokenPair.IDToken.SignedString)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+	})
+
+	t.Run("ID token records how the user signed in", func(t *testing.T) {
+		session := &model.Session{
+			CreatedAt: time.Now(),
+			AMR:       []string{model.AMRPassword, model.AMROTP},
+		}
+
+		signedString, err := generateIDToken(user, session, keyRing.signingKey(), &idTokenSettings{}, 60)
+		assert.NoError(t, err)
+
+		claims := unverifiedIDTokenClaims(signedString)
+		assert.Equal(t, []string{"pwd", "otp"}, claims.AMR)
+	})
+
+	t.Run("Lists the user's sessions", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+		})
+
+		sessionID, _ := uuid.NewRandom()
+		mockSessions := []*model.Session{{ID: sessionID, DeviceName: "Kostya's phone"}}
+		mockTokenRepository.On("GetUserSessions", mock.Anything, userID.String()).Return(mockSessions, nil)
+
+		sessions, err := tokenService.Sessions(context.Background(), userID)
+		assert.NoError(t, err)
+		assert.Equal(t, mockSessions, sessions)
+	})
+
+	t.Run("Deletes a session", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+		})
+
+		sessionID, _ := uuid.NewRandom()
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), sessionID.String()).Return(int64(1), nil)
+
+		err := tokenService.DeleteSession(context.Background(), userID, sessionID)
+		assert.NoError(t, err)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Session not found", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository: mockTokenRepository,
+		})
+
+		sessionID, _ := uuid.NewRandom()
+		mockTokenRepository.On("DeleteRefreshTokenFamily", mock.Anything, userID.String(), sessionID.String()).Return(int64(0), nil)
+
+		err := tokenService.DeleteSession(context.Background(), userID, sessionID)
+
+		appError, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.NotFound, appError.Type)
+	})
+}
+
 func TestSignOut(t *testing.T) {
 	mockTokenRepository := new(mocks.MockTokenRepository)
 	tokenService := NewTokenService(&TokenServiceConfig{
@@ -230,13 +559,15 @@ func TestValidateIDToken(t *testing.T) {
 
 	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
 	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
-	public, _ := ioutil.ReadFile("../rsa_public_test.pem")
-	publicKey, _ := jwt.ParseRSAPublicKeyFromPEM(public)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	mockTokenRepository := new(mocks.MockTokenRepository)
+	mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
 
 	// Instantiate a common token service to be used by all tests.
 	tokenService := NewTokenService(&TokenServiceConfig{
-		PrivateKey:          privateKey,
-		PublicKey:           publicKey,
+		TokenRepository:     mockTokenRepository,
+		KeyRing:             keyRing,
 		IDExpirationSecrets: idExpiration,
 	})
 
@@ -252,9 +583,9 @@ func TestValidateIDToken(t *testing.T) {
 	t.Run("Valid token", func(t *testing.T) {
 		// Maybe not the best approach to depend on utility method.
 		// Token will be valid for 15 minutes.
-		signedString, _ := generateIDToken(user, privateKey, idExpiration)
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
 
-		userFromToken, err := tokenService.ValidateIDToken(signedString)
+		userFromToken, err := tokenService.ValidateIDToken(context.Background(), signedString)
 		assert.NoError(t, err)
 
 		assert.ElementsMatch(
@@ -267,34 +598,265 @@ func TestValidateIDToken(t *testing.T) {
 	t.Run("Expired token", func(t *testing.T) {
 		// Maybe not the best approach to depend on utility method.
 		// Token will be valid for 15 minutes.
-		signedString, _ := generateIDToken(user, privateKey, -1) // Expired one second ago.
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, -1) // Expired one second ago.
 
 		expectedError := apperrors.NewAuthorization("Unable to verify the user from the idToken")
 
-		_, err := tokenService.ValidateIDToken(signedString)
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
 		assert.EqualError(t, err, expectedError.Message)
 	})
 
 	t.Run("Invalid signature", func(t *testing.T) {
 		// Maybe not the best approach to depend on utility method.
 		// Token won't be valid.
-		signedString, _ := generateIDToken(user, privateKey, -1) // Expired one second ago.
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, -1) // Expired one second ago.
 
 		expectedError := apperrors.NewAuthorization("Unable to verify the user from the idToken")
 
-		_, err := tokenService.ValidateIDToken(signedString)
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
 		assert.EqualError(t, err, expectedError.Message)
 	})
 
+	t.Run("Signed with a retiring key", func(t *testing.T) {
+		retiringKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		retiringKeyRing, _ := NewKeyRing(jwa.RS256, retiringKey)
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, retiringKeyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		rotatedKeyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+		_, err := rotatedKeyRing.AddVerificationKey(jwa.RS256, &retiringKey.PublicKey)
+		assert.NoError(t, err)
+
+		rotatedTokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			KeyRing:             rotatedKeyRing,
+			IDExpirationSecrets: idExpiration,
+		})
+
+		userFromToken, err := rotatedTokenService.ValidateIDToken(context.Background(), signedString)
+		assert.NoError(t, err)
+		assert.Equal(t, user.UserID, userFromToken.UserID)
+	})
+
+	t.Run("Unknown key ID", func(t *testing.T) {
+		unknownKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+		unknownKeyRing, _ := NewKeyRing(jwa.RS256, unknownKey)
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, unknownKeyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		expectedError := apperrors.NewAuthorization("Unable to verify the user from the idToken")
+
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.EqualError(t, err, expectedError.Message)
+	})
+
+	t.Run("Issuer and audience", func(t *testing.T) {
+		settings := &idTokenSettings{
+			Issuer:   "https://accounts.test",
+			Audience: "web",
+		}
+
+		strictTokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			KeyRing:             keyRing,
+			IDExpirationSecrets: idExpiration,
+			Issuer:              settings.Issuer,
+			Audience:            settings.Audience,
+		})
+
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), settings, idExpiration)
+		_, err := strictTokenService.ValidateIDToken(context.Background(), signedString)
+		assert.NoError(t, err)
+
+		otherIssuer, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{Issuer: "https://evil.test", Audience: "web"}, idExpiration)
+		_, err = strictTokenService.ValidateIDToken(context.Background(), otherIssuer)
+		assert.Error(t, err)
+
+		// A token minted for another of our apps is not accepted.
+		otherAudience, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{Issuer: settings.Issuer, Audience: "mobile"}, idExpiration)
+		_, err = strictTokenService.ValidateIDToken(context.Background(), otherAudience)
+		assert.Error(t, err)
+	})
+
+	t.Run("Unexpected algorithm", func(t *testing.T) {
+		claims := &idTokenCustomClaims{
+			StandardClaims: jwt.StandardClaims{
+				Subject:   userID.String(),
+				ExpiresAt: time.Now().Add(time.Minute).Unix(),
+			},
+		}
+
+		// Signed with the public key as an HMAC secret.
+		signedString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(x509.MarshalPKCS1PublicKey(&privateKey.PublicKey))
+
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.Error(t, err)
+	})
+
+	t.Run("Clock skew", func(t *testing.T) {
+		skewedTokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			KeyRing:             keyRing,
+			IDExpirationSecrets: idExpiration,
+			ClockSkew:           30,
+		})
+
+		recentlyExpired, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, -10)
+		_, err := skewedTokenService.ValidateIDToken(context.Background(), recentlyExpired)
+		assert.NoError(t, err)
+
+		expired, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, -60)
+		_, err = skewedTokenService.ValidateIDToken(context.Background(), expired)
+		assert.Error(t, err)
+	})
+
 	// TODO - Add other invalid token types (maybe in the future).
 }
 
+func TestRevokeIDToken(t *testing.T) {
+	var idExpiration int64 = 15 * 60
+
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newTokenService := func(mockTokenRepository *mocks.MockTokenRepository, cacheExpiration int64) model.TokenService {
+		return NewTokenService(&TokenServiceConfig{
+			TokenRepository:           mockTokenRepository,
+			KeyRing:                   keyRing,
+			IDExpirationSecrets:       idExpiration,
+			RevocationCacheExpiration: cacheExpiration,
+		})
+	}
+
+	tokenID := func(signedString string) string {
+		return unverifiedStandardClaims(signedString).Id
+	}
+
+	t.Run("Revoked token is rejected", func(t *testing.T) {
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("RevokeIDToken", mock.Anything, tokenID(signedString), mock.MatchedBy(func(expiresIn time.Duration) bool {
+			return expiresIn > 0 && expiresIn <= time.Duration(idExpiration)*time.Second
+		})).Return(nil)
+		tokenService := newTokenService(mockTokenRepository, 0)
+
+		err := tokenService.RevokeIDToken(context.Background(), signedString)
+		assert.NoError(t, err)
+
+		// The revocation is cached, so the denylist isn't read.
+		_, err = tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.EqualError(t, err, apperrors.NewAuthorization("The idToken has been revoked").Error())
+		mockTokenRepository.AssertExpectations(t)
+		mockTokenRepository.AssertNotCalled(t, "IsIDTokenRevoked", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Token revoked by another instance is rejected", func(t *testing.T) {
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, tokenID(signedString)).Return(true, nil)
+		tokenService := newTokenService(mockTokenRepository, 60)
+
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.Error(t, err)
+
+		_, err = tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.Error(t, err)
+		mockTokenRepository.AssertNumberOfCalls(t, "IsIDTokenRevoked", 1)
+	})
+
+	t.Run("Caches tokens which are not revoked", func(t *testing.T) {
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, tokenID(signedString)).Return(false, nil)
+		tokenService := newTokenService(mockTokenRepository, 60)
+
+		for i := 0; i < 3; i++ {
+			_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+			assert.NoError(t, err)
+		}
+
+		mockTokenRepository.AssertNumberOfCalls(t, "IsIDTokenRevoked", 1)
+	})
+
+	t.Run("Cache disabled", func(t *testing.T) {
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, tokenID(signedString)).Return(false, nil)
+		tokenService := newTokenService(mockTokenRepository, 0)
+
+		for i := 0; i < 3; i++ {
+			_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+			assert.NoError(t, err)
+		}
+
+		mockTokenRepository.AssertNumberOfCalls(t, "IsIDTokenRevoked", 3)
+	})
+
+	t.Run("Denylist failure", func(t *testing.T) {
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, idExpiration)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("IsIDTokenRevoked", mock.Anything, tokenID(signedString)).Return(false, apperrors.NewInternal())
+		tokenService := newTokenService(mockTokenRepository, 60)
+
+		_, err := tokenService.ValidateIDToken(context.Background(), signedString)
+
+		appError, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Internal, appError.Type)
+	})
+
+	t.Run("Revoked within the clock skew", func(t *testing.T) {
+		// The token is still accepted for 20 seconds.
+		signedString, _ := generateIDToken(user, &model.Session{CreatedAt: time.Now()}, keyRing.signingKey(), &idTokenSettings{}, -10)
+
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockTokenRepository.On("RevokeIDToken", mock.Anything, tokenID(signedString), mock.MatchedBy(func(expiresIn time.Duration) bool {
+			return expiresIn > 15*time.Second && expiresIn <= 20*time.Second
+		})).Return(nil)
+		tokenService := NewTokenService(&TokenServiceConfig{
+			TokenRepository:     mockTokenRepository,
+			KeyRing:             keyRing,
+			IDExpirationSecrets: idExpiration,
+			ClockSkew:           30,
+		})
+
+		err := tokenService.RevokeIDToken(context.Background(), signedString)
+		assert.NoError(t, err)
+
+		_, err = tokenService.ValidateIDToken(context.Background(), signedString)
+		assert.EqualError(t, err, apperrors.NewAuthorization("The idToken has been revoked").Error())
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository, 0)
+
+		err := tokenService.RevokeIDToken(context.Background(), "notatoken")
+
+		appError, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Authorization, appError.Type)
+		mockTokenRepository.AssertNotCalled(t, "RevokeIDToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 func TestValidateRefreshToken(t *testing.T) {
 	var refreshExpiration int64 = 3 * 24 * 2600
 	secret := "anothersomerandomtestsecret"
 
 	tokenService := NewTokenService(&TokenServiceConfig{
-		RefreshSecret:            secret,
+		RefreshSecrets:           []string{secret},
 		RefreshExpirationSecrets: refreshExpiration,
 	})
 
@@ -306,20 +868,186 @@ func TestValidateRefreshToken(t *testing.T) {
 	}
 
 	t.Run("Valid token", func(t *testing.T) {
-		testRefreshToken, _ := generateRefreshToken(user.UserID, secret, refreshExpiration)
+		familyID, _ := uuid.NewRandom()
+		testRefreshToken, _ := generateRefreshToken(user.UserID, familyID, time.Now(), secret, refreshExpiration)
 
 		validatedRefreshToken, err := tokenService.ValidateRefreshToken(testRefreshToken.SignedString)
 		assert.NoError(t, err)
 
 		assert.Equal(t, user.UserID, validatedRefreshToken.UserID)
+		assert.Equal(t, familyID, validatedRefreshToken.FamilyID)
 		assert.Equal(t, testRefreshToken.SignedString, validatedRefreshToken.SignedString)
 	})
 	t.Run("Expired token", func(t *testing.T) {
-		testRefreshToken, _ := generateRefreshToken(user.UserID, secret, -1)
+		testRefreshToken, _ := generateRefreshToken(user.UserID, uuid.New(), time.Now(), secret, -1)
 
 		expectedError := apperrors.NewAuthorization("Unable to verify the user from the refresh token")
 
 		_, err := tokenService.ValidateRefreshToken(testRefreshToken.SignedString)
 		assert.EqualError(t, err, expectedError.Message)
 	})
+
+	t.Run("Rotated secret", func(t *testing.T) {
+		newSecret := "thenewestsomerandomtestsecret"
+		rotatedTokenService := NewTokenService(&TokenServiceConfig{
+			RefreshSecrets:           []string{newSecret, secret},
+			RefreshExpirationSecrets: refreshExpiration,
+		})
+
+		// Tokens signed with the previous secret stay valid.
+		previousRefreshToken, _ := generateRefreshToken(user.UserID, uuid.New(), time.Now(), secret, refreshExpiration)
+		_, err := rotatedTokenService.ValidateRefreshToken(previousRefreshToken.SignedString)
+		assert.NoError(t, err)
+
+		newRefreshToken, _ := generateRefreshToken(user.UserID, uuid.New(), time.Now(), newSecret, refreshExpiration)
+		_, err = rotatedTokenService.ValidateRefreshToken(newRefreshToken.SignedString)
+		assert.NoError(t, err)
+
+		// Once the previous secret is dropped, its tokens are invalid.
+		_, err = NewTokenService(&TokenServiceConfig{
+			RefreshSecrets: []string{newSecret},
+		}).ValidateRefreshToken(previousRefreshToken.SignedString)
+		assert.Error(t, err)
+	})
+}
+
+func TestSessionLimits(t *testing.T) {
+	private, _ := ioutil.ReadFile("../rsa_private_test.pem")
+	privateKey, _ := jwt.ParseRSAPrivateKeyFromPEM(private)
+	keyRing, _ := NewKeyRing(jwa.RS256, privateKey)
+
+	userID, _ := uuid.NewRandom()
+	user := &model.User{
+		UserID: userID,
+		Email:  "kostya@kostya.com",
+	}
+
+	newTokenService := func(mockTokenRepository *mocks.MockTokenRepository) model.TokenService {
+		return NewTokenService(&TokenServiceConfig{
+			TokenRepository:          mockTokenRepository,
+			KeyRing:                  keyRing,
+			RefreshSecrets:           []string{"anothersomerandomtestsecret"},
+			RefreshExpirationSecrets: 3 * 24 * 60 * 60,
+			SessionMaxAge:            30 * 24 * 60 * 60,
+			SessionIdleTimeout:       24 * 60 * 60,
+		})
+	}
+
+	newPreviousToken := func(authTime time.Time) *model.RefreshToken {
+		return &model.RefreshToken{
+			ID:       uuid.New(),
+			UserID:   userID,
+			FamilyID: uuid.New(),
+			AuthTime: authTime,
+		}
+	}
+
+	t.Run("New sign in is limited by the idle timeout", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository)
+
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), 24*time.Hour).Return(nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, nil, nil)
+		assert.NoError(t, err)
+
+		refreshToken, err := tokenService.ValidateRefreshToken(tokenPair.RefreshToken.SignedString)
+		assert.NoError(t, err)
+
+		assert.WithinDuration(t, time.Now(), refreshToken.AuthTime, 5*time.Second)
+		assert.Equal(t, 24*time.Hour, tokenPair.RefreshToken.ExpiresIn)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Keeps the sign in time across rotation", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository)
+
+		// The session reaches its maximum age in an hour.
+		authTime := time.Now().Add(-30*24*time.Hour + time.Hour).Truncate(time.Second)
+		previousToken := newPreviousToken(authTime)
+		previousSession := &model.Session{
+			ID:              previousToken.FamilyID,
+			CreatedAt:       time.Now().Add(-time.Minute), // The token wins over the metadata.
+			LastRefreshedAt: time.Now().Add(-time.Hour),
+		}
+
+		var storedSession *model.Session
+		var storedExpiration time.Duration
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), previousToken.ID.String()).Return(previousSession, nil)
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), mock.AnythingOfType("time.Duration")).
+			Run(func(args mock.Arguments) {
+				storedSession = args.Get(3).(*model.Session)
+				storedExpiration = args.Get(4).(time.Duration)
+			}).Return(nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, previousToken, nil)
+		assert.NoError(t, err)
+
+		assert.True(t, authTime.Equal(tokenPair.RefreshToken.AuthTime))
+		assert.True(t, authTime.Equal(storedSession.CreatedAt))
+		assert.LessOrEqual(t, int64(storedExpiration), int64(time.Hour))
+		assert.Greater(t, int64(storedExpiration), int64(time.Hour-time.Minute))
+	})
+
+	t.Run("New session of an earlier sign in", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository)
+
+		authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+		mockTokenRepository.On("SetRefreshToken", mock.Anything, userID.String(), mock.AnythingOfType("string"), mock.AnythingOfType("*model.Session"), 24*time.Hour).Return(nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, nil, &model.Session{CreatedAt: authTime})
+		assert.NoError(t, err)
+		assert.True(t, authTime.Equal(tokenPair.RefreshToken.AuthTime))
+
+		// The sign in is past the maximum session age.
+		tokenPair, err = tokenService.NewPairFromUser(context.Background(), user, nil, &model.Session{CreatedAt: time.Now().Add(-31 * 24 * time.Hour)})
+		assert.Nil(t, tokenPair)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNumberOfCalls(t, "SetRefreshToken", 1)
+	})
+
+	t.Run("Session past its maximum age", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository)
+
+		previousToken := newPreviousToken(time.Now().Add(-31 * 24 * time.Hour))
+		previousSession := &model.Session{
+			ID:              previousToken.FamilyID,
+			LastRefreshedAt: time.Now().Add(-time.Hour),
+		}
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), previousToken.ID.String()).Return(previousSession, nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, previousToken, nil)
+
+		assert.Nil(t, tokenPair)
+		appError, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Authorization, appError.Type)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Idle session", func(t *testing.T) {
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		tokenService := newTokenService(mockTokenRepository)
+
+		previousToken := newPreviousToken(time.Now().Add(-3 * 24 * time.Hour))
+		previousSession := &model.Session{
+			ID:              previousToken.FamilyID,
+			LastRefreshedAt: time.Now().Add(-25 * time.Hour),
+		}
+
+		mockTokenRepository.On("DeleteRefreshToken", mock.Anything, userID.String(), previousToken.ID.String()).Return(previousSession, nil)
+
+		tokenPair, err := tokenService.NewPairFromUser(context.Background(), user, previousToken, nil)
+
+		assert.Nil(t, tokenPair)
+		appError, ok := err.(*apperrors.Error)
+		assert.True(t, ok)
+		assert.Equal(t, apperrors.Authorization, appError.Type)
+		mockTokenRepository.AssertNotCalled(t, "SetRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
@@ -1,38 +1,146 @@
 package service
 
 import (
-	"crypto/rsa"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/jwa"
 	"github.com/yachnytskyi/base-go/account/model"
 )
 
 // idTokenCustomClaims holds structure of jwt claims of idToken.
+// The user is identified by the standard sub claim. Profile claims
+// are only included when configured, since they go stale as soon
+// as the user updates their details.
+// Tokens issued to OAuth clients name the client in azp and only
+// hold the claims of the scopes the user allowed the client.
 type idTokenCustomClaims struct {
-	User *model.User `json:"user"`
+	Email           string `json:"email,omitempty"`
+	EmailVerified   bool   `json:"email_verified"`
+	AuthTime        int64  `json:"auth_time,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Picture         string `json:"picture,omitempty"`
+	Website         string `json:"website,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	// AMR lists how the user authenticated at the sign in, like ["pwd","otp"].
+	AMR []string `json:"amr,omitempty"`
+	// Audience shadows the aud of the standard claims,
+	// which can't hold the array tokens of OAuth clients have.
+	Audience model.Audience `json:"aud,omitempty"`
 	jwt.StandardClaims
 }
 
+// idTokenSettings holds the configured claims
+// ID tokens are issued and verified with.
+type idTokenSettings struct {
+	Issuer        string
+	Audience      string
+	ProfileClaims []string
+	ClockSkew     time.Duration // Tolerated difference between our clock and the issuer's.
+}
+
+// profileClaims maps the names of the optional profile claims to the user fields they hold.
+var profileClaims = map[string]func(user *model.User, claims *idTokenCustomClaims){
+	"name":    func(user *model.User, claims *idTokenCustomClaims) { claims.Name = user.Username },
+	"picture": func(user *model.User, claims *idTokenCustomClaims) { claims.Picture = user.ImageURL },
+	"website": func(user *model.User, claims *idTokenCustomClaims) { claims.Website = user.Website },
+}
+
+// user returns the user the token was issued to. Only the fields which
+// are in the token are set, so handlers should fetch the user to get the rest.
+func (c *idTokenCustomClaims) user() (*model.User, error) {
+	userID, err := uuid.Parse(c.Subject)
+
+	if err != nil {
+		return nil, fmt.Errorf("subject is not a valid user id: %w", err)
+	}
+
+	user := &model.User{
+		UserID:        userID,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerified,
+		Username:      c.Name,
+		ImageURL:      c.Picture,
+		Website:       c.Website,
+		AMR:           c.AMR,
+	}
+
+	if c.AuthTime != 0 {
+		user.AuthTime = time.Unix(c.AuthTime, 0)
+	}
+
+	return user, nil
+}
+
+// IsProfileClaim reports whether a claim can be configured as a profile claim of ID tokens.
+func IsProfileClaim(name string) bool {
+	_, ok := profileClaims[name]
+	return ok
+}
+
 // generateIDToken generates an IDToken which is a jwt with myCustomClaims.
 // Could call this GenerateIDTokenString, but the signature makes this fairly clear.
-func generateIDToken(user *model.User, key *rsa.PrivateKey, expiration int64) (string, error) {
+// The kid header tells verifiers which key of the key ring to use.
+// The session was created when the user signed in, which stays the same across refreshes.
+func generateIDToken(user *model.User, session *model.Session, key *signingKey, settings *idTokenSettings, expiration int64) (string, error) {
 	unixTime := time.Now().Unix()
 	tokenExpiration := unixTime + expiration
+	tokenID, err := uuid.NewRandom() // Lets the token be revoked before it expires.
 
-	claims := idTokenCustomClaims{
-		User: user,
+	if err != nil {
+		log.Println("Failed to generate id token ID")
+		return "", err
+	}
+
+	claims := &idTokenCustomClaims{
+		AuthTime: session.CreatedAt.Unix(),
+		AMR:      session.AMR,
+		Audience: model.Audience{settings.Audience},
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID.String(),
+			Subject:   user.UserID.String(),
+			Issuer:    settings.Issuer,
 			IssuedAt:  unixTime,
 			ExpiresAt: tokenExpiration,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signedString, err := token.SignedString(key)
+	// Our own sign in gets all of the claims.
+	scopes := []string{model.ScopeEmail, model.ScopeProfile}
+
+	// OpenID Connect requires the client in the audience. The token is
+	// only for the client, so our APIs, which require our audience, refuse it.
+	if session.ClientID != "" {
+		scopes = strings.Fields(session.Scope)
+
+		claims.Audience = model.Audience{session.ClientID}
+		claims.AuthorizedParty = session.ClientID
+		claims.Scope = session.Scope
+		claims.Nonce = session.Nonce
+	}
+
+	if contains(scopes, model.ScopeEmail) {
+		claims.Email = user.Email
+		claims.EmailVerified = user.EmailVerified
+	}
+
+	if contains(scopes, model.ScopeProfile) {
+		for _, name := range settings.ProfileClaims {
+			if setClaim, ok := profileClaims[name]; ok {
+				setClaim(user, claims)
+			}
+		}
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	signedString, err := token.SignedString(key.PrivateKey)
 
 	if err != nil {
 		log.Println("Failed to sign id token string")
@@ -42,25 +150,119 @@ func generateIDToken(user *model.User, key *rsa.PrivateKey, expiration int64) (s
 	return signedString, nil
 }
 
+// serviceTokenType is the typ header of access tokens of machine clients, from RFC 9068.
+const serviceTokenType = "at+jwt"
+
+// serviceTokenCustomClaims holds the claims of an access token issued
+// to a machine client with the client credentials grant.
+// The client is both the subject and the client_id.
+type serviceTokenCustomClaims struct {
+	ClientID string         `json:"client_id"`
+	Scope    string         `json:"scope,omitempty"`
+	Audience model.Audience `json:"aud,omitempty"`
+	jwt.StandardClaims
+}
+
+// generateServiceToken generates an access token for a machine client,
+// signed with the key ring like ID tokens, so other services verify
+// both with our JWKS.
+func generateServiceToken(clientID string, scopes []string, key *signingKey, settings *idTokenSettings, expiration int64) (string, error) {
+	unixTime := time.Now().Unix()
+	tokenID, err := uuid.NewRandom() // Lets the token be revoked before it expires.
+
+	if err != nil {
+		log.Println("Failed to generate service token ID")
+		return "", err
+	}
+
+	claims := &serviceTokenCustomClaims{
+		ClientID: clientID,
+		Scope:    strings.Join(scopes, " "),
+		Audience: model.Audience{settings.Audience},
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenID.String(),
+			Subject:   clientID,
+			Issuer:    settings.Issuer,
+			IssuedAt:  unixTime,
+			ExpiresAt: unixTime + expiration,
+		},
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = serviceTokenType
+	signedString, err := token.SignedString(key.PrivateKey)
+
+	if err != nil {
+		log.Println("Failed to sign service token string")
+		return "", err
+	}
+
+	return signedString, nil
+}
+
+// validateServiceToken returns the claims of an access token of a machine
+// client if the token is valid. The checks are the ones of ID tokens.
+// Tokens without a jti can't be revoked, so they are refused.
+func validateServiceToken(tokenString string, keyRing *KeyRing, settings *idTokenSettings) (*serviceTokenCustomClaims, error) {
+	claims := &serviceTokenCustomClaims{}
+
+	// The time based claims are checked below, with the clock skew.
+	parser := &jwt.Parser{
+		ValidMethods:         jwa.Algorithms(),
+		SkipClaimsValidation: true,
+	}
+
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != serviceTokenType {
+			return nil, fmt.Errorf("token is not an access token of a client")
+		}
+
+		return verificationKey(token, keyRing)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyIDTokenClaims(&claims.StandardClaims, claims.Audience, settings.Audience, settings, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if claims.ClientID != claims.Subject {
+		return nil, fmt.Errorf("subject is not the client: %s", claims.Subject)
+	}
+
+	if claims.Id == "" {
+		return nil, fmt.Errorf("token has no jti")
+	}
+
+	return claims, nil
+}
+
 // refreshTokenData holds the actual signed jwt string along with the ID.
 // We return the id so it can be used without re-parsing the JWT from a signed string.
 type refreshTokenData struct {
 	SignedString string
 	ID           uuid.UUID
+	FamilyID     uuid.UUID
 	ExpiresIn    time.Duration
 }
 
 // refreshTokenCustomClaims holds the payload of a refresh token.
 // This can be used to extract a user id for subsequent
 // application operations (IE, fetch user in Redis)
+// FamilyID and AuthTime, when the user signed in, stay the same across rotations of a sign in.
 type refreshTokenCustomClaims struct {
-	UserID uuid.UUID `json:"userID"`
+	UserID   uuid.UUID `json:"userID"`
+	FamilyID uuid.UUID `json:"familyID"`
+	AuthTime int64     `json:"auth_time,omitempty"`
 	jwt.StandardClaims
 }
 
 // generateRefreshToken creates a refresh token.
-// The refresh token stores only the user's ID, a string.
-func generateRefreshToken(userID uuid.UUID, key string, exp int64) (*refreshTokenData, error) {
+// The refresh token stores the user's ID, the token family ID and the time of the sign in.
+func generateRefreshToken(userID uuid.UUID, familyID uuid.UUID, authTime time.Time, key string, exp int64) (*refreshTokenData, error) {
 	currentTime := time.Now()
 	tokenExpiration := currentTime.Add(time.Duration(exp) * time.Second)
 	tokenID, err := uuid.NewRandom() // v4 uuid in the google uuid lib.
@@ -71,7 +273,9 @@ func generateRefreshToken(userID uuid.UUID, key string, exp int64) (*refreshToke
 	}
 
 	claims := refreshTokenCustomClaims{
-		UserID: userID,
+		UserID:   userID,
+		FamilyID: familyID,
+		AuthTime: authTime.Unix(),
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  currentTime.Unix(),
 			ExpiresAt: tokenExpiration.Unix(),
@@ -90,16 +294,34 @@ func generateRefreshToken(userID uuid.UUID, key string, exp int64) (*refreshToke
 	return &refreshTokenData{
 		SignedString: signedString,
 		ID:           tokenID,
+		FamilyID:     familyID,
 		ExpiresIn:    tokenExpiration.Sub(currentTime),
 	}, nil
 }
 
 // validateIDToken returns the token's claims if the token is valid.
-func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClaims, error) {
+// The verification key is picked from the key ring by the kid header.
+// Besides the signature, the token must be signed with the algorithm of the key,
+// issued by the configured issuer for the configured audience, or for the
+// OAuth client it was issued to, and within its validity period give or
+// take the configured clock skew.
+func validateIDToken(tokenString string, keyRing *KeyRing, settings *idTokenSettings) (*idTokenCustomClaims, error) {
 	claims := &idTokenCustomClaims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return key, nil
+	// The time based claims are checked below, with the clock skew.
+	parser := &jwt.Parser{
+		ValidMethods:         jwa.Algorithms(),
+		SkipClaimsValidation: true,
+	}
+
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Access tokens of machine clients are signed with the same keys,
+		// so they must not be mistaken for the ID token of a user.
+		if typ, _ := token.Header["typ"].(string); typ == serviceTokenType {
+			return nil, fmt.Errorf("access token of a client used as an ID token")
+		}
+
+		return verificationKey(token, keyRing)
 	})
 
 	// For now we will just return the error and handle logging in service level.
@@ -117,17 +339,105 @@ func validateIDToken(tokenString string, key *rsa.PublicKey) (*idTokenCustomClai
 		return nil, fmt.Errorf("ID token valid but couldn't parse claims")
 	}
 
+	// Tokens of OAuth clients are issued for the client alone.
+	audience := settings.Audience
+
+	if claims.AuthorizedParty != "" {
+		audience = claims.AuthorizedParty
+	}
+
+	if err := verifyIDTokenClaims(&claims.StandardClaims, claims.Audience, audience, settings, time.Now()); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
-// validateRefreshToken uses the secret key to validate a refresh token.
-func validateRefreshToken(tokenString string, key string) (*refreshTokenCustomClaims, error) {
+// verificationKey picks the key of the key ring a token is verified with by the kid header.
+func verificationKey(token *jwt.Token, keyRing *KeyRing) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	// Tokens issued before key rotation was introduced have no kid.
+	// They can only have been signed with the current key.
+	key := keyRing.signingKey()
+
+	if kid != "" {
+		var ok bool
+
+		if key, ok = keyRing.verificationKey(kid); !ok {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s for key id: %s", token.Method.Alg(), key.ID)
+	}
+
+	return key.PublicKey, nil
+}
+
+// verifyIDTokenClaims checks the registered claims of a validly signed token.
+// The audience is passed separately, as the token claims shadow the standard one,
+// and has to contain the expected audience.
+func verifyIDTokenClaims(claims *jwt.StandardClaims, audience model.Audience, expectedAudience string, settings *idTokenSettings, now time.Time) error {
+	skew := int64(settings.ClockSkew / time.Second)
+	unixTime := now.Unix()
+
+	if claims.Subject == "" {
+		return fmt.Errorf("ID token has no subject")
+	}
+
+	if claims.Issuer != settings.Issuer {
+		return fmt.Errorf("unexpected issuer: %s", claims.Issuer)
+	}
+
+	if !audience.Contains(expectedAudience) {
+		return fmt.Errorf("unexpected audience: %v", audience)
+	}
+
+	if unixTime > claims.ExpiresAt+skew {
+		return fmt.Errorf("ID token is expired")
+	}
+
+	if claims.IssuedAt > unixTime+skew {
+		return fmt.Errorf("ID token used before issued")
+	}
+
+	if claims.NotBefore > unixTime+skew {
+		return fmt.Errorf("ID token is not valid yet")
+	}
+
+	return nil
+}
+
+// validateRefreshToken validates a refresh token against the secrets, newest first,
+// so tokens signed with a previous secret stay valid while the secret is rotated.
+func validateRefreshToken(tokenString string, secrets []string) (*refreshTokenCustomClaims, error) {
+	err := fmt.Errorf("no refresh secret configured")
+
+	for _, secret := range secrets {
+		var claims *refreshTokenCustomClaims
+
+		if claims, err = validateRefreshTokenWithSecret(tokenString, secret); err == nil {
+			return claims, nil
+		}
+	}
+
+	// For now we will just return the error and handle logging in service level.
+	return nil, err
+}
+
+// validateRefreshTokenWithSecret uses the secret key to validate a refresh token.
+func validateRefreshTokenWithSecret(tokenString string, key string) (*refreshTokenCustomClaims, error) {
 	claims := &refreshTokenCustomClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+	parser := &jwt.Parser{
+		ValidMethods: []string{jwt.SigningMethodHS256.Alg()},
+	}
+
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(key), nil
 	})
 
-	// For now we will just return the error and handle logging in service level.
 	if err != nil {
 		return nil, err
 	}
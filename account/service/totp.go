@@ -0,0 +1,227 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTP codes are made as RFC 6238 describes, with the defaults every
+// authenticator app supports: HMAC-SHA1, 6 digits and 30 second steps.
+const (
+	totpDigits       = 6
+	totpPeriod       = 30 * time.Second
+	totpSkew         = 1  // Steps before and after the current one a code is accepted for, as clocks drift.
+	totpSecretLength = 20 // Bytes, the length of an HMAC-SHA1 output as RFC 4226 recommends.
+)
+
+// Recovery codes sign users in once each, when they lost their authenticator.
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // Random bytes, which are 16 base32 characters.
+)
+
+// totpEncoding encodes secrets the way authenticator apps expect them.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateTOTPSecret returns a new random secret in base32.
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpStep returns the time step of the time.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// totpCode returns the code of the time step, which is the
+// HOTP value of RFC 4226 with the step as the counter.
+func totpCode(secret []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation picks 31 bits at the offset the last nibble holds.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulus *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus)
+}
+
+// matchTOTPCode returns the time step the code belongs to, if it
+// is the code of a step close enough to the time.
+func matchTOTPCode(secret string, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpStep(t)
+
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// isTOTPCode reports whether the code looks like a TOTP code
+// rather than a recovery code.
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// totpURI returns the otpauth:// provisioning URI of the secret,
+// which authenticator apps read from a QR code. The account name
+// tells the user which account the codes are for.
+func totpURI(issuer string, accountName string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod/time.Second)))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+// generateRecoveryCodes returns new recovery codes, formatted
+// in groups of four characters so they are easy to copy.
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		random := make([]byte, recoveryCodeLength)
+
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+
+		code := totpEncoding.EncodeToString(random)
+
+		var groups []string
+		for len(code) > 0 {
+			groups = append(groups, code[:4])
+			code = code[4:]
+		}
+
+		codes[i] = strings.Join(groups, "-")
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage. The codes are
+// random enough to be hashed like other tokens, and the hash
+// ignores how the code was grouped or capitalized when it was typed.
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	return hashToken(code)
+}
+
+// encryptTOTPSecret seals the secret with AES-GCM. The user ID is
+// authenticated along with it, so a secret copied to another user
+// doesn't decrypt. The nonce is prepended to the ciphertext.
+func encryptTOTPSecret(key []byte, userID uuid.UUID, secret string) (string, error) {
+	aead, err := newTOTPCipher(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(secret), userID[:])
+
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptTOTPSecret opens a secret sealed by encryptTOTPSecret.
+func decryptTOTPSecret(key []byte, userID uuid.UUID, encrypted string) (string, error) {
+	aead, err := newTOTPCipher(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encrypted)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted TOTP secret is too short")
+	}
+
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], userID[:])
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// newTOTPCipher returns the AES-GCM cipher of the key,
+// which is 16, 24 or 32 bytes long.
+func newTOTPCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
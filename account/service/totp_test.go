@@ -0,0 +1,107 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// The SHA-1 test vectors of RFC 6238, cut to 6 digits.
+	secret := []byte("12345678901234567890")
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unixTime, code := range vectors {
+		assert.Equal(t, code, totpCode(secret, totpStep(time.Unix(unixTime, 0))))
+	}
+}
+
+func TestMatchTOTPCode(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	assert.NoError(t, err)
+
+	key, err := totpEncoding.DecodeString(secret)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	step := totpStep(now)
+
+	t.Run("Codes of nearby steps match", func(t *testing.T) {
+		for _, codeStep := range []int64{step - 1, step, step + 1} {
+			matchedStep, ok := matchTOTPCode(secret, totpCode(key, codeStep), now)
+			assert.True(t, ok)
+			assert.Equal(t, codeStep, matchedStep)
+		}
+	})
+
+	t.Run("Codes of distant steps don't match", func(t *testing.T) {
+		_, ok := matchTOTPCode(secret, totpCode(key, step-3), now)
+		assert.False(t, ok)
+
+		_, ok = matchTOTPCode(secret, "12345", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("base-go", "kostya@kostya.com", "JBSWY3DPEHPK3PXP"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/base-go:kostya@kostya.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "base-go", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := generateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	for _, code := range codes {
+		assert.Len(t, code, 19) // Four groups of four characters.
+		assert.False(t, isTOTPCode(code))
+	}
+
+	assert.NotEqual(t, codes[0], codes[1])
+
+	// Hashes ignore how the code was typed.
+	typed := strings.ToLower(strings.ReplaceAll(codes[0], "-", " "))
+	assert.Equal(t, hashRecoveryCode(codes[0]), hashRecoveryCode(typed))
+	assert.NotEqual(t, hashRecoveryCode(codes[0]), hashRecoveryCode(codes[1]))
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	key := []byte("somemfaencryptionkeyof32bytes!!!")
+	userID, _ := uuid.NewRandom()
+
+	encrypted, err := encryptTOTPSecret(key, userID, "JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	secret, err := decryptTOTPSecret(key, userID, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+
+	// A secret copied to another user doesn't decrypt.
+	otherUserID, _ := uuid.NewRandom()
+	_, err = decryptTOTPSecret(key, otherUserID, encrypted)
+	assert.Error(t, err)
+
+	_, err = decryptTOTPSecret([]byte("anothermfaencryptionkeyof32byte!"), userID, encrypted)
+	assert.Error(t, err)
+}
@@ -2,39 +2,111 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"mime/multipart"
 	"net/url"
 	"path"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/yachnytskyi/base-go/account/model"
 	"github.com/yachnytskyi/base-go/account/model/apperrors"
 )
 
+// Password reset emails are sent after ForgotPassword returns. At most
+// maxPendingPasswordResets are sent at a time, and each may take up to
+// passwordResetTimeout, so a flood of requests can't pile up goroutines.
+const (
+	maxPendingPasswordResets = 16
+	passwordResetTimeout     = 30 * time.Second
+)
+
 // userService acts as a struct for injecting
 // an implementation of UserRepository
 // for use in service methods.
 type userService struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository              model.UserRepository
+	ImageRepository             model.ImageRepository
+	TokenRepository             model.TokenRepository
+	MailSender                  model.MailSender
+	SignInAttemptRepository     model.SignInAttemptRepository
+	SignInMaxFailures           int
+	SignInIPMaxFailures         int
+	SignInBackoffBase           time.Duration
+	SignInLockoutMax            time.Duration
+	SignInFailureWindow         time.Duration
+	PasswordPolicy              *PasswordPolicy
+	PasswordResetURL            string
+	PasswordResetExpiration     time.Duration
+	EmailVerificationURL        string
+	EmailVerificationSecret     string
+	EmailVerificationExpiration time.Duration
+	MagicLinkURL                string
+	MagicLinkSecret             string
+	MagicLinkExpiration         time.Duration
+	MagicLinkSignUp             bool
+	passwordResets              chan struct{} // Holds a slot for each password reset being sent.
 }
 
 // UserConfig will hold repositories that
 // will eventually be injected into
 // this service layer.
 type UserConfig struct {
-	UserRepository  model.UserRepository
-	ImageRepository model.ImageRepository
+	UserRepository              model.UserRepository
+	ImageRepository             model.ImageRepository
+	TokenRepository             model.TokenRepository
+	MailSender                  model.MailSender
+	SignInAttemptRepository     model.SignInAttemptRepository // Nil allows unlimited sign in attempts.
+	SignInMaxFailures           int                           // Sign in attempts of an email which may fail in a row before it is locked.
+	SignInIPMaxFailures         int                           // Sign in attempts from an IP address which may fail in a row before it is locked.
+	SignInBackoffBase           int64                         // Seconds the first lock lasts. Each further failure doubles it.
+	SignInLockoutMax            int64                         // Seconds a lock lasts at most.
+	SignInFailureWindow         int64                         // Seconds failed attempts are remembered after the last one.
+	PasswordPolicy              *PasswordPolicy               // New passwords have to meet it. Defaults to the default limits.
+	PasswordResetURL            string                        // The page of the frontend the reset token is sent to as the token query parameter.
+	PasswordResetExpiration     int64                         // Seconds a password reset token is valid for.
+	EmailVerificationURL        string                        // The page of the frontend the verification token is sent to as the token query parameter.
+	EmailVerificationSecret     string                        // Signs the verification tokens.
+	EmailVerificationExpiration int64                         // Seconds an email verification token is valid for.
+	MagicLinkURL                string                        // The page of the frontend the sign in link token is sent to as the token query parameter.
+	MagicLinkSecret             string                        // Signs the sign in link tokens.
+	MagicLinkExpiration         int64                         // Seconds a sign in link is valid for.
+	MagicLinkSignUp             bool                          // Lets sign in links create accounts for unknown emails.
 }
 
 // NewUserService is a factory function for
 // initializing a UserService with its
 // repository layer dependencies.
 func NewUserService(c *UserConfig) model.UserService {
+	passwordPolicy := c.PasswordPolicy
+
+	if passwordPolicy == nil {
+		passwordPolicy = NewPasswordPolicy(&PasswordPolicyConfig{MinStrength: defaultPasswordMinStrength})
+	}
+
 	return &userService{
-		UserRepository:  c.UserRepository,
-		ImageRepository: c.ImageRepository,
+		UserRepository:              c.UserRepository,
+		ImageRepository:             c.ImageRepository,
+		TokenRepository:             c.TokenRepository,
+		MailSender:                  c.MailSender,
+		SignInAttemptRepository:     c.SignInAttemptRepository,
+		SignInMaxFailures:           c.SignInMaxFailures,
+		SignInIPMaxFailures:         c.SignInIPMaxFailures,
+		SignInBackoffBase:           time.Duration(c.SignInBackoffBase) * time.Second,
+		SignInLockoutMax:            time.Duration(c.SignInLockoutMax) * time.Second,
+		SignInFailureWindow:         time.Duration(c.SignInFailureWindow) * time.Second,
+		PasswordPolicy:              passwordPolicy,
+		PasswordResetURL:            c.PasswordResetURL,
+		PasswordResetExpiration:     time.Duration(c.PasswordResetExpiration) * time.Second,
+		EmailVerificationURL:        c.EmailVerificationURL,
+		EmailVerificationSecret:     c.EmailVerificationSecret,
+		EmailVerificationExpiration: time.Duration(c.EmailVerificationExpiration) * time.Second,
+		MagicLinkURL:                c.MagicLinkURL,
+		MagicLinkSecret:             c.MagicLinkSecret,
+		MagicLinkExpiration:         time.Duration(c.MagicLinkExpiration) * time.Second,
+		MagicLinkSignUp:             c.MagicLinkSignUp,
+		passwordResets:              make(chan struct{}, maxPendingPasswordResets),
 	}
 }
 
@@ -81,9 +153,16 @@ func (s *userService) Get(ctx context.Context, userID uuid.UUID) (*model.User, e
 
 // SignUp reaches out to a UserRepository to verify the
 // email adress is available and signs up the user
-// if this is the case.
+// if this is the case. The password has to meet the password policy.
+// A link to verify the email is mailed to it.
 func (s *userService) SignUp(ctx context.Context, user *model.User) error {
-	password, err := hashPassword(user.Password)
+	password, err := s.PasswordPolicy.Check(ctx, user.Password, user.Email)
+
+	if err != nil {
+		return err
+	}
+
+	password, err = hashPassword(password)
 
 	if err != nil {
 		log.Printf("Unable to signup user for email: %v\n", user.Email)
@@ -97,6 +176,8 @@ func (s *userService) SignUp(ctx context.Context, user *model.User) error {
 		return err
 	}
 
+	s.sendEmailVerification(ctx, user.UserID, user.Email)
+
 	return nil
 }
 
@@ -104,37 +185,254 @@ func (s *userService) SignUp(ctx context.Context, user *model.User) error {
 // and when compares the supplied password with the provided password
 // if a valid email/password combo is provided, u will hold all
 // available user fields.
-func (s *userService) SignIn(ctx context.Context, user *model.User) error {
+// Failed attempts are counted by email and by IP address, which get
+// locked for a growing backoff after too many failures in a row.
+func (s *userService) SignIn(ctx context.Context, user *model.User, ipAddress string) error {
+	var attemptKeys []signInAttemptKey
+
+	if s.SignInAttemptRepository != nil {
+		attemptKeys = s.signInAttemptKeys(user.Email, ipAddress)
+
+		if err := s.checkSignInLock(ctx, attemptKeys); err != nil {
+			return err
+		}
+	}
+
 	userFetched, err := s.UserRepository.FindByEmail(ctx, user.Email)
 
 	// Will return NotAuthorized to client to omit details of why.
+	// Unknown emails count as failures too, so locks don't tell which emails are registered.
 	if err != nil {
+		s.recordSignInFailure(ctx, attemptKeys)
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
 	// verify password - we previously created this method.
-	match, err := comparePasswords(userFetched.Password, user.Password)
+	match, needsRehash, err := matchPassword(userFetched.Password, user.Password)
 
 	if err != nil {
+		log.Printf("Unable to verify the password of userID: %v. Error: %v\n", userFetched.UserID, err)
 		return apperrors.NewInternal()
 	}
 
 	if !match {
+		s.recordSignInFailure(ctx, attemptKeys)
 		return apperrors.NewAuthorization("Invalid email and password combination")
 	}
 
+	s.resetSignInFailures(ctx, userFetched.Email)
+
+	// The password is only known now, so outdated hashes are upgraded
+	// to the current hasher. The user can sign in either way.
+	if needsRehash {
+		if password, err := hashPassword(normalizePassword(user.Password)); err != nil {
+			log.Printf("Unable to rehash the password of userID: %v. Error: %v\n", userFetched.UserID, err)
+		} else if err := s.UserRepository.UpdatePassword(ctx, userFetched.UserID, password); err == nil {
+			userFetched.Password = password
+		}
+	}
+
 	*user = *userFetched
 	return nil
 }
 
+// UpdatePassword replaces the password of a user after verifying
+// the current one. The new password has to meet the password policy.
+// It returns the user, so new tokens can be issued.
+func (s *userService) UpdatePassword(ctx context.Context, userID uuid.UUID, currentPassword string, newPassword string) (*model.User, error) {
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	match, _, err := matchPassword(user.Password, currentPassword)
+
+	if err != nil {
+		log.Printf("Unable to verify the password of userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if !match {
+		return nil, apperrors.NewAuthorization("Invalid current password")
+	}
+
+	password, err := s.PasswordPolicy.Check(ctx, newPassword, user.Email)
+
+	if err != nil {
+		return nil, err
+	}
+
+	password, err = hashPassword(password)
+
+	if err != nil {
+		log.Printf("Unable to hash the new password of userID: %v. Error: %v\n", userID, err)
+		return nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, userID, password); err != nil {
+		return nil, err
+	}
+
+	user.Password = password
+
+	return user, nil
+}
+
+// ForgotPassword mails a link with a single use password reset token
+// to the user with the email. Only the hash of the token is stored.
+// Nothing tells the caller whether the email is registered: the link is
+// looked up and mailed after it returns, as the time it takes would tell,
+// and failures are only logged. Requests beyond the pending limit are dropped.
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	select {
+	case s.passwordResets <- struct{}{}:
+	default:
+		log.Printf("Too many pending password resets, dropped the one for email: %v\n", email)
+		return nil
+	}
+
+	go func() {
+		defer func() { <-s.passwordResets }()
+
+		// The request context is canceled once the response is written.
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+		defer cancel()
+
+		s.sendPasswordReset(ctx, email)
+	}()
+
+	return nil
+}
+
+// sendPasswordReset mails a password reset link to the user with the email, if there is one.
+func (s *userService) sendPasswordReset(ctx context.Context, email string) {
+	user, err := s.UserRepository.FindByEmail(ctx, email)
+
+	if err != nil {
+		return
+	}
+
+	token, err := randomToken(32)
+
+	if err != nil {
+		log.Printf("Unable to generate a password reset token for userID: %v. Error: %v\n", user.UserID, err)
+		return
+	}
+
+	if err := s.TokenRepository.SetPasswordResetToken(ctx, hashToken(token), user.UserID.String(), s.PasswordResetExpiration); err != nil {
+		return
+	}
+
+	link, err := linkWithToken(s.PasswordResetURL, token)
+
+	if err != nil {
+		log.Printf("Unable to parse the password reset URL: %v. Error: %v\n", s.PasswordResetURL, err)
+		return
+	}
+
+	mail := &model.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your account. If it was you, follow the link within %d minutes:\n\n%s\n\nOtherwise you can ignore this email.",
+			int(s.PasswordResetExpiration.Minutes()), link),
+	}
+
+	if err := s.MailSender.Send(ctx, mail); err != nil {
+		log.Printf("Unable to send the password reset email to userID: %v. Error: %v\n", user.UserID, err)
+	}
+}
+
+// ResetPassword sets a new password with a password reset token,
+// which can't be used again. The new password has to meet the password
+// policy, which is checked before the token is used up, so a rejected
+// password doesn't use up the token. It returns the ID of the user,
+// whose sessions should be signed out.
+func (s *userService) ResetPassword(ctx context.Context, token string, newPassword string) (uuid.UUID, error) {
+	tokenHash := hashToken(token)
+
+	userIDString, err := s.TokenRepository.GetPasswordResetToken(ctx, tokenHash)
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userID, err := uuid.Parse(userIDString)
+
+	if err != nil {
+		log.Printf("Invalid userID: %v of a password reset token. Error: %v\n", userIDString, err)
+		return uuid.Nil, apperrors.NewInternal()
+	}
+
+	user, err := s.UserRepository.FindByID(ctx, userID)
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	password, err := s.PasswordPolicy.Check(ctx, newPassword, user.Email)
+
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Another request may have used the token since it was read.
+	if _, err := s.TokenRepository.ConsumePasswordResetToken(ctx, tokenHash); err != nil {
+		return uuid.Nil, err
+	}
+
+	password, err = hashPassword(password)
+
+	if err != nil {
+		log.Printf("Unable to hash the new password of userID: %v. Error: %v\n", userID, err)
+		return uuid.Nil, apperrors.NewInternal()
+	}
+
+	if err := s.UserRepository.UpdatePassword(ctx, userID, password); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// UpdateDetails updates the profile of a user. A new email becomes the pending
+// email of the user, which replaces the email once it is verified. The link to
+// verify it is mailed to the new address and a notice to the current one.
+// Asking for the pending email again sends a new link.
 func (s *userService) UpdateDetails(ctx context.Context, user *model.User) error {
+	current, err := s.UserRepository.FindByID(ctx, user.UserID)
+
+	if err != nil {
+		return err
+	}
+
+	newEmail := user.Email
+	user.Email = current.Email
+	user.PendingEmail = current.PendingEmail
+
+	if newEmail != current.Email && newEmail != current.PendingEmail {
+		if _, err := s.UserRepository.FindByEmail(ctx, newEmail); err == nil {
+			return apperrors.NewConflict("email", newEmail)
+		}
+
+		user.PendingEmail = newEmail
+	}
+
 	// Update a user in UserRepository.
-	err := s.UserRepository.Update(ctx, user)
+	err = s.UserRepository.Update(ctx, user)
 
 	if err != nil {
 		return err
 	}
 
+	if newEmail != current.Email {
+		if newEmail != current.PendingEmail {
+			s.sendEmailChangeNotice(ctx, current, newEmail)
+		}
+
+		s.sendEmailVerification(ctx, user.UserID, newEmail)
+	}
+
 	return nil
 }
 
@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -63,12 +65,17 @@ func TestSignup(t *testing.T) {
 
 		mockUser := &model.User{
 			Email:    "kostya@kostya.com",
-			Password: "heyman!",
+			Password: "correct horse battery staple",
 		}
 
 		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
 		user := NewUserService(&UserConfig{
-			UserRepository: mockUserRepository,
+			UserRepository:              mockUserRepository,
+			MailSender:                  mockMailSender,
+			EmailVerificationURL:        "http://localhost:8080/verify-email",
+			EmailVerificationSecret:     "someverificationsecret",
+			EmailVerificationExpiration: 24 * 60 * 60,
 		})
 
 		// We can use Run method to modify the user when the Create method is called.
@@ -79,6 +86,12 @@ func TestSignup(t *testing.T) {
 				userArg.UserID = userID
 			}).Return(nil)
 
+		var sentMail *model.Mail
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sentMail = args.Get(1).(*model.Mail)
+			}).Return(nil)
+
 		ctx := context.TODO()
 		err := user.SignUp(ctx, mockUser)
 
@@ -87,13 +100,17 @@ func TestSignup(t *testing.T) {
 		// assert the user now has a userID.
 		assert.Equal(t, userID, mockUser.UserID)
 
+		// The signed up email gets a link to verify it.
+		assert.Equal(t, mockUser.Email, sentMail.To)
+		assert.Contains(t, sentMail.Body, "http://localhost:8080/verify-email?token=")
+
 		mockUserRepository.AssertExpectations(t)
 	})
 
 	t.Run("Error", func(t *testing.T) {
 		mockUser := &model.User{
 			Email:    "kostya@kostya.com",
-			Password: "heyman!",
+			Password: "correct horse battery staple",
 		}
 
 		mockUserRepository := new(mocks.MockUserRepository)
@@ -115,6 +132,24 @@ func TestSignup(t *testing.T) {
 
 		mockUserRepository.AssertExpectations(t)
 	})
+
+	t.Run("Password rejected by the policy", func(t *testing.T) {
+		mockUser := &model.User{
+			Email:    "kostya@kostya.com",
+			Password: "kostya2023",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		user := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		err := user.SignUp(context.TODO(), mockUser)
+
+		assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type)
+		assert.Equal(t, PasswordWeak, err.(*apperrors.Error).Reasons[0].Code)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
 }
 
 func TestSignIn(t *testing.T) {
@@ -154,7 +189,7 @@ func TestSignIn(t *testing.T) {
 		mockUserRepository.On("FindByEmail", mockArguments...).Return(mockUserResponse, nil)
 
 		ctx := context.TODO()
-		err := user.SignIn(ctx, mockUser)
+		err := user.SignIn(ctx, mockUser, "127.0.0.1")
 
 		assert.NoError(t, err)
 		mockUserRepository.AssertCalled(t, "FindByEmail", mockArguments...)
@@ -184,14 +219,219 @@ func TestSignIn(t *testing.T) {
 		mockUserRepository.On("FindByEmail", mockArguments...).Return(mockUserResponse, nil)
 
 		ctx := context.TODO()
-		err := user.SignIn(ctx, mockUser)
+		err := user.SignIn(ctx, mockUser, "127.0.0.1")
 
 		assert.Error(t, err)
 		assert.EqualError(t, err, "Invalid email and password combination")
 		mockUserRepository.AssertCalled(t, "FindByEmail", mockArguments...)
 	})
+
+	t.Run("Upgrades an outdated hash", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		user := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		userID, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			Email:    email,
+			Password: validPassword,
+		}
+
+		mockUserResponse := &model.User{
+			UserID:   userID,
+			Email:    email,
+			Password: legacyHashPassword(validPassword),
+		}
+
+		var upgradedPassword string
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUserResponse, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, userID, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				upgradedPassword = args.Get(2).(string)
+			}).Return(nil)
+
+		err := user.SignIn(context.TODO(), mockUser, "127.0.0.1")
+		assert.NoError(t, err)
+
+		assert.False(t, passwordNeedsRehash(upgradedPassword))
+		assert.Equal(t, upgradedPassword, mockUser.Password)
+
+		match, _ := comparePasswords(upgradedPassword, validPassword)
+		assert.True(t, match)
+	})
+
+	t.Run("Failed upgrade doesn't fail the sign in", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		user := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		userID, _ := uuid.NewRandom()
+
+		mockUser := &model.User{
+			Email:    email,
+			Password: validPassword,
+		}
+
+		mockUserResponse := &model.User{
+			UserID:   userID,
+			Email:    email,
+			Password: legacyHashPassword(validPassword),
+		}
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUserResponse, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, userID, mock.AnythingOfType("string")).Return(apperrors.NewInternal())
+
+		err := user.SignIn(context.TODO(), mockUser, "127.0.0.1")
+		assert.NoError(t, err)
+		assert.Equal(t, userID, mockUser.UserID)
+	})
+
+	t.Run("Malformed stored hash", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		user := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserResponse := &model.User{
+			UserID:   uuid.New(),
+			Email:    email,
+			Password: "nodot",
+		}
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUserResponse, nil)
+
+		err := user.SignIn(context.TODO(), &model.User{Email: email, Password: validPassword}, "127.0.0.1")
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Account without a password", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		user := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUserResponse := &model.User{
+			UserID: uuid.New(),
+			Email:  email,
+		}
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUserResponse, nil)
+
+		err := user.SignIn(context.TODO(), &model.User{Email: email, Password: validPassword}, "127.0.0.1")
+
+		assert.EqualError(t, err, "Invalid email and password combination")
+	})
 }
 
+func TestSignInLockout(t *testing.T) {
+	email := "Kostya@kostya.com"
+	validPassword := "somerandomvalidpasssword"
+	hashedValidPassword, _ := hashPassword(validPassword)
+	ipAddress := "203.0.113.7"
+	userID, _ := uuid.NewRandom()
+
+	newUserService := func(mockUserRepository *mocks.MockUserRepository, mockSignInAttemptRepository *mocks.MockSignInAttemptRepository) model.UserService {
+		return NewUserService(&UserConfig{
+			UserRepository:          mockUserRepository,
+			SignInAttemptRepository: mockSignInAttemptRepository,
+			SignInMaxFailures:       5,
+			SignInIPMaxFailures:     50,
+			SignInBackoffBase:       1,
+			SignInLockoutMax:        900,
+			SignInFailureWindow:     3600,
+		})
+	}
+
+	t.Run("Locked", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockSignInAttemptRepository := new(mocks.MockSignInAttemptRepository)
+		mockSignInAttemptRepository.On("LockedFor", mock.Anything, "email:kostya@kostya.com").Return(90*time.Second+time.Millisecond, nil)
+		mockSignInAttemptRepository.On("LockedFor", mock.Anything, "ip:"+ipAddress).Return(time.Duration(0), nil)
+
+		err := newUserService(mockUserRepository, mockSignInAttemptRepository).SignIn(context.TODO(), &model.User{Email: email, Password: validPassword}, ipAddress)
+
+		assert.Equal(t, apperrors.TooManyRequests, err.(*apperrors.Error).Type)
+		assert.Equal(t, 91, err.(*apperrors.Error).RetryAfter)
+
+		// A locked attempt doesn't cost a password hash.
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failure over the limit locks with a backoff", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email, Password: hashedValidPassword}, nil)
+
+		mockSignInAttemptRepository := new(mocks.MockSignInAttemptRepository)
+		mockSignInAttemptRepository.On("LockedFor", mock.Anything, mock.AnythingOfType("string")).Return(time.Duration(0), nil)
+		mockSignInAttemptRepository.On("AddFailure", mock.Anything, "email:kostya@kostya.com", time.Hour).Return(8, nil)
+		mockSignInAttemptRepository.On("AddFailure", mock.Anything, "ip:"+ipAddress, time.Hour).Return(8, nil)
+		mockSignInAttemptRepository.On("Lock", mock.Anything, "email:kostya@kostya.com", 4*time.Second).Return(nil)
+
+		err := newUserService(mockUserRepository, mockSignInAttemptRepository).SignIn(context.TODO(), &model.User{Email: email, Password: "somerandominvalidpassword"}, ipAddress)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockSignInAttemptRepository.AssertExpectations(t)
+		mockSignInAttemptRepository.AssertNotCalled(t, "Lock", mock.Anything, "ip:"+ipAddress, mock.Anything)
+	})
+
+	t.Run("Unknown email counts as a failure", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		mockSignInAttemptRepository := new(mocks.MockSignInAttemptRepository)
+		mockSignInAttemptRepository.On("LockedFor", mock.Anything, mock.AnythingOfType("string")).Return(time.Duration(0), nil)
+		mockSignInAttemptRepository.On("AddFailure", mock.Anything, mock.AnythingOfType("string"), time.Hour).Return(1, nil)
+
+		err := newUserService(mockUserRepository, mockSignInAttemptRepository).SignIn(context.TODO(), &model.User{Email: email, Password: validPassword}, ipAddress)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockSignInAttemptRepository.AssertNumberOfCalls(t, "AddFailure", 2)
+		mockSignInAttemptRepository.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Success resets the failures of the email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email, Password: hashedValidPassword}, nil)
+
+		mockSignInAttemptRepository := new(mocks.MockSignInAttemptRepository)
+		mockSignInAttemptRepository.On("LockedFor", mock.Anything, mock.AnythingOfType("string")).Return(time.Duration(0), nil)
+		mockSignInAttemptRepository.On("Reset", mock.Anything, "email:kostya@kostya.com").Return(nil)
+
+		err := newUserService(mockUserRepository, mockSignInAttemptRepository).SignIn(context.TODO(), &model.User{Email: email, Password: validPassword}, ipAddress)
+
+		assert.NoError(t, err)
+		mockSignInAttemptRepository.AssertExpectations(t)
+		mockSignInAttemptRepository.AssertNotCalled(t, "Reset", mock.Anything, "ip:"+ipAddress)
+	})
+
+	t.Run("Backoff doubles up to the maximum", func(t *testing.T) {
+		userService := newUserService(nil, nil).(*userService)
+
+		assert.Equal(t, time.Second, userService.signInBackoff(1))
+		assert.Equal(t, 2*time.Second, userService.signInBackoff(2))
+		assert.Equal(t, 512*time.Second, userService.signInBackoff(10))
+		assert.Equal(t, 15*time.Minute, userService.signInBackoff(11))
+		assert.Equal(t, 15*time.Minute, userService.signInBackoff(1000))
+	})
+
+	t.Run("Admin unlocks the email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID, Email: email}, nil)
+
+		mockSignInAttemptRepository := new(mocks.MockSignInAttemptRepository)
+		mockSignInAttemptRepository.On("Reset", mock.Anything, "email:kostya@kostya.com").Return(nil)
+
+		err := newUserService(mockUserRepository, mockSignInAttemptRepository).UnlockSignIn(context.TODO(), userID)
+
+		assert.NoError(t, err)
+		mockSignInAttemptRepository.AssertExpectations(t)
+	})
+}
 func TestUpdateDetails(t *testing.T) {
 	mockUserRepository := new(mocks.MockUserRepository)
 	user := NewUserService(&UserConfig{
@@ -213,6 +453,7 @@ func TestUpdateDetails(t *testing.T) {
 			mockUser,
 		}
 
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID, Email: "new@kostya.com"}, nil)
 		mockUserRepository.On("Update", mockArguments...).Return(nil)
 
 		ctx := context.TODO()
@@ -236,6 +477,7 @@ func TestUpdateDetails(t *testing.T) {
 
 		mockError := apperrors.NewInternal()
 
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID}, nil)
 		mockUserRepository.On("Update", mockArguments...).Return(mockError)
 
 		ctx := context.TODO()
@@ -248,6 +490,147 @@ func TestUpdateDetails(t *testing.T) {
 
 		mockUserRepository.AssertCalled(t, "Update", mockArguments...)
 	})
+
+	t.Run("New email is pending until verified", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := NewUserService(&UserConfig{
+			UserRepository:              mockUserRepository,
+			MailSender:                  mockMailSender,
+			EmailVerificationURL:        "http://localhost:8080/verify-email",
+			EmailVerificationSecret:     "someverificationsecret",
+			EmailVerificationExpiration: 24 * 60 * 60,
+		})
+
+		userID, _ := uuid.NewRandom()
+		currentUser := &model.User{
+			UserID:        userID,
+			Email:         "kostya@kostya.com",
+			EmailVerified: true,
+		}
+		mockUser := &model.User{
+			UserID:   userID,
+			Email:    "new@kostya.com",
+			Username: "A New Kostya!",
+		}
+
+		var sentMails []*model.Mail
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(currentUser, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "new@kostya.com").Return(nil, apperrors.NewNotFound("email", "new@kostya.com"))
+		mockUserRepository.On("Update", mock.Anything, mockUser).Return(nil)
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sentMails = append(sentMails, args.Get(1).(*model.Mail))
+			}).Return(nil)
+
+		err := userService.UpdateDetails(context.TODO(), mockUser)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "kostya@kostya.com", mockUser.Email)
+		assert.Equal(t, "new@kostya.com", mockUser.PendingEmail)
+
+		// The current address gets a notice and the new one a link to verify it.
+		assert.Len(t, sentMails, 2)
+		assert.Equal(t, "kostya@kostya.com", sentMails[0].To)
+		assert.Equal(t, "new@kostya.com", sentMails[1].To)
+		assert.Contains(t, sentMails[1].Body, "http://localhost:8080/verify-email?token=")
+	})
+
+	t.Run("Email of another user", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		userID, _ := uuid.NewRandom()
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  "taken@kostya.com",
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(&model.User{UserID: userID, Email: "kostya@kostya.com"}, nil)
+		mockUserRepository.On("FindByEmail", mock.Anything, "taken@kostya.com").Return(&model.User{UserID: uuid.New()}, nil)
+
+		err := userService.UpdateDetails(context.TODO(), mockUser)
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	secret := "someverificationsecret"
+	userID, _ := uuid.NewRandom()
+
+	newUserService := func(mockUserRepository *mocks.MockUserRepository) model.UserService {
+		return NewUserService(&UserConfig{
+			UserRepository:          mockUserRepository,
+			EmailVerificationSecret: secret,
+		})
+	}
+
+	t.Run("Verifies the pending email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := newUserService(mockUserRepository)
+
+		mockUser := &model.User{
+			UserID:       userID,
+			Email:        "kostya@kostya.com",
+			PendingEmail: "new@kostya.com",
+		}
+		verifiedUser := &model.User{
+			UserID:        userID,
+			Email:         "new@kostya.com",
+			EmailVerified: true,
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+		mockUserRepository.On("VerifyEmail", mock.Anything, userID, "new@kostya.com").Return(verifiedUser, nil)
+
+		token, _ := generateEmailVerificationToken(userID, "new@kostya.com", secret, time.Hour)
+
+		user, err := userService.VerifyEmail(context.TODO(), token)
+		assert.NoError(t, err)
+
+		assert.Equal(t, verifiedUser, user)
+	})
+
+	t.Run("Link to an address the user moved on from", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := newUserService(mockUserRepository)
+
+		mockUser := &model.User{
+			UserID:       userID,
+			Email:        "kostya@kostya.com",
+			PendingEmail: "newer@kostya.com",
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+
+		token, _ := generateEmailVerificationToken(userID, "new@kostya.com", secret, time.Hour)
+
+		_, err := userService.VerifyEmail(context.TODO(), token)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "VerifyEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid tokens", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := newUserService(mockUserRepository)
+
+		expiredToken, _ := generateEmailVerificationToken(userID, "kostya@kostya.com", secret, -time.Minute)
+		otherSecretToken, _ := generateEmailVerificationToken(userID, "kostya@kostya.com", "anothersecret", time.Hour)
+		refreshToken, _ := generateRefreshToken(userID, uuid.New(), time.Now(), secret, 60)
+
+		for _, token := range []string{expiredToken, otherSecretToken, refreshToken.SignedString, "notatoken"} {
+			_, err := userService.VerifyEmail(context.TODO(), token)
+
+			assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		}
+
+		mockUserRepository.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
 }
 
 func TestSetProfileImage(t *testing.T) {
@@ -506,3 +889,564 @@ func TestSetProfileImage(t *testing.T) {
 
 	})
 }
+
+func TestUpdatePassword(t *testing.T) {
+	currentPassword := "somerandomvalidpasssword"
+	hashedCurrentPassword, _ := hashPassword(currentPassword)
+	newPassword := "somenewrandompassword"
+
+	userID, _ := uuid.NewRandom()
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUser := &model.User{
+			UserID:   userID,
+			Email:    "kostya@kostya.com",
+			Password: hashedCurrentPassword,
+		}
+
+		var storedPassword string
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, userID, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				storedPassword = args.Get(2).(string)
+			}).Return(nil)
+
+		user, err := userService.UpdatePassword(context.TODO(), userID, currentPassword, newPassword)
+		assert.NoError(t, err)
+
+		assert.Equal(t, userID, user.UserID)
+		assert.Equal(t, storedPassword, user.Password)
+
+		match, _ := comparePasswords(storedPassword, newPassword)
+		assert.True(t, match)
+	})
+
+	t.Run("Invalid current password", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository: mockUserRepository,
+		})
+
+		mockUser := &model.User{
+			UserID:   userID,
+			Password: hashedCurrentPassword,
+		}
+
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+
+		user, err := userService.UpdatePassword(context.TODO(), userID, "somerandominvalidpassword", newPassword)
+
+		assert.Nil(t, user)
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestForgotPassword(t *testing.T) {
+	email := "kostya@kostya.com"
+	userID, _ := uuid.NewRandom()
+
+	newUserService := func(mockUserRepository *mocks.MockUserRepository, mockTokenRepository *mocks.MockTokenRepository, mockMailSender *mocks.MockMailSender) model.UserService {
+		return NewUserService(&UserConfig{
+			UserRepository:          mockUserRepository,
+			TokenRepository:         mockTokenRepository,
+			MailSender:              mockMailSender,
+			PasswordResetURL:        "http://localhost:8080/reset-password",
+			PasswordResetExpiration: 15 * 60,
+		})
+	}
+
+	t.Run("Mails a reset token", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockTokenRepository, mockMailSender)
+
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  email,
+		}
+
+		var storedTokenHash string
+		var sentMail *model.Mail
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUser, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), userID.String(), 15*time.Minute).
+			Run(func(args mock.Arguments) {
+				storedTokenHash = args.Get(1).(string)
+			}).Return(nil)
+		sent := make(chan struct{})
+		var hasDeadline bool
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				_, hasDeadline = args.Get(0).(context.Context).Deadline()
+				sentMail = args.Get(1).(*model.Mail)
+				close(sent)
+			}).Return(nil)
+
+		err := userService.ForgotPassword(context.TODO(), email)
+		assert.NoError(t, err)
+
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Fatal("the password reset email wasn't sent")
+		}
+
+		assert.Equal(t, email, sentMail.To)
+		assert.True(t, hasDeadline)
+
+		// Only the hash of the mailed token is stored.
+		match := regexp.MustCompile(`http://localhost:8080/reset-password\?token=([\w-]+)`).FindStringSubmatch(sentMail.Body)
+		assert.Len(t, match, 2)
+		assert.Equal(t, hashToken(match[1]), storedTokenHash)
+		assert.NotContains(t, sentMail.Body, storedTokenHash)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockTokenRepository, mockMailSender)
+
+		found := make(chan struct{})
+		mockUserRepository.On("FindByEmail", mock.Anything, email).
+			Run(func(args mock.Arguments) {
+				close(found)
+			}).Return(nil, apperrors.NewNotFound("email", email))
+
+		err := userService.ForgotPassword(context.TODO(), email)
+		assert.NoError(t, err)
+
+		select {
+		case <-found:
+		case <-time.After(time.Second):
+			t.Fatal("the email wasn't looked up")
+		}
+
+		mockTokenRepository.AssertNotCalled(t, "SetPasswordResetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockMailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Returns before the email is sent", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockTokenRepository, mockMailSender)
+
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  email,
+		}
+
+		// The mail sender blocks until the test is over.
+		release := make(chan struct{})
+		defer close(release)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUser, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), userID.String(), 15*time.Minute).Return(nil)
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				<-release
+			}).Return(nil)
+
+		returned := make(chan error)
+
+		go func() {
+			returned <- userService.ForgotPassword(context.TODO(), email)
+		}()
+
+		select {
+		case err := <-returned:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("ForgotPassword waited on the mail sender")
+		}
+	})
+
+	t.Run("Drops resets beyond the pending limit", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockTokenRepository, mockMailSender)
+
+		mockUser := &model.User{
+			UserID: userID,
+			Email:  email,
+		}
+
+		// The mail sender blocks until every pending reset is sending.
+		sending := make(chan struct{})
+		release := make(chan struct{})
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(mockUser, nil)
+		mockTokenRepository.On("SetPasswordResetToken", mock.Anything, mock.AnythingOfType("string"), userID.String(), 15*time.Minute).Return(nil)
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sending <- struct{}{}
+				<-release
+			}).Return(nil)
+
+		for i := 0; i < maxPendingPasswordResets; i++ {
+			assert.NoError(t, userService.ForgotPassword(context.TODO(), email))
+		}
+
+		for i := 0; i < maxPendingPasswordResets; i++ {
+			select {
+			case <-sending:
+			case <-time.After(time.Second):
+				t.Fatal("the password reset emails weren't sent")
+			}
+		}
+
+		err := userService.ForgotPassword(context.TODO(), email)
+		assert.NoError(t, err)
+
+		close(release)
+
+		mockUserRepository.AssertNumberOfCalls(t, "FindByEmail", maxPendingPasswordResets)
+		mockMailSender.AssertNumberOfCalls(t, "Send", maxPendingPasswordResets)
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	token := "somerandomresettoken"
+	newPassword := "somenewrandompassword"
+	userID, _ := uuid.NewRandom()
+	mockUser := &model.User{
+		UserID: userID,
+		Email:  "kostya.yachnytskyi@kostya.com",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		var storedPassword string
+		mockTokenRepository.On("GetPasswordResetToken", mock.Anything, hashToken(token)).Return(userID.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+		mockTokenRepository.On("ConsumePasswordResetToken", mock.Anything, hashToken(token)).Return(userID.String(), nil)
+		mockUserRepository.On("UpdatePassword", mock.Anything, userID, mock.AnythingOfType("string")).
+			Run(func(args mock.Arguments) {
+				storedPassword = args.Get(2).(string)
+			}).Return(nil)
+
+		resetUserID, err := userService.ResetPassword(context.TODO(), token, newPassword)
+		assert.NoError(t, err)
+
+		assert.Equal(t, userID, resetUserID)
+
+		match, _ := comparePasswords(storedPassword, newPassword)
+		assert.True(t, match)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		mockTokenRepository.On("GetPasswordResetToken", mock.Anything, hashToken(token)).Return("", apperrors.NewAuthorization("Invalid or expired password reset token"))
+
+		_, err := userService.ResetPassword(context.TODO(), token, newPassword)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Token used in the meantime", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := NewUserService(&UserConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+		})
+
+		mockTokenRepository.On("GetPasswordResetToken", mock.Anything, hashToken(token)).Return(userID.String(), nil)
+		mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+		mockTokenRepository.On("ConsumePasswordResetToken", mock.Anything, hashToken(token)).Return("", apperrors.NewAuthorization("Invalid or expired password reset token"))
+
+		_, err := userService.ResetPassword(context.TODO(), token, newPassword)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("New password rejected by the policy", func(t *testing.T) {
+		// Passwords made of the email are as weak as common ones.
+		for _, password := range []string{"password", "kostya.yachnytskyi"} {
+			mockUserRepository := new(mocks.MockUserRepository)
+			mockTokenRepository := new(mocks.MockTokenRepository)
+			userService := NewUserService(&UserConfig{
+				UserRepository:  mockUserRepository,
+				TokenRepository: mockTokenRepository,
+			})
+
+			mockTokenRepository.On("GetPasswordResetToken", mock.Anything, hashToken(token)).Return(userID.String(), nil)
+			mockUserRepository.On("FindByID", mock.Anything, userID).Return(mockUser, nil)
+
+			_, err := userService.ResetPassword(context.TODO(), token, password)
+
+			assert.Equal(t, apperrors.BadRequest, err.(*apperrors.Error).Type, password)
+
+			// The token can still be used with another password.
+			mockTokenRepository.AssertNotCalled(t, "ConsumePasswordResetToken", mock.Anything, mock.Anything)
+			mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestSendMagicLink(t *testing.T) {
+	email := "kostya@kostya.com"
+	userID, _ := uuid.NewRandom()
+
+	newUserService := func(mockUserRepository *mocks.MockUserRepository, mockMailSender *mocks.MockMailSender, signUp bool) model.UserService {
+		return NewUserService(&UserConfig{
+			UserRepository:      mockUserRepository,
+			MailSender:          mockMailSender,
+			MagicLinkURL:        "http://localhost:8080/signin/link",
+			MagicLinkSecret:     "somemagiclinksecret",
+			MagicLinkExpiration: 15 * 60,
+			MagicLinkSignUp:     signUp,
+		})
+	}
+
+	t.Run("Mails a sign in link", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockMailSender, false)
+
+		var sentMail *model.Mail
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email}, nil)
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sentMail = args.Get(1).(*model.Mail)
+			}).Return(nil)
+
+		err := userService.SendMagicLink(context.TODO(), email)
+		assert.NoError(t, err)
+
+		assert.Equal(t, email, sentMail.To)
+
+		match := regexp.MustCompile(`http://localhost:8080/signin/link\?token=([\w.-]+)`).FindStringSubmatch(sentMail.Body)
+		assert.Len(t, match, 2)
+
+		claims, err := validateMagicLinkToken(match[1], "somemagiclinksecret")
+		assert.NoError(t, err)
+		assert.Equal(t, email, claims.Email)
+		assert.NotEmpty(t, claims.Id)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockMailSender, false)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		err := userService.SendMagicLink(context.TODO(), email)
+		assert.NoError(t, err)
+
+		mockMailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown email when links sign up", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockMailSender, true)
+
+		var sentMail *model.Mail
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sentMail = args.Get(1).(*model.Mail)
+			}).Return(nil)
+
+		err := userService.SendMagicLink(context.TODO(), email)
+		assert.NoError(t, err)
+
+		assert.Contains(t, sentMail.Body, "create your account")
+		assert.Contains(t, sentMail.Body, "http://localhost:8080/signin/link?token=")
+	})
+
+	t.Run("Users which can't be read", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockMailSender, true)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewInternal())
+
+		err := userService.SendMagicLink(context.TODO(), email)
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+		mockMailSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Two-factor authentication enabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockMailSender := new(mocks.MockMailSender)
+		userService := newUserService(mockUserRepository, mockMailSender, false)
+
+		var sentMail *model.Mail
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email, TOTPEnabled: true}, nil)
+		mockMailSender.On("Send", mock.Anything, mock.AnythingOfType("*model.Mail")).
+			Run(func(args mock.Arguments) {
+				sentMail = args.Get(1).(*model.Mail)
+			}).Return(nil)
+
+		err := userService.SendMagicLink(context.TODO(), email)
+		assert.NoError(t, err)
+
+		assert.NotContains(t, sentMail.Body, "token=")
+	})
+}
+
+func TestSignInWithMagicLink(t *testing.T) {
+	secret := "somemagiclinksecret"
+	email := "kostya@kostya.com"
+	userID, _ := uuid.NewRandom()
+
+	newUserService := func(mockUserRepository *mocks.MockUserRepository, mockTokenRepository *mocks.MockTokenRepository, signUp bool) model.UserService {
+		return NewUserService(&UserConfig{
+			UserRepository:  mockUserRepository,
+			TokenRepository: mockTokenRepository,
+			MagicLinkSecret: secret,
+			MagicLinkSignUp: signUp,
+		})
+	}
+
+	t.Run("Signs in and verifies the email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, false)
+
+		verifiedUser := &model.User{UserID: userID, Email: email, EmailVerified: true}
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+		claims, _ := validateMagicLinkToken(token, secret)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email}, nil)
+		mockTokenRepository.On("UseMagicLinkToken", mock.Anything, claims.Id, mock.AnythingOfType("time.Duration")).Return(true, nil)
+		mockUserRepository.On("VerifyEmail", mock.Anything, userID, email).Return(verifiedUser, nil)
+
+		user, err := userService.SignInWithMagicLink(context.TODO(), token)
+		assert.NoError(t, err)
+
+		assert.Equal(t, verifiedUser, user)
+		mockTokenRepository.AssertExpectations(t)
+	})
+
+	t.Run("Used link", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, false)
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email}, nil)
+		mockTokenRepository.On("UseMagicLinkToken", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+		_, err := userService.SignInWithMagicLink(context.TODO(), token)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "VerifyEmail", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Creates the account on first use", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, true)
+
+		verifiedUser := &model.User{UserID: userID, Email: email, EmailVerified: true}
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+		mockTokenRepository.On("UseMagicLinkToken", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		mockUserRepository.On("Create", mock.Anything, &model.User{Email: email}).
+			Run(func(args mock.Arguments) {
+				args.Get(1).(*model.User).UserID = userID
+			}).Return(nil)
+		mockUserRepository.On("VerifyEmail", mock.Anything, userID, email).Return(verifiedUser, nil)
+
+		user, err := userService.SignInWithMagicLink(context.TODO(), token)
+		assert.NoError(t, err)
+
+		assert.Equal(t, verifiedUser, user)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, false)
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewNotFound("email", email))
+
+		_, err := userService.SignInWithMagicLink(context.TODO(), token)
+
+		assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Users which can't be read", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, true)
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(nil, apperrors.NewInternal())
+
+		_, err := userService.SignInWithMagicLink(context.TODO(), token)
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "UseMagicLinkToken", mock.Anything, mock.Anything, mock.Anything)
+		mockUserRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Two-factor authentication enabled", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, false)
+
+		token, _ := generateMagicLinkToken(email, secret, 15*time.Minute)
+
+		mockUserRepository.On("FindByEmail", mock.Anything, email).Return(&model.User{UserID: userID, Email: email, TOTPEnabled: true}, nil)
+
+		_, err := userService.SignInWithMagicLink(context.TODO(), token)
+
+		assert.Equal(t, apperrors.Forbidden, err.(*apperrors.Error).Type)
+		mockTokenRepository.AssertNotCalled(t, "UseMagicLinkToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid tokens", func(t *testing.T) {
+		mockUserRepository := new(mocks.MockUserRepository)
+		mockTokenRepository := new(mocks.MockTokenRepository)
+		userService := newUserService(mockUserRepository, mockTokenRepository, true)
+
+		expiredToken, _ := generateMagicLinkToken(email, secret, -time.Minute)
+		otherSecretToken, _ := generateMagicLinkToken(email, "anothersecret", time.Hour)
+		verificationToken, _ := generateEmailVerificationToken(userID, email, secret, time.Hour)
+
+		for _, token := range []string{expiredToken, otherSecretToken, verificationToken, "notatoken"} {
+			_, err := userService.SignInWithMagicLink(context.TODO(), token)
+
+			assert.Equal(t, apperrors.Authorization, err.(*apperrors.Error).Type)
+		}
+
+		mockUserRepository.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,146 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// maxRevocationCacheEntries bounds the memory used by the cache.
+const maxRevocationCacheEntries = 10000
+
+// revocationCacheEntry remembers whether a token was active.
+type revocationCacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// revocationChecker asks the account service's introspection endpoint
+// whether tokens have been revoked. Answers are cached like the account
+// service caches its own denylist lookups: revoked tokens until they
+// expire and active tokens for a short time.
+type revocationChecker struct {
+	url          string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+	expiration   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]revocationCacheEntry
+}
+
+func newRevocationChecker(url string, clientID string, clientSecret string, httpClient *http.Client, expiration time.Duration) *revocationChecker {
+	return &revocationChecker{
+		url:          url,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   httpClient,
+		expiration:   expiration,
+		entries:      make(map[string]revocationCacheEntry),
+	}
+}
+
+// check returns ErrRevokedToken if the token is no longer active.
+func (c *revocationChecker) check(ctx context.Context, tokenString string, tokenID string, tokenExpiresAt time.Time) error {
+	active, ok := c.get(tokenID)
+
+	if !ok {
+		introspection, err := c.introspect(ctx, tokenString)
+
+		if err != nil {
+			return err
+		}
+
+		active = introspection.Active
+		c.set(tokenID, active, tokenExpiresAt)
+	}
+
+	if !active {
+		return ErrRevokedToken
+	}
+
+	return nil
+}
+
+func (c *revocationChecker) introspect(ctx context.Context, tokenString string) (*model.TokenIntrospection, error) {
+	form := url.Values{
+		"token":           {tokenString},
+		"token_type_hint": {model.AccessTokenType},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+
+	response, err := c.httpClient.Do(request)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not introspect token: %v", ErrUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: introspection responded with status %d", ErrUnavailable, response.StatusCode)
+	}
+
+	introspection := &model.TokenIntrospection{}
+
+	if err := json.NewDecoder(response.Body).Decode(introspection); err != nil {
+		return nil, fmt.Errorf("%w: could not decode introspection: %v", ErrUnavailable, err)
+	}
+
+	return introspection, nil
+}
+
+func (c *revocationChecker) get(tokenID string) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[tokenID]
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.active, true
+}
+
+func (c *revocationChecker) set(tokenID string, active bool, tokenExpiresAt time.Time) {
+	expiresAt := tokenExpiresAt
+
+	if cacheExpiresAt := time.Now().Add(c.expiration); active && cacheExpiresAt.Before(expiresAt) {
+		expiresAt = cacheExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Expired entries are only removed when the cache is full.
+	if len(c.entries) >= maxRevocationCacheEntries {
+		now := time.Now()
+
+		for id, entry := range c.entries {
+			if now.After(entry.expiresAt) || entry.active {
+				delete(c.entries, id)
+			}
+		}
+	}
+
+	c.entries[tokenID] = revocationCacheEntry{
+		active:    active,
+		expiresAt: expiresAt,
+	}
+}
@@ -0,0 +1,167 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// minKeysRefreshInterval limits how often an unknown kid makes us fetch the
+// keys again, so made up kids can't be used to flood the account service.
+const minKeysRefreshInterval = 30 * time.Second
+
+// verificationKey is a public key from the key set with its algorithm.
+type verificationKey struct {
+	algorithm string
+	publicKey crypto.PublicKey
+}
+
+// keySet caches the keys published by the account service.
+// New signing keys are published before they are used, so an unknown kid
+// usually means a retired key or a token we didn't issue. We still fetch
+// the keys again in case the account service rotated in between.
+type keySet struct {
+	url             string
+	httpClient      *http.Client
+	refreshInterval time.Duration
+
+	fetchMu   sync.Mutex // Held while fetching, so only one fetch runs at a time.
+	mu        sync.RWMutex
+	keys      map[string]*verificationKey
+	fetchedAt time.Time
+}
+
+func newKeySet(url string, httpClient *http.Client, refreshInterval time.Duration) *keySet {
+	return &keySet{
+		url:             url,
+		httpClient:      httpClient,
+		refreshInterval: refreshInterval,
+		keys:            make(map[string]*verificationKey),
+	}
+}
+
+// get returns the key with the kid, fetching the keys if they are stale.
+func (s *keySet) get(ctx context.Context, kid string) (*verificationKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	age := time.Since(s.fetchedAt)
+	s.mu.RUnlock()
+
+	if ok && age < s.refreshInterval {
+		return key, nil
+	}
+
+	if !ok && age < minKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		// Keep using a known key while the account service is unreachable.
+		if ok {
+			log.Printf("Using cached key %s after failing to fetch the key set: %v\n", kid, err)
+			return key, nil
+		}
+
+		return nil, err
+	}
+
+	s.mu.RLock()
+	key, ok = s.keys[kid]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	return key, nil
+}
+
+// refresh fetches the key set and replaces the cached keys.
+func (s *keySet) refresh(ctx context.Context) error {
+	requestedAt := time.Now()
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	// Another request fetched the keys while we were waiting.
+	s.mu.RLock()
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+
+	if fetchedAt.After(requestedAt) {
+		return nil
+	}
+
+	keys, err := s.fetch(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+
+	return nil
+}
+
+func (s *keySet) fetch(ctx context.Context) (map[string]*verificationKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	response, err := s.httpClient.Do(request)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not fetch key set: %v", ErrUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: key set responded with status %d", ErrUnavailable, response.StatusCode)
+	}
+
+	keySet := &model.JSONWebKeySet{}
+
+	if err := json.NewDecoder(response.Body).Decode(keySet); err != nil {
+		return nil, fmt.Errorf("%w: could not decode key set: %v", ErrUnavailable, err)
+	}
+
+	keys := make(map[string]*verificationKey, len(keySet.Keys))
+
+	for i := range keySet.Keys {
+		jwk := &keySet.Keys[i]
+
+		// Keys we can't use are skipped, so a key of a newer
+		// algorithm doesn't break verification with the others.
+		if jwk.KeyID == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		publicKey, err := jwa.PublicKey(jwk)
+
+		if err != nil {
+			log.Printf("Skipping key %s of the key set: %v\n", jwk.KeyID, err)
+			continue
+		}
+
+		keys[jwk.KeyID] = &verificationKey{
+			algorithm: jwk.Algorithm,
+			publicKey: publicKey,
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,90 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+type contextKey struct{}
+
+// PrincipalFromContext returns the principal the middleware
+// put into the request context.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
+
+// Middleware returns net/http middleware which verifies the ID token
+// in the Authorization header, which is of the form "Bearer token".
+// It puts the principal into the request context, or responds with
+// an error in the format of the account service.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		principal, err := v.authenticate(request)
+
+		if err != nil {
+			writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			writer.WriteHeader(err.Status())
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"error": err,
+			})
+			return
+		}
+
+		next.ServeHTTP(writer, request.WithContext(withPrincipal(request.Context(), principal)))
+	})
+}
+
+// Gin returns gin middleware which verifies the ID token in the Authorization header.
+// It puts the principal into the request context, and sets it on the gin
+// context as "principal", or aborts with an error in the format of the account service.
+func (v *Verifier) Gin() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		principal, err := v.authenticate(context.Request)
+
+		if err != nil {
+			context.AbortWithStatusJSON(err.Status(), gin.H{
+				"error": err,
+			})
+			return
+		}
+
+		context.Request = context.Request.WithContext(withPrincipal(context.Request.Context(), principal))
+		context.Set("principal", principal)
+
+		context.Next()
+	}
+}
+
+func withPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// authenticate verifies the bearer token of a request.
+func (v *Verifier) authenticate(request *http.Request) (*Principal, *apperrors.Error) {
+	tokenString, ok := bearerToken(request.Header.Get("Authorization"))
+
+	if !ok {
+		return nil, apperrors.NewAuthorization("Must provide Authorization header with format `Bearer {token}`")
+	}
+
+	principal, err := v.Verify(request.Context(), tokenString)
+
+	if errors.Is(err, ErrUnavailable) {
+		log.Printf("Unable to verify the token: %v\n", err)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	if err != nil {
+		log.Printf("Unable to verify the token: %v\n", err)
+		return nil, apperrors.NewAuthorization("Provided token is invalid")
+	}
+
+	return principal, nil
+}
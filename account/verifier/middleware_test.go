@@ -0,0 +1,116 @@
+package verifier
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model/apperrors"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	userID, _ := uuid.NewRandom()
+
+	accountService := newTestAccountService(t)
+	kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+	verifier, _ := NewVerifier(accountService.config())
+	validToken := signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID))
+
+	downService := newTestAccountService(t)
+	downService.down = true
+	downVerifier, _ := NewVerifier(downService.config())
+
+	// Both middlewares should behave the same.
+	newRouters := func(verifier *Verifier) map[string]http.Handler {
+		router := gin.Default()
+		router.GET("/me", verifier.Gin(), func(c *gin.Context) {
+			principal, ok := PrincipalFromContext(c.Request.Context())
+			assert.True(t, ok)
+
+			contextPrincipal, exists := c.Get("principal")
+			assert.True(t, exists)
+			assert.Equal(t, principal, contextPrincipal)
+
+			c.JSON(http.StatusOK, gin.H{"uid": principal.UserID})
+		})
+
+		handler := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFromContext(r.Context())
+			assert.True(t, ok)
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"uid": principal.UserID})
+		}))
+
+		return map[string]http.Handler{"gin": router, "net/http": handler}
+	}
+
+	serve := func(handler http.Handler, authorization string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/me", nil)
+
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		handler.ServeHTTP(rr, request)
+
+		return rr
+	}
+
+	for name, handler := range newRouters(verifier) {
+		t.Run(name+" valid token", func(t *testing.T) {
+			rr := serve(handler, "Bearer "+validToken)
+
+			expectedBody, _ := json.Marshal(gin.H{"uid": userID})
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+		})
+
+		t.Run(name+" missing token", func(t *testing.T) {
+			rr := serve(handler, "")
+
+			expectedBody, _ := json.Marshal(gin.H{
+				"error": apperrors.NewAuthorization("Must provide Authorization header with format `Bearer {token}`"),
+			})
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+		})
+
+		t.Run(name+" invalid token", func(t *testing.T) {
+			rr := serve(handler, "Bearer "+validToken+"x")
+
+			expectedBody, _ := json.Marshal(gin.H{
+				"error": apperrors.NewAuthorization("Provided token is invalid"),
+			})
+
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+		})
+	}
+
+	for name, handler := range newRouters(downVerifier) {
+		t.Run(name+" account service unavailable", func(t *testing.T) {
+			rr := serve(handler, "Bearer "+validToken)
+
+			expectedBody, _ := json.Marshal(gin.H{
+				"error": apperrors.NewServiceUnavailable(),
+			})
+
+			assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+			assert.JSONEq(t, string(expectedBody), rr.Body.String())
+		})
+	}
+}
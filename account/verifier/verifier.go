@@ -0,0 +1,274 @@
+// Package verifier verifies ID tokens issued by the account service,
+// and the access tokens it issues to machine clients.
+// Services running next to the account service import it instead of
+// copying the token validation and a public key PEM file. Keys are fetched
+// from the account service's JWKS endpoint, so key rotation needs no
+// changes in other services, and revoked tokens can be rejected through
+// the introspection endpoint.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+// Errors returned by Verify. Other errors wrap one of them.
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrRevokedToken = errors.New("token has been revoked")
+	ErrUnavailable  = errors.New("account service is unavailable")
+)
+
+// Principal is the user an ID token was issued to, or the machine
+// client an access token of the client credentials grant was issued to.
+// Profile fields are only set if the account service is
+// configured to include them in ID tokens.
+type Principal struct {
+	UserID        uuid.UUID
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Website       string
+	AuthTime      time.Time // When the user signed in.
+	ClientID      string    // The OAuth client the token was issued to, which is the audience of the verifier. Empty for our own sign in.
+	Scope         string    // The scopes the user allowed the client, or the machine client was granted.
+	Service       bool      // A machine client acting on its own behalf, with no UserID.
+	TokenID       string
+	ExpiresAt     time.Time
+}
+
+// Config holds the settings of a Verifier.
+// JWKSURL, Issuer and Audience are required.
+type Config struct {
+	JWKSURL   string // Such as https://example.com/api/account/.well-known/jwks.json
+	Issuer    string
+	Audience  string
+	ClockSkew time.Duration
+
+	// KeysRefreshInterval is how long fetched keys are used before
+	// fetching them again. Defaults to five minutes.
+	KeysRefreshInterval time.Duration
+
+	// IntrospectionURL enables checking that tokens have not been revoked.
+	// The client credentials must be registered with the account service
+	// with the introspect scope, which makes the client a resource server.
+	IntrospectionURL string
+	ClientID         string
+	ClientSecret     string
+
+	// RevocationCacheExpiration is how long a token which is not revoked
+	// is remembered. Defaults to five seconds.
+	RevocationCacheExpiration time.Duration
+
+	HTTPClient *http.Client // Defaults to a client with a ten second timeout.
+}
+
+// Verifier verifies ID tokens. It is safe for concurrent use.
+type Verifier struct {
+	issuer     string
+	audience   string
+	clockSkew  time.Duration
+	keys       *keySet
+	revocation *revocationChecker
+}
+
+// serviceTokenType is the typ header of access tokens of machine clients.
+const serviceTokenType = "at+jwt"
+
+// idTokenClaims holds the claims of ID tokens issued by the account service.
+type idTokenClaims struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Website       string `json:"website,omitempty"`
+	ClientID      string `json:"client_id,omitempty"` // Set in access tokens of machine clients.
+	// AuthorizedParty and Scope are set in tokens of OAuth clients,
+	// whose audience is an array, which the standard claims can't hold.
+	AuthorizedParty string         `json:"azp,omitempty"`
+	Scope           string         `json:"scope,omitempty"`
+	Audience        model.Audience `json:"aud,omitempty"`
+	jwt.StandardClaims
+}
+
+// NewVerifier is a factory function for
+// initializing a Verifier from its config.
+func NewVerifier(c *Config) (*Verifier, error) {
+	if c.JWKSURL == "" || c.Issuer == "" || c.Audience == "" {
+		return nil, fmt.Errorf("JWKSURL, Issuer and Audience must be set")
+	}
+
+	httpClient := c.HTTPClient
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	refreshInterval := c.KeysRefreshInterval
+
+	if refreshInterval <= 0 {
+		refreshInterval = 5 * time.Minute
+	}
+
+	verifier := &Verifier{
+		issuer:    c.Issuer,
+		audience:  c.Audience,
+		clockSkew: c.ClockSkew,
+		keys:      newKeySet(c.JWKSURL, httpClient, refreshInterval),
+	}
+
+	if c.IntrospectionURL != "" {
+		cacheExpiration := c.RevocationCacheExpiration
+
+		if cacheExpiration <= 0 {
+			cacheExpiration = 5 * time.Second
+		}
+
+		verifier.revocation = newRevocationChecker(c.IntrospectionURL, c.ClientID, c.ClientSecret, httpClient, cacheExpiration)
+	}
+
+	return verifier, nil
+}
+
+// Verify checks the signature and claims of an ID token and,
+// if introspection is configured, that it has not been revoked.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Principal, error) {
+	claims := &idTokenClaims{}
+
+	// The time based claims are checked below, with the clock skew.
+	parser := &jwt.Parser{
+		ValidMethods:         jwa.Algorithms(),
+		SkipClaimsValidation: true,
+	}
+
+	var keyErr error
+	var service bool
+
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		typ, _ := token.Header["typ"].(string)
+		service = typ == serviceTokenType
+
+		key, err := v.keys.get(ctx, kid)
+
+		if err != nil {
+			keyErr = err
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s for key id: %s", token.Method.Alg(), kid)
+		}
+
+		return key.publicKey, nil
+	})
+
+	// Not being able to fetch the keys says nothing about the token.
+	if errors.Is(keyErr, ErrUnavailable) {
+		return nil, keyErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	principal, err := v.verifyClaims(claims, service, time.Now())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v.revocation != nil && principal.TokenID != "" {
+		if err := v.revocation.check(ctx, tokenString, principal.TokenID, principal.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return principal, nil
+}
+
+// verifyClaims checks the registered claims of a validly signed ID token,
+// or access token of a machine client if service is set.
+func (v *Verifier) verifyClaims(claims *idTokenClaims, service bool, now time.Time) (*Principal, error) {
+	skew := int64(v.clockSkew / time.Second)
+	unixTime := now.Unix()
+
+	if claims.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer: %s", ErrInvalidToken, claims.Issuer)
+	}
+
+	if !claims.Audience.Contains(v.audience) {
+		return nil, fmt.Errorf("%w: unexpected audience: %v", ErrInvalidToken, claims.Audience)
+	}
+
+	// Tokens of OAuth clients only let the client know who the user is.
+	// Our APIs refuse them, even if they carry our audience.
+	if claims.AuthorizedParty != "" && claims.AuthorizedParty != v.audience {
+		return nil, fmt.Errorf("%w: token was issued to the OAuth client: %s", ErrInvalidToken, claims.AuthorizedParty)
+	}
+
+	if unixTime > claims.ExpiresAt+skew {
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	}
+
+	if claims.IssuedAt > unixTime+skew || claims.NotBefore > unixTime+skew {
+		return nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+
+	if service {
+		if claims.ClientID == "" || claims.ClientID != claims.Subject {
+			return nil, fmt.Errorf("%w: subject is not the client", ErrInvalidToken)
+		}
+
+		return &Principal{
+			ClientID:  claims.ClientID,
+			Scope:     claims.Scope,
+			Service:   true,
+			TokenID:   claims.Id,
+			ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+		}, nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject is not a valid user id", ErrInvalidToken)
+	}
+
+	return &Principal{
+		UserID:        userID,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		Website:       claims.Website,
+		AuthTime:      time.Unix(claims.AuthTime, 0),
+		ClientID:      claims.AuthorizedParty,
+		Scope:         claims.Scope,
+		TokenID:       claims.Id,
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// bearerToken extracts the token from an Authorization header
+// of the form "Bearer token".
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,427 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/jwa"
+	"github.com/yachnytskyi/base-go/account/model"
+)
+
+const (
+	testIssuer   = "http://localhost:8080/api/account"
+	testAudience = "base-go"
+)
+
+// testAccountService serves the key set and introspection
+// endpoints of the account service.
+type testAccountService struct {
+	mu      sync.Mutex
+	keys    []model.JSONWebKey
+	revoked map[string]bool
+	down    bool
+
+	keysFetches    int32
+	introspections int32
+
+	server *httptest.Server
+}
+
+func newTestAccountService(t *testing.T) *testAccountService {
+	s := &testAccountService{revoked: make(map[string]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.keysFetches, 1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		json.NewEncoder(w).Encode(&model.JSONWebKeySet{Keys: s.keys})
+	})
+	mux.HandleFunc("/oauth/introspect", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.introspections, 1)
+
+		clientID, clientSecret, ok := r.BasicAuth()
+
+		if !ok || clientID != "gateway" || clientSecret != "somegatewaysecret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		tokenString := r.PostFormValue("token")
+		claims := &jwt.StandardClaims{}
+		(&jwt.Parser{}).ParseUnverified(tokenString, claims)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		json.NewEncoder(w).Encode(&model.TokenIntrospection{Active: !s.revoked[claims.Id]})
+	})
+
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// addKey publishes the public key of a signer and returns its kid.
+func (s *testAccountService) addKey(t *testing.T, algorithm string, signer crypto.Signer) string {
+	jwk, err := jwa.PublicJWK(algorithm, signer.Public())
+	assert.NoError(t, err)
+
+	jwk.KeyID, err = jwa.Thumbprint(jwk)
+	assert.NoError(t, err)
+	jwk.Use = "sig"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = append(s.keys, *jwk)
+
+	return jwk.KeyID
+}
+
+func (s *testAccountService) config() *Config {
+	return &Config{
+		JWKSURL:  s.server.URL + "/.well-known/jwks.json",
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	}
+}
+
+func testClaims(userID uuid.UUID) *idTokenClaims {
+	now := time.Now()
+
+	return &idTokenClaims{
+		Email:    "bob@bob.com",
+		AuthTime: now.Unix(),
+		Name:     "Bobby Bobson",
+		Audience: model.Audience{testAudience},
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			Subject:   userID.String(),
+			Issuer:    testIssuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(15 * time.Minute).Unix(),
+		},
+	}
+}
+
+func signTestToken(t *testing.T, algorithm string, kid string, signer crypto.Signer, claims *idTokenClaims) string {
+	method, err := jwa.SigningMethod(algorithm)
+	assert.NoError(t, err)
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	tokenString, err := token.SignedString(signer)
+	assert.NoError(t, err)
+
+	return tokenString
+}
+
+func TestVerify(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	userID, _ := uuid.NewRandom()
+	ctx := context.Background()
+
+	t.Run("Valid token", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, err := NewVerifier(accountService.config())
+		assert.NoError(t, err)
+
+		claims := testClaims(userID)
+		tokenString := signTestToken(t, jwa.EdDSA, kid, edKey, claims)
+
+		principal, err := verifier.Verify(ctx, tokenString)
+		assert.NoError(t, err)
+		assert.Equal(t, userID, principal.UserID)
+		assert.Equal(t, "bob@bob.com", principal.Email)
+		assert.Equal(t, "Bobby Bobson", principal.Name)
+		assert.Equal(t, claims.Id, principal.TokenID)
+		assert.Equal(t, claims.AuthTime, principal.AuthTime.Unix())
+
+		// Keys are cached.
+		_, err = verifier.Verify(ctx, tokenString)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&accountService.keysFetches))
+	})
+
+	t.Run("Token of an OAuth client", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		config := accountService.config()
+		config.Audience = "grafana"
+		verifier, _ := NewVerifier(config)
+
+		claims := testClaims(userID)
+		claims.Audience = model.Audience{"grafana"}
+		claims.AuthorizedParty = "grafana"
+		claims.Scope = "openid profile"
+
+		principal, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, claims))
+		assert.NoError(t, err)
+		assert.Equal(t, "grafana", principal.ClientID)
+		assert.Equal(t, "openid profile", principal.Scope)
+	})
+
+	t.Run("Token of an OAuth client used on our APIs", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		// Even with our audience, which tokens of clients used to carry.
+		claims := testClaims(userID)
+		claims.Audience = model.Audience{"grafana", testAudience}
+		claims.AuthorizedParty = "grafana"
+		claims.Scope = "openid email"
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, claims))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Access token of a machine client", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		claims := testClaims(userID)
+		claims.Subject = "billing"
+		claims.ClientID = "billing"
+		claims.Scope = "users:read"
+
+		token := jwt.NewWithClaims(jwa.SigningMethodEdDSA, claims)
+		token.Header["kid"] = kid
+		token.Header["typ"] = serviceTokenType
+		tokenString, _ := token.SignedString(edKey)
+
+		principal, err := verifier.Verify(ctx, tokenString)
+		assert.NoError(t, err)
+		assert.True(t, principal.Service)
+		assert.Equal(t, uuid.Nil, principal.UserID)
+		assert.Equal(t, "billing", principal.ClientID)
+		assert.Equal(t, "users:read", principal.Scope)
+
+		// The subject must be the client.
+		claims.ClientID = ""
+		token = jwt.NewWithClaims(jwa.SigningMethodEdDSA, claims)
+		token.Header["kid"] = kid
+		token.Header["typ"] = serviceTokenType
+		tokenString, _ = token.SignedString(edKey)
+
+		_, err = verifier.Verify(ctx, tokenString)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Invalid claims", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		wrongAudience := testClaims(userID)
+		wrongAudience.Audience = model.Audience{"another-service"}
+
+		wrongIssuer := testClaims(userID)
+		wrongIssuer.Issuer = "http://evil.com"
+
+		expired := testClaims(userID)
+		expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+		invalidSubject := testClaims(userID)
+		invalidSubject.Subject = "bob"
+
+		for _, claims := range []*idTokenClaims{wrongAudience, wrongIssuer, expired, invalidSubject} {
+			_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, claims))
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		}
+	})
+
+	t.Run("Clock skew", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		config := accountService.config()
+		config.ClockSkew = time.Minute
+		verifier, _ := NewVerifier(config)
+
+		claims := testClaims(userID)
+		claims.ExpiresAt = time.Now().Add(-30 * time.Second).Unix()
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, claims))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Signed by an unknown key", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, otherKey, testClaims(userID)))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Algorithm of the key is enforced", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.ES256, ecKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		// Published as ES256 but signed with an alg header of ES384.
+		token := jwt.NewWithClaims(jwt.SigningMethodES384, testClaims(userID))
+		token.Header["kid"] = kid
+		tokenString, _ := token.SignedString(ecKey)
+
+		_, err := verifier.Verify(ctx, tokenString)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Refetches keys for an unknown kid after rotation", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, accountService.keys[0].KeyID, edKey, testClaims(userID)))
+		assert.NoError(t, err)
+
+		// The account service rotates to a new key.
+		newKID := accountService.addKey(t, jwa.ES256, ecKey)
+		// Pretend the keys were fetched long enough ago.
+		verifier.keys.fetchedAt = time.Now().Add(-minKeysRefreshInterval)
+
+		principal, err := verifier.Verify(ctx, signTestToken(t, jwa.ES256, newKID, ecKey, testClaims(userID)))
+		assert.NoError(t, err)
+		assert.Equal(t, userID, principal.UserID)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&accountService.keysFetches))
+	})
+
+	t.Run("Unknown kids don't refetch keys too often", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID)))
+		assert.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err = verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, "unknown", edKey, testClaims(userID)))
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		}
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&accountService.keysFetches))
+	})
+
+	t.Run("Key set unavailable", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+		accountService.down = true
+
+		verifier, _ := NewVerifier(accountService.config())
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID)))
+		assert.ErrorIs(t, err, ErrUnavailable)
+		assert.NotErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Known keys are used while the key set is unavailable", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		config := accountService.config()
+		config.KeysRefreshInterval = time.Millisecond
+		verifier, _ := NewVerifier(config)
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID)))
+		assert.NoError(t, err)
+
+		accountService.mu.Lock()
+		accountService.down = true
+		accountService.mu.Unlock()
+		time.Sleep(2 * time.Millisecond)
+
+		_, err = verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID)))
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&accountService.keysFetches))
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		config := accountService.config()
+		config.IntrospectionURL = accountService.server.URL + "/oauth/introspect"
+		config.ClientID = "gateway"
+		config.ClientSecret = "somegatewaysecret"
+		verifier, _ := NewVerifier(config)
+
+		activeClaims := testClaims(userID)
+		revokedClaims := testClaims(userID)
+		accountService.revoked[revokedClaims.Id] = true
+
+		activeToken := signTestToken(t, jwa.EdDSA, kid, edKey, activeClaims)
+		revokedToken := signTestToken(t, jwa.EdDSA, kid, edKey, revokedClaims)
+
+		_, err := verifier.Verify(ctx, activeToken)
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(ctx, revokedToken)
+		assert.ErrorIs(t, err, ErrRevokedToken)
+
+		// Both answers are cached.
+		_, err = verifier.Verify(ctx, activeToken)
+		assert.NoError(t, err)
+		_, err = verifier.Verify(ctx, revokedToken)
+		assert.ErrorIs(t, err, ErrRevokedToken)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&accountService.introspections))
+	})
+
+	t.Run("Introspection rejects the client", func(t *testing.T) {
+		accountService := newTestAccountService(t)
+		kid := accountService.addKey(t, jwa.EdDSA, edKey)
+
+		config := accountService.config()
+		config.IntrospectionURL = accountService.server.URL + "/oauth/introspect"
+		config.ClientID = "gateway"
+		config.ClientSecret = "wrongsecret"
+		verifier, _ := NewVerifier(config)
+
+		_, err := verifier.Verify(ctx, signTestToken(t, jwa.EdDSA, kid, edKey, testClaims(userID)))
+		assert.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	t.Run("Config is validated", func(t *testing.T) {
+		_, err := NewVerifier(&Config{JWKSURL: "http://localhost/jwks.json"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,83 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Flags of the authenticator data.
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackupState            byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+// authenticatorDataMinLength is the length of the RP ID hash, the flags and the sign count.
+const authenticatorDataMinLength = 32 + 1 + 4
+
+// AuthenticatorData is what the authenticator signs about itself.
+type AuthenticatorData struct {
+	RPIDHash           []byte
+	Flags              byte
+	SignCount          uint32
+	AttestedCredential *AttestedCredentialData // Only set on registration.
+}
+
+// AttestedCredentialData describes a new credential.
+type AttestedCredentialData struct {
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // The COSE key.
+}
+
+// ParseAuthenticatorData parses the binary authenticator data.
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < authenticatorDataMinLength {
+		return nil, fmt.Errorf("webauthn: the authenticator data is too short")
+	}
+
+	authenticatorData := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if authenticatorData.Flags&FlagAttestedCredentialData == 0 {
+		return authenticatorData, nil
+	}
+
+	rest := data[authenticatorDataMinLength:]
+
+	// The AAGUID is followed by the length of the credential ID.
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("webauthn: the attested credential data is too short")
+	}
+
+	credentialIDLength := int(binary.BigEndian.Uint16(rest[16:18]))
+
+	if len(rest) < 18+credentialIDLength {
+		return nil, fmt.Errorf("webauthn: the credential ID is cut off")
+	}
+
+	attested := &AttestedCredentialData{
+		AAGUID:       rest[:16],
+		CredentialID: rest[18 : 18+credentialIDLength],
+	}
+
+	// The COSE key is the next CBOR value. Extensions may follow it.
+	var publicKey codec.Raw
+
+	if err := codec.NewDecoderBytes(rest[18+credentialIDLength:], cborHandle).Decode(&publicKey); err != nil {
+		return nil, fmt.Errorf("webauthn: invalid credential public key: %w", err)
+	}
+
+	attested.PublicKey = publicKey
+	authenticatorData.AttestedCredential = attested
+
+	return authenticatorData, nil
+}
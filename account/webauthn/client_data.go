@@ -0,0 +1,28 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ClientData is the JSON the browser collects for a ceremony, which
+// the authenticator signs the hash of.
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"` // Base64url encoded without padding.
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// ParseClientData parses the client data JSON of a ceremony.
+// Callers look up the state of the ceremony by its challenge,
+// before they verify the response.
+func ParseClientData(clientDataJSON []byte) (*ClientData, error) {
+	clientData := &ClientData{}
+
+	if err := json.Unmarshal(clientDataJSON, clientData); err != nil {
+		return nil, fmt.Errorf("webauthn: invalid client data: %w", err)
+	}
+
+	return clientData, nil
+}
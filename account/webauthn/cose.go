@@ -0,0 +1,182 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+)
+
+// COSE algorithms of credential public keys, in the order they are preferred.
+const (
+	AlgorithmES256 int64 = -7   // ECDSA on the P-256 curve with SHA-256.
+	AlgorithmEdDSA int64 = -8   // Ed25519.
+	AlgorithmRS256 int64 = -257 // RSA PKCS #1 v1.5 with SHA-256, which Windows Hello uses.
+)
+
+// Algorithms lists the supported COSE algorithms, most preferred first.
+func Algorithms() []int64 {
+	return []int64{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+}
+
+// Parameters of COSE keys, as RFC 8152 numbers them.
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1 // The curve of EC2 and OKP keys, or the modulus of RSA keys.
+	coseX         = -2 // The x coordinate of EC2 and OKP keys, or the exponent of RSA keys.
+	coseY         = -3
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// PublicKey is a credential public key along with its algorithm.
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey parses a COSE key of a supported algorithm.
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	parameters := map[int64]interface{}{}
+
+	if err := codec.NewDecoderBytes(coseKey, cborHandle).Decode(&parameters); err != nil {
+		return nil, fmt.Errorf("webauthn: invalid COSE key: %w", err)
+	}
+
+	keyType, _ := coseInt(parameters[coseKeyType])
+	algorithm, _ := coseInt(parameters[coseAlgorithm])
+	curve, _ := coseInt(parameters[coseCurve])
+	x, _ := parameters[coseX].([]byte)
+	y, _ := parameters[coseY].([]byte)
+
+	switch {
+	case algorithm == AlgorithmES256 && keyType == coseKeyTypeEC2 && curve == coseCurveP256:
+		publicKey := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		if len(x) != 32 || len(y) != 32 || !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, fmt.Errorf("webauthn: the EC2 key is not on the P-256 curve")
+		}
+
+		return &PublicKey{Algorithm: algorithm, Key: publicKey}, nil
+
+	case algorithm == AlgorithmEdDSA && keyType == coseKeyTypeOKP && curve == coseCurveEd25519:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("webauthn: the OKP key is not an Ed25519 key")
+		}
+
+		return &PublicKey{Algorithm: algorithm, Key: ed25519.PublicKey(x)}, nil
+
+	case algorithm == AlgorithmRS256 && keyType == coseKeyTypeRSA:
+		modulus, _ := parameters[coseCurve].([]byte)
+		exponent := new(big.Int).SetBytes(x)
+
+		if len(modulus) < 256 || !exponent.IsInt64() || exponent.Int64() < 3 {
+			return nil, fmt.Errorf("webauthn: the RSA key is shorter than 2048 bits or invalid")
+		}
+
+		publicKey := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(exponent.Int64()),
+		}
+
+		return &PublicKey{Algorithm: algorithm, Key: publicKey}, nil
+	}
+
+	return nil, fmt.Errorf("webauthn: unsupported COSE key of type %d and algorithm %d", keyType, algorithm)
+}
+
+// Verify verifies the signature of the data.
+func (k *PublicKey) Verify(data []byte, signature []byte) error {
+	digest := sha256.Sum256(data)
+	valid := false
+
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		// Authenticators sign with ASN.1 DER encoded ECDSA signatures.
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", ErrVerification)
+	}
+
+	return nil
+}
+
+// MarshalPublicKey encodes a public key as a COSE key,
+// the way authenticators do.
+func MarshalPublicKey(publicKey crypto.PublicKey) ([]byte, error) {
+	var parameters map[int64]interface{}
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("webauthn: only P-256 EC keys are supported")
+		}
+
+		parameters = map[int64]interface{}{
+			coseKeyType:   coseKeyTypeEC2,
+			coseAlgorithm: AlgorithmES256,
+			coseCurve:     coseCurveP256,
+			coseX:         key.X.FillBytes(make([]byte, 32)),
+			coseY:         key.Y.FillBytes(make([]byte, 32)),
+		}
+	case ed25519.PublicKey:
+		parameters = map[int64]interface{}{
+			coseKeyType:   coseKeyTypeOKP,
+			coseAlgorithm: AlgorithmEdDSA,
+			coseCurve:     coseCurveEd25519,
+			coseX:         []byte(key),
+		}
+	case *rsa.PublicKey:
+		parameters = map[int64]interface{}{
+			coseKeyType:   coseKeyTypeRSA,
+			coseAlgorithm: AlgorithmRS256,
+			coseCurve:     key.N.Bytes(),
+			coseX:         big.NewInt(int64(key.E)).Bytes(),
+		}
+	default:
+		return nil, fmt.Errorf("webauthn: unsupported public key type %T", publicKey)
+	}
+
+	var coseKey []byte
+
+	if err := codec.NewEncoderBytes(&coseKey, cborHandle).Encode(parameters); err != nil {
+		return nil, err
+	}
+
+	return coseKey, nil
+}
+
+// coseInt reads an integer parameter, which CBOR decodes
+// as unsigned when it is positive.
+func coseInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,206 @@
+// Package webauthn verifies the registration and authentication ceremonies
+// of Web Authentication, which let users sign in with passkeys and security
+// keys. Only what passkeys need is supported: attestation statements are not
+// verified, since we ask for none and don't trust authenticators by model.
+// The service layer keeps the challenges and credentials, and this package
+// checks what authenticators sign.
+package webauthn
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Types of the client data of the ceremonies.
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// ErrVerification is wrapped by the errors of ceremonies which
+// don't verify, as opposed to responses which can't be parsed.
+var ErrVerification = errors.New("webauthn: verification failed")
+
+// cborHandle decodes the CBOR of attestation objects and COSE keys.
+var cborHandle = &codec.CborHandle{}
+
+// RelyingParty is the site credentials are scoped to. The ID is its
+// domain, which authenticators hash into what they sign, and the origins
+// are the pages allowed to run the ceremonies, like https://example.com.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// Credential is a public key credential an authenticator created.
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // The COSE key, which is stored as it is.
+	Algorithm      int64
+	SignCount      uint32
+	AAGUID         []byte // Identifies the model of the authenticator.
+	UserVerified   bool
+	BackupEligible bool // Passkeys which sync between devices are eligible.
+}
+
+// Assertion is what the authenticator data of a verified assertion tells.
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+// attestationObject is the CBOR an authenticator returns on registration.
+type attestationObject struct {
+	Format       string                 `codec:"fmt"`
+	AuthData     []byte                 `codec:"authData"`
+	AttStatement map[string]interface{} `codec:"attStmt"`
+}
+
+// VerifyRegistration verifies the response to a registration ceremony of the
+// challenge, and returns the new credential. Registrations for passkeys
+// should require user verification, so the passkey alone signs the user in.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON []byte, attestation []byte, requireUserVerification bool) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	object := &attestationObject{}
+
+	if err := codec.NewDecoderBytes(attestation, cborHandle).Decode(object); err != nil {
+		return nil, fmt.Errorf("webauthn: invalid attestation object: %w", err)
+	}
+
+	authenticatorData, err := ParseAuthenticatorData(object.AuthData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authenticatorData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	attested := authenticatorData.AttestedCredential
+
+	if attested == nil {
+		return nil, fmt.Errorf("%w: the authenticator data holds no credential", ErrVerification)
+	}
+
+	publicKey, err := ParsePublicKey(attested.PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             attested.CredentialID,
+		PublicKey:      attested.PublicKey,
+		Algorithm:      publicKey.Algorithm,
+		SignCount:      authenticatorData.SignCount,
+		AAGUID:         attested.AAGUID,
+		UserVerified:   authenticatorData.Flags&FlagUserVerified != 0,
+		BackupEligible: authenticatorData.Flags&FlagBackupEligible != 0,
+	}, nil
+}
+
+// VerifyAssertion verifies the response to an authentication ceremony of the
+// challenge, which the credential with the COSE public key signed.
+// Checking the sign count against the stored one is up to the caller.
+func (rp *RelyingParty) VerifyAssertion(challenge string, clientDataJSON []byte, authenticatorDataBytes []byte, signature []byte, credentialPublicKey []byte, requireUserVerification bool) (*Assertion, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := ParseAuthenticatorData(authenticatorDataBytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authenticatorData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := ParsePublicKey(credentialPublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the authenticator data and the hash of the client data.
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authenticatorDataBytes...), clientDataHash[:]...)
+
+	if err := publicKey.Verify(signed, signature); err != nil {
+		return nil, err
+	}
+
+	return &Assertion{
+		SignCount:    authenticatorData.SignCount,
+		UserVerified: authenticatorData.Flags&FlagUserVerified != 0,
+	}, nil
+}
+
+// verifyClientData checks the client data was collected for the ceremony
+// of the challenge on one of our origins.
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge string) error {
+	clientData, err := ParseClientData(clientDataJSON)
+
+	if err != nil {
+		return err
+	}
+
+	if clientData.Type != ceremony {
+		return fmt.Errorf("%w: the client data is of the %q ceremony", ErrVerification, clientData.Type)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return fmt.Errorf("%w: the challenge does not match", ErrVerification)
+	}
+
+	if !contains(rp.Origins, clientData.Origin) {
+		return fmt.Errorf("%w: the origin %q is not allowed", ErrVerification, clientData.Origin)
+	}
+
+	if clientData.CrossOrigin {
+		return fmt.Errorf("%w: the ceremony ran in a cross origin frame", ErrVerification)
+	}
+
+	return nil
+}
+
+// verifyAuthenticatorData checks the authenticator data is scoped to us
+// and the user was present, and verified if that is required.
+func (rp *RelyingParty) verifyAuthenticatorData(authenticatorData *AuthenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+
+	if subtle.ConstantTimeCompare(authenticatorData.RPIDHash, rpIDHash[:]) != 1 {
+		return fmt.Errorf("%w: the credential is scoped to another relying party", ErrVerification)
+	}
+
+	if authenticatorData.Flags&FlagUserPresent == 0 {
+		return fmt.Errorf("%w: the user was not present", ErrVerification)
+	}
+
+	if requireUserVerification && authenticatorData.Flags&FlagUserVerified == 0 {
+		return fmt.Errorf("%w: the user was not verified", ErrVerification)
+	}
+
+	return nil
+}
+
+// contains reports whether the values hold the value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,190 @@
+package webauthn_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/webauthn"
+	"github.com/yachnytskyi/base-go/account/webauthn/webauthntest"
+)
+
+func decode(t *testing.T, value string) []byte {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	assert.NoError(t, err)
+
+	return decoded
+}
+
+func TestCeremonies(t *testing.T) {
+	rp := &webauthn.RelyingParty{
+		ID:      "localhost",
+		Name:    "base-go",
+		Origins: []string{"http://localhost:8080"},
+	}
+
+	creationOptions := &model.PasskeyCreationOptions{
+		Challenge:    "cmVnaXN0cmF0aW9uY2hhbGxlbmdl",
+		RelyingParty: model.PasskeyRelyingParty{ID: rp.ID, Name: rp.Name},
+		User:         model.PasskeyUser{ID: "dXNlcg", Name: "kostya@kostya.com"},
+	}
+
+	requestOptions := &model.PasskeyRequestOptions{
+		Challenge:      "YXV0aGVudGljYXRpb25jaGFsbGVuZ2U",
+		RelyingPartyID: rp.ID,
+	}
+
+	authenticator := webauthntest.NewAuthenticator("http://localhost:8080")
+	authenticator.CountSignatures = true
+
+	registration, err := authenticator.Create(creationOptions)
+	assert.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(
+		creationOptions.Challenge,
+		decode(t, registration.Response.ClientDataJSON),
+		decode(t, registration.Response.AttestationObject),
+		true,
+	)
+
+	t.Run("Registers a credential", func(t *testing.T) {
+		assert.NoError(t, err)
+		assert.Equal(t, decode(t, registration.ID), credential.ID)
+		assert.Equal(t, webauthn.AlgorithmES256, credential.Algorithm)
+		assert.Equal(t, uint32(1), credential.SignCount)
+		assert.True(t, credential.UserVerified)
+		assert.True(t, credential.BackupEligible)
+	})
+
+	t.Run("Verifies an assertion", func(t *testing.T) {
+		assertion, err := authenticator.Get(requestOptions)
+		assert.NoError(t, err)
+		assert.Equal(t, registration.ID, assertion.ID)
+		assert.Equal(t, "dXNlcg", assertion.Response.UserHandle)
+
+		verified, err := rp.VerifyAssertion(
+			requestOptions.Challenge,
+			decode(t, assertion.Response.ClientDataJSON),
+			decode(t, assertion.Response.AuthenticatorData),
+			decode(t, assertion.Response.Signature),
+			credential.PublicKey,
+			true,
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(2), verified.SignCount)
+		assert.True(t, verified.UserVerified)
+	})
+
+	t.Run("Rejects responses which don't verify", func(t *testing.T) {
+		assertion, err := authenticator.Get(requestOptions)
+		assert.NoError(t, err)
+
+		clientDataJSON := decode(t, assertion.Response.ClientDataJSON)
+		authenticatorData := decode(t, assertion.Response.AuthenticatorData)
+		signature := decode(t, assertion.Response.Signature)
+
+		otherOrigin := &webauthn.RelyingParty{ID: rp.ID, Origins: []string{"https://evil.com"}}
+		otherID := &webauthn.RelyingParty{ID: "evil.com", Origins: rp.Origins}
+
+		tampered := append([]byte{}, authenticatorData...)
+		tampered[len(tampered)-1]++
+
+		cases := map[string]func() error{
+			"Another challenge": func() error {
+				_, err := rp.VerifyAssertion("b3RoZXI", clientDataJSON, authenticatorData, signature, credential.PublicKey, true)
+				return err
+			},
+			"Another origin": func() error {
+				_, err := otherOrigin.VerifyAssertion(requestOptions.Challenge, clientDataJSON, authenticatorData, signature, credential.PublicKey, true)
+				return err
+			},
+			"Another relying party": func() error {
+				_, err := otherID.VerifyAssertion(requestOptions.Challenge, clientDataJSON, authenticatorData, signature, credential.PublicKey, true)
+				return err
+			},
+			"Tampered authenticator data": func() error {
+				_, err := rp.VerifyAssertion(requestOptions.Challenge, clientDataJSON, tampered, signature, credential.PublicKey, true)
+				return err
+			},
+			"A registration response": func() error {
+				_, err := rp.VerifyAssertion(creationOptions.Challenge, decode(t, registration.Response.ClientDataJSON), authenticatorData, signature, credential.PublicKey, true)
+				return err
+			},
+		}
+
+		for name, verify := range cases {
+			assert.ErrorIs(t, verify(), webauthn.ErrVerification, name)
+		}
+	})
+
+	t.Run("Requires user verification", func(t *testing.T) {
+		securityKey := webauthntest.NewAuthenticator("http://localhost:8080")
+		securityKey.SkipUserVerification = true
+
+		registration, err := securityKey.Create(creationOptions)
+		assert.NoError(t, err)
+
+		clientDataJSON := decode(t, registration.Response.ClientDataJSON)
+		attestationObject := decode(t, registration.Response.AttestationObject)
+
+		_, err = rp.VerifyRegistration(creationOptions.Challenge, clientDataJSON, attestationObject, true)
+		assert.ErrorIs(t, err, webauthn.ErrVerification)
+
+		credential, err := rp.VerifyRegistration(creationOptions.Challenge, clientDataJSON, attestationObject, false)
+		assert.NoError(t, err)
+		assert.False(t, credential.UserVerified)
+	})
+}
+
+func TestPublicKey(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	data := []byte("authenticator data and client data hash")
+	digest := sha256.Sum256(data)
+
+	rsaSignature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+
+	cases := map[int64]struct {
+		key       crypto.Signer
+		signature []byte
+	}{
+		webauthn.AlgorithmRS256: {rsaKey, rsaSignature},
+		webauthn.AlgorithmES256: {ecKey, ecSignature},
+		webauthn.AlgorithmEdDSA: {edKey, ed25519.Sign(edKey, data)},
+	}
+
+	for algorithm, c := range cases {
+		coseKey, err := webauthn.MarshalPublicKey(c.key.Public())
+		assert.NoError(t, err)
+
+		publicKey, err := webauthn.ParsePublicKey(coseKey)
+		assert.NoError(t, err, algorithm)
+		assert.Equal(t, algorithm, publicKey.Algorithm)
+		assert.Equal(t, c.key.Public(), publicKey.Key, algorithm)
+
+		assert.NoError(t, publicKey.Verify(data, c.signature), algorithm)
+		assert.ErrorIs(t, publicKey.Verify([]byte("other data"), c.signature), webauthn.ErrVerification, algorithm)
+	}
+
+	t.Run("Rejects short RSA keys", func(t *testing.T) {
+		shortKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+
+		coseKey, err := webauthn.MarshalPublicKey(&shortKey.PublicKey)
+		assert.NoError(t, err)
+
+		_, err = webauthn.ParsePublicKey(coseKey)
+		assert.Error(t, err)
+	})
+}
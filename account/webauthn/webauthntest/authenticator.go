@@ -0,0 +1,248 @@
+// Package webauthntest provides a software authenticator, which answers
+// the ceremonies of Web Authentication in tests the way a browser and
+// a passkey provider would.
+package webauthntest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+	"github.com/yachnytskyi/base-go/account/model"
+	"github.com/yachnytskyi/base-go/account/webauthn"
+)
+
+// Authenticator creates ES256 credentials and signs with them.
+// Credentials are discoverable, so they can be used without
+// the site naming them.
+type Authenticator struct {
+	Origin string // The origin of the page the ceremonies run on.
+	AAGUID []byte
+	// CountSignatures makes credentials count their signatures
+	// like security keys do. Synced passkeys always report zero.
+	CountSignatures bool
+	// SkipUserVerification makes the authenticator only
+	// test the presence of the user, like a key without a PIN.
+	SkipUserVerification bool
+
+	credentials []*credential
+}
+
+// credential is a key pair of the authenticator.
+type credential struct {
+	id         []byte
+	rpID       string
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+// NewAuthenticator returns an authenticator, which runs ceremonies on the origin.
+func NewAuthenticator(origin string) *Authenticator {
+	return &Authenticator{
+		Origin: origin,
+		AAGUID: make([]byte, 16),
+	}
+}
+
+// Clone returns an authenticator with copies of the credentials,
+// like an attacker who extracted the keys would have.
+func (a *Authenticator) Clone() *Authenticator {
+	clone := *a
+	clone.credentials = nil
+
+	for _, c := range a.credentials {
+		copied := *c
+		clone.credentials = append(clone.credentials, &copied)
+	}
+
+	return &clone
+}
+
+// Create answers navigator.credentials.create with a new credential.
+func (a *Authenticator) Create(options *model.PasskeyCreationOptions) (*model.PasskeyCredential, error) {
+	rpID := options.RelyingParty.ID
+
+	userHandle, err := base64.RawURLEncoding.DecodeString(options.User.ID)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid user handle: %w", err)
+	}
+
+	for _, excluded := range options.ExcludeCredentials {
+		if a.find(rpID, excluded.ID) != nil {
+			return nil, fmt.Errorf("the authenticator already holds a credential of the user")
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 32)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	c := &credential{id: id, rpID: rpID, userHandle: userHandle, key: key}
+	a.credentials = append(a.credentials, c)
+
+	publicKey, err := webauthn.MarshalPublicKey(&key.PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Attested credential data: the AAGUID, the length of the ID, the ID and the key.
+	idLength := make([]byte, 2)
+	binary.BigEndian.PutUint16(idLength, uint16(len(id)))
+
+	attested := append([]byte{}, a.AAGUID...)
+	attested = append(attested, idLength...)
+	attested = append(attested, id...)
+	attested = append(attested, publicKey...)
+
+	authenticatorData := a.authenticatorData(c, webauthn.FlagAttestedCredentialData, attested)
+
+	var attestationObject []byte
+	err = codec.NewEncoderBytes(&attestationObject, &codec.CborHandle{}).Encode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authenticatorData,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.create", options.Challenge)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PasskeyCredential{
+		ID:   base64.RawURLEncoding.EncodeToString(id),
+		Type: "public-key",
+		Response: model.PasskeyCredentialResponse{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Get answers navigator.credentials.get with a credential of the site,
+// which the options allow. Any credential of the site is allowed
+// when the options allow none.
+func (a *Authenticator) Get(options *model.PasskeyRequestOptions) (*model.PasskeyCredential, error) {
+	var c *credential
+
+	if len(options.AllowCredentials) == 0 {
+		for _, candidate := range a.credentials {
+			if candidate.rpID == options.RelyingPartyID {
+				c = candidate
+				break
+			}
+		}
+	}
+
+	for _, allowed := range options.AllowCredentials {
+		if c = a.find(options.RelyingPartyID, allowed.ID); c != nil {
+			break
+		}
+	}
+
+	if c == nil {
+		return nil, fmt.Errorf("the authenticator holds no allowed credential")
+	}
+
+	authenticatorData := a.authenticatorData(c, 0, nil)
+
+	clientDataJSON, err := a.clientData("webauthn.get", options.Challenge)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, c.key, digest[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.PasskeyCredential{
+		ID:   base64.RawURLEncoding.EncodeToString(c.id),
+		Type: "public-key",
+		Response: model.PasskeyCredentialResponse{
+			ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			AuthenticatorData: base64.RawURLEncoding.EncodeToString(authenticatorData),
+			Signature:         base64.RawURLEncoding.EncodeToString(signature),
+			UserHandle:        base64.RawURLEncoding.EncodeToString(c.userHandle),
+		},
+	}, nil
+}
+
+// find returns the credential of the site with the base64url encoded ID.
+func (a *Authenticator) find(rpID string, id string) *credential {
+	decoded, err := base64.RawURLEncoding.DecodeString(id)
+
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range a.credentials {
+		if c.rpID == rpID && bytes.Equal(c.id, decoded) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// authenticatorData returns the authenticator data of a signature
+// of the credential, which counts the signature.
+func (a *Authenticator) authenticatorData(c *credential, flags byte, attested []byte) []byte {
+	flags |= webauthn.FlagUserPresent | webauthn.FlagBackupEligible | webauthn.FlagBackupState
+
+	if !a.SkipUserVerification {
+		flags |= webauthn.FlagUserVerified
+	}
+
+	if a.CountSignatures {
+		c.signCount++
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.rpID))
+
+	signCount := make([]byte, 4)
+	binary.BigEndian.PutUint32(signCount, c.signCount)
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = append(data, signCount...)
+
+	return append(data, attested...)
+}
+
+// clientData returns the client data JSON a browser collects for the ceremony.
+func (a *Authenticator) clientData(ceremony string, challenge string) ([]byte, error) {
+	return json.Marshal(&webauthn.ClientData{
+		Type:      ceremony,
+		Challenge: challenge,
+		Origin:    a.Origin,
+	})
+}